@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"log"
 	"os"
 	"os/signal"
@@ -36,7 +37,39 @@ func main() {
 	questRepo := repository.NewQuestRepository(db)
 	sceneRepo := repository.NewSceneRepository(db)
 	locRepo := repository.NewLocationRepository(db)
-	vectorRepo := repository.NewVectorRepository(db)
+	itemRepo := repository.NewItemRepository(db)
+	shopRepo := repository.NewShopRepository(db)
+	ledgerRepo := repository.NewLedgerRepository(db)
+	tradeRepo := repository.NewTradeRepository(db)
+	repRepo := repository.NewReputationRepository(db)
+	clockRepo := repository.NewClockRepository(db)
+	sheetRepo := repository.NewSheetRepository(db)
+	vectorEnc, err := repository.ParseVectorEncoding(cfg.VectorEncoding)
+	if err != nil {
+		log.Fatalf("vector encoding: %v", err)
+	}
+	embedder, err := embeddings.NewEmbedder(embeddings.Options{
+		Provider:  cfg.EmbeddingProvider,
+		Model:     cfg.EmbeddingModel,
+		Dims:      cfg.EmbeddingDims,
+		BaseURL:   cfg.EmbeddingURL,
+		APIKey:    cfg.EmbeddingKey,
+		RateLimit: cfg.EmbeddingRPM,
+	})
+	if err != nil {
+		log.Fatalf("embeddings init failed: %v", err)
+	}
+	vectorRepo, err := repository.OpenVectorRepository(context.Background(), db, repository.VectorOptions{
+		Backend:     cfg.VectorBackend,
+		PostgresDSN: cfg.PGVectorDSN,
+		IndexPath:   cfg.VectorIndexPath,
+		Encoding:    vectorEnc,
+		Model:       embedder.Model(),
+		Dim:         embedder.Dims(),
+	})
+	if err != nil {
+		log.Fatalf("vector store init failed: %v", err)
+	}
 
 	// Lore
 	loreRepo, err := lore.NewFileLoreRepo("lore")
@@ -44,6 +77,41 @@ func main() {
 		log.Printf("lore init failed: %v", err)
 	}
 
+	shops, err := lore.LoadShops("lore")
+	if err != nil {
+		log.Printf("shops init failed: %v", err)
+	}
+
+	progression, err := lore.LoadProgression("lore")
+	if err != nil {
+		log.Printf("progression init failed: %v", err)
+	}
+
+	factions, err := lore.LoadFactions("lore")
+	if err != nil {
+		log.Printf("factions init failed: %v", err)
+	}
+
+	worldMap, err := lore.LoadWorldMap("lore")
+	if err != nil {
+		log.Printf("world map init failed: %v", err)
+	}
+
+	calendar, err := lore.LoadCalendar("lore")
+	if err != nil {
+		log.Printf("calendar init failed: %v", err)
+	}
+
+	effectBook, err := lore.LoadEffects("lore")
+	if err != nil {
+		log.Printf("effects init failed: %v", err)
+	}
+
+	creationBook, err := lore.LoadCreation("lore")
+	if err != nil {
+		log.Printf("creation rules init failed: %v", err)
+	}
+
 	// LLM Client
 	var llmClient llm.Client
 	if cfg.LLMProvider == "openai" {
@@ -53,8 +121,14 @@ func main() {
 	}
 
 	// RAG
-	embedder := embeddings.NewGeminiEmbedder(cfg.GeminiKey)
 	ragService := rag.NewService(embedder, vectorRepo, loreRepo)
+	ragService.SetEmbedWorkers(cfg.EmbeddingWorkers)
+	ragService.SetWorldMap(worldMap)
+	if stats, err := ragService.GetStats(context.Background()); err == nil && stats.TotalDocuments == 0 {
+		for model, n := range stats.Models {
+			log.Printf("⚠️ no lore vectors for %s, but %d from %s: run cmd/reembed or cmd/indexer", embedder.Model(), n, model)
+		}
+	}
 	if gc, ok := llmClient.(*llm.GeminiClient); ok {
 		gc.SetRAGService(ragService)
 		log.Println("✅ RAG enabled")
@@ -64,14 +138,55 @@ func main() {
 	vkAPI := api.NewVK(cfg.VKToken)
 
 	// Services
-	charService := service.NewCharacterService(charRepo)
+	effectService := service.NewEffectService(charRepo, effectBook)
+	clockService := service.NewClockService(clockRepo, calendar, charRepo, questRepo, effectService)
+	if err := clockService.Load(context.Background()); err != nil {
+		log.Printf("world clock load failed: %v", err)
+	}
+	lifeService := service.NewLifeService(charRepo, questRepo)
+	clockService.SetLife(lifeService)
+	inventoryService := service.NewInventoryService(itemRepo, charRepo)
+	inventoryService.SetEffects(effectService)
+	inventoryService.SetClock(clockService)
+	if n, err := inventoryService.ImportLegacy(context.Background()); err != nil {
+		log.Printf("legacy inventory import failed: %v", err)
+	} else if n > 0 {
+		log.Printf("imported legacy inventory of %d characters", n)
+	}
+	charService := service.NewCharacterService(charRepo, inventoryService)
+	charService.SetMaxCharacters(cfg.MaxCharacters)
+	sheetService := service.NewSheetService(sheetRepo, charService)
+	creationService := service.NewCreationService(creationBook, charService, sheetService)
+	econService := service.NewEconomyService(shops, shopRepo, inventoryService)
 	questService := service.NewQuestService(questRepo)
+	questService.SetClock(clockService)
 	sceneService := service.NewSceneService(sceneRepo)
+	sceneService.SetClock(clockService)
 	locService := service.NewLocationService(locRepo)
-	gmService := service.NewGMService(cfg, sceneService, charService, llmClient, vkAPI, db)
+	locService.SetIndexer(ragService)
+	if err := locService.SyncMap(context.Background(), worldMap); err != nil {
+		log.Printf("world map sync failed: %v", err)
+	}
+	ledgerService := service.NewLedgerService(ledgerRepo)
+	tradeService := service.NewTradeService(tradeRepo)
+	repService := service.NewReputationService(repRepo, factions)
+	if err := repService.SyncFactions(context.Background()); err != nil {
+		log.Printf("factions sync failed: %v", err)
+	}
+	charService.SetReputationService(repService)
+	econService.SetReputationSource(repService)
+	econService.SetClock(clockService)
+	questService.SetFactions(repService)
+	progService := service.NewProgressionService(charRepo, progression)
+	rewardService := service.NewRewardService(ledgerService, inventoryService, progService, repService)
+	combatService := service.NewCombatService(llmClient, charService, rewardService)
+	combatService.SetLife(lifeService)
+	travelService := service.NewTravelService(locService, sceneService, charRepo, combatService, worldMap)
+	gmService := service.NewGMService(cfg, sceneService, charService, llmClient, vkAPI, db, ledgerService, progService, repService, locService, clockService, effectService, lifeService, sheetService)
+	gmService.SetCombat(combatService)
 
 	// Handler
-	handler := vk.NewHandler(cfg, vkAPI, llmClient, charService, questService, sceneService, locService, gmService)
+	handler := vk.NewHandler(cfg, vkAPI, llmClient, charService, questService, sceneService, locService, gmService, inventoryService, econService, tradeService, combatService, rewardService, repService, travelService, clockService, lifeService, creationService, sheetService)
 
 	// LongPoll
 	lp, err := longpoll.NewLongPoll(vkAPI, cfg.VKGroupID)
@@ -92,4 +207,7 @@ func main() {
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 	log.Println("Shutdown...")
+	if err := vectorRepo.Flush(context.Background()); err != nil {
+		log.Printf("vector index flush failed: %v", err)
+	}
 }
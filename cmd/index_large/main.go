@@ -2,6 +2,8 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"log"
@@ -22,6 +24,7 @@ func main() {
 	zonePtr := flag.String("zone", "extended_lore", "Zone/category for documents")
 	tagsPtr := flag.String("tags", "", "Comma-separated tags")
 	strategyPtr := flag.String("strategy", "auto", "Chunking strategy: auto, hierarchical, paragraph, sentence")
+	failedPtr := flag.String("failed", "index_failed.jsonl", "File for chunks that failed to embed (rerun the same command to retry them)")
 
 	flag.Parse()
 
@@ -48,26 +51,64 @@ func main() {
 		log.Printf("⚠️  Failed to load lore repo: %v", err)
 	}
 
-	embedder := embeddings.NewGeminiEmbedder(cfg.GeminiKey)
-	vectorRepo := repository.NewVectorRepository(db)
+	ctx := context.Background()
+
+	embedder, err := embeddings.NewEmbedder(embeddings.Options{
+		Provider:  cfg.EmbeddingProvider,
+		Model:     cfg.EmbeddingModel,
+		Dims:      cfg.EmbeddingDims,
+		BaseURL:   cfg.EmbeddingURL,
+		APIKey:    cfg.EmbeddingKey,
+		RateLimit: cfg.EmbeddingRPM,
+	})
+	if err != nil {
+		log.Fatalf("❌ Failed to init embeddings: %v", err)
+	}
+	vectorEnc, err := repository.ParseVectorEncoding(cfg.VectorEncoding)
+	if err != nil {
+		log.Fatalf("❌ Invalid VECTOR_ENCODING: %v", err)
+	}
+	vectorRepo, err := repository.OpenVectorRepository(ctx, db, repository.VectorOptions{
+		Backend:     cfg.VectorBackend,
+		PostgresDSN: cfg.PGVectorDSN,
+		IndexPath:   cfg.VectorIndexPath,
+		Encoding:    vectorEnc,
+		Model:       embedder.Model(),
+		Dim:         embedder.Dims(),
+	})
+	if err != nil {
+		log.Fatalf("❌ Failed to open vector store: %v", err)
+	}
 	ragService := rag.NewService(embedder, vectorRepo, loreRepo)
+	ragService.SetEmbedWorkers(cfg.EmbeddingWorkers)
+	ragService.SetProgress(logProgress)
 
 	log.Println("✅ Initialized RAG service")
 
-	ctx := context.Background()
-
 	tags := parseTags(*tagsPtr)
 	strategy := parseStrategy(*strategyPtr)
 
+	var failed []rag.FailedChunk
 	if *filePtr != "" {
-		if err := indexFile(ctx, ragService, *filePtr, *zonePtr, tags, strategy); err != nil {
+		failed, err = indexFile(ctx, ragService, *filePtr, *zonePtr, tags, strategy)
+		if err != nil {
 			log.Fatalf("❌ Failed to index file: %v", err)
 		}
 	} else if *dirPtr != "" {
-		if err := indexDirectory(ctx, ragService, *dirPtr, *zonePtr, tags, strategy); err != nil {
+		failed, err = indexDirectory(ctx, ragService, *dirPtr, *zonePtr, tags, strategy)
+		if err != nil {
 			log.Fatalf("❌ Failed to index directory: %v", err)
 		}
 	}
+	if err := writeFailed(*failedPtr, failed); err != nil {
+		log.Printf("⚠️  Failed to write %s: %v", *failedPtr, err)
+	} else if len(failed) > 0 {
+		log.Printf("⚠️  %d chunks failed to embed, listed in %s; run the same command again to retry them", len(failed), *failedPtr)
+	}
+
+	if err := vectorRepo.Flush(ctx); err != nil {
+		log.Printf("⚠️  Failed to save vector index: %v", err)
+	}
 
 	stats, err := ragService.GetStats(ctx)
 	if err != nil {
@@ -84,10 +125,12 @@ func main() {
 	log.Println("\n🎉 Indexing complete!")
 }
 
-func indexFile(ctx context.Context, ragService *rag.Service, filePath, zone string, tags []string, strategy rag.ChunkingStrategy) error {
+// indexFile возвращает фрагменты, эмбеддинг которых не посчитался; уже
+// проиндексированные фрагменты при повторном запуске пропускаются.
+func indexFile(ctx context.Context, ragService *rag.Service, filePath, zone string, tags []string, strategy rag.ChunkingStrategy) ([]rag.FailedChunk, error) {
 	content, err := os.ReadFile(filePath)
 	if err != nil {
-		return fmt.Errorf("read file: %w", err)
+		return nil, fmt.Errorf("read file: %w", err)
 	}
 
 	filename := filepath.Base(filePath)
@@ -104,15 +147,20 @@ func indexFile(ctx context.Context, ragService *rag.Service, filePath, zone stri
 		err = ragService.IndexLargeDocument(ctx, docID, title, string(content), zone, tags, strategy)
 	}
 
+	var failures *rag.EmbedFailures
+	if errors.As(err, &failures) {
+		log.Printf("   ⚠️  Indexed with %d failed chunks", len(failures.Failed))
+		return failures.Failed, nil
+	}
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	log.Println("   ✅ Indexed successfully")
-	return nil
+	return nil, nil
 }
 
-func indexDirectory(ctx context.Context, ragService *rag.Service, dirPath, zone string, tags []string, strategy rag.ChunkingStrategy) error {
+func indexDirectory(ctx context.Context, ragService *rag.Service, dirPath, zone string, tags []string, strategy rag.ChunkingStrategy) ([]rag.FailedChunk, error) {
 	extensions := []string{".txt", ".md", ".markdown"}
 	var files []string
 
@@ -126,19 +174,49 @@ func indexDirectory(ctx context.Context, ragService *rag.Service, dirPath, zone
 	}
 
 	if len(files) == 0 {
-		return fmt.Errorf("no files found in %s", dirPath)
+		return nil, fmt.Errorf("no files found in %s", dirPath)
 	}
 
 	log.Printf("📁 Found %d files in %s\n", len(files), dirPath)
 
+	var failed []rag.FailedChunk
 	for i, file := range files {
 		log.Printf("\n[%d/%d] ", i+1, len(files))
-		if err := indexFile(ctx, ragService, file, zone, tags, strategy); err != nil {
+		fileFailed, err := indexFile(ctx, ragService, file, zone, tags, strategy)
+		if err != nil {
 			log.Printf("   ⚠️  Error: %v", err)
 		}
+		failed = append(failed, fileFailed...)
+	}
+
+	return failed, nil
+}
+
+// writeFailed пишет список неудавшихся фрагментов построчно в JSON; без
+// неудач убирает файл от прошлого запуска.
+func writeFailed(path string, failed []rag.FailedChunk) error {
+	if len(failed) == 0 {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
 	}
+	enc := json.NewEncoder(f)
+	for _, chunk := range failed {
+		if err := enc.Encode(chunk); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	return f.Close()
+}
 
-	return nil
+func logProgress(done, total int) {
+	log.Printf("   embedded %d/%d", done, total)
 }
 
 func parseTags(tagsStr string) []string {
@@ -36,28 +36,62 @@ func main() {
 	}
 	log.Println("✅ Loaded lore repository")
 
-	embedder := embeddings.NewGeminiEmbedder(cfg.GeminiKey)
-	log.Println("✅ Initialized embedding service")
+	embedder, err := embeddings.NewEmbedder(embeddings.Options{
+		Provider:  cfg.EmbeddingProvider,
+		Model:     cfg.EmbeddingModel,
+		Dims:      cfg.EmbeddingDims,
+		BaseURL:   cfg.EmbeddingURL,
+		APIKey:    cfg.EmbeddingKey,
+		RateLimit: cfg.EmbeddingRPM,
+	})
+	if err != nil {
+		log.Fatalf("❌ Failed to init embeddings: %v", err)
+	}
+	log.Printf("✅ Initialized embedding service: %s %s (%d)", cfg.EmbeddingProvider, embedder.Model(), embedder.Dims())
 
-	vectorRepo := repository.NewVectorRepository(db)
+	ctx := context.Background()
+
+	vectorEnc, err := repository.ParseVectorEncoding(cfg.VectorEncoding)
+	if err != nil {
+		log.Fatalf("❌ Invalid VECTOR_ENCODING: %v", err)
+	}
+	vectorRepo, err := repository.OpenVectorRepository(ctx, db, repository.VectorOptions{
+		Backend:     cfg.VectorBackend,
+		PostgresDSN: cfg.PGVectorDSN,
+		IndexPath:   cfg.VectorIndexPath,
+		Encoding:    vectorEnc,
+		Model:       embedder.Model(),
+		Dim:         embedder.Dims(),
+	})
+	if err != nil {
+		log.Fatalf("❌ Failed to open vector store: %v", err)
+	}
 	log.Println("✅ Initialized vector repository")
 
 	ragService := rag.NewService(embedder, vectorRepo, loreRepo)
+	ragService.SetEmbedWorkers(cfg.EmbeddingWorkers)
+	ragService.SetProgress(logProgress)
 	log.Println("✅ Initialized RAG service")
 
-	ctx := context.Background()
-
+	var report rag.IndexReport
 	if len(os.Args) > 1 && os.Args[1] == "--reindex" {
 		log.Println("🔄 Reindexing all lore (deleting old vectors)...")
-		if err := ragService.ReindexAll(ctx); err != nil {
+		report, err = ragService.ReindexAll(ctx)
+		if err != nil {
 			log.Fatalf("❌ Reindex failed: %v", err)
 		}
 	} else {
-		log.Println("📚 Indexing lore...")
-		if err := ragService.IndexLore(ctx); err != nil {
+		log.Println("📚 Indexing changed lore...")
+		report, err = ragService.IndexLore(ctx)
+		if err != nil {
 			log.Fatalf("❌ Indexing failed: %v", err)
 		}
 	}
+	printReport(report)
+
+	if err := vectorRepo.Flush(ctx); err != nil {
+		log.Printf("⚠️  Failed to save vector index: %v", err)
+	}
 
 	stats, err := ragService.GetStats(ctx)
 	if err != nil {
@@ -74,6 +108,28 @@ func main() {
 	log.Println("\n🎉 Done! You can now use semantic search in your bot.")
 
 	if len(os.Args) <= 1 {
-		fmt.Println("\nTip: Use --reindex flag to delete and reindex all vectors")
+		fmt.Println("\nTip: only changed lore is re-embedded; use --reindex to delete and rebuild all vectors")
 	}
 }
+
+func printReport(report rag.IndexReport) {
+	log.Printf("📝 Added %d, updated %d, removed %d, unchanged %d, failed %d",
+		len(report.Added), len(report.Updated), len(report.Removed), report.Unchanged, len(report.Failed))
+	for _, group := range []struct {
+		mark  string
+		items []string
+	}{
+		{"+", report.Added},
+		{"~", report.Updated},
+		{"-", report.Removed},
+		{"!", report.Failed},
+	} {
+		for _, item := range group.items {
+			log.Printf("   %s %s", group.mark, item)
+		}
+	}
+}
+
+func logProgress(done, total int) {
+	log.Printf("   embedded %d/%d", done, total)
+}
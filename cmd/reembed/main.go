@@ -0,0 +1,178 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"flag"
+	"log"
+	"os"
+
+	"aurora/internal/embeddings"
+	"aurora/internal/rag"
+	"aurora/internal/repository"
+	"aurora/pkg/config"
+)
+
+// Пересчитывает векторы лора другой моделью эмбеддингов. Новые векторы пишутся
+// рядом со старыми, в ту же таблицу под своей моделью, так что бот работает на
+// старых, пока перенос не закончен. После — переключить EMBEDDING_* в
+// окружении на новую модель и перезапустить бота.
+func main() {
+	fromPtr := flag.String("from", embeddings.EmbeddingModel, "Model whose vectors are re-embedded")
+	fromDimsPtr := flag.Int("from-dims", 0, "Dimension of the source model (0 — known default)")
+	providerPtr := flag.String("provider", "", "Target provider: gemini, openai, ollama (default EMBEDDING_PROVIDER)")
+	modelPtr := flag.String("model", "", "Target model (default EMBEDDING_MODEL)")
+	dimsPtr := flag.Int("dims", 0, "Target dimension (default EMBEDDING_DIMS or known default)")
+	urlPtr := flag.String("url", "", "Target server for openai and ollama (default EMBEDDING_URL)")
+	dropPtr := flag.Bool("drop", false, "Delete the source model's vectors after a complete copy")
+	failedPtr := flag.String("failed", "reembed_failed.jsonl", "File for documents that failed to embed (rerun the same command to retry them)")
+	flag.Parse()
+
+	log.Println("🔁 Lore Re-embedding")
+	log.Println("====================")
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("❌ Failed to load config: %v", err)
+	}
+	opts := embeddings.Options{
+		Provider:  cfg.EmbeddingProvider,
+		Model:     cfg.EmbeddingModel,
+		Dims:      cfg.EmbeddingDims,
+		BaseURL:   cfg.EmbeddingURL,
+		APIKey:    cfg.EmbeddingKey,
+		RateLimit: cfg.EmbeddingRPM,
+	}
+	if *providerPtr != "" && *providerPtr != opts.Provider {
+		// ключ и адрес из окружения относятся к другому провайдеру
+		opts = embeddings.Options{Provider: *providerPtr, RateLimit: cfg.EmbeddingRPM}
+		switch *providerPtr {
+		case embeddings.ProviderGemini:
+			opts.APIKey = cfg.GeminiKey
+		case embeddings.ProviderOpenAI:
+			opts.APIKey = cfg.OpenAIKey
+		}
+	}
+	if *modelPtr != "" {
+		opts.Model, opts.Dims = *modelPtr, 0
+	}
+	if *dimsPtr != 0 {
+		opts.Dims = *dimsPtr
+	}
+	if *urlPtr != "" {
+		opts.BaseURL = *urlPtr
+	}
+	embedder, err := embeddings.NewEmbedder(opts)
+	if err != nil {
+		log.Fatalf("❌ Failed to init embeddings: %v", err)
+	}
+	if embedder.Model() == *fromPtr {
+		log.Fatalf("❌ Target model is the same as the source: %s", *fromPtr)
+	}
+	fromDims := *fromDimsPtr
+	if fromDims == 0 {
+		fromDims = embeddings.KnownDims(*fromPtr)
+	}
+	if fromDims == 0 {
+		log.Fatalf("❌ Unknown dimension of %s, set -from-dims", *fromPtr)
+	}
+
+	ctx := context.Background()
+	db, err := repository.NewSQLite(cfg.DBPath)
+	if err != nil {
+		log.Fatalf("❌ Failed to connect to database: %v", err)
+	}
+	defer db.Close()
+
+	source, target, err := openStores(ctx, cfg, db, *fromPtr, fromDims, embedder)
+	if err != nil {
+		log.Fatalf("❌ Failed to open vector store: %v", err)
+	}
+	stats, err := source.GetStats(ctx)
+	if err != nil {
+		log.Fatalf("❌ Failed to read vector stats: %v", err)
+	}
+	if stats.TotalDocuments == 0 {
+		log.Fatalf("❌ No vectors of %s in the store (have: %v)", *fromPtr, stats.Models)
+	}
+	log.Printf("📦 %d documents: %s → %s (%d)", stats.TotalDocuments, *fromPtr, embedder.Model(), embedder.Dims())
+
+	ragService := rag.NewService(embedder, target, nil)
+	ragService.SetEmbedWorkers(cfg.EmbeddingWorkers)
+	ragService.SetProgress(func(done, total int) {
+		log.Printf("   embedded %d/%d", done, total)
+	})
+
+	total, err := ragService.Reembed(ctx, source)
+	var failures *rag.EmbedFailures
+	switch {
+	case errors.As(err, &failures):
+		log.Printf("⚠️  %d of %d documents failed to embed", len(failures.Failed), total)
+	case err != nil:
+		log.Fatalf("❌ Re-embedding failed: %v", err)
+	}
+	var failed []rag.FailedChunk
+	if failures != nil {
+		failed = failures.Failed
+	}
+	if err := writeFailed(*failedPtr, failed); err != nil {
+		log.Printf("⚠️  Failed to write %s: %v", *failedPtr, err)
+	} else if len(failed) > 0 {
+		log.Printf("📝 Failed documents written to %s; rerun the same command to retry them", *failedPtr)
+		os.Exit(1)
+	}
+	log.Printf("✅ %d documents re-embedded with %s", total, embedder.Model())
+
+	if *dropPtr {
+		if err := source.DeleteAll(ctx); err != nil {
+			log.Fatalf("❌ Failed to delete %s vectors: %v", *fromPtr, err)
+		}
+		log.Printf("🧹 Deleted vectors of %s", *fromPtr)
+	}
+	log.Printf("👉 Switch EMBEDDING_PROVIDER/EMBEDDING_MODEL to %s and restart the bot", embedder.Model())
+}
+
+// openStores открывает векторы обеих моделей в хранилище из конфигурации.
+// HNSW-индекс в памяти здесь не нужен: бот перестроит его при старте.
+func openStores(ctx context.Context, cfg *config.Config, db *sql.DB, from string, fromDims int, embedder embeddings.Service) (repository.VectorRepository, repository.VectorRepository, error) {
+	if cfg.VectorBackend != repository.VectorBackendPgvector {
+		return repository.NewVectorRepository(db, from, fromDims),
+			repository.NewVectorRepository(db, embedder.Model(), embedder.Dims()), nil
+	}
+	pg, err := repository.NewPostgres(cfg.PGVectorDSN)
+	if err != nil {
+		return nil, nil, err
+	}
+	source, err := repository.NewPgVectorRepository(ctx, pg, from, fromDims)
+	if err != nil {
+		return nil, nil, err
+	}
+	target, err := repository.NewPgVectorRepository(ctx, pg, embedder.Model(), embedder.Dims())
+	if err != nil {
+		return nil, nil, err
+	}
+	return source, target, nil
+}
+
+func writeFailed(path string, failed []rag.FailedChunk) error {
+	if len(failed) == 0 {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(f)
+	for _, chunk := range failed {
+		if err := enc.Encode(chunk); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	return f.Close()
+}
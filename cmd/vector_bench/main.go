@@ -0,0 +1,103 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"math/rand"
+	"strconv"
+	"time"
+
+	"aurora/internal/vectorindex"
+)
+
+// Сравнивает HNSW-индекс с полным перебором на синтетических векторах,
+// собранных в кластеры, как эмбеддинги близких по смыслу фрагментов.
+// С -storage сравнивает форматы хранения векторов в SQLite.
+func main() {
+	nPtr := flag.Int("n", 20000, "Number of indexed vectors")
+	dimPtr := flag.Int("dim", 768, "Vector dimension")
+	queriesPtr := flag.Int("queries", 200, "Number of queries")
+	kPtr := flag.Int("k", 5, "Results per query")
+	efPtr := flag.Int("ef", 64, "Search width (efSearch)")
+	mPtr := flag.Int("m", 16, "Graph degree (M)")
+	efcPtr := flag.Int("efc", 200, "Construction width (efConstruction)")
+	clustersPtr := flag.Int("clusters", 50, "Number of topic clusters")
+	storagePtr := flag.Bool("storage", false, "Benchmark SQLite vector encodings instead of the index")
+	migrationsPtr := flag.String("migrations", "migrations", "Migrations directory (for -storage)")
+	flag.Parse()
+
+	log.Println("📐 Vector Index Benchmark")
+	log.Println("=========================")
+	log.Printf("   n=%d dim=%d queries=%d k=%d M=%d efC=%d efS=%d", *nPtr, *dimPtr, *queriesPtr, *kPtr, *mPtr, *efcPtr, *efPtr)
+
+	rng := rand.New(rand.NewSource(1))
+	centers := make([][]float32, *clustersPtr)
+	for i := range centers {
+		centers[i] = randomVector(rng, *dimPtr, nil, 1)
+	}
+	sample := func() []float32 {
+		return randomVector(rng, *dimPtr, centers[rng.Intn(len(centers))], 0.35)
+	}
+
+	if *storagePtr {
+		runStorage(*migrationsPtr, *nPtr, *queriesPtr, *kPtr, sample)
+		return
+	}
+
+	idx := vectorindex.New(vectorindex.Config{M: *mPtr, EfConstruction: *efcPtr, EfSearch: *efPtr})
+	start := time.Now()
+	for i := 0; i < *nPtr; i++ {
+		idx.Add(docID(i), sample())
+	}
+	log.Printf("✅ Built in %s", time.Since(start).Round(time.Millisecond))
+
+	queries := make([][]float32, *queriesPtr)
+	for i := range queries {
+		queries[i] = sample()
+	}
+
+	var exactTime, annTime time.Duration
+	var hits, total int
+	for _, q := range queries {
+		t := time.Now()
+		exact := idx.Exact(q, *kPtr)
+		exactTime += time.Since(t)
+
+		t = time.Now()
+		approx := idx.Search(q, *kPtr, *efPtr, nil)
+		annTime += time.Since(t)
+
+		want := make(map[string]bool, len(exact))
+		for _, r := range exact {
+			want[r.ID] = true
+		}
+		for _, r := range approx {
+			if want[r.ID] {
+				hits++
+			}
+		}
+		total += len(exact)
+	}
+
+	n := time.Duration(len(queries))
+	log.Println("\n📊 Results:")
+	log.Printf("   Brute force: %s per query", (exactTime / n).Round(time.Microsecond))
+	log.Printf("   HNSW:        %s per query", (annTime / n).Round(time.Microsecond))
+	log.Printf("   Recall@%d:   %.3f", *kPtr, float64(hits)/float64(total))
+}
+
+// randomVector — гауссов шум вокруг center (или вокруг нуля) с разбросом spread.
+func randomVector(rng *rand.Rand, dim int, center []float32, spread float64) []float32 {
+	v := make([]float32, dim)
+	for i := range v {
+		v[i] = float32(rng.NormFloat64() * spread)
+		if center != nil {
+			v[i] += center[i]
+		}
+	}
+	return v
+}
+
+func docID(i int) string {
+	return "doc_" + strconv.Itoa(i)
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"aurora/internal/repository"
+)
+
+// storageMigrations — схема lore_vectors, которую бенчмарк поднимает во временной базе.
+var storageMigrations = []string{"010_vector_lore.sql", "025_vector_binary.sql", "027_vector_model.sql"}
+
+// benchModel — модель эмбеддингов, под которой бенчмарк пишет случайные векторы.
+const benchModel = "bench"
+
+// runStorage сравнивает полный перебор по таблице со старыми JSON-векторами
+// и с двоичными форматами: размер, время конвертации, время запроса и
+// полноту относительно точного JSON-поиска.
+func runStorage(migrations string, n, queries, k int, sample func() []float32) {
+	ctx := context.Background()
+	dir, err := os.MkdirTemp("", "vector_bench")
+	if err != nil {
+		log.Fatalf("❌ Temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	db, err := repository.NewSQLite(filepath.Join(dir, "bench.db"))
+	if err != nil {
+		log.Fatalf("❌ Open database: %v", err)
+	}
+	defer db.Close()
+	for _, name := range storageMigrations {
+		script, err := os.ReadFile(filepath.Join(migrations, name))
+		if err != nil {
+			log.Fatalf("❌ Read migration: %v", err)
+		}
+		if _, err := db.Exec(string(script)); err != nil {
+			log.Fatalf("❌ Apply %s: %v", name, err)
+		}
+	}
+
+	start := time.Now()
+	if err := insertLegacy(ctx, db, n, sample); err != nil {
+		log.Fatalf("❌ Insert vectors: %v", err)
+	}
+	log.Printf("✅ Inserted %d JSON vectors in %s", n, time.Since(start).Round(time.Millisecond))
+
+	qs := make([][]float32, queries)
+	for i := range qs {
+		qs[i] = sample()
+	}
+	repo := repository.NewVectorRepository(db, benchModel, len(qs[0]))
+
+	baseline := make([]map[string]bool, len(qs))
+	elapsed := timeQueries(ctx, repo, qs, k, func(i int, ids []string) {
+		baseline[i] = make(map[string]bool, len(ids))
+		for _, id := range ids {
+			baseline[i][id] = true
+		}
+	})
+	log.Println("\n📊 Results:")
+	jsonSize := tableSize(ctx, db)
+	log.Printf("   json: %6.1f MB, search %s per query", mb(jsonSize), elapsed)
+
+	for _, enc := range []repository.VectorEncoding{repository.VectorFloat32, repository.VectorFloat16, repository.VectorInt8} {
+		start := time.Now()
+		if _, err := repository.UpgradeVectors(ctx, db, enc); err != nil {
+			log.Fatalf("❌ Convert to %s: %v", enc, err)
+		}
+		convert := time.Since(start).Round(time.Millisecond)
+
+		var hits, total int
+		elapsed := timeQueries(ctx, repo, qs, k, func(i int, ids []string) {
+			for _, id := range ids {
+				if baseline[i][id] {
+					hits++
+				}
+			}
+			total += len(baseline[i])
+		})
+		size := tableSize(ctx, db)
+		log.Printf("   %-4s: %6.1f MB (×%.1f smaller), convert %s, search %s per query, Recall@%d %.3f",
+			enc, mb(size), float64(jsonSize)/float64(size), convert, elapsed, k, float64(hits)/float64(total))
+	}
+}
+
+// insertLegacy пишет векторы так, как их хранили до двоичного формата.
+func insertLegacy(ctx context.Context, db *sql.DB, n int, sample func() []float32) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	stmt, err := tx.PrepareContext(ctx, `INSERT INTO lore_vectors (id, model, dims, title, content, zone, tags, vector, metadata)
+VALUES (?, ?, ?, '', '', 'bench', '[]', ?, '{}')`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	for i := 0; i < n; i++ {
+		vec := sample()
+		blob, err := json.Marshal(vec)
+		if err != nil {
+			return err
+		}
+		if _, err := stmt.ExecContext(ctx, docID(i), benchModel, len(vec), blob); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func timeQueries(ctx context.Context, repo repository.VectorRepository, qs [][]float32, k int, check func(i int, ids []string)) time.Duration {
+	var elapsed time.Duration
+	for i, q := range qs {
+		start := time.Now()
+		docs, err := repo.SearchSimilar(ctx, q, k, nil)
+		elapsed += time.Since(start)
+		if err != nil {
+			log.Fatalf("❌ Search: %v", err)
+		}
+		ids := make([]string, len(docs))
+		for j, d := range docs {
+			ids[j] = d.ID
+		}
+		check(i, ids)
+	}
+	return (elapsed / time.Duration(len(qs))).Round(time.Microsecond)
+}
+
+func tableSize(ctx context.Context, db *sql.DB) int64 {
+	var size int64
+	if err := db.QueryRowContext(ctx, `SELECT IFNULL(SUM(length(vector)), 0) FROM lore_vectors`).Scan(&size); err != nil {
+		log.Fatalf("❌ Table size: %v", err)
+	}
+	return size
+}
+
+func mb(b int64) float64 {
+	return float64(b) / (1 << 20)
+}
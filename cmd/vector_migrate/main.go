@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"math/rand"
+	"os"
+	"time"
+
+	"aurora/internal/embeddings"
+	"aurora/internal/repository"
+	"aurora/pkg/config"
+)
+
+// Переносит векторы одной модели эмбеддингов из lore_vectors в SQLite в
+// PostgreSQL с pgvector и проверяет, что поиск в обеих базах находит одно и то же.
+func main() {
+	sqlitePtr := flag.String("sqlite", envOr("DB_PATH", config.DefaultDBPath), "SQLite database with lore_vectors")
+	pgPtr := flag.String("pg", os.Getenv("PG_VECTOR_DSN"), "PostgreSQL DSN")
+	modelPtr := flag.String("model", envOr("EMBEDDING_MODEL", embeddings.EmbeddingModel), "Embedding model whose vectors are copied")
+	dimPtr := flag.Int("dim", embeddings.EmbeddingDims, "Embedding dimension of the model")
+	batchPtr := flag.Int("batch", 500, "Documents per batch")
+	resetPtr := flag.Bool("reset", false, "Delete existing vectors in PostgreSQL before copying")
+	verifyPtr := flag.Int("verify", 20, "Sample queries to compare SQLite and pgvector results (0 — skip)")
+	kPtr := flag.Int("k", 5, "Results per verification query")
+	flag.Parse()
+
+	if *pgPtr == "" {
+		log.Fatal("❌ Please specify --pg or PG_VECTOR_DSN")
+	}
+
+	log.Println("🚚 Vector Migration: SQLite → pgvector")
+	log.Println("======================================")
+
+	ctx := context.Background()
+	db, err := repository.NewSQLite(*sqlitePtr)
+	if err != nil {
+		log.Fatalf("❌ Failed to open SQLite: %v", err)
+	}
+	defer db.Close()
+
+	pgDB, err := repository.NewPostgres(*pgPtr)
+	if err != nil {
+		log.Fatalf("❌ Failed to connect to PostgreSQL: %v", err)
+	}
+	defer pgDB.Close()
+
+	pg, err := repository.NewPgVectorRepository(ctx, pgDB, *modelPtr, *dimPtr)
+	if err != nil {
+		log.Fatalf("❌ Failed to prepare pgvector schema: %v", err)
+	}
+	if *resetPtr {
+		if err := pg.DeleteAll(ctx); err != nil {
+			log.Fatalf("❌ Failed to clear PostgreSQL: %v", err)
+		}
+		log.Println("🧹 Cleared existing vectors")
+	}
+
+	start := time.Now()
+	var copied int
+	// случайная выборка документов для проверки поиска
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+	var samples []repository.VectorDocument
+	err = repository.ExportVectors(ctx, db, *modelPtr, *batchPtr, func(docs []repository.VectorDocument) error {
+		if err := pg.IndexBatch(ctx, docs); err != nil {
+			return err
+		}
+		for i, doc := range docs {
+			if len(samples) < *verifyPtr {
+				samples = append(samples, doc)
+			} else if j := rng.Intn(copied + i + 1); j < len(samples) {
+				samples[j] = doc
+			}
+		}
+		copied += len(docs)
+		log.Printf("   copied %d", copied)
+		return nil
+	})
+	if err != nil {
+		log.Fatalf("❌ Copy failed after %d documents: %v", copied, err)
+	}
+	log.Printf("✅ Copied %d documents in %s", copied, time.Since(start).Round(time.Millisecond))
+
+	src := repository.NewVectorRepository(db, *modelPtr, *dimPtr)
+	srcStats, err := src.GetStats(ctx)
+	if err != nil {
+		log.Fatalf("❌ SQLite stats: %v", err)
+	}
+	dstStats, err := pg.GetStats(ctx)
+	if err != nil {
+		log.Fatalf("❌ PostgreSQL stats: %v", err)
+	}
+	log.Printf("📊 SQLite: %d documents, PostgreSQL: %d documents", srcStats.TotalDocuments, dstStats.TotalDocuments)
+	for zone, n := range srcStats.DocumentsByZone {
+		if dstStats.DocumentsByZone[zone] < n {
+			log.Printf("⚠️  Zone %s: %d in SQLite, %d in PostgreSQL", zone, n, dstStats.DocumentsByZone[zone])
+		}
+	}
+
+	if len(samples) > 0 {
+		verify(ctx, src, pg, samples, *kPtr)
+	}
+}
+
+// verify ищет в обеих базах по векторам документов из выборки — без фильтров
+// и с фильтром по их зоне — и сравнивает выдачу pgvector с точным перебором.
+func verify(ctx context.Context, src, pg repository.VectorRepository, samples []repository.VectorDocument, k int) {
+	log.Printf("🔎 Verifying search on %d sample documents...", len(samples))
+	var hits, total, selfHits int
+	for _, doc := range samples {
+		for _, filters := range []map[string]string{nil, {"zone": doc.Zone}} {
+			want, err := src.SearchSimilar(ctx, doc.Vector, k, filters)
+			if err != nil {
+				log.Fatalf("❌ SQLite search: %v", err)
+			}
+			got, err := pg.SearchSimilar(ctx, doc.Vector, k, filters)
+			if err != nil {
+				log.Fatalf("❌ pgvector search: %v", err)
+			}
+			found := make(map[string]bool, len(got))
+			for _, d := range got {
+				found[d.ID] = true
+				if filters != nil && d.Zone != doc.Zone {
+					log.Fatalf("❌ pgvector returned %s from zone %s for zone filter %s", d.ID, d.Zone, doc.Zone)
+				}
+			}
+			for _, d := range want {
+				if found[d.ID] {
+					hits++
+				}
+			}
+			total += len(want)
+			if found[doc.ID] {
+				selfHits++
+			}
+		}
+	}
+	queries := 2 * len(samples)
+	log.Printf("   Recall@%d vs exact SQLite search: %.3f", k, float64(hits)/float64(max(total, 1)))
+	log.Printf("   Document finds itself: %d/%d", selfHits, queries)
+	if selfHits < queries {
+		log.Println("⚠️  Some documents were not found by their own vector — check the HNSW index and ef_search")
+	}
+}
+
+func envOr(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
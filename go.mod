@@ -4,6 +4,7 @@ go 1.22
 
 require (
 	github.com/SevereCloud/vksdk/v2 v2.17.0
+	github.com/lib/pq v1.10.9
 	github.com/mattn/go-sqlite3 v1.14.33
 )
 
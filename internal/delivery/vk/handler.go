@@ -2,9 +2,9 @@ package vk
 
 import (
 	"context"
+	"errors"
 	"log"
 	"strings"
-	"sync"
 	"time"
 
 	"aurora/internal/llm"
@@ -15,26 +15,29 @@ import (
 	"github.com/SevereCloud/vksdk/v2/api"
 	"github.com/SevereCloud/vksdk/v2/events"
 	longpoll "github.com/SevereCloud/vksdk/v2/longpoll-bot"
+	"github.com/SevereCloud/vksdk/v2/object"
 )
 
-type formBuffer struct {
-	PeerID    int
-	StartedAt time.Time
-	Raw       strings.Builder
-}
-
 type Handler struct {
-	cfg          *config.Config
-	vk           *api.VK
-	llm          llm.Client
-	charService  *service.CharacterService
-	questService *service.QuestService
-	sceneService *service.SceneService
-	locService   *service.LocationService
-	gmService    *service.GMService
-
-	formMu  sync.Mutex
-	formBuf map[int64]*formBuffer
+	cfg           *config.Config
+	vk            *api.VK
+	llm           llm.Client
+	charService   *service.CharacterService
+	questService  *service.QuestService
+	sceneService  *service.SceneService
+	locService    *service.LocationService
+	gmService     *service.GMService
+	invService    *service.InventoryService
+	econService   *service.EconomyService
+	tradeService  *service.TradeService
+	combatService *service.CombatService
+	rewardService *service.RewardService
+	repService    *service.ReputationService
+	travelService *service.TravelService
+	clockService  *service.ClockService
+	lifeService   *service.LifeService
+	creation      *service.CreationService
+	sheetService  *service.SheetService
 }
 
 func NewHandler(
@@ -46,18 +49,42 @@ func NewHandler(
 	sceneService *service.SceneService,
 	locService *service.LocationService,
 	gmService *service.GMService,
+	invService *service.InventoryService,
+	econService *service.EconomyService,
+	tradeService *service.TradeService,
+	combatService *service.CombatService,
+	rewardService *service.RewardService,
+	repService *service.ReputationService,
+	travelService *service.TravelService,
+	clockService *service.ClockService,
+	lifeService *service.LifeService,
+	creation *service.CreationService,
+	sheetService *service.SheetService,
 ) *Handler {
-	return &Handler{
-		cfg:          cfg,
-		vk:           vk,
-		llm:          llm,
-		charService:  charService,
-		questService: questService,
-		sceneService: sceneService,
-		locService:   locService,
-		gmService:    gmService,
-		formBuf:      make(map[int64]*formBuffer),
+	h := &Handler{
+		cfg:           cfg,
+		vk:            vk,
+		llm:           llm,
+		charService:   charService,
+		questService:  questService,
+		sceneService:  sceneService,
+		locService:    locService,
+		gmService:     gmService,
+		invService:    invService,
+		econService:   econService,
+		tradeService:  tradeService,
+		combatService: combatService,
+		rewardService: rewardService,
+		repService:    repService,
+		travelService: travelService,
+		clockService:  clockService,
+		lifeService:   lifeService,
+		creation:      creation,
+		sheetService:  sheetService,
 	}
+	clockService.SetNotifier(h.notifyTick)
+	sheetService.SetNotifier(h.notifySheet)
+	return h
 }
 
 func (h *Handler) send(peerID int, msg string) {
@@ -71,7 +98,49 @@ func (h *Handler) send(peerID int, msg string) {
 	}
 }
 
+// maxKeyboardButtons — предел кнопок во встроенной клавиатуре VK.
+const maxKeyboardButtons = 10
+
+// sendOptions отправляет сообщение со встроенной клавиатурой вариантов ответа.
+// Кнопка присылает свою надпись обычным сообщением; «Отмена» всегда последняя.
+func (h *Handler) sendOptions(peerID int, msg string, options []string) {
+	if len(options) == 0 {
+		h.send(peerID, msg)
+		return
+	}
+	if len(options) > maxKeyboardButtons {
+		options = append(options[:maxKeyboardButtons-1:maxKeyboardButtons-1], options[len(options)-1])
+	}
+	kb := object.NewMessagesKeyboardInline()
+	for i, opt := range options {
+		if i%3 == 0 {
+			kb.AddRow()
+		}
+		color := object.Secondary
+		switch opt {
+		case "Сохранить":
+			color = object.Positive
+		case "Отмена":
+			color = object.Negative
+		}
+		kb.AddTextButton(opt, "", color)
+	}
+	_, err := h.vk.MessagesSend(api.Params{
+		"peer_id":   peerID,
+		"random_id": time.Now().UnixNano(),
+		"message":   msg,
+		"keyboard":  kb,
+	})
+	if err != nil {
+		log.Printf("send error: %v", err)
+	}
+}
+
 func (h *Handler) Start(lp *longpoll.LongPoll) {
+	go h.sweepTrades()
+	go h.runClock()
+	go h.sweepLife()
+
 	lp.MessageNew(func(ctx context.Context, obj events.MessageNewObject) {
 		m := obj.Message
 		fromID := m.FromID
@@ -85,7 +154,7 @@ func (h *Handler) Start(lp *longpoll.LongPoll) {
 			return
 		}
 
-		if h.formAppendIfActive(fromID, peerID, text) {
+		if h.creationInputIfActive(ctx, peerID, fromID, text) {
 			return
 		}
 
@@ -124,8 +193,10 @@ func (h *Handler) Start(lp *longpoll.LongPoll) {
 
 			switch intent.Type {
 			case llm.IntentUseItem:
-				// h.handleUseItem(ctx, peerID, fromID, intent.Target)
-				h.send(peerID, "Использование предметов пока не реализовано в новой архитектуре.")
+				h.handleUseItem(ctx, peerID, fromID, intent.Target, text)
+				return
+			case llm.IntentEquip:
+				h.handleEquip(ctx, peerID, fromID, intent.Target, text)
 				return
 			default:
 				h.handleLapidariusChat(ctx, peerID, fromID, text)
@@ -137,48 +208,19 @@ func (h *Handler) Start(lp *longpoll.LongPoll) {
 
 		if isMainChat {
 			if len(text) > 5 && !strings.HasPrefix(text, "((") {
-				if err := h.logSceneMessage(ctx, int64(fromID), text); err != nil {
+				logged, err := h.logSceneMessage(ctx, peerID, int64(fromID), text)
+				if err != nil {
 					log.Printf("log scene msg error: %v", err)
 				}
+				// время идёт только от отыгрыша живого персонажа
+				if logged {
+					h.advanceClock(ctx, int64(h.cfg.MinutesPerAction))
+				}
 			}
 		}
 	})
 }
 
-func (h *Handler) formAppendIfActive(fromID, peerID int, text string) bool {
-	low := strings.ToLower(strings.TrimSpace(text))
-	parts := strings.Fields(low)
-	if len(parts) >= 1 && parts[0] == "!анкета" {
-		return false
-	}
-
-	h.formMu.Lock()
-	buf, ok := h.formBuf[int64(fromID)]
-	if ok && time.Since(buf.StartedAt) > 15*time.Minute {
-		delete(h.formBuf, int64(fromID))
-		ok = false
-	}
-	h.formMu.Unlock()
-
-	if !ok {
-		return false
-	}
-	if buf.PeerID != peerID {
-		return false
-	}
-
-	t := strings.TrimSpace(text)
-	if t == "" {
-		return true
-	}
-
-	h.formMu.Lock()
-	buf.Raw.WriteString("\n")
-	buf.Raw.WriteString(t)
-	h.formMu.Unlock()
-	return true
-}
-
 func (h *Handler) handlePlayerCommand(ctx context.Context, peerID, fromID int, text string) {
 	lower := strings.ToLower(strings.TrimSpace(text))
 
@@ -190,22 +232,41 @@ func (h *Handler) handlePlayerCommand(ctx context.Context, peerID, fromID int, t
 	case strings.HasPrefix(lower, "!сюжет") || strings.HasPrefix(lower, "!хроника"):
 		h.handleSummaryRequest(ctx, peerID, fromID)
 	case strings.HasPrefix(lower, "!квест"):
-		h.handleQuestRequest(ctx, peerID, fromID)
+		h.handleQuestRequest(ctx, peerID, fromID, strings.TrimSpace(text[len("!квест"):]))
+	case strings.HasPrefix(lower, "!бой"):
+		h.handleCombat(ctx, peerID, fromID, strings.TrimSpace(text[len("!бой"):]))
+	case strings.HasPrefix(lower, "!путь"):
+		h.handleTravel(ctx, peerID, fromID, strings.TrimSpace(text[len("!путь"):]))
+	case strings.HasPrefix(lower, "!время"):
+		h.handleTime(peerID)
+	case strings.HasPrefix(lower, "!инвентарь"):
+		h.handleInventory(ctx, peerID, fromID)
+	case strings.HasPrefix(lower, "!репутация"):
+		h.handleReputation(ctx, peerID, fromID)
+	case strings.HasPrefix(lower, "!эффекты"):
+		h.handleEffects(ctx, peerID, fromID)
+	case strings.HasPrefix(lower, "!персонажи"):
+		h.handleRoster(ctx, peerID, fromID)
+	case strings.HasPrefix(lower, "!играть за"):
+		h.handleSwitchCharacter(ctx, peerID, fromID, strings.TrimSpace(text[len("!играть за"):]))
+	case strings.HasPrefix(lower, "!новый персонаж"):
+		h.handleNewCharacter(ctx, peerID, fromID)
+	case strings.HasPrefix(lower, "!воскрешение"):
+		h.handleResurrectionRequest(ctx, peerID, fromID, strings.TrimSpace(text[len("!воскрешение"):]))
+	case strings.HasPrefix(lower, "!магазин"):
+		h.handleShop(ctx, peerID, fromID, strings.TrimSpace(text[len("!магазин"):]))
+	case strings.HasPrefix(lower, "!купить"):
+		h.handleBuy(ctx, peerID, fromID, strings.TrimSpace(text[len("!купить"):]))
+	case strings.HasPrefix(lower, "!продать"):
+		h.handleSell(ctx, peerID, fromID, strings.TrimSpace(text[len("!продать"):]))
+	case strings.HasPrefix(lower, "!обмен"):
+		h.handleTrade(ctx, peerID, fromID, strings.TrimSpace(text[len("!обмен"):]))
 	case strings.HasPrefix(lower, "!анкета пример"):
 		// h.handleFormExample(ctx, peerID)
 	case strings.HasPrefix(lower, "!анкета"):
-		if strings.Contains(lower, "отмена") {
-			h.formMu.Lock()
-			delete(h.formBuf, int64(fromID))
-			h.formMu.Unlock()
-			h.send(peerID, "Ввод анкеты отменён.")
-		} else if strings.Contains(lower, "конец") {
-			h.finishCharacterForm(ctx, peerID, fromID)
-		} else {
-			h.startOrAppendCharacterForm(ctx, peerID, fromID, text)
-		}
+		h.handleCreation(ctx, peerID, fromID, strings.TrimSpace(text[len("!анкета"):]))
 	default:
-		h.send(peerID, "Неизвестная команда. Доступно: !квест, !принимаю, !отказываюсь, !анкета, !сюжет.")
+		h.send(peerID, "Неизвестная команда. Доступно: !квест, !принимаю, !отказываюсь, !анкета, !сюжет, !инвентарь, !репутация, !эффекты, !магазин, !купить, !продать, !обмен, !бой, !путь, !время, !персонажи, !играть за, !новый персонаж, !воскрешение.")
 	}
 }
 
@@ -228,9 +289,11 @@ func (h *Handler) handleLapidariusChat(ctx context.Context, peerID, fromID int,
 		return
 	}
 
-	ch, err := h.charService.GetOrCreateByVK(ctx, int64(fromID))
-	if err != nil {
-		h.send(peerID, "Сфера не видит твою ауру.")
+	ch, ok := h.activeCharacter(ctx, peerID, fromID)
+	if !ok {
+		return
+	}
+	if !h.checkLife(ctx, peerID, ch, service.ActionRP) {
 		return
 	}
 
@@ -263,21 +326,34 @@ func (h *Handler) handleLapidariusChat(ctx context.Context, peerID, fromID int,
 	h.send(peerID, answer)
 }
 
-func (h *Handler) logSceneMessage(ctx context.Context, fromID int64, text string) error {
-	ch, err := h.charService.GetOrCreateByVK(ctx, fromID)
+// logSceneMessage пишет реплику в сцену персонажа. false — реплика не
+// принята: у игрока нет персонажа или состояние не даёт отыгрывать. Отказ
+// здесь молчаливый: это обычная болтовня в общем чате, и ответ на каждую
+// реплику мёртвого засорял бы его. О запрете игрок узнаёт из команд.
+func (h *Handler) logSceneMessage(ctx context.Context, peerID int, fromID int64, text string) (bool, error) {
+	ch, err := h.charService.GetActive(ctx, fromID)
+	if errors.Is(err, service.ErrNoCharacter) {
+		// сообщения без персонажа в сцену не пишутся
+		return false, nil
+	}
 	if err != nil {
-		return err
+		return false, err
+	}
+	h.syncLife(ctx, peerID, ch)
+	if h.lifeService.Allow(ctx, ch, service.ActionRP) != nil {
+		return false, nil
 	}
 	sc, err := h.sceneService.GetOrCreateSceneForCharacter(ctx, ch.ID)
 	if err != nil {
-		return err
+		return false, err
 	}
 
-	return h.sceneService.AppendMessage(ctx, models.SceneMessage{
+	err = h.sceneService.AppendMessage(ctx, models.SceneMessage{
 		SceneID:    sc.ID,
 		SenderType: "player",
 		SenderID:   ch.ID,
 		Content:    text,
 		CreatedAt:  time.Now(),
 	})
+	return err == nil, err
 }
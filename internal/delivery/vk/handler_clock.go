@@ -0,0 +1,54 @@
+package vk
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"aurora/internal/service"
+)
+
+// runClock двигает мировые часы по реальному времени, если задан WORLD_TIME_RATIO.
+func (h *Handler) runClock() {
+	if h.cfg.WorldTimeRatio <= 0 {
+		return
+	}
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for range ticker.C {
+		h.advanceClock(context.Background(), int64(h.cfg.WorldTimeRatio))
+	}
+}
+
+// advanceClock сдвигает часы после принятого действия персонажа; уведомления
+// рассылает notifyTick.
+func (h *Handler) advanceClock(ctx context.Context, minutes int64) {
+	if minutes <= 0 {
+		return
+	}
+	if _, err := h.clockService.Advance(ctx, minutes); err != nil && !errors.Is(err, service.ErrInvalidDuration) {
+		log.Printf("clock advance error: %v", err)
+	}
+}
+
+// passTime проматывает время в пути одного персонажа; мировые часы не двигаются.
+func (h *Handler) passTime(ctx context.Context, charID, minutes int64) {
+	if minutes <= 0 {
+		return
+	}
+	if _, err := h.clockService.PassTime(ctx, charID, minutes); err != nil && !errors.Is(err, service.ErrInvalidDuration) {
+		log.Printf("clock pass time error: %v", err)
+	}
+}
+
+func (h *Handler) notifyTick(tick *service.ClockTick) {
+	for vkID, lines := range tick.Notices {
+		h.send(int(vkID), strings.Join(lines, "\n"))
+	}
+}
+
+func (h *Handler) handleTime(peerID int) {
+	h.send(peerID, "🕰 "+h.clockService.Describe())
+}
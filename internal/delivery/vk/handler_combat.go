@@ -0,0 +1,111 @@
+package vk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"aurora/internal/models"
+	"aurora/internal/service"
+)
+
+func (h *Handler) handleCombat(ctx context.Context, peerID, fromID int, args string) {
+	ch, ok := h.activeCharacter(ctx, peerID, fromID)
+	if !ok {
+		return
+	}
+	if !h.checkLife(ctx, peerID, ch, service.ActionCombat) {
+		return
+	}
+
+	enc, inCombat := h.combatService.Active(ch.ID)
+	lower := strings.ToLower(args)
+
+	switch {
+	case args == "":
+		if !inCombat {
+			h.send(peerID, "Начать бой: !бой <противник>\nХод в бою: !бой <действие>\nВыйти из боя: !бой отступить")
+			return
+		}
+		h.send(peerID, fmt.Sprintf("⚔️ Бой с «%s», раунд %d. Твоё здоровье: %d. Противник: %d (%s).",
+			enc.Enemy, enc.Round, ch.CombatHealth, enc.EnemyHP, enemyStatusText(enc.EnemyStatus)))
+		return
+
+	case lower == "отступить" || lower == "бежать":
+		if _, err := h.combatService.Flee(ch.ID); err != nil {
+			h.send(peerID, "Ты ни с кем не сражаешься.")
+			return
+		}
+		h.send(peerID, ch.Name+" отступает. Бой окончен без награды.")
+		return
+
+	case !inCombat:
+		enc, err := h.combatService.Start(ch.ID, args)
+		if err != nil {
+			h.send(peerID, "Бой уже идёт.")
+			return
+		}
+		h.send(peerID, fmt.Sprintf("⚔️ %s вступает в бой с «%s». Опиши свой ход: !бой <действие>\nОпыт дают только схватки, которые выставили дорога или ГМ.", ch.Name, enc.Enemy))
+		return
+	}
+
+	sc, err := h.sceneService.GetOrCreateSceneForCharacter(ctx, ch.ID)
+	if err != nil {
+		h.send(peerID, "Сфера не может определить, где ты находишься.")
+		return
+	}
+	history, _ := h.sceneService.GetLastMessagesSummary(ctx, sc.ID, 10)
+
+	var quest *models.Quest
+	if active, _ := h.questService.GetActiveForCharacter(ctx, ch.ID); len(active) > 0 {
+		quest = &active[0]
+	}
+
+	round, err := h.combatService.Round(ctx, ch, sc, quest, history, args)
+	if errors.Is(err, service.ErrNotInCombat) {
+		h.send(peerID, "Бой уже закончился.")
+		return
+	}
+	if errors.Is(err, service.ErrRoundBusy) {
+		h.send(peerID, "Предыдущий ход ещё не разыгран — подожди его исхода.")
+		return
+	}
+	if err != nil && round == nil {
+		log.Printf("combat round error: %v", err)
+		h.send(peerID, "Туман войны сгустился (Ошибка магии).")
+		return
+	}
+	if err != nil {
+		log.Printf("combat reward error: %v", err)
+	}
+	h.advanceClock(ctx, int64(h.cfg.MinutesPerAction))
+
+	var b strings.Builder
+	b.WriteString(round.Result.RoundDesc)
+	fmt.Fprintf(&b, "\n\n❤️ %d | 👹 %d (%s)", round.Result.PlayerHP, round.Result.EnemyHP, enemyStatusText(round.Encounter.EnemyStatus))
+	if round.Life != nil && round.Life.Text() != "" {
+		b.WriteString("\n\n" + round.Life.Text())
+	}
+	if round.Result.IsFinished {
+		if round.Won {
+			b.WriteString("\n\n🏆 Победа!")
+		} else {
+			b.WriteString("\n\nБой окончен.")
+		}
+		if round.Reward != nil {
+			if s := h.rewardService.FormatSummary(round.Reward); s != "" {
+				b.WriteString("\n" + s)
+			}
+		}
+	}
+	h.send(peerID, b.String())
+}
+
+func enemyStatusText(status string) string {
+	if status == "" {
+		return "невредим"
+	}
+	return status
+}
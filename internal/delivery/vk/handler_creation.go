@@ -0,0 +1,169 @@
+package vk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+
+	"aurora/internal/models"
+	"aurora/internal/service"
+)
+
+// botMentionRe — упоминание сообщества, которое VK ставит перед текстом
+// кнопки в беседе.
+var botMentionRe = regexp.MustCompile(`^\[club\d+\|[^\]]*\][,\s]*`)
+
+// handleCreation — команды мастера анкеты: !анкета [заново|сохранить|отмена|изменить <поле>].
+func (h *Handler) handleCreation(ctx context.Context, peerID, fromID int, args string) {
+	low := strings.ToLower(args)
+	vkID := int64(fromID)
+	switch {
+	case low == "отмена":
+		h.cancelCreation(peerID, vkID)
+	case low == "сохранить":
+		h.saveCreation(ctx, peerID, vkID)
+	case strings.HasPrefix(low, "изменить"):
+		h.editCreation(peerID, vkID, strings.TrimSpace(args[len("изменить"):]))
+	case low == "заново":
+		h.askCreation(peerID, h.creation.Start(vkID, peerID))
+	default:
+		if d, ok := h.creation.Draft(vkID); ok && d.PeerID == peerID {
+			h.askCreation(peerID, d)
+			return
+		}
+		if sheet := h.returnedSheet(ctx, vkID); sheet != nil {
+			h.send(peerID, "✏️ Возвращаемся к анкете, которую ГМ просил доработать.\nКомментарий ГМа: "+sheet.Comment)
+			h.askCreation(peerID, h.creation.Resume(vkID, peerID, sheet.Form))
+			return
+		}
+		h.send(peerID, "📜 Начинаем анкету. Отвечай на вопросы по одному; выйти — «Отмена».")
+		h.askCreation(peerID, h.creation.Start(vkID, peerID))
+	}
+}
+
+// creationInputIfActive принимает ответ мастеру анкеты, если игрок заполняет
+// её в этой беседе. Команды с «!» проходят мимо мастера.
+func (h *Handler) creationInputIfActive(ctx context.Context, peerID, fromID int, text string) bool {
+	if strings.HasPrefix(text, "!") {
+		return false
+	}
+	vkID := int64(fromID)
+	d, ok := h.creation.Draft(vkID)
+	if !ok || d.PeerID != peerID {
+		return false
+	}
+
+	text = strings.TrimSpace(botMentionRe.ReplaceAllString(text, ""))
+	low := strings.ToLower(text)
+	switch {
+	case low == "отмена":
+		h.cancelCreation(peerID, vkID)
+		return true
+	case d.Step == service.StepPreview && low == "сохранить":
+		h.saveCreation(ctx, peerID, vkID)
+		return true
+	case d.Step == service.StepPreview && strings.HasPrefix(low, "изменить"):
+		h.editCreation(peerID, vkID, strings.TrimSpace(text[len("изменить"):]))
+		return true
+	case d.Step == service.StepPreview:
+		h.askCreation(peerID, d)
+		return true
+	}
+
+	next, err := h.creation.Answer(ctx, vkID, text)
+	if err != nil {
+		h.creationError(peerID, vkID, err)
+		return true
+	}
+	h.askCreation(peerID, next)
+	return true
+}
+
+func (h *Handler) askCreation(peerID int, d service.CreationDraft) {
+	p := h.creation.Prompt(d)
+	h.sendOptions(peerID, p.Text, append(p.Options, "Отмена"))
+}
+
+func (h *Handler) cancelCreation(peerID int, vkID int64) {
+	if h.creation.Cancel(vkID) {
+		h.send(peerID, "Заполнение анкеты отменено.")
+		return
+	}
+	h.send(peerID, "Анкета и так не заполняется.")
+}
+
+func (h *Handler) editCreation(peerID int, vkID int64, field string) {
+	d, err := h.creation.Edit(vkID, field)
+	if err != nil {
+		h.creationError(peerID, vkID, err)
+		return
+	}
+	h.askCreation(peerID, d)
+}
+
+// returnedSheet — анкета активного персонажа, которую ГМ вернул на доработку.
+func (h *Handler) returnedSheet(ctx context.Context, vkID int64) *models.CharacterSheet {
+	ch, err := h.charService.GetActive(ctx, vkID)
+	if err != nil {
+		return nil
+	}
+	sheet, err := h.sheetService.Latest(ctx, ch.ID)
+	if err != nil {
+		log.Printf("latest sheet error: %v", err)
+		return nil
+	}
+	if sheet == nil || sheet.Status != models.SheetChanges {
+		return nil
+	}
+	return sheet
+}
+
+func (h *Handler) saveCreation(ctx context.Context, peerID int, vkID int64) {
+	sheet, err := h.creation.Save(ctx, vkID)
+	switch {
+	case errors.Is(err, service.ErrCharacterLimit):
+		h.send(peerID, "Новых персонажей больше не завести: достигнут предел. Список: !персонажи")
+		return
+	case err != nil && service.CreationErrorText(err) != "":
+		h.creationError(peerID, vkID, err)
+		return
+	case err != nil:
+		log.Printf("save character form error: %v", err)
+		h.send(peerID, "Сфера пошла трещинами (Ошибка магии). Анкета сохранена в черновике — попробуй «Сохранить» ещё раз.")
+		return
+	}
+	h.send(peerID, "📨 Анкета отправлена ГМу на проверку. Персонаж изменится, когда её одобрят.")
+	if h.cfg.GMUserID != 0 {
+		h.send(h.cfg.GMUserID, fmt.Sprintf("📜 Новая анкета #%d: %s (vk %d). Посмотреть: !gm sheet %d", sheet.ID, sheet.Form.Name, vkID, sheet.ID))
+	}
+}
+
+// notifySheet сообщает игроку решение ГМа по анкете туда, откуда она подана.
+func (h *Handler) notifySheet(sheet models.CharacterSheet) {
+	peerID := sheet.PeerID
+	if peerID == 0 {
+		peerID = int(sheet.VKUserID)
+	}
+	if text := service.SheetReviewText(sheet); text != "" {
+		h.send(peerID, text)
+	}
+}
+
+// creationError объясняет, что не так с ответом, и повторяет вопрос.
+func (h *Handler) creationError(peerID int, vkID int64, err error) {
+	text := service.CreationErrorText(err)
+	if text == "" {
+		log.Printf("character form error: %v", err)
+		text = "Сфера пошла трещинами (Ошибка магии)."
+	}
+	d, ok := h.creation.Draft(vkID)
+	if !ok {
+		h.send(peerID, text)
+		return
+	}
+	p := h.creation.Prompt(d)
+	h.sendOptions(peerID, "⚠️ "+text+"\n\n"+p.Text, append(p.Options, "Отмена"))
+}
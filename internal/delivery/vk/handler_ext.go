@@ -2,23 +2,39 @@ package vk
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
 	"strings"
-	"time"
 
 	"aurora/internal/llm"
 	"aurora/internal/models"
+	"aurora/internal/repository"
+	"aurora/internal/service"
 )
 
-func (h *Handler) handleQuestRequest(ctx context.Context, peerID, fromID int) {
-	ch, err := h.charService.GetOrCreateByVK(ctx, int64(fromID))
-	if err != nil {
+func (h *Handler) handleQuestRequest(ctx context.Context, peerID, fromID int, action string) {
+	ch, ok := h.activeCharacter(ctx, peerID, fromID)
+	if !ok {
+		return
+	}
+	if !h.checkLife(ctx, peerID, ch, service.ActionQuest) {
 		return
 	}
 
 	active, err := h.questService.GetActiveForCharacter(ctx, ch.ID)
+	if ch.IsDead() {
+		// мёртвому доступен только путь назад
+		if q, _ := h.lifeService.ResurrectionQuest(ctx, ch.ID); q != nil {
+			active = []models.Quest{*q}
+		}
+	}
 	if len(active) > 0 {
-		h.send(peerID, "У тебя уже есть активный квест.")
+		if action == "" {
+			h.send(peerID, fmt.Sprintf("Активный квест: %s (стадия %d).\nОпиши, что делаешь: !квест <действие>", active[0].Title, active[0].Stage))
+			return
+		}
+		h.handleQuestAction(ctx, peerID, ch, active[0], action)
 		return
 	}
 
@@ -37,6 +53,7 @@ func (h *Handler) handleQuestRequest(ctx context.Context, peerID, fromID int) {
 		FactionTag:    ch.FactionName,
 		CustomTags:    []string{"квест", "экономика"},
 		PlayerMessage: "PlayerMessage: `Дай новое задание...`",
+		Factions:      h.repService.FactionNames(),
 	}
 
 	reply, err := h.llm.GenerateForPlayer(ctx, pctx)
@@ -45,79 +62,213 @@ func (h *Handler) handleQuestRequest(ctx context.Context, peerID, fromID int) {
 		return
 	}
 
-	if q, err := h.questService.CreateFromAI(ctx, ch.ID, reply); err == nil && q != nil {
+	q, err := h.questService.CreateFromAI(ctx, ch.ID, reply)
+	switch {
+	case errors.Is(err, service.ErrQuestFactionRefuses):
+		reply += "\n\nНо заказчик не доверяет тебе — с такой репутацией поручение не дадут."
+	case err == nil && q != nil:
 		reply += "\n\nСоздан квест: " + q.Title
+		if q.FactionName != "" {
+			reply += " (" + q.FactionName + ")"
+		}
 	}
 
 	h.send(peerID, reply)
 }
 
-func (h *Handler) handleQuestDecision(_ context.Context, peerID, _ int, decision string) {
-	h.send(peerID, "Решения по квестам: "+decision)
+// handleQuestAction продвигает активный квест по заявке игрока и выдаёт
+// награду, если квест завершён.
+func (h *Handler) handleQuestAction(ctx context.Context, peerID int, ch *models.Character, q models.Quest, action string) {
+	sc, err := h.sceneService.GetOrCreateSceneForCharacter(ctx, ch.ID)
+	if err != nil {
+		h.send(peerID, "Сфера не может определить, где ты находишься.")
+		return
+	}
+	history, _ := h.sceneService.GetLastMessagesSummary(ctx, sc.ID, 10)
+
+	res, err := h.llm.GenerateQuestProgress(ctx, llm.QuestProgressContext{
+		Character:    *ch,
+		Scene:        sc,
+		Quest:        q,
+		History:      history,
+		PlayerAction: action,
+	})
+	if err != nil {
+		log.Printf("quest progress error: %v", err)
+		h.send(peerID, "Духи молчат.")
+		return
+	}
+
+	if res.Stage > q.Stage {
+		q.Stage = res.Stage
+	}
+	if res.Completed {
+		q.Status = "completed"
+	}
+	if err := h.questService.UpdateProgress(ctx, q); errors.Is(err, service.ErrQuestClosed) {
+		h.send(peerID, "Квест «"+q.Title+"» уже закрыт.")
+		return
+	} else if err != nil {
+		log.Printf("quest update error: %v", err)
+		h.send(peerID, "Сфера пошла трещинами (Ошибка магии).")
+		return
+	}
+	h.advanceClock(ctx, int64(h.cfg.MinutesPerAction))
+
+	reply := res.Narration
+	if res.Completed && q.Kind == models.QuestKindResurrection {
+		reply += "\n\n✅ Квест «" + q.Title + "» завершён."
+		change, err := h.lifeService.Resurrect(ctx, ch)
+		if err != nil {
+			log.Printf("resurrect error: %v", err)
+			reply += "\nГрань не отпустила душу (Ошибка магии)."
+		} else {
+			reply += "\n" + change.Text()
+		}
+	} else if res.Completed {
+		reply += "\n\n✅ Квест «" + q.Title + "» завершён."
+		sum, err := h.rewardService.ApplyQuest(ctx, ch, q, res)
+		if err != nil {
+			log.Printf("quest reward error: %v", err)
+			reply += "\nНаграда затерялась в пути (Ошибка магии)."
+		} else {
+			if s := h.rewardService.FormatSummary(sum); s != "" {
+				reply += "\n" + s
+			}
+			if sum.AbilityRequest != "" && h.cfg.GMUserID != 0 {
+				h.send(h.cfg.GMUserID, fmt.Sprintf("📜 %s (vk %d) завершил квест «%s» и просит способность «%s».\nВыдать: !gm ability %d %s",
+					ch.Name, ch.VKUserID, q.Title, sum.AbilityRequest, ch.VKUserID, sum.AbilityRequest))
+			}
+		}
+	}
+	h.send(peerID, reply)
 }
 
-func (h *Handler) handleSummaryRequest(_ context.Context, peerID, _ int) {
-	h.send(peerID, "Саммари пока не подключено.")
+func (h *Handler) handleReputation(ctx context.Context, peerID, fromID int) {
+	ch, ok := h.activeCharacter(ctx, peerID, fromID)
+	if !ok {
+		return
+	}
+	h.send(peerID, service.FormatReputation(ch.Name, ch.Reputation))
 }
 
-func (h *Handler) startOrAppendCharacterForm(ctx context.Context, peerID, fromID int, text string) {
-	h.formMu.Lock()
-	buf, exists := h.formBuf[int64(fromID)]
-	if !exists {
-		buf = &formBuffer{PeerID: peerID, StartedAt: time.Now()}
-		h.formBuf[int64(fromID)] = buf
-		h.send(peerID, "Начат ввод анкеты. Пиши и в конце: !анкета конец")
+func (h *Handler) handleEffects(ctx context.Context, peerID, fromID int) {
+	ch, ok := h.activeCharacter(ctx, peerID, fromID)
+	if !ok {
+		return
 	}
-	h.formMu.Unlock()
+	h.send(peerID, service.FormatEffects(ch.Name, ch.Effects, false))
+}
 
-	clean := strings.TrimSpace(strings.TrimPrefix(text, "!анкета"))
-	if clean != "" {
-		h.formMu.Lock()
-		buf.Raw.WriteString("\n" + clean)
-		h.formMu.Unlock()
+func (h *Handler) handleInventory(ctx context.Context, peerID, fromID int) {
+	ch, ok := h.activeCharacter(ctx, peerID, fromID)
+	if !ok {
+		return
 	}
+	h.send(peerID, service.FormatInventory(ch))
 }
 
-func (h *Handler) finishCharacterForm(ctx context.Context, peerID, fromID int) {
-	h.formMu.Lock()
-	buf, exists := h.formBuf[int64(fromID)]
-	if exists {
-		delete(h.formBuf, int64(fromID))
+func (h *Handler) handleUseItem(ctx context.Context, peerID, fromID int, target, text string) {
+	ch, ok := h.activeCharacter(ctx, peerID, fromID)
+	if !ok {
+		return
+	}
+	if !h.checkLife(ctx, peerID, ch, service.ActionItem) {
+		return
 	}
-	h.formMu.Unlock()
 
-	if !exists {
+	res, err := h.invService.UseItem(ctx, ch, target)
+	switch {
+	case errors.Is(err, service.ErrItemNotOwned):
+		h.send(peerID, "У тебя нет ничего похожего на «"+target+"». Загляни в !инвентарь.")
+		return
+	case errors.Is(err, service.ErrItemNotUsable):
+		if res.Item.EquipSlot != "" {
+			h.send(peerID, "«"+res.Item.Name+"» не используют — его экипируют.")
+		} else {
+			h.send(peerID, "«"+res.Item.Name+"» нельзя использовать.")
+		}
+		return
+	case errors.Is(err, repository.ErrFullHealth):
+		h.send(peerID, "Ты и так полон сил — «"+res.Item.Name+"» сейчас не нужен.")
+		return
+	case errors.Is(err, service.ErrEffectActive):
+		h.send(peerID, "«"+res.Effect+"» уже действует — «"+res.Item.Name+"» сейчас не поможет.")
+		return
+	case errors.Is(err, repository.ErrHealingLimit):
+		h.send(peerID, "Тело не примет больше лечения до следующего хода — «"+res.Item.Name+"» пока не поможет.")
+		return
+	case err != nil:
+		log.Printf("use item error: %v", err)
+		h.send(peerID, "Сфера пошла трещинами (Ошибка магии).")
 		return
 	}
 
-	raw := buf.Raw.String()
-	form, err := h.normalizeCharacterForm(ctx, raw)
-	if err != nil {
-		h.send(peerID, "Ошибка анкеты")
+	summary := res.Summary()
+	if change, err := h.lifeService.Sync(ctx, ch); err != nil {
+		log.Printf("life sync error: %v", err)
+	} else if change != nil && change.Text() != "" {
+		summary += " " + change.Text()
+	}
+	h.narrateItemAction(ctx, peerID, ch, text, summary)
+}
+
+func (h *Handler) handleEquip(ctx context.Context, peerID, fromID int, target, text string) {
+	ch, ok := h.activeCharacter(ctx, peerID, fromID)
+	if !ok {
+		return
+	}
+	if !h.checkLife(ctx, peerID, ch, service.ActionItem) {
 		return
 	}
 
-	ch, err := h.charService.UpdateFromNormalizedForm(ctx, int64(fromID), form)
-	if err == nil {
-		h.send(peerID, "Персонаж обновлен: "+ch.Name)
+	res, err := h.invService.Equip(ctx, ch, target)
+	switch {
+	case errors.Is(err, service.ErrItemNotOwned):
+		h.send(peerID, "У тебя нет ничего похожего на «"+target+"». Загляни в !инвентарь.")
+		return
+	case errors.Is(err, service.ErrItemNotEquip):
+		h.send(peerID, "«"+res.Item.Name+"» нельзя надеть или взять в руки.")
+		return
+	case errors.Is(err, service.ErrAlreadyEquipped):
+		h.send(peerID, "«"+res.Item.Name+"» уже экипирован.")
+		return
+	case err != nil:
+		log.Printf("equip error: %v", err)
+		h.send(peerID, "Сфера пошла трещинами (Ошибка магии).")
+		return
 	}
+
+	h.narrateItemAction(ctx, peerID, ch, text, res.Summary())
 }
 
-func (h *Handler) normalizeCharacterForm(ctx context.Context, raw string) (*models.NormalizedCharacterForm, error) {
-	prompt := llm.BuildCharacterNormalizePrompt(raw)
-	reply, err := h.llm.GeneratePlain(ctx, prompt)
+// narrateItemAction просит LLM описать уже применённый итог; цифры из
+// итога всегда дублируются отдельной строкой.
+func (h *Handler) narrateItemAction(ctx context.Context, peerID int, ch *models.Character, text, summary string) {
+	sc, err := h.sceneService.GetOrCreateSceneForCharacter(ctx, ch.ID)
 	if err != nil {
-		return nil, err
+		sc = models.Scene{Name: "Ошибка мира", LocationName: "Пустота"}
 	}
 
-	clean := strings.TrimSpace(reply)
-	clean = strings.TrimPrefix(clean, "```json")
-	clean = strings.TrimPrefix(clean, "```")
-	clean = strings.TrimSuffix(clean, "```")
-
-	var form models.NormalizedCharacterForm
-	if err := json.Unmarshal([]byte(clean), &form); err != nil {
-		return nil, err
+	story, err := h.llm.NarrateItemAction(ctx, llm.PlayerContext{
+		Character:     *ch,
+		Scene:         sc,
+		LocationTag:   sc.LocationName,
+		PlayerMessage: text,
+	}, summary)
+	if err != nil {
+		log.Printf("narrate item error: %v", err)
+		h.send(peerID, summary)
+		return
 	}
-	return &form, nil
+
+	h.send(peerID, strings.TrimSpace(story)+"\n\n⚙️ "+summary)
+}
+
+func (h *Handler) handleQuestDecision(_ context.Context, peerID, _ int, decision string) {
+	h.send(peerID, "Решения по квестам: "+decision)
+}
+
+func (h *Handler) handleSummaryRequest(_ context.Context, peerID, _ int) {
+	h.send(peerID, "Саммари пока не подключено.")
 }
@@ -0,0 +1,120 @@
+package vk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"aurora/internal/models"
+	"aurora/internal/service"
+)
+
+// checkLife сверяет состояние персонажа со здоровьем и проверяет, можно ли
+// ему сейчас действовать. При отказе игрок получает объяснение.
+func (h *Handler) checkLife(ctx context.Context, peerID int, ch *models.Character, action service.LifeAction) bool {
+	h.syncLife(ctx, peerID, ch)
+	if err := h.lifeService.Allow(ctx, ch, action); err != nil {
+		h.send(peerID, service.BlockedText(err))
+		return false
+	}
+	return true
+}
+
+// syncLife применяет смену состояния и сообщает о ней игроку.
+func (h *Handler) syncLife(ctx context.Context, peerID int, ch *models.Character) {
+	change, err := h.lifeService.Sync(ctx, ch)
+	if err != nil {
+		log.Printf("life sync error: %v", err)
+		return
+	}
+	if change != nil {
+		if text := change.Text(); text != "" {
+			h.send(peerID, text)
+		}
+	}
+}
+
+// sweepLife раз в минуту проверяет умирающих: кровотечение идёт по реальному
+// времени, даже когда в чате тихо и мировые часы стоят.
+func (h *Handler) sweepLife() {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for range ticker.C {
+		changes, err := h.lifeService.Sweep(context.Background())
+		if err != nil {
+			log.Printf("life sweep error: %v", err)
+		}
+		for _, c := range changes {
+			if text := c.Text(); text != "" {
+				h.send(int(c.VKUserID), text)
+			}
+		}
+	}
+}
+
+// handleNewCharacter заводит игроку нового персонажа. Мёртвый активный
+// персонаж при этом уходит в архив и не занимает место в лимите.
+func (h *Handler) handleNewCharacter(ctx context.Context, peerID, fromID int) {
+	ch, err := h.charService.GetActive(ctx, int64(fromID))
+	switch {
+	case err == nil:
+		h.syncLife(ctx, peerID, ch)
+		if ch.IsDead() {
+			if err := h.lifeService.Archive(ctx, ch); err != nil {
+				log.Printf("archive character error: %v", err)
+				h.send(peerID, "Сфера пошла трещинами (Ошибка магии).")
+				return
+			}
+			h.send(peerID, "🕯 Память о "+ch.Name+" сохранена в летописях.")
+		}
+	case !errors.Is(err, service.ErrNoCharacter):
+		log.Printf("get character error: %v", err)
+		h.send(peerID, "Сфера не видит твою ауру.")
+		return
+	}
+
+	fresh, err := h.charService.Create(ctx, int64(fromID))
+	if errors.Is(err, service.ErrCharacterLimit) {
+		h.send(peerID, fmt.Sprintf("У тебя уже %d персонажа в игре — это предел. Список: !персонажи", h.charService.MaxCharacters()))
+		return
+	}
+	if err != nil {
+		log.Printf("create character error: %v", err)
+		h.send(peerID, "Сфера пошла трещинами (Ошибка магии).")
+		return
+	}
+	h.send(peerID, fmt.Sprintf("✨ Новая душа (%s) ждёт своей истории: заполни !анкета. Вернуться к другим персонажам: !играть за <имя>", fresh.Name))
+}
+
+// handleResurrectionRequest передаёт ГМу просьбу мёртвого персонажа о воскрешении.
+func (h *Handler) handleResurrectionRequest(ctx context.Context, peerID, fromID int, plea string) {
+	ch, ok := h.activeCharacter(ctx, peerID, fromID)
+	if !ok {
+		return
+	}
+	h.syncLife(ctx, peerID, ch)
+
+	if !ch.IsDead() {
+		h.send(peerID, "Воскрешать некого: твой персонаж жив.")
+		return
+	}
+	if q, _ := h.lifeService.ResurrectionQuest(ctx, ch.ID); q != nil {
+		h.send(peerID, "Путь назад уже открыт: «"+q.Title+"». Действуй: !квест <действие>")
+		return
+	}
+	if h.cfg.GMUserID == 0 {
+		h.send(peerID, "ГМ не назначен — просить о воскрешении некого.")
+		return
+	}
+
+	msg := fmt.Sprintf("☠️ %s (vk %d) просит о воскрешении.", ch.Name, ch.VKUserID)
+	if plea = strings.TrimSpace(plea); plea != "" {
+		msg += "\n«" + plea + "»"
+	}
+	msg += fmt.Sprintf("\nОдобрить: !gm resurrect %d [| задание], вернуть сразу: !gm resurrect %d now", ch.VKUserID, ch.VKUserID)
+	h.send(h.cfg.GMUserID, msg)
+	h.send(peerID, "🕯 Твоя просьба услышана Гранью. ГМ решит, есть ли путь назад.")
+}
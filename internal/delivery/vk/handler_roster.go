@@ -0,0 +1,106 @@
+package vk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"aurora/internal/models"
+	"aurora/internal/service"
+)
+
+// activeCharacter загружает активного персонажа игрока. Если персонажа нет,
+// игрок получает подсказку, как его завести.
+func (h *Handler) activeCharacter(ctx context.Context, peerID, fromID int) (*models.Character, bool) {
+	ch, err := h.charService.GetActive(ctx, int64(fromID))
+	if errors.Is(err, service.ErrNoCharacter) {
+		h.send(peerID, "У тебя нет персонажа в игре. Создай его: !анкета, или выбери из своих: !персонажи")
+		return nil, false
+	}
+	if err != nil {
+		log.Printf("get character error: %v", err)
+		h.send(peerID, "Сфера не видит твою ауру.")
+		return nil, false
+	}
+	return ch, true
+}
+
+func (h *Handler) handleRoster(ctx context.Context, peerID, fromID int) {
+	roster, err := h.charService.Roster(ctx, int64(fromID))
+	if err != nil {
+		log.Printf("roster error: %v", err)
+		h.send(peerID, "Сфера не видит твою ауру.")
+		return
+	}
+	if len(roster) == 0 {
+		h.send(peerID, "У тебя пока нет персонажей. Создай первого: !анкета")
+		return
+	}
+
+	var activeID int64
+	if ch, err := h.charService.GetActive(ctx, int64(fromID)); err == nil {
+		activeID = ch.ID
+	}
+	var b strings.Builder
+	playable := 0
+	b.WriteString("👥 Твои персонажи:\n")
+	for _, ch := range roster {
+		mark := "•"
+		if ch.ID == activeID {
+			mark = "▶"
+		}
+		fmt.Fprintf(&b, "%s %s", mark, ch.Name)
+		if ch.Race != "" {
+			b.WriteString(", " + ch.Race)
+		}
+		fmt.Fprintf(&b, ", ур. %d, %s", ch.Level, ch.Status)
+		if ch.IsArchived() {
+			b.WriteString(" — в архиве")
+		} else {
+			playable++
+			if ch.LocationName != "" {
+				b.WriteString(" — " + ch.LocationName)
+			}
+		}
+		b.WriteString("\n")
+	}
+	fmt.Fprintf(&b, "В игре %d из %d. Сменить: !играть за <имя>, новый: !новый персонаж", playable, h.charService.MaxCharacters())
+	h.send(peerID, b.String())
+}
+
+// handleSwitchCharacter переключает активного персонажа. Посреди боя или
+// обмена переключаться нельзя: они привязаны к текущему персонажу.
+func (h *Handler) handleSwitchCharacter(ctx context.Context, peerID, fromID int, name string) {
+	if name == "" {
+		h.send(peerID, "Использование: !играть за <имя>. Список: !персонажи")
+		return
+	}
+	if cur, err := h.charService.GetActive(ctx, int64(fromID)); err == nil {
+		if _, inCombat := h.combatService.Active(cur.ID); inCombat {
+			h.send(peerID, "Сначала закончи бой: !бой <действие> или !бой отступить.")
+			return
+		}
+	}
+	if _, trading := h.tradeService.Active(int64(fromID)); trading {
+		h.send(peerID, "Сначала заверши обмен: !обмен да или !обмен отмена.")
+		return
+	}
+
+	ch, err := h.charService.Switch(ctx, int64(fromID), name)
+	if errors.Is(err, service.ErrCharacterNotFound) {
+		h.send(peerID, "Среди твоих персонажей в игре нет «"+name+"». Список: !персонажи")
+		return
+	}
+	if err != nil {
+		log.Printf("switch character error: %v", err)
+		h.send(peerID, "Сфера пошла трещинами (Ошибка магии).")
+		return
+	}
+	msg := fmt.Sprintf("🎭 Теперь ты играешь за %s (%s).", ch.Name, ch.Status)
+	if ch.LocationName != "" {
+		msg += " Локация: " + ch.LocationName + "."
+	}
+	h.send(peerID, msg)
+}
@@ -0,0 +1,163 @@
+package vk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"aurora/internal/repository"
+	"aurora/internal/service"
+)
+
+func (h *Handler) handleShop(ctx context.Context, peerID, fromID int, args string) {
+	ch, ok := h.activeCharacter(ctx, peerID, fromID)
+	if !ok {
+		return
+	}
+	sc, err := h.sceneService.GetOrCreateSceneForCharacter(ctx, ch.ID)
+	if err != nil {
+		h.send(peerID, "Сфера не может определить, где ты находишься.")
+		return
+	}
+
+	shops := h.econService.ShopsAt(sc.LocationName)
+	if len(shops) == 0 {
+		h.send(peerID, "В локации «"+sc.LocationName+"» торговцев нет.")
+		return
+	}
+
+	if args == "" && len(shops) > 1 {
+		var b strings.Builder
+		b.WriteString("Торговцы в локации «" + sc.LocationName + "»:\n")
+		for _, sh := range shops {
+			b.WriteString("— " + sh.Name)
+			if sh.Merchant != "" {
+				b.WriteString(" (" + sh.Merchant + ")")
+			}
+			b.WriteString("\n")
+		}
+		b.WriteString("\nПосмотреть товары: !магазин <название>")
+		h.send(peerID, b.String())
+		return
+	}
+
+	shop, err := h.econService.FindShop(sc.LocationName, args)
+	if err != nil {
+		h.send(peerID, "Такой лавки здесь нет.")
+		return
+	}
+
+	offers, err := h.econService.Offers(ctx, ch, *shop)
+	if errors.Is(err, service.ErrMerchantRefuses) {
+		h.send(peerID, shop.Merchant+" отворачивается: с тобой здесь не торгуют.")
+		return
+	}
+	if err != nil {
+		log.Printf("shop offers error: %v", err)
+		h.send(peerID, "Торговец занят. Попробуй позже.")
+		return
+	}
+
+	h.send(peerID, service.FormatShop(*shop, offers, ch.Gold))
+}
+
+func (h *Handler) handleBuy(ctx context.Context, peerID, fromID int, args string) {
+	target, qty := parseItemAndQty(args)
+	if target == "" {
+		h.send(peerID, "Использование: !купить <предмет> [кол-во]")
+		return
+	}
+
+	ch, ok := h.activeCharacter(ctx, peerID, fromID)
+	if !ok {
+		return
+	}
+	if !h.checkLife(ctx, peerID, ch, service.ActionTrade) {
+		return
+	}
+	sc, err := h.sceneService.GetOrCreateSceneForCharacter(ctx, ch.ID)
+	if err != nil {
+		h.send(peerID, "Сфера не может определить, где ты находишься.")
+		return
+	}
+
+	r, err := h.econService.Buy(ctx, ch, sc.LocationName, target, qty)
+	if err != nil {
+		h.send(peerID, shopErrorText(err, target))
+		return
+	}
+
+	h.send(peerID, fmt.Sprintf("🪙 Куплено: %s ×%d за %d ₸ (%d ₸/шт.) в «%s». Остаток: %d ₸.",
+		r.ItemName, r.Quantity, r.Total, r.UnitPrice, r.Shop.Name, r.Balance))
+}
+
+func (h *Handler) handleSell(ctx context.Context, peerID, fromID int, args string) {
+	target, qty := parseItemAndQty(args)
+	if target == "" {
+		h.send(peerID, "Использование: !продать <предмет> [кол-во]")
+		return
+	}
+
+	ch, ok := h.activeCharacter(ctx, peerID, fromID)
+	if !ok {
+		return
+	}
+	if !h.checkLife(ctx, peerID, ch, service.ActionTrade) {
+		return
+	}
+	sc, err := h.sceneService.GetOrCreateSceneForCharacter(ctx, ch.ID)
+	if err != nil {
+		h.send(peerID, "Сфера не может определить, где ты находишься.")
+		return
+	}
+
+	r, err := h.econService.Sell(ctx, ch, sc.LocationName, "", target, qty)
+	if err != nil {
+		h.send(peerID, shopErrorText(err, target))
+		return
+	}
+
+	h.send(peerID, fmt.Sprintf("🪙 Продано: %s ×%d за %d ₸ (%d ₸/шт.) в «%s». Теперь у тебя %d ₸.",
+		r.ItemName, r.Quantity, r.Total, r.UnitPrice, r.Shop.Name, r.Balance))
+}
+
+// parseItemAndQty отделяет необязательное количество в конце: "зелье лечения 3".
+func parseItemAndQty(args string) (string, int) {
+	fields := strings.Fields(args)
+	if len(fields) == 0 {
+		return "", 0
+	}
+	if n, err := strconv.Atoi(fields[len(fields)-1]); err == nil && len(fields) > 1 {
+		return strings.Join(fields[:len(fields)-1], " "), n
+	}
+	return strings.Join(fields, " "), 1
+}
+
+func shopErrorText(err error, target string) string {
+	switch {
+	case errors.Is(err, service.ErrNoShopHere):
+		return "Здесь некому продать или купить товар."
+	case errors.Is(err, service.ErrNotInShop):
+		return "Здешние торговцы не торгуют «" + target + "»."
+	case errors.Is(err, service.ErrItemNotOwned):
+		return "У тебя нет ничего похожего на «" + target + "»."
+	case errors.Is(err, service.ErrNoBuyer):
+		return "Торговец морщится: это ничего не стоит."
+	case errors.Is(err, service.ErrMerchantRefuses):
+		return "Торговец отворачивается: с тобой здесь не торгуют."
+	case errors.Is(err, service.ErrInvalidQuantity):
+		return "Количество должно быть положительным числом."
+	case errors.Is(err, repository.ErrNotEnoughGold):
+		return "Не хватает золота."
+	case errors.Is(err, repository.ErrOutOfStock):
+		return "Столько товара у торговца нет."
+	case errors.Is(err, repository.ErrNotEnoughItems):
+		return "У тебя нет столько предметов."
+	default:
+		log.Printf("shop error: %v", err)
+		return "Сделка сорвалась (Ошибка магии)."
+	}
+}
@@ -0,0 +1,164 @@
+package vk
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"aurora/internal/repository"
+	"aurora/internal/service"
+)
+
+const tradeUsage = `Обмен между игроками:
+!обмен @игрок — предложить обмен
+!обмен дать <предмет> [кол-во] — выложить предмет
+!обмен убрать <предмет> — забрать предмет со стола
+!обмен золото <сумма> — выложить золото (0 — убрать)
+!обмен да — подтвердить
+!обмен отмена — отменить`
+
+func (h *Handler) handleTrade(ctx context.Context, peerID, fromID int, args string) {
+	fields := strings.Fields(args)
+	if len(fields) == 0 {
+		if o, ok := h.tradeService.Active(int64(fromID)); ok {
+			h.send(peerID, service.FormatTrade(o))
+			return
+		}
+		h.send(peerID, tradeUsage)
+		return
+	}
+
+	sub := strings.ToLower(fields[0])
+	rest := strings.TrimSpace(strings.Join(fields[1:], " "))
+
+	switch sub {
+	case "дать", "добавить":
+		target, qty := parseItemAndQty(rest)
+		if target == "" {
+			h.send(peerID, "Использование: !обмен дать <предмет> [кол-во]")
+			return
+		}
+		ch, ok := h.activeCharacter(ctx, peerID, fromID)
+		if !ok {
+			return
+		}
+		if !h.checkLife(ctx, peerID, ch, service.ActionTrade) {
+			return
+		}
+		o, err := h.tradeService.AddItem(ch, target, qty)
+		h.replyTrade(peerID, o, err, target)
+
+	case "убрать":
+		if rest == "" {
+			h.send(peerID, "Использование: !обмен убрать <предмет>")
+			return
+		}
+		o, err := h.tradeService.RemoveItem(int64(fromID), rest)
+		h.replyTrade(peerID, o, err, rest)
+
+	case "золото":
+		amount, err := strconv.Atoi(rest)
+		if err != nil {
+			h.send(peerID, "Использование: !обмен золото <сумма>")
+			return
+		}
+		ch, ok := h.activeCharacter(ctx, peerID, fromID)
+		if !ok {
+			return
+		}
+		if !h.checkLife(ctx, peerID, ch, service.ActionTrade) {
+			return
+		}
+		o, err := h.tradeService.SetGold(ch, amount)
+		h.replyTrade(peerID, o, err, "")
+
+	case "да", "подтверждаю", "подтвердить":
+		o, done, err := h.tradeService.Confirm(ctx, int64(fromID))
+		if err != nil {
+			h.replyTrade(peerID, o, err, "")
+			return
+		}
+		if !done {
+			h.send(peerID, service.FormatTrade(o)+"\n\nЖдём подтверждения второй стороны: !обмен да")
+			return
+		}
+		h.send(peerID, "✅ Обмен состоялся.\n\n"+service.FormatTrade(o))
+
+	case "отмена", "отменить":
+		o, err := h.tradeService.Cancel(int64(fromID))
+		if err != nil {
+			h.replyTrade(peerID, o, err, "")
+			return
+		}
+		h.send(peerID, "Обмен между "+o.Sides[0].Name+" и "+o.Sides[1].Name+" отменён.")
+
+	default:
+		h.openTrade(ctx, peerID, fromID, args)
+	}
+}
+
+func (h *Handler) openTrade(ctx context.Context, peerID, fromID int, ref string) {
+	ch, ok := h.activeCharacter(ctx, peerID, fromID)
+	if !ok {
+		return
+	}
+	if !h.checkLife(ctx, peerID, ch, service.ActionTrade) {
+		return
+	}
+	partner, err := h.charService.FindPlayer(ctx, ref)
+	if err != nil || partner.IsArchived() {
+		h.send(peerID, "Сфера не нашла такого персонажа. Упомяни игрока: !обмен @игрок")
+		return
+	}
+	if partner.IsDead() {
+		h.send(peerID, partner.Name+" мёртв и не может торговать.")
+		return
+	}
+
+	o, err := h.tradeService.Open(ch, partner, peerID)
+	if err != nil {
+		h.replyTrade(peerID, o, err, "")
+		return
+	}
+	h.send(peerID, ch.Name+" предлагает обмен персонажу "+partner.Name+".\n\n"+service.FormatTrade(o)+"\n\n"+tradeUsage)
+}
+
+func (h *Handler) replyTrade(peerID int, o *service.TradeOffer, err error, target string) {
+	switch {
+	case err == nil:
+		h.send(peerID, service.FormatTrade(o))
+	case errors.Is(err, service.ErrNoTrade):
+		h.send(peerID, "У тебя нет открытого обмена. Начни: !обмен @игрок")
+	case errors.Is(err, service.ErrTradeBusy):
+		h.send(peerID, "Кто-то из вас уже участвует в обмене. Сначала заверши его: !обмен отмена")
+	case errors.Is(err, service.ErrTradeSelf):
+		h.send(peerID, "Меняться с самим собой бессмысленно.")
+	case errors.Is(err, service.ErrTradeEmpty):
+		h.send(peerID, "Стол пуст — нечего подтверждать.")
+	case errors.Is(err, service.ErrItemNotOwned):
+		h.send(peerID, "У тебя нет ничего похожего на «"+target+"».")
+	case errors.Is(err, service.ErrInvalidQuantity):
+		h.send(peerID, "Количество должно быть положительным числом.")
+	case errors.Is(err, repository.ErrNotEnoughItems):
+		h.send(peerID, "У тебя нет столько предметов. Подтверждения сброшены.")
+	case errors.Is(err, repository.ErrNotEnoughGold):
+		h.send(peerID, "Не хватает золота. Подтверждения сброшены.")
+	default:
+		log.Printf("trade error: %v", err)
+		h.send(peerID, "Обмен сорвался (Ошибка магии).")
+	}
+}
+
+// sweepTrades раз в минуту закрывает просроченные обмены и сообщает об этом в чат.
+func (h *Handler) sweepTrades() {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for now := range ticker.C {
+		for _, o := range h.tradeService.Sweep(now) {
+			h.send(o.PeerID, "⌛ Обмен между "+o.Sides[0].Name+" и "+o.Sides[1].Name+" истёк и отменён.")
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package vk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"aurora/internal/service"
+)
+
+func (h *Handler) handleTravel(ctx context.Context, peerID, fromID int, args string) {
+	ch, ok := h.activeCharacter(ctx, peerID, fromID)
+	if !ok {
+		return
+	}
+	sc, err := h.sceneService.GetOrCreateSceneForCharacter(ctx, ch.ID)
+	if err != nil {
+		h.send(peerID, "Сфера не может определить, где ты находишься.")
+		return
+	}
+
+	if args != "" && !h.checkLife(ctx, peerID, ch, service.ActionTravel) {
+		return
+	}
+	if args == "" {
+		here, err := h.locService.GetByName(ctx, sc.LocationName)
+		if err != nil {
+			h.send(peerID, "📍 "+sc.LocationName+". Этого места нет на картах Сферы.")
+			return
+		}
+		links, err := h.locService.Neighbors(ctx, here.ID)
+		if err != nil {
+			log.Printf("neighbors error: %v", err)
+			h.send(peerID, "Карты затянуло туманом.")
+			return
+		}
+		h.send(peerID, service.FormatNeighbors(here.Name, links))
+		return
+	}
+
+	res, err := h.travelService.Travel(ctx, ch, sc, args)
+	switch {
+	case errors.Is(err, service.ErrInCombat):
+		h.send(peerID, "Сначала закончи бой: !бой <действие> или !бой отступить.")
+		return
+	case errors.Is(err, service.ErrUnknownLocation):
+		h.send(peerID, "Такого места нет на картах Сферы.")
+		return
+	case errors.Is(err, service.ErrLocationClosed):
+		h.send(peerID, "Путь туда сейчас закрыт.")
+		return
+	case errors.Is(err, service.ErrAlreadyThere):
+		h.send(peerID, "Ты уже здесь.")
+		return
+	case errors.Is(err, service.ErrNoRoute):
+		h.send(peerID, "Туда не ведёт ни одна известная дорога.")
+		return
+	case errors.Is(err, service.ErrOffMap):
+		h.send(peerID, "Из «"+sc.LocationName+"» не ведут дороги, отмеченные на картах.")
+		return
+	case err != nil && res == nil:
+		log.Printf("travel error: %v", err)
+		h.send(peerID, "Дорога растворилась в тумане (Ошибка магии).")
+		return
+	case err != nil:
+		log.Printf("travel log error: %v", err)
+	}
+	// дорога — личное время путника: его эффекты отрабатывают все часы пути,
+	// а мир сдвигается как от обычного действия
+	h.passTime(ctx, ch.ID, int64(res.Hours)*60)
+	h.advanceClock(ctx, int64(h.cfg.MinutesPerAction))
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "🧭 %s в пути %d ч.\n", ch.Name, res.Hours)
+	for _, leg := range res.Passed {
+		fmt.Fprintf(&b, "— %s → %s (%d ч)\n", leg.From.Name, leg.To.Name, leg.Link.TravelHours)
+	}
+	if res.Encounter != nil {
+		fmt.Fprintf(&b, "\n⚔️ У локации «%s» путь преграждает %s! Опиши свой ход: !бой <действие>", res.Arrived.Name, res.Encounter.Enemy)
+		if len(res.Passed) < len(res.Route.Legs) {
+			b.WriteString("\nПосле боя можно продолжить путь: !путь " + res.Route.Legs[len(res.Route.Legs)-1].To.Name)
+		}
+	} else {
+		fmt.Fprintf(&b, "\n📍 Ты прибыл в «%s».", res.Arrived.Name)
+	}
+	h.send(peerID, b.String())
+}
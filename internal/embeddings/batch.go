@@ -0,0 +1,78 @@
+package embeddings
+
+import (
+	"context"
+	"sync"
+)
+
+// EmbedAll считает эмбеддинги texts пачками по MaxBatchSize в workers
+// потоков. Если пачка не прошла и после повторов, её тексты пробуются по
+// одному, чтобы один плохой текст не тянул за собой остальные. У каждого
+// текста в ответе либо вектор, либо ошибка. progress, если задан,
+// вызывается после каждой пачки с числом обработанных текстов.
+func EmbedAll(ctx context.Context, svc Service, texts []string, workers int, progress func(done, total int)) ([][]float32, []error) {
+	vectors := make([][]float32, len(texts))
+	errs := make([]error, len(texts))
+	if len(texts) == 0 {
+		return vectors, errs
+	}
+	workers = max(workers, 1)
+
+	batches := make(chan int)
+	go func() {
+		defer close(batches)
+		for start := 0; start < len(texts); start += MaxBatchSize {
+			select {
+			case batches <- start:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var mu sync.Mutex
+	var done int
+	var wg sync.WaitGroup
+	for w := 0; w < min(workers, (len(texts)+MaxBatchSize-1)/MaxBatchSize); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for start := range batches {
+				end := min(start+MaxBatchSize, len(texts))
+				embedBatch(ctx, svc, texts[start:end], vectors[start:end], errs[start:end])
+				if progress != nil {
+					mu.Lock()
+					done += end - start
+					progress(done, len(texts))
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	// пачки, до которых не дошло из-за отмены
+	for i := range texts {
+		if vectors[i] == nil && errs[i] == nil {
+			errs[i] = ctx.Err()
+		}
+	}
+	return vectors, errs
+}
+
+func embedBatch(ctx context.Context, svc Service, texts []string, vectors [][]float32, errs []error) {
+	batch, err := svc.EmbedBatch(ctx, texts)
+	if err == nil {
+		copy(vectors, batch)
+		return
+	}
+	if len(texts) == 1 || ctx.Err() != nil {
+		for i := range texts {
+			errs[i] = err
+		}
+		return
+	}
+	for i, text := range texts {
+		vectors[i], errs[i] = svc.Embed(ctx, text)
+	}
+}
@@ -0,0 +1,100 @@
+package embeddings
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+const (
+	// maxAttempts — попыток на запрос, считая первую.
+	maxAttempts = 4
+	// retryDelay — пауза перед первым повтором, дальше она удваивается.
+	retryDelay = time.Second
+)
+
+// apiClient — общая часть HTTP-провайдеров: ограничение темпа и повторы.
+type apiClient struct {
+	client  *http.Client
+	limiter *rateLimiter
+}
+
+func newAPIClient() apiClient {
+	return apiClient{
+		client: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+	}
+}
+
+// SetRateLimit ограничивает запросы к API числом в минуту на все потоки
+// сразу; пакетный запрос считается одним. 0 — без ограничения.
+func (c *apiClient) SetRateLimit(perMinute int) {
+	c.limiter = newRateLimiter(perMinute)
+}
+
+// call отправляет req в url и разбирает ответ в out. Сетевые ошибки, 429 и
+// 5xx повторяются с растущей паузой, остальные ответы — нет.
+func (c *apiClient) call(ctx context.Context, url string, header http.Header, req, out any) error {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return fmt.Errorf("marshal request: %w", err)
+	}
+
+	delay := retryDelay
+	for attempt := 1; ; attempt++ {
+		respBody, retry, err := c.post(ctx, url, header, body)
+		if err == nil {
+			if err := json.Unmarshal(respBody, out); err != nil {
+				return fmt.Errorf("unmarshal response: %w", err)
+			}
+			return nil
+		}
+		if !retry || attempt == maxAttempts {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+// post выполняет один запрос; retry — стоит ли его повторить.
+func (c *apiClient) post(ctx context.Context, url string, header http.Header, body []byte) ([]byte, bool, error) {
+	if err := c.limiter.wait(ctx); err != nil {
+		return nil, false, err
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
+	if err != nil {
+		return nil, false, fmt.Errorf("create request: %w", err)
+	}
+	for key, values := range header {
+		httpReq.Header[key] = values
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.client.Do(httpReq)
+	if err != nil {
+		return nil, ctx.Err() == nil, fmt.Errorf("do request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, true, fmt.Errorf("read response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		retry := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+		return nil, retry, fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(respBody))
+	}
+	return respBody, false, nil
+}
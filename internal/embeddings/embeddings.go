@@ -1,16 +1,13 @@
 package embeddings
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
 	"fmt"
-	"io"
-	"net/http"
-	"time"
+	"net/url"
 )
 
 const (
+	// EmbeddingModel и EmbeddingDims — модель Gemini по умолчанию.
 	EmbeddingModel = "text-embedding-004"
 	EmbeddingDims  = 768
 	MaxBatchSize   = 100
@@ -19,22 +16,32 @@ const (
 type Service interface {
 	Embed(ctx context.Context, text string) ([]float32, error)
 	EmbedBatch(ctx context.Context, texts []string) ([][]float32, error)
+	// Model и Dims — какой моделью и какой размерности считаются векторы.
+	// Векторы разных моделей несравнимы, поэтому хранилище помнит их для
+	// каждой строки.
+	Model() string
+	Dims() int
 }
 
 type GeminiEmbedder struct {
+	apiClient
 	apiKey string
-	client *http.Client
+	model  string
+	dims   int
 }
 
-func NewGeminiEmbedder(apiKey string) *GeminiEmbedder {
+func NewGeminiEmbedder(apiKey, model string, dims int) *GeminiEmbedder {
 	return &GeminiEmbedder{
-		apiKey: apiKey,
-		client: &http.Client{
-			Timeout: 30 * time.Second,
-		},
+		apiClient: newAPIClient(),
+		apiKey:    apiKey,
+		model:     model,
+		dims:      dims,
 	}
 }
 
+func (g *GeminiEmbedder) Model() string { return g.model }
+func (g *GeminiEmbedder) Dims() int     { return g.dims }
+
 type embeddingRequest struct {
 	Model   string `json:"model"`
 	Content struct {
@@ -75,47 +82,10 @@ func (g *GeminiEmbedder) Embed(ctx context.Context, text string) ([]float32, err
 		return nil, fmt.Errorf("empty text provided")
 	}
 
-	req := embeddingRequest{
-		Model: EmbeddingModel,
-	}
-	req.Content.Parts = []struct {
-		Text string `json:"text"`
-	}{{Text: text}}
-
-	body, err := json.Marshal(req)
-	if err != nil {
-		return nil, fmt.Errorf("marshal request: %w", err)
-	}
-
-	url := fmt.Sprintf("https://generativelanguage.googleapis.com/v1beta/models/%s:embedContent?key=%s",
-		EmbeddingModel, g.apiKey)
-
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
-	if err != nil {
-		return nil, fmt.Errorf("create request: %w", err)
-	}
-	httpReq.Header.Set("Content-Type", "application/json")
-
-	resp, err := g.client.Do(httpReq)
-	if err != nil {
-		return nil, fmt.Errorf("do request: %w", err)
-	}
-	defer resp.Body.Close()
-
-	respBody, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("read response: %w", err)
-	}
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(respBody))
-	}
-
 	var embResp embeddingResponse
-	if err := json.Unmarshal(respBody, &embResp); err != nil {
-		return nil, fmt.Errorf("unmarshal response: %w", err)
+	if err := g.call(ctx, g.endpoint("embedContent"), nil, newEmbeddingRequest(g.model, text), &embResp); err != nil {
+		return nil, err
 	}
-
 	if embResp.Error != nil {
 		return nil, fmt.Errorf("API error: %s (code %d)", embResp.Error.Message, embResp.Error.Code)
 	}
@@ -123,10 +93,14 @@ func (g *GeminiEmbedder) Embed(ctx context.Context, text string) ([]float32, err
 	if len(embResp.Embedding.Values) == 0 {
 		return nil, fmt.Errorf("empty embedding returned")
 	}
+	if err := checkDims(embResp.Embedding.Values, g.dims); err != nil {
+		return nil, err
+	}
 
 	return embResp.Embedding.Values, nil
 }
 
+// EmbedBatch — один запрос batchEmbedContents на все тексты.
 func (g *GeminiEmbedder) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
 	if len(texts) == 0 {
 		return nil, fmt.Errorf("empty texts slice")
@@ -136,14 +110,50 @@ func (g *GeminiEmbedder) EmbedBatch(ctx context.Context, texts []string) ([][]fl
 		return nil, fmt.Errorf("batch size %d exceeds maximum %d", len(texts), MaxBatchSize)
 	}
 
-	results := make([][]float32, len(texts))
+	req := batchEmbeddingRequest{Requests: make([]embeddingRequest, len(texts))}
 	for i, text := range texts {
-		embedding, err := g.Embed(ctx, text)
-		if err != nil {
-			return nil, fmt.Errorf("embed text %d: %w", i, err)
+		if text == "" {
+			return nil, fmt.Errorf("empty text %d", i)
+		}
+		// в пакете модель указывается полным именем ресурса
+		req.Requests[i] = newEmbeddingRequest("models/"+g.model, text)
+	}
+
+	var batchResp batchEmbeddingResponse
+	if err := g.call(ctx, g.endpoint("batchEmbedContents"), nil, req, &batchResp); err != nil {
+		return nil, err
+	}
+	if batchResp.Error != nil {
+		return nil, fmt.Errorf("API error: %s (code %d)", batchResp.Error.Message, batchResp.Error.Code)
+	}
+	if len(batchResp.Embeddings) != len(texts) {
+		return nil, fmt.Errorf("got %d embeddings for %d texts", len(batchResp.Embeddings), len(texts))
+	}
+
+	results := make([][]float32, len(texts))
+	for i, emb := range batchResp.Embeddings {
+		if len(emb.Values) == 0 {
+			return nil, fmt.Errorf("empty embedding returned for text %d", i)
+		}
+		if err := checkDims(emb.Values, g.dims); err != nil {
+			return nil, fmt.Errorf("text %d: %w", i, err)
 		}
-		results[i] = embedding
+		results[i] = emb.Values
 	}
 
 	return results, nil
 }
+
+func newEmbeddingRequest(model, text string) embeddingRequest {
+	req := embeddingRequest{Model: model}
+	req.Content.Parts = []struct {
+		Text string `json:"text"`
+	}{{Text: text}}
+	return req
+}
+
+// endpoint — адрес метода модели.
+func (g *GeminiEmbedder) endpoint(method string) string {
+	return fmt.Sprintf("https://generativelanguage.googleapis.com/v1beta/models/%s:%s?key=%s",
+		url.PathEscape(g.model), method, url.QueryEscape(g.apiKey))
+}
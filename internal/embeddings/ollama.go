@@ -0,0 +1,86 @@
+package embeddings
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// DefaultOllamaURL — адрес локального Ollama по умолчанию.
+const DefaultOllamaURL = "http://localhost:11434"
+
+// OllamaEmbedder ходит в POST {baseURL}/api/embed локального Ollama. Модель
+// должна быть скачана заранее (ollama pull).
+type OllamaEmbedder struct {
+	apiClient
+	baseURL string
+	model   string
+	dims    int
+}
+
+func NewOllamaEmbedder(baseURL, model string, dims int) *OllamaEmbedder {
+	return &OllamaEmbedder{
+		apiClient: newAPIClient(),
+		baseURL:   strings.TrimRight(baseURL, "/"),
+		model:     model,
+		dims:      dims,
+	}
+}
+
+func (o *OllamaEmbedder) Model() string { return o.model }
+func (o *OllamaEmbedder) Dims() int     { return o.dims }
+
+type ollamaRequest struct {
+	Model string   `json:"model"`
+	Input []string `json:"input"`
+}
+
+type ollamaResponse struct {
+	Embeddings [][]float32 `json:"embeddings"`
+	Error      string      `json:"error,omitempty"`
+}
+
+func (o *OllamaEmbedder) Embed(ctx context.Context, text string) ([]float32, error) {
+	if text == "" {
+		return nil, fmt.Errorf("empty text provided")
+	}
+	vectors, err := o.EmbedBatch(ctx, []string{text})
+	if err != nil {
+		return nil, err
+	}
+	return vectors[0], nil
+}
+
+func (o *OllamaEmbedder) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	if len(texts) == 0 {
+		return nil, fmt.Errorf("empty texts slice")
+	}
+	if len(texts) > MaxBatchSize {
+		return nil, fmt.Errorf("batch size %d exceeds maximum %d", len(texts), MaxBatchSize)
+	}
+	for i, text := range texts {
+		if text == "" {
+			return nil, fmt.Errorf("empty text %d", i)
+		}
+	}
+
+	var resp ollamaResponse
+	if err := o.call(ctx, o.baseURL+"/api/embed", nil, ollamaRequest{Model: o.model, Input: texts}, &resp); err != nil {
+		return nil, err
+	}
+	if resp.Error != "" {
+		return nil, fmt.Errorf("ollama error: %s", resp.Error)
+	}
+	if len(resp.Embeddings) != len(texts) {
+		return nil, fmt.Errorf("got %d embeddings for %d texts", len(resp.Embeddings), len(texts))
+	}
+	for i, vec := range resp.Embeddings {
+		if len(vec) == 0 {
+			return nil, fmt.Errorf("empty embedding returned for text %d", i)
+		}
+		if err := checkDims(vec, o.dims); err != nil {
+			return nil, fmt.Errorf("text %d: %w", i, err)
+		}
+	}
+	return resp.Embeddings, nil
+}
@@ -0,0 +1,109 @@
+package embeddings
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// DefaultOpenAIURL — API OpenAI; подходит и любой совместимый сервер
+// (vLLM, LM Studio, text-embeddings-inference).
+const DefaultOpenAIURL = "https://api.openai.com/v1"
+
+// OpenAIEmbedder ходит в POST {baseURL}/embeddings.
+type OpenAIEmbedder struct {
+	apiClient
+	baseURL string
+	apiKey  string
+	model   string
+	dims    int
+}
+
+// NewOpenAIEmbedder — apiKey может быть пустым для локальных серверов без
+// авторизации.
+func NewOpenAIEmbedder(baseURL, apiKey, model string, dims int) *OpenAIEmbedder {
+	return &OpenAIEmbedder{
+		apiClient: newAPIClient(),
+		baseURL:   strings.TrimRight(baseURL, "/"),
+		apiKey:    apiKey,
+		model:     model,
+		dims:      dims,
+	}
+}
+
+func (o *OpenAIEmbedder) Model() string { return o.model }
+func (o *OpenAIEmbedder) Dims() int     { return o.dims }
+
+type openAIRequest struct {
+	Model string   `json:"model"`
+	Input []string `json:"input"`
+	// Dimensions — укороченный вектор (text-embedding-3-*); без него сервер
+	// отдаёт родную размерность модели.
+	Dimensions int `json:"dimensions,omitempty"`
+}
+
+type openAIResponse struct {
+	Data []struct {
+		Index     int       `json:"index"`
+		Embedding []float32 `json:"embedding"`
+	} `json:"data"`
+}
+
+func (o *OpenAIEmbedder) Embed(ctx context.Context, text string) ([]float32, error) {
+	if text == "" {
+		return nil, fmt.Errorf("empty text provided")
+	}
+	vectors, err := o.EmbedBatch(ctx, []string{text})
+	if err != nil {
+		return nil, err
+	}
+	return vectors[0], nil
+}
+
+// EmbedBatch — один запрос со всеми текстами в input. Ответ может прийти не
+// по порядку, место вектора задаёт index.
+func (o *OpenAIEmbedder) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	if len(texts) == 0 {
+		return nil, fmt.Errorf("empty texts slice")
+	}
+	if len(texts) > MaxBatchSize {
+		return nil, fmt.Errorf("batch size %d exceeds maximum %d", len(texts), MaxBatchSize)
+	}
+	for i, text := range texts {
+		if text == "" {
+			return nil, fmt.Errorf("empty text %d", i)
+		}
+	}
+
+	header := http.Header{}
+	if o.apiKey != "" {
+		header.Set("Authorization", "Bearer "+o.apiKey)
+	}
+	req := openAIRequest{Model: o.model, Input: texts}
+	if o.dims != KnownDims(o.model) {
+		req.Dimensions = o.dims
+	}
+	var resp openAIResponse
+	if err := o.call(ctx, o.baseURL+"/embeddings", header, req, &resp); err != nil {
+		return nil, err
+	}
+	if len(resp.Data) != len(texts) {
+		return nil, fmt.Errorf("got %d embeddings for %d texts", len(resp.Data), len(texts))
+	}
+
+	results := make([][]float32, len(texts))
+	for _, item := range resp.Data {
+		if item.Index < 0 || item.Index >= len(texts) || results[item.Index] != nil {
+			return nil, fmt.Errorf("bad embedding index %d", item.Index)
+		}
+		if len(item.Embedding) == 0 {
+			return nil, fmt.Errorf("empty embedding returned for text %d", item.Index)
+		}
+		if err := checkDims(item.Embedding, o.dims); err != nil {
+			return nil, fmt.Errorf("text %d: %w", item.Index, err)
+		}
+		results[item.Index] = item.Embedding
+	}
+	return results, nil
+}
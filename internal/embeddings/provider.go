@@ -0,0 +1,110 @@
+package embeddings
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Провайдеры эмбеддингов для Options.Provider.
+const (
+	ProviderGemini = "gemini"
+	ProviderOpenAI = "openai"
+	ProviderOllama = "ollama"
+)
+
+// defaultModels — модель провайдера, если она не указана.
+var defaultModels = map[string]string{
+	ProviderGemini: EmbeddingModel,
+	ProviderOpenAI: "text-embedding-3-small",
+	ProviderOllama: "nomic-embed-text",
+}
+
+// knownDims — размерности известных моделей; для остальных её нужно задать
+// явно.
+var knownDims = map[string]int{
+	"text-embedding-004":     768,
+	"gemini-embedding-001":   3072,
+	"text-embedding-3-small": 1536,
+	"text-embedding-3-large": 3072,
+	"text-embedding-ada-002": 1536,
+	"nomic-embed-text":       768,
+	"mxbai-embed-large":      1024,
+	"all-minilm":             384,
+	"bge-m3":                 1024,
+}
+
+// Options — какой моделью считать эмбеддинги.
+type Options struct {
+	Provider string
+	// Model — пусто: модель провайдера по умолчанию.
+	Model string
+	// Dims — 0: размерность известной модели.
+	Dims int
+	// BaseURL — для openai и ollama; пусто — адрес по умолчанию.
+	BaseURL string
+	APIKey  string
+	// RateLimit — запросов в минуту, 0 — без ограничения.
+	RateLimit int
+}
+
+// NewEmbedder создаёт эмбеддер провайдера из opts.
+func NewEmbedder(opts Options) (Service, error) {
+	provider := opts.Provider
+	if provider == "" {
+		provider = ProviderGemini
+	}
+	model := opts.Model
+	if model == "" {
+		model = defaultModels[provider]
+	}
+	dims := opts.Dims
+	if dims == 0 {
+		dims = KnownDims(model)
+	}
+	if dims <= 0 {
+		return nil, fmt.Errorf("unknown dimension of embedding model %q, set it explicitly", model)
+	}
+
+	switch provider {
+	case ProviderGemini:
+		if opts.APIKey == "" {
+			return nil, fmt.Errorf("gemini embeddings need an API key")
+		}
+		e := NewGeminiEmbedder(opts.APIKey, model, dims)
+		e.SetRateLimit(opts.RateLimit)
+		return e, nil
+	case ProviderOpenAI:
+		baseURL := opts.BaseURL
+		if baseURL == "" {
+			baseURL = DefaultOpenAIURL
+		}
+		e := NewOpenAIEmbedder(baseURL, opts.APIKey, model, dims)
+		e.SetRateLimit(opts.RateLimit)
+		return e, nil
+	case ProviderOllama:
+		baseURL := opts.BaseURL
+		if baseURL == "" {
+			baseURL = DefaultOllamaURL
+		}
+		e := NewOllamaEmbedder(baseURL, model, dims)
+		e.SetRateLimit(opts.RateLimit)
+		return e, nil
+	}
+	return nil, fmt.Errorf("unknown embedding provider %q", provider)
+}
+
+// KnownDims — размерность известной модели, 0 — неизвестна.
+func KnownDims(model string) int {
+	// у моделей Ollama бывает тег версии: nomic-embed-text:latest
+	base, _, _ := strings.Cut(model, ":")
+	return knownDims[base]
+}
+
+// checkDims не даёт записать вектор не той размерности, что обещана
+// хранилищу: сервер мог молча подменить модель.
+func checkDims(vec []float32, dims int) error {
+	if len(vec) != dims {
+		return fmt.Errorf("embedding dimension %d, want %d", len(vec), dims)
+	}
+	return nil
+}
@@ -22,6 +22,29 @@ const (
 	ModelPro   = "gemini-3-pro-preview"
 )
 
+// Пороги и веса поиска лора по типам вызовов. Оценки пишутся в лог
+// «rag <label>», по нему пороги и подбираются. Вес слов выше там, где в
+// запросе чаще имена и названия. Лапидарию, отвечающему по книгам, нужен
+// связный текст — ему разделы целиком и краткое содержание; бою хватает
+// коротких фрагментов. Прибавка за зону больше там, где важна обстановка
+// вокруг, и меньше у Лапидария: его спрашивают обо всём мире.
+var (
+	retrievalPlayer = rag.RetrievalOptions{Label: "player", Limit: 5, MinScore: 0.5, RelativeScore: 0.75, VectorWeight: 1, KeywordWeight: 0.5,
+		Diversity: 0.3, ZoneBoost: 0.05}
+	retrievalQuest = rag.RetrievalOptions{Label: "quest", Limit: 5, MinScore: 0.5, RelativeScore: 0.75, VectorWeight: 1, KeywordWeight: 0.7,
+		Diversity: 0.3, Neighbors: 1, ZoneBoost: 0.05}
+	retrievalCombat = rag.RetrievalOptions{Label: "combat", Limit: 5, MinScore: 0.55, RelativeScore: 0.8, VectorWeight: 1, KeywordWeight: 0.3,
+		ZoneBoost: 0.08}
+	retrievalLapidarius = rag.RetrievalOptions{Label: "lapidarius", Limit: 7, MinScore: 0.45, RelativeScore: 0.7, VectorWeight: 1, KeywordWeight: 1,
+		Diversity: 0.3, ParentChars: 3000, Neighbors: 1, SummaryAfter: 2, ZoneBoost: 0.02}
+)
+
+// inZone — профиль поиска с фильтром по зоне; предков зоны добавляет rag.
+func inZone(profile rag.RetrievalOptions, zone string) rag.RetrievalOptions {
+	profile.Filters = map[string]string{"zone": zone}
+	return profile
+}
+
 type GeminiClient struct {
 	apiKey     string
 	model      string
@@ -197,7 +220,7 @@ func (c *GeminiClient) GenerateForPlayer(ctx context.Context, pCtx PlayerContext
 	pCtx.PlayerMessage = sanitized.CleanInput
 
 	// Проверка способностей
-	if !ValidateAbilityUse(pCtx.PlayerMessage, pCtx.Character.Abilities) {
+	if !ValidateAbilityUse(pCtx.PlayerMessage, pCtx.Character.UsableAbilities()) {
 		return "Сфера молчит. Ты пытаешься использовать силу, которой не обладаешь. Проверь свои способности.", nil
 	}
 
@@ -208,12 +231,7 @@ func (c *GeminiClient) GenerateForPlayer(ctx context.Context, pCtx PlayerContext
 	if c.ragService != nil {
 		query := fmt.Sprintf("Персонаж %s в локации %s. %s", pCtx.Character.Name, pCtx.LocationTag, pCtx.PlayerMessage)
 		var err error
-		loreBlocks, err = c.ragService.RetrieveRelevant(ctx, query, rag.RetrievalOptions{
-			Limit: 5,
-			Filters: map[string]string{
-				"zone": pCtx.LocationTag,
-			},
-		})
+		loreBlocks, err = c.ragService.RetrieveRelevant(ctx, query, inZone(retrievalPlayer, pCtx.LocationTag))
 		if err != nil {
 			loreBlocks = c.loreRepo.SelectRelevant(pCtx.LocationTag, pCtx.FactionTag, pCtx.CustomTags)
 		}
@@ -306,12 +324,7 @@ func (c *GeminiClient) GenerateQuestProgress(ctx context.Context, qCtx QuestProg
 	if c.ragService != nil {
 		query := fmt.Sprintf("Квест %s: %s в локации %s", qCtx.Quest.Title, qCtx.PlayerAction, qCtx.Scene.LocationName)
 		var err error
-		loreBlocks, err = c.ragService.RetrieveRelevant(ctx, query, rag.RetrievalOptions{
-			Limit: 5,
-			Filters: map[string]string{
-				"zone": qCtx.Scene.LocationName,
-			},
-		})
+		loreBlocks, err = c.ragService.RetrieveRelevant(ctx, query, inZone(retrievalQuest, qCtx.Scene.LocationName))
 		if err != nil {
 			loreBlocks = c.loreRepo.SelectRelevant(qCtx.Scene.LocationName, qCtx.Character.FactionName, []string{"экономика", "квест"})
 		}
@@ -352,12 +365,7 @@ func (c *GeminiClient) GenerateCombatTurn(ctx context.Context, cCtx CombatContex
 	if c.ragService != nil {
 		query := fmt.Sprintf("Бой в локации %s: %s", cCtx.Scene.LocationName, cCtx.PlayerAction)
 		var err error
-		loreBlocks, err = c.ragService.RetrieveRelevant(ctx, query, rag.RetrievalOptions{
-			Limit: 5,
-			Filters: map[string]string{
-				"zone": cCtx.Scene.LocationName,
-			},
-		})
+		loreBlocks, err = c.ragService.RetrieveRelevant(ctx, query, inZone(retrievalCombat, cCtx.Scene.LocationName))
 		if err != nil {
 			loreBlocks = c.loreRepo.SelectRelevant(cCtx.Scene.LocationName, cCtx.Character.FactionName, []string{"бой", "магия", "экономика"})
 		}
@@ -386,7 +394,7 @@ func (c *GeminiClient) GenerateCombatTurn(ctx context.Context, cCtx CombatContex
 	result := parseCombatResult(raw)
 
 	// Валидация результатов боя (ограничение урона)
-	ValidateCombatResult(&result, cCtx.Character.CombatHealth, 100)
+	ValidateCombatResult(&result, cCtx.Character.CombatHealth, cCtx.EnemyHP)
 
 	return result, nil
 }
@@ -397,12 +405,7 @@ func (c *GeminiClient) AskLapidarius(ctx context.Context, pCtx PlayerContext, qu
 	var loreBlocks []lore.Chunk
 	if c.ragService != nil {
 		var err error
-		loreBlocks, err = c.ragService.RetrieveRelevant(ctx, question, rag.RetrievalOptions{
-			Limit: 7,
-			Filters: map[string]string{
-				"zone": pCtx.LocationTag,
-			},
-		})
+		loreBlocks, err = c.ragService.RetrieveRelevant(ctx, question, inZone(retrievalLapidarius, pCtx.LocationTag))
 		if err != nil {
 			searchTags := append(pCtx.CustomTags, question)
 			loreBlocks = c.loreRepo.SelectRelevant(pCtx.LocationTag, pCtx.FactionTag, searchTags)
@@ -457,3 +460,15 @@ func (c *GeminiClient) Summarize(ctx context.Context, oldSummary string, newMess
 
 	return c.callGenerateContent(ctx, prompt, opts)
 }
+
+func (c *GeminiClient) NarrateItemAction(ctx context.Context, pCtx PlayerContext, outcome string) (string, error) {
+	prompt := BuildItemNarrationPrompt(pCtx, outcome)
+
+	opts := &GenOptions{
+		Model:       ModelFast,
+		Temperature: 0.8,
+		MaxTokens:   600,
+	}
+
+	return c.callGenerateContent(ctx, prompt, opts)
+}
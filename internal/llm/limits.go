@@ -14,6 +14,8 @@ const (
 	MaxHealingPerTurn = 30
 
 	MaxItemValue = 200
+
+	MaxQuestReputation = 15
 )
 
 func ValidateQuestReward(result *QuestProgressResult) {
@@ -24,12 +26,26 @@ func ValidateQuestReward(result *QuestProgressResult) {
 		result.RewardGold = 0
 	}
 
+	if result.RewardReputation > MaxQuestReputation {
+		result.RewardReputation = MaxQuestReputation
+	}
+	if result.RewardReputation < -MaxQuestReputation {
+		result.RewardReputation = -MaxQuestReputation
+	}
+
 	if len(result.RewardItems) > MaxItemsPerQuest {
 		result.RewardItems = result.RewardItems[:MaxItemsPerQuest]
 	}
 }
 
 func ValidateCombatResult(result *CombatResult, originalPlayerHP, originalEnemyHP int) {
+	// Ответ модели не разобран — состояние боя не меняем.
+	if result.PlayerHP == -1 && result.EnemyHP == -1 {
+		result.PlayerHP = originalPlayerHP
+		result.EnemyHP = originalEnemyHP
+		return
+	}
+
 	damageTaken := originalPlayerHP - result.PlayerHP
 	if damageTaken > MaxDamagePerHit {
 		result.PlayerHP = originalPlayerHP - MaxDamagePerHit
@@ -40,6 +56,13 @@ func ValidateCombatResult(result *CombatResult, originalPlayerHP, originalEnemyH
 		result.EnemyHP = originalEnemyHP - MaxDamagePerHit
 	}
 
+	// Если исход держался на урезанном ударе, бой продолжается.
+	if (result.Winner == "player" && damageDealt > MaxDamagePerHit && result.EnemyHP > 0) ||
+		(result.Winner == "enemy" && damageTaken > MaxDamagePerHit && result.PlayerHP > 0) {
+		result.Winner = "none"
+		result.IsFinished = false
+	}
+
 	if result.PlayerHP > 100 {
 		result.PlayerHP = 100
 	}
@@ -149,7 +149,9 @@ func (c *OpenAIClient) GenerateQuestProgress(ctx context.Context, qCtx QuestProg
 	if err != nil {
 		return QuestProgressResult{}, err
 	}
-	return parseQuestProgress(raw), nil
+	result := parseQuestProgress(raw)
+	ValidateQuestReward(&result)
+	return result, nil
 }
 
 func (c *OpenAIClient) GenerateCombatTurn(ctx context.Context, cCtx CombatContext) (CombatResult, error) {
@@ -165,5 +167,12 @@ func (c *OpenAIClient) GenerateCombatTurn(ctx context.Context, cCtx CombatContex
 	if err != nil {
 		return CombatResult{}, err
 	}
-	return parseCombatResult(raw), nil
+	result := parseCombatResult(raw)
+	ValidateCombatResult(&result, cCtx.Character.CombatHealth, cCtx.EnemyHP)
+	return result, nil
+}
+
+func (c *OpenAIClient) NarrateItemAction(ctx context.Context, pCtx PlayerContext, outcome string) (string, error) {
+	msgs := []chatMessage{{Role: "user", Content: BuildItemNarrationPrompt(pCtx, outcome)}}
+	return c.callChat(ctx, msgs)
 }
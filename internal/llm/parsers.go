@@ -19,6 +19,7 @@ func parseQuestProgress(raw string) QuestProgressResult {
 		RewardGold       int      `json:"reward_gold"`
 		RewardItems      []string `json:"reward_items"`
 		RewardReputation int      `json:"reward_reputation"`
+		UnlockAbility    string   `json:"unlock_ability"`
 	}
 
 	var temp questJson
@@ -29,11 +30,13 @@ func parseQuestProgress(raw string) QuestProgressResult {
 	}
 
 	return QuestProgressResult{
-		Stage:       temp.Stage,
-		Completed:   temp.Completed,
-		Narration:   temp.Narration,
-		RewardGold:  temp.RewardGold,
-		RewardItems: temp.RewardItems,
+		Stage:            temp.Stage,
+		Completed:        temp.Completed,
+		Narration:        temp.Narration,
+		RewardGold:       temp.RewardGold,
+		RewardItems:      temp.RewardItems,
+		RewardReputation: temp.RewardReputation,
+		UnlockAbility:    temp.UnlockAbility,
 	}
 }
 
@@ -15,6 +15,7 @@ func BuildPlayerSystemPrompt() string {
 - Давать персонажу идеи действий.
 - Предлагать личные побочные квесты, не переписывая глобальный сюжет.
 - Учитывать характер, фракцию, локацию, активные квесты и экономику мира.
+- Учитывать время суток, погоду и праздники из поля "Время" сцены.
 - Не отменять решения живого ведущего (GM) и не устраивать мировых катастроф.
 
 ФОРМАТ КВЕСТА:
@@ -23,7 +24,13 @@ func BuildPlayerSystemPrompt() string {
 [QUEST_DESCRIPTION]: ...
 [QUEST_TYPE]: побочный / личная цель / моральный выбор
 [QUEST_DIFFICULTY]: trivial / easy / normal / hard / deadly
-[QUEST_VALUE]: целое число, отражающее примерную ценность награды с точки зрения экономики (10–500, НЕ БОЛЬШЕ).`
+[QUEST_VALUE]: целое число, отражающее примерную ценность награды с точки зрения экономики (10–500, НЕ БОЛЬШЕ).
+[QUEST_FACTION]: фракция-заказчик из списка [ФРАКЦИИ МИРА] или "нет"
+[QUEST_DEADLINE]: срок в игровых днях (1–30) или "нет", если спешить некуда
+
+РЕПУТАЦИЯ:
+- Фракции, у которых персонаж в немилости (Враждебность, Ненависть), НЕ дают ему поручений.
+- Чем выше репутация, тем доверительнее и выгоднее поручения фракции.`
 }
 
 func BuildGMSystemPrompt() string {
@@ -149,15 +156,19 @@ func BuildPlayerContextBlock(pctx PlayerContext, coreLore string, loreChunks []l
 Черты характера: %s
 Личная цель: %s
 Способности: %s
+Инвентарь: %s
 Краткая биография: %s
 Состояние: %s
+Уровень: %d (опыт: %d)
 Боевой потенциал: %d
 Здоровье: %d
 Золото: %d
+Репутация: %s
 
 [СЦЕНА]
 Название: %s
 Локация: %s
+Время: %s
 Краткое резюме сцены: %s
 
 [КРАТКАЯ ИСТОРИЯ СЦЕНЫ]
@@ -166,25 +177,31 @@ func BuildPlayerContextBlock(pctx PlayerContext, coreLore string, loreChunks []l
 %s
 
 %s
-
+%s
 Учти всё выше и отвечай от лица мира/советника для этого персонажа.`,
 		coreLore,
 		ch.Name,
 		ch.Race,
 		ch.Traits,
 		ch.Goal,
-		ch.Abilities,
+		buildAbilitiesLine(ch),
+		buildInventoryList(ch.Items),
 		ch.Bio,
 		ch.Status,
-		ch.CombatPower,
+		ch.Level,
+		ch.XP,
+		ch.EffectiveCombatPower(),
 		ch.CombatHealth,
 		ch.Gold,
+		buildReputationList(ch.Reputation),
 		sc.Name,
 		sc.LocationName,
+		worldTime(sc),
 		sc.Summary,
 		pctx.History,
 		loreBlock,
 		questBlock,
+		buildFactionsBlock(pctx.Factions),
 	)
 }
 
@@ -192,26 +209,33 @@ func BuildQuestSystemPrompt() string {
 	return `Ты — системный помощник по квестам в мире "Аврора".
 Твоя задача — по действиям игрока определить прогресс квеста, его завершение и награду, уважая экономику мира.
 
-Отвечай строго в формате с блоками:
-[STAGE]: <число>
-[COMPLETED]: да/нет
-[NARRATION]: <описание последствий>
-[REWARD_GOLD]: <целое число>
-[REWARD_ITEMS]:
-- ...
-[REWARD_REPUTATION]:
-- ...
+Отвечай строго валидным JSON без пояснений:
+{
+  "stage": 2,                // новая стадия квеста
+  "completed": false,        // завершён ли квест
+  "narration": "...",        // описание последствий (до 120 слов)
+  "reward_gold": 0,          // только если квест завершён
+  "reward_items": [],        // только если квест завершён, не больше 3
+  "reward_reputation": 0,    // изменение репутации у фракции-заказчика, от -15 до 15
+  "unlock_ability": ""       // только если персонаж по сюжету действительно освоил новый навык
+}
 
 Учитывай поля [QUEST_DIFFICULTY] и [QUEST_VALUE]:
 - trivial/easy: небольшие награды,
 - normal: умеренные,
 - hard/deadly: ощутимые, но не ломают экономику,
-- epic: очень крупные, но редкие.`
+- epic: очень крупные, но редкие.
+
+Новые способности — редкость: выдавай их, только если действия игрока прямо вели к обучению (наставник, тренировка, древний свиток).`
 }
 
 func BuildQuestProgressPrompt(qCtx QuestProgressContext, coreLore string, loreChunks []lore.Chunk) string {
 	q := qCtx.Quest
 	loreBlock := buildLoreBlock(loreChunks)
+	questFaction := q.FactionName
+	if questFaction == "" {
+		questFaction = "нет"
+	}
 
 	return fmt.Sprintf(
 		`[БАЗОВЫЙ ЛОР МИРА]
@@ -222,6 +246,7 @@ func BuildQuestProgressPrompt(qCtx QuestProgressContext, coreLore string, loreCh
 [QUEST_DESCRIPTION]: %s
 [QUEST_DIFFICULTY]: %s
 [QUEST_VALUE]: %d
+Фракция-заказчик: %s
 Текущая стадия: %d
 Статус: %s
 
@@ -233,6 +258,7 @@ func BuildQuestProgressPrompt(qCtx QuestProgressContext, coreLore string, loreCh
 [СЦЕНА]
 Название: %s
 Локация: %s
+Время: %s
 
 [КРАТКАЯ ИСТОРИЯ СЦЕНЫ]
 %s
@@ -252,6 +278,7 @@ func BuildQuestProgressPrompt(qCtx QuestProgressContext, coreLore string, loreCh
 		q.Description,
 		q.Difficulty,
 		q.RewardValue,
+		questFaction,
 		q.Stage,
 		q.Status,
 		qCtx.Character.Name,
@@ -259,6 +286,7 @@ func BuildQuestProgressPrompt(qCtx QuestProgressContext, coreLore string, loreCh
 		qCtx.Character.Gold,
 		qCtx.Scene.Name,
 		qCtx.Scene.LocationName,
+		worldTime(qCtx.Scene),
 		qCtx.History,
 		loreBlock,
 		qCtx.PlayerAction,
@@ -297,6 +325,15 @@ func BuildCombatPrompt(cCtx CombatContext, coreLore string, loreChunks []lore.Ch
 	sc := cCtx.Scene
 	loreBlock := buildLoreBlock(loreChunks)
 
+	enemy := cCtx.EnemyName
+	if enemy == "" {
+		enemy = "Определи противника по ходу игрока и сцене"
+	}
+	enemyStatus := cCtx.EnemyStatus
+	if enemyStatus == "" {
+		enemyStatus = "невредим"
+	}
+
 	questPart := ""
 	if cCtx.Quest != nil {
 		questPart = fmt.Sprintf(
@@ -312,12 +349,19 @@ func BuildCombatPrompt(cCtx CombatContext, coreLore string, loreChunks []lore.Ch
 [ПЕРСОНАЖ]
 Имя: %s
 Фракция: %s
+Уровень: %d
 Боевой потенциал: %d
 Здоровье: %d
 
+[ПРОТИВНИК]
+%s
+Здоровье: %d
+Состояние: %s
+
 [СЦЕНА]
 Название: %s
 Локация: %s
+Время: %s
 
 %s
 
@@ -330,10 +374,15 @@ func BuildCombatPrompt(cCtx CombatContext, coreLore string, loreChunks []lore.Ch
 		coreLore,
 		ch.Name,
 		ch.FactionName,
-		ch.CombatPower,
+		ch.Level,
+		ch.EffectiveCombatPower(),
 		ch.CombatHealth,
+		enemy,
+		cCtx.EnemyHP,
+		enemyStatus,
 		sc.Name,
 		sc.LocationName,
+		worldTime(sc),
 		questPart,
 		loreBlock,
 		cCtx.PlayerAction,
@@ -392,50 +441,143 @@ func BuildLapidariusSystemPrompt() string {
 Используя [КОНТЕКСТ], ответь на [ВОПРОС ПЕРСОНАЖА]. Если ответа нет в контексте, скажи, что "архивы повреждены", но не выдумывай.`
 }
 
+func BuildItemNarrationPrompt(pctx PlayerContext, outcome string) string {
+	return fmt.Sprintf(`Ты — рассказчик мира мрачного фэнтези "Аврора".
+Механика действия уже рассчитана движком. Твоя задача — только описать её.
+
+ПРАВИЛА:
+- Не меняй цифры и факты из блока [ИТОГ]. Не добавляй лечения, эффектов или предметов, которых там нет.
+- 2–4 предложения, прошедшее время, третье лицо.
+- Учитывай состояние персонажа и место действия.
+
+%s
+[СЦЕНА]
+Локация: %s
+
+[ДЕЙСТВИЕ ИГРОКА]
+%s
+
+[ИТОГ]
+%s`, buildCharacterBlock(pctx.Character), pctx.Scene.LocationName, pctx.PlayerMessage, outcome)
+}
+
 func buildCharacterBlock(ch models.Character) string {
-	abilities := ch.Abilities
-	if abilities == "" {
-		abilities = "Нет особых способностей"
-	}
-	inventory := ch.Inventory
-	if inventory == "" {
-		inventory = "Пусто"
-	}
+	abilities := buildAbilitiesLine(ch)
+	inventory := buildInventoryList(ch.Items)
 
 	return fmt.Sprintf(`[ПЕРСОНАЖ]
 Имя: %s
 Раса: %s
 Класс: %s
+Уровень: %d (опыт: %d)
 Здоровье: %d
+Состояние: %s
 Боевой потенциал (CombatPower): %d
 Способности: %s
 Инвентарь: %s
 Эффекты: %s
-`, ch.Name, ch.Race, ch.Class, ch.CombatHealth, ch.CombatPower, abilities, inventory, buildEffectsList(ch.Effects))
+Репутация: %s
+`, ch.Name, ch.Race, ch.Class, ch.Level, ch.XP, ch.CombatHealth, ch.GetStatusDescription(), ch.EffectiveCombatPower(), abilities, inventory, buildEffectsList(ch.Effects), buildReputationList(ch.Reputation))
 }
 
-func buildEffectsList(effects []models.Effect) string {
-	if len(effects) == 0 {
-		return "Нет"
+// buildAbilitiesLine — способности с пометкой о заблокированных эффектами.
+func buildAbilitiesLine(ch models.Character) string {
+	usable, blocked := ch.SplitAbilities()
+	if len(usable) == 0 && len(blocked) == 0 {
+		return "Нет особых способностей"
+	}
+	line := strings.Join(usable, ", ")
+	if line == "" {
+		line = "нет доступных"
+	}
+	if len(blocked) > 0 {
+		line += " (заблокированы эффектами, использовать нельзя: " + strings.Join(blocked, ", ") + ")"
 	}
-	var names []string
+	return line
+}
+
+// buildEffectsList — видимые эффекты с модификаторами; скрытые модель не видит.
+func buildEffectsList(effects []models.Effect) string {
+	var parts []string
 	for _, e := range effects {
-		if !e.IsHidden {
-			names = append(names, e.Name)
+		if e.IsHidden {
+			continue
 		}
+		var mods []string
+		if e.HPPerTurn != 0 {
+			mods = append(mods, fmt.Sprintf("%+d HP/ход", e.HPDelta()))
+		}
+		if e.PowerMod != 0 {
+			mods = append(mods, fmt.Sprintf("сила %+d", e.Power()))
+		}
+		if e.Duration > 0 {
+			mods = append(mods, fmt.Sprintf("ещё %d х.", e.Duration))
+		}
+		name := e.Name
+		if e.Stacks > 1 {
+			name += fmt.Sprintf(" ×%d", e.Stacks)
+		}
+		if len(mods) > 0 {
+			name += " (" + strings.Join(mods, ", ") + ")"
+		}
+		parts = append(parts, name)
 	}
-	if len(names) == 0 {
+	if len(parts) == 0 {
 		return "Нет"
 	}
-	return strings.Join(names, ", ")
+	return strings.Join(parts, ", ")
+}
+
+func buildReputationList(reps []models.Reputation) string {
+	if len(reps) == 0 {
+		return "Нейтральна со всеми фракциями"
+	}
+	parts := make([]string, 0, len(reps))
+	for _, r := range reps {
+		parts = append(parts, fmt.Sprintf("%s — %s (%+d)", r.FactionName, r.Tier, r.Score))
+	}
+	return strings.Join(parts, "; ")
+}
+
+func buildFactionsBlock(factions []string) string {
+	if len(factions) == 0 {
+		return ""
+	}
+	return "[ФРАКЦИИ МИРА]\n" + strings.Join(factions, ", ") + "\n"
+}
+
+func buildInventoryList(items []models.InventoryItem) string {
+	if len(items) == 0 {
+		return "Пусто"
+	}
+	var parts []string
+	for _, inv := range items {
+		entry := inv.Item.Name
+		if inv.Quantity > 1 {
+			entry += fmt.Sprintf(" ×%d", inv.Quantity)
+		}
+		if inv.IsEquipped {
+			entry += " (экипировано)"
+		}
+		parts = append(parts, entry)
+	}
+	return strings.Join(parts, ", ")
 }
 
 func buildSceneBlock(sc models.Scene) string {
 	return fmt.Sprintf(`[СЦЕНА]
 Название: %s
 Локация: %s
+Время: %s
 Описание: %s
-`, sc.Name, sc.LocationName, sc.Summary)
+`, sc.Name, sc.LocationName, worldTime(sc), sc.Summary)
+}
+
+func worldTime(sc models.Scene) string {
+	if sc.WorldTime == "" {
+		return "не отмечено"
+	}
+	return sc.WorldTime
 }
 
 func buildQuestBlock(q models.Quest) string {
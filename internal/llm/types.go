@@ -14,6 +14,7 @@ type Client interface {
 	GenerateCombatTurn(ctx context.Context, cCtx CombatContext) (CombatResult, error)
 	AskLapidarius(ctx context.Context, pCtx PlayerContext, question string) (string, error)
 	Summarize(ctx context.Context, oldSummary string, newMessages []string) (string, error)
+	NarrateItemAction(ctx context.Context, pCtx PlayerContext, outcome string) (string, error)
 
 	ClassifyIntent(ctx context.Context, text string, isGM bool) (IntentResult, error)
 }
@@ -33,6 +34,8 @@ type PlayerContext struct {
 	FactionTag    string
 	CustomTags    []string
 	PlayerMessage string
+	// Factions — фракции мира, которые могут выступать заказчиками квестов.
+	Factions []string
 }
 
 type QuestProgressContext struct {
@@ -49,6 +52,10 @@ type QuestProgressResult struct {
 	Narration   string
 	RewardGold  int
 	RewardItems []string
+	// RewardReputation — изменение репутации у фракции-заказчика квеста.
+	RewardReputation int
+	// UnlockAbility — способность, которую персонаж освоил по ходу квеста.
+	UnlockAbility string
 }
 
 type CombatContext struct {
@@ -57,6 +64,9 @@ type CombatContext struct {
 	Quest        *models.Quest
 	History      string
 	PlayerAction string
+	EnemyName    string
+	EnemyHP      int
+	EnemyStatus  string
 }
 
 type CombatResult struct {
@@ -0,0 +1,57 @@
+package lore
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+type CalendarMonth struct {
+	Name   string `json:"name"`
+	Season string `json:"season"`
+}
+
+type Holiday struct {
+	Month       int    `json:"month"`
+	Day         int    `json:"day"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// CalendarStart — игровая дата, с которой начинаются мировые часы.
+type CalendarStart struct {
+	Year  int `json:"year"`
+	Month int `json:"month"`
+	Day   int `json:"day"`
+	Hour  int `json:"hour"`
+}
+
+// Calendar описывает летоисчисление мира. Сезоны задают возможную погоду,
+// TurnMinutes — сколько игровых минут длится один ход эффектов.
+type Calendar struct {
+	Era          string              `json:"era"`
+	Start        CalendarStart       `json:"start"`
+	DaysPerMonth int                 `json:"days_per_month"`
+	TurnMinutes  int                 `json:"turn_minutes"`
+	Months       []CalendarMonth     `json:"months"`
+	Seasons      map[string][]string `json:"seasons"`
+	Holidays     []Holiday           `json:"holidays"`
+}
+
+// LoadCalendar читает dir/world/calendar.json. Отсутствие файла не ошибка.
+func LoadCalendar(dir string) (*Calendar, error) {
+	data, err := os.ReadFile(filepath.Join(dir, "world", "calendar.json"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read calendar.json: %w", err)
+	}
+
+	var c Calendar
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("parse calendar.json: %w", err)
+	}
+	return &c, nil
+}
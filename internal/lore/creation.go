@@ -0,0 +1,53 @@
+package lore
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// CreationOption — допустимое значение поля анкеты: раса, страна или класс.
+type CreationOption struct {
+	Name        string   `json:"name"`
+	Aliases     []string `json:"aliases"`
+	Description string   `json:"description"`
+}
+
+// Matches сравнивает ввод игрока с названием и синонимами без учёта регистра.
+func (o CreationOption) Matches(s string) bool {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == strings.ToLower(o.Name) {
+		return true
+	}
+	for _, a := range o.Aliases {
+		if s == strings.ToLower(a) {
+			return true
+		}
+	}
+	return false
+}
+
+type CreationBook struct {
+	Races     []CreationOption `json:"races"`
+	Countries []CreationOption `json:"countries"`
+	Classes   []CreationOption `json:"classes"`
+}
+
+// LoadCreation читает dir/rules/creation.json. Отсутствие файла не ошибка.
+func LoadCreation(dir string) (*CreationBook, error) {
+	data, err := os.ReadFile(filepath.Join(dir, "rules", "creation.json"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read creation.json: %w", err)
+	}
+
+	var b CreationBook
+	if err := json.Unmarshal(data, &b); err != nil {
+		return nil, fmt.Errorf("parse creation.json: %w", err)
+	}
+	return &b, nil
+}
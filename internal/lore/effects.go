@@ -0,0 +1,44 @@
+package lore
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// EffectTemplate — эффект из справочника правил. BlockedAbilities — подстроки
+// названий способностей, которые эффект запрещает; Duration 0 — бессрочный.
+type EffectTemplate struct {
+	Name             string   `json:"name"`
+	Aliases          []string `json:"aliases"`
+	Description      string   `json:"description"`
+	HPPerTurn        int      `json:"hp_per_turn"`
+	Power            int      `json:"power"`
+	BlockedAbilities []string `json:"blocked_abilities"`
+	Duration         int      `json:"duration"`
+	Hidden           bool     `json:"hidden"`
+	Stacking         string   `json:"stacking"`
+	MaxStacks        int      `json:"max_stacks"`
+}
+
+type EffectBook struct {
+	Effects []EffectTemplate `json:"effects"`
+}
+
+// LoadEffects читает dir/rules/effects.json. Отсутствие файла не ошибка.
+func LoadEffects(dir string) (*EffectBook, error) {
+	data, err := os.ReadFile(filepath.Join(dir, "rules", "effects.json"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read effects.json: %w", err)
+	}
+
+	var b EffectBook
+	if err := json.Unmarshal(data, &b); err != nil {
+		return nil, fmt.Errorf("parse effects.json: %w", err)
+	}
+	return &b, nil
+}
@@ -0,0 +1,45 @@
+package lore
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+type Faction struct {
+	ID          string   `json:"id"`
+	Name        string   `json:"name"`
+	Aliases     []string `json:"aliases"`
+	Description string   `json:"description"`
+}
+
+// ReputationTier — именованная ступень репутации, действует от Min и выше.
+type ReputationTier struct {
+	Min  int    `json:"min"`
+	Name string `json:"name"`
+}
+
+type FactionBook struct {
+	Tiers    []ReputationTier `json:"tiers"`
+	Factions []Faction        `json:"factions"`
+}
+
+// LoadFactions читает dir/factions/factions.json. Отсутствие файла не ошибка.
+func LoadFactions(dir string) (*FactionBook, error) {
+	data, err := os.ReadFile(filepath.Join(dir, "factions", "factions.json"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read factions.json: %w", err)
+	}
+
+	var book FactionBook
+	if err := json.Unmarshal(data, &book); err != nil {
+		return nil, fmt.Errorf("parse factions.json: %w", err)
+	}
+	sort.Slice(book.Tiers, func(i, j int) bool { return book.Tiers[i].Min < book.Tiers[j].Min })
+	return &book, nil
+}
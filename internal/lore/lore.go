@@ -13,12 +13,18 @@ type Chunk struct {
 	Content string   `json:"content"`
 	Zone    string   `json:"zone"` // world/region/city/faction/magic/economy
 	Tags    []string `json:"tags"`
+	// Score — близость к запросу при семантическом поиске; 0 для тегового.
+	Score float32 `json:"-"`
+	// Source — файл лора, из которого загружен фрагмент (относительно каталога лора).
+	Source string `json:"-"`
 }
 
 type Repository interface {
 	GetCoreLore() string
 	GetMasterInstruction() string
 	SelectRelevant(locationTag, factionTag string, extraTags []string) []Chunk
+	// AllChunks — все фрагменты лора в порядке загрузки, для индексации.
+	AllChunks() []Chunk
 }
 
 type fileRepo struct {
@@ -71,6 +77,9 @@ func (r *fileRepo) load(dir string) error {
 		if err := json.Unmarshal(b, &chunks); err != nil {
 			return fmt.Errorf("parse %s: %w", e.Name(), err)
 		}
+		for i := range chunks {
+			chunks[i].Source = e.Name()
+		}
 		r.chunks = append(r.chunks, chunks...)
 	}
 	return nil
@@ -113,6 +122,10 @@ func (r *fileRepo) SelectRelevant(locationTag, factionTag string, extraTags []st
 	return res
 }
 
+func (r *fileRepo) AllChunks() []Chunk {
+	return r.chunks
+}
+
 func (r *fileRepo) GetMasterInstruction() string {
 	if r.masterInstruction == "" {
 		return ""
@@ -0,0 +1,53 @@
+package lore
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// LevelRule — порог опыта уровня и прибавка характеристик при его получении.
+type LevelRule struct {
+	Level       int `json:"level"`
+	XP          int `json:"xp"`
+	CombatPower int `json:"combat_power"`
+}
+
+// AbilityUnlock — способность, которая открывается по достижении уровня.
+// Если Race задана, способность получают только персонажи этой расы.
+type AbilityUnlock struct {
+	Level       int    `json:"level"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Race        string `json:"race"`
+}
+
+type Progression struct {
+	Levels    []LevelRule     `json:"levels"`
+	QuestXP   map[string]int  `json:"quest_xp"`
+	CombatXP  map[string]int  `json:"combat_xp"`
+	Abilities []AbilityUnlock `json:"abilities"`
+	// QuestAbilities — способности, которые квест может дать сам; любые
+	// другие, предложенные моделью, выдаёт только ГМ.
+	QuestAbilities []AbilityUnlock `json:"quest_abilities"`
+}
+
+// LoadProgression читает dir/rules/progression.json. Отсутствие файла не ошибка.
+func LoadProgression(dir string) (*Progression, error) {
+	data, err := os.ReadFile(filepath.Join(dir, "rules", "progression.json"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read progression.json: %w", err)
+	}
+
+	var p Progression
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("parse progression.json: %w", err)
+	}
+	sort.Slice(p.Levels, func(i, j int) bool { return p.Levels[i].Level < p.Levels[j].Level })
+	return &p, nil
+}
@@ -0,0 +1,55 @@
+package lore
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+const defaultShopBuyRate = 0.4
+
+type ShopItem struct {
+	Name        string            `json:"name"`
+	Description string            `json:"description"`
+	Rarity      string            `json:"rarity"`
+	Price       int               `json:"price"`
+	Stock       int               `json:"stock"`
+	EquipSlot   string            `json:"equip_slot"`
+	Properties  map[string]string `json:"properties"`
+}
+
+// Shop — торговая точка, привязанная к локации. Остатки живут в БД,
+// лор задаёт ассортимент, цены и период пополнения.
+type Shop struct {
+	ID           string     `json:"id"`
+	Name         string     `json:"name"`
+	Merchant     string     `json:"merchant"`
+	Location     string     `json:"location"`
+	Faction      string     `json:"faction"`
+	RestockHours int        `json:"restock_hours"`
+	BuyRate      float64    `json:"buy_rate"` // доля стоимости, за которую торговец выкупает товар, меньше 1
+	Items        []ShopItem `json:"items"`
+}
+
+// LoadShops читает dir/economy/shops.json. Отсутствие файла не ошибка.
+func LoadShops(dir string) ([]Shop, error) {
+	data, err := os.ReadFile(filepath.Join(dir, "economy", "shops.json"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read shops.json: %w", err)
+	}
+
+	var shops []Shop
+	if err := json.Unmarshal(data, &shops); err != nil {
+		return nil, fmt.Errorf("parse shops.json: %w", err)
+	}
+	for i := range shops {
+		if shops[i].BuyRate <= 0 || shops[i].BuyRate >= 1 {
+			shops[i].BuyRate = defaultShopBuyRate
+		}
+	}
+	return shops, nil
+}
@@ -0,0 +1,85 @@
+package lore
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+type MapLocation struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Tags        string `json:"tags"`
+}
+
+// MapLink — дорога между двумя локациями, проходимая в обе стороны.
+// Danger от 0 (безопасно) до 5 (смертельно опасно).
+type MapLink struct {
+	From       string   `json:"from"`
+	To         string   `json:"to"`
+	Hours      int      `json:"hours"`
+	Danger     int      `json:"danger"`
+	Encounters []string `json:"encounters"`
+}
+
+// MapZone — узел дерева зон лора: мир → регион → город → район. Имя
+// совпадает с zone документов лора и с названием локации.
+type MapZone struct {
+	Name   string `json:"name"`
+	Parent string `json:"parent"`
+	Kind   string `json:"kind"` // world/region/city/district; дороги и дикие места — на уровне города
+}
+
+// RootZone — зона всего мира, корень дерева зон.
+const RootZone = "world"
+
+type WorldMap struct {
+	Locations         []MapLocation `json:"locations"`
+	Links             []MapLink     `json:"links"`
+	Zones             []MapZone     `json:"zones"`
+	DefaultEncounters []string      `json:"default_encounters"`
+}
+
+// LoadWorldMap читает dir/world/map.json. Отсутствие файла не ошибка.
+func LoadWorldMap(dir string) (*WorldMap, error) {
+	data, err := os.ReadFile(filepath.Join(dir, "world", "map.json"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read map.json: %w", err)
+	}
+
+	var m WorldMap
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("parse map.json: %w", err)
+	}
+	return &m, nil
+}
+
+// ZoneChain — зона и её предки от ближайшего до корня. Зона не из дерева
+// (например, локация, созданная мастером) считается прямым потомком мира.
+// Работает и без карты.
+func (m *WorldMap) ZoneChain(zone string) []string {
+	parents := make(map[string]string)
+	if m != nil {
+		for _, z := range m.Zones {
+			parents[z.Name] = z.Parent
+		}
+	}
+	chain := []string{zone}
+	seen := map[string]bool{zone: true}
+	for {
+		parent, ok := parents[zone]
+		if !ok && zone != RootZone {
+			parent = RootZone
+		}
+		if parent == "" || seen[parent] {
+			return chain
+		}
+		chain = append(chain, parent)
+		seen[parent] = true
+		zone = parent
+	}
+}
@@ -0,0 +1,42 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+const (
+	AbilitySourceLevel = "level"
+	AbilitySourceQuest = "quest"
+	AbilitySourceGM    = "gm"
+)
+
+// AbilitySeparator разделяет способности в анкете персонажа.
+const AbilitySeparator = "; "
+
+// CharacterAbility — способность, полученная в игре (а не из анкеты).
+type CharacterAbility struct {
+	ID          int64
+	CharacterID int64
+	Name        string
+	Description string
+	Source      string
+	UnlockedAt  time.Time
+}
+
+// ParseAbilities разбирает способности из анкеты. Старые записи разделены
+// запятыми, поэтому понимаются и «,», и «;».
+func ParseAbilities(s string) []string {
+	var out []string
+	for _, a := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ';' }) {
+		if a = strings.TrimSpace(a); a != "" {
+			out = append(out, a)
+		}
+	}
+	return out
+}
+
+// JoinAbilities собирает способности обратно в строку анкеты.
+func JoinAbilities(abilities []string) string {
+	return strings.Join(abilities, AbilitySeparator)
+}
@@ -0,0 +1,41 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseAbilities(t *testing.T) {
+	for in, want := range map[string][]string{
+		"":                  nil,
+		" ; , ":             nil,
+		"Огненный шар":      {"Огненный шар"},
+		"Огненный шар, Щит": {"Огненный шар", "Щит"},
+		"Огненный шар; Щит": {"Огненный шар", "Щит"},
+		"Огненный шар; Щит, Невидимость;": {"Огненный шар", "Щит", "Невидимость"},
+		"  Магия льда ;;  Магия огня ,  ": {"Магия льда", "Магия огня"},
+	} {
+		if got := ParseAbilities(in); !reflect.DeepEqual(got, want) {
+			t.Errorf("ParseAbilities(%q) = %q, want %q", in, got, want)
+		}
+	}
+	list := []string{"Огненный шар", "Щит"}
+	if got := ParseAbilities(JoinAbilities(list)); !reflect.DeepEqual(got, list) {
+		t.Errorf("round trip = %q", got)
+	}
+}
+
+func TestSplitAbilitiesMixedSeparators(t *testing.T) {
+	// анкета пишет через «;», а полученные в игре раньше дописывались через «,»
+	ch := Character{
+		Abilities: "Магия огня; Фехтование, Щит",
+		Effects:   []Effect{{Name: "Немота", Blocked: []string{"маг"}}},
+	}
+	usable, blocked := ch.SplitAbilities()
+	if !reflect.DeepEqual(usable, []string{"Фехтование", "Щит"}) {
+		t.Errorf("usable = %q", usable)
+	}
+	if !reflect.DeepEqual(blocked, []string{"Магия огня"}) {
+		t.Errorf("blocked = %q", blocked)
+	}
+}
@@ -1,6 +1,10 @@
 package models
 
-import "time"
+import (
+	"database/sql"
+	"strings"
+	"time"
+)
 
 type Character struct {
 	ID           int64
@@ -20,11 +24,72 @@ type Character struct {
 	CombatPower  int
 	CombatHealth int
 	Gold         int
+	XP           int
+	Level        int
 	Gender       string
 	Country      string
 	SheetJSON    string
-	Inventory    string
-	CreatedAt    time.Time
+	// DyingUntil — unix-время, когда умирающий истечёт кровью.
+	DyingUntil int64
+	ArchivedAt sql.NullTime
+	CreatedAt  time.Time
 
-	Effects []Effect
-}
\ No newline at end of file
+	Effects    []Effect
+	Items      []InventoryItem
+	Reputation []Reputation
+}
+
+func (c *Character) IsDead() bool {
+	return c.Status == LifeDead
+}
+
+// MaxHealth — предел здоровья персонажа: выше него не лечат ни предметы, ни эффекты.
+func (c *Character) MaxHealth() int {
+	return HealthMax
+}
+
+func (c *Character) IsArchived() bool {
+	return c.ArchivedAt.Valid
+}
+
+// EffectiveCombatPower учитывает бонусы экипированных предметов и эффектов.
+func (c *Character) EffectiveCombatPower() int {
+	power := c.CombatPower
+	for _, inv := range c.Items {
+		if inv.IsEquipped {
+			power += inv.Item.IntProp(PropPower)
+		}
+	}
+	for _, e := range c.Effects {
+		power += e.Power()
+	}
+	if power < 0 {
+		power = 0
+	}
+	return power
+}
+
+// SplitAbilities разделяет способности из анкеты на доступные и заблокированные эффектами.
+func (c *Character) SplitAbilities() (usable, blocked []string) {
+	for _, a := range ParseAbilities(c.Abilities) {
+		isBlocked := false
+		for _, e := range c.Effects {
+			if e.BlocksAbility(a) {
+				isBlocked = true
+				break
+			}
+		}
+		if isBlocked {
+			blocked = append(blocked, a)
+		} else {
+			usable = append(usable, a)
+		}
+	}
+	return usable, blocked
+}
+
+// UsableAbilities — способности, которые сейчас не заблокированы эффектами.
+func (c *Character) UsableAbilities() string {
+	usable, _ := c.SplitAbilities()
+	return strings.Join(usable, ", ")
+}
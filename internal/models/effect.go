@@ -1,5 +1,16 @@
 package models
 
+import "strings"
+
+// Правила наложения одноимённого эффекта.
+const (
+	EffectStackRefresh = "refresh" // обновить длительность
+	EffectStackAdd     = "stack"   // добавить стак до предела и обновить длительность
+	EffectStackIgnore  = "ignore"  // повторное наложение не действует
+)
+
+// Effect — состояние персонажа. Duration в ходах, 0 — бессрочно.
+// Модификаторы умножаются на число стаков.
 type Effect struct {
 	ID          int64
 	CharacterID int64
@@ -7,4 +18,30 @@ type Effect struct {
 	Description string
 	Duration    int
 	IsHidden    bool
-}
\ No newline at end of file
+	HPPerTurn   int
+	PowerMod    int
+	Blocked     []string
+	Stacks      int
+}
+
+func (e Effect) stacks() int {
+	if e.Stacks < 1 {
+		return 1
+	}
+	return e.Stacks
+}
+
+func (e Effect) HPDelta() int { return e.HPPerTurn * e.stacks() }
+
+func (e Effect) Power() int { return e.PowerMod * e.stacks() }
+
+// BlocksAbility — запрещает ли эффект способность (по подстроке названия).
+func (e Effect) BlocksAbility(ability string) bool {
+	ability = strings.ToLower(ability)
+	for _, b := range e.Blocked {
+		if b = strings.ToLower(strings.TrimSpace(b)); b != "" && strings.Contains(ability, b) {
+			return true
+		}
+	}
+	return false
+}
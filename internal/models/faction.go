@@ -0,0 +1,17 @@
+package models
+
+type Faction struct {
+	ID          int64
+	Key         string
+	Name        string
+	Description string
+}
+
+// Reputation — отношение фракции к персонажу, от -100 до 100.
+type Reputation struct {
+	CharacterID int64
+	FactionID   int64
+	FactionName string
+	Score       int
+	Tier        string
+}
@@ -0,0 +1,95 @@
+package models
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	RarityCommon    = "common"
+	RarityUncommon  = "uncommon"
+	RarityRare      = "rare"
+	RarityEpic      = "epic"
+	RarityLegendary = "legendary"
+)
+
+const (
+	SlotWeapon    = "weapon"
+	SlotOffhand   = "offhand"
+	SlotArmor     = "armor"
+	SlotHead      = "head"
+	SlotAccessory = "accessory"
+)
+
+// Ключи Item.Properties, которые понимает движок.
+const (
+	PropHeal       = "heal"       // восстановление здоровья при использовании
+	PropPower      = "power"      // бонус к боевому потенциалу, пока предмет экипирован
+	PropBuff       = "buff"       // название эффекта, накладываемого при использовании
+	PropDuration   = "duration"   // длительность эффекта в ходах
+	PropConsumable = "consumable" // "true" — предмет расходуется при использовании
+)
+
+type Item struct {
+	ID          int64
+	Name        string
+	Description string
+	Rarity      string
+	Value       int
+	EquipSlot   string
+	Properties  map[string]string
+	CreatedAt   time.Time
+}
+
+type InventoryItem struct {
+	ID          int64
+	CharacterID int64
+	Item        Item
+	Quantity    int
+	IsEquipped  bool
+}
+
+func (i Item) IntProp(key string) int {
+	v, err := strconv.Atoi(strings.TrimSpace(i.Properties[key]))
+	if err != nil {
+		return 0
+	}
+	return v
+}
+
+func (i Item) IsConsumable() bool {
+	return i.Properties[PropConsumable] == "true"
+}
+
+func (i Item) RarityTitle() string {
+	switch i.Rarity {
+	case RarityUncommon:
+		return "необычный"
+	case RarityRare:
+		return "редкий"
+	case RarityEpic:
+		return "эпический"
+	case RarityLegendary:
+		return "легендарный"
+	default:
+		return "обычный"
+	}
+}
+
+func SlotTitle(slot string) string {
+	switch slot {
+	case SlotWeapon:
+		return "оружие"
+	case SlotOffhand:
+		return "вторая рука"
+	case SlotArmor:
+		return "броня"
+	case SlotHead:
+		return "голова"
+	case SlotAccessory:
+		return "аксессуар"
+	default:
+		return slot
+	}
+}
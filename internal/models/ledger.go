@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+const (
+	LedgerSourceQuest  = "quest"
+	LedgerSourceCombat = "combat"
+	LedgerSourceShop   = "shop"
+	LedgerSourceGM     = "gm"
+	LedgerSourceTrade  = "trade"
+)
+
+type LedgerEntry struct {
+	ID           int64
+	CharacterID  int64
+	Amount       int
+	BalanceAfter int
+	Reason       string
+	Source       string
+	RefID        string
+	CreatedAt    time.Time
+}
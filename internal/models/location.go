@@ -10,4 +10,14 @@ type Location struct {
 	IsActive    bool
 	CreatedBy   string
 	CreatedAt   time.Time
-}
\ No newline at end of file
+}
+
+// LocationLink — направленное ребро графа локаций.
+type LocationLink struct {
+	FromID      int64
+	ToID        int64
+	ToName      string
+	TravelHours int
+	Danger      int
+	Encounters  []string
+}
@@ -6,6 +6,7 @@ import (
 )
 
 const (
+	HealthMax       = 100
 	HealthExcellent = 90
 	HealthGood      = 70
 	HealthWounded   = 50
@@ -13,51 +14,14 @@ const (
 	HealthAlive     = 0
 )
 
-type Character struct {
-	ID           int64
-	VKUserID     int64
-	Name         string
-	Race         string
-	Class        string
-	FactionID    int64
-	FactionName  string
-	Traits       string
-	Goal         string
-	LocationID   int64
-	LocationName string
-	Status       string
-	Abilities    string
-	Bio          string
-	CombatPower  int
-	CombatHealth int
-	Gold         int
-	Gender       string
-	Country      string
-	SheetJSON    string
-	Inventory    string
-	CreatedAt    time.Time
-
-	Effects []Effect
-}
-
-type Effect struct {
-	ID          int64
-	CharacterID int64
-	Name        string
-	Description string
-	Duration    int
-	IsHidden    bool
-}
-
-type Location struct {
-	ID          int64
-	Name        string
-	Description string
-	Tags        string
-	IsActive    bool
-	CreatedBy   string
-	CreatedAt   time.Time
-}
+// Состояния жизни персонажа (поле Status). Переходы ведёт LifeService:
+// жив ↔ ранен по здоровью, при 0 — при смерти с таймером, по истечении — мёртв.
+const (
+	LifeAlive   = "жив"
+	LifeWounded = "ранен"
+	LifeDying   = "при смерти"
+	LifeDead    = "мёртв"
+)
 
 type Scene struct {
 	ID           int64
@@ -72,6 +36,8 @@ type Scene struct {
 	IsActive     bool
 	CreatedAt    time.Time
 	UpdatedAt    time.Time
+	// WorldTime — дата, время и погода по мировым часам; в БД не хранится.
+	WorldTime string
 }
 
 type SceneMessage struct {
@@ -83,50 +49,14 @@ type SceneMessage struct {
 	CreatedAt  time.Time
 }
 
-type Quest struct {
-	ID          int64
-	CharacterID int64
-	LocationID  int64
-	Title       string
-	Description string
-	From        string
-	Status      string
-	Stage       int
-	Difficulty  string
-	RewardGold  int
-	RewardItem  string
-	RewardValue int
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
-}
-
-type NormalizedCharacterForm struct {
-	Name       string   `json:"name"`
-	Surname    string   `json:"surname"`
-	Nickname   string   `json:"nickname"`
-	Gender     string   `json:"gender"`
-	Age        int      `json:"age"`
-	Race       string   `json:"race"`
-	Class      string   `json:"class"`
-	Country    string   `json:"country"`
-	City       string   `json:"city"`
-	Faction    string   `json:"faction"`
-	Occupation string   `json:"occupation"`
-	TraitsPos  []string `json:"traits_positive"`
-	TraitsNeg  []string `json:"traits_negative"`
-	Motivation string   `json:"motivation"`
-	Fears      []string `json:"fears"`
-	Abilities  []string `json:"abilities"`
-	Skills     []string `json:"skills"`
-	Inventory  []string `json:"inventory"`
-	Bio        string   `json:"bio"`
-	Appearance string   `json:"appearance"`
-}
-
 func (c *Character) GetStatusDescription() string {
 	hp := c.CombatHealth
 
 	switch {
+	case c.Status == LifeDead:
+		return "Твое тело бездыханно."
+	case c.Status == LifeDying:
+		return "ТЫ ИСТЕКАЕШЬ КРОВЬЮ. Без помощи смерть придёт совсем скоро."
 	case hp >= HealthExcellent:
 		return "Ты полон сил и готов к свершениям."
 	case hp >= HealthGood:
@@ -2,10 +2,14 @@ package models
 
 import "time"
 
+const QuestKindResurrection = "resurrection"
+
 type Quest struct {
 	ID          int64
 	CharacterID int64
 	LocationID  int64
+	FactionID   int64
+	FactionName string
 	Title       string
 	Description string
 	From        string
@@ -15,6 +19,10 @@ type Quest struct {
 	RewardGold  int
 	RewardItem  string
 	RewardValue int
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
-}
\ No newline at end of file
+	// DeadlineAt — срок в игровых минутах мировых часов; 0 — без срока.
+	DeadlineAt int64
+	// Kind — особый тип квеста; QuestKindResurrection ведёт мёртвого персонажа обратно.
+	Kind      string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
@@ -0,0 +1,28 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+const (
+	SheetPending   = "pending"
+	SheetApproved  = "approved"
+	SheetRejected  = "rejected"
+	SheetChanges   = "changes"
+	SheetWithdrawn = "withdrawn"
+)
+
+// CharacterSheet — анкета, поданная игроком на проверку ГМу. Персонаж
+// меняется только после одобрения.
+type CharacterSheet struct {
+	ID          int64
+	CharacterID int64
+	VKUserID    int64
+	PeerID      int
+	Form        NormalizedCharacterForm
+	Status      string
+	Comment     string
+	CreatedAt   time.Time
+	ReviewedAt  sql.NullTime
+}
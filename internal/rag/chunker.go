@@ -329,3 +329,36 @@ func getLastNChars(text string, n int) string {
 	}
 	return string(runes[len(runes)-n:])
 }
+
+// documentOf — id исходного документа по parent фрагмента: у абзацев
+// большого раздела parent — сам раздел (<doc>_sec<i>).
+func documentOf(parent string) string {
+	i := strings.LastIndex(parent, "_sec")
+	if i <= 0 || i+4 == len(parent) {
+		return parent
+	}
+	for _, r := range parent[i+4:] {
+		if r < '0' || r > '9' {
+			return parent
+		}
+	}
+	return parent[:i]
+}
+
+// minOverlap — короче этого совпадение конца и начала соседних фрагментов
+// считается случайным, а не перекрытием.
+const minOverlap = 16
+
+// joinChunks склеивает соседние фрагменты, убирая перекрытие (Overlap), с
+// которым второй начинается.
+func joinChunks(prev, next string) string {
+	if prev == "" {
+		return next
+	}
+	for k := min(len(prev), len(next)); k >= minOverlap; k-- {
+		if (k == len(next) || utf8.RuneStart(next[k])) && strings.HasSuffix(prev, next[:k]) {
+			return prev + next[k:]
+		}
+	}
+	return prev + "\n\n" + next
+}
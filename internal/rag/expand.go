@@ -0,0 +1,161 @@
+package rag
+
+import (
+	"context"
+	"log"
+	"sort"
+	"strconv"
+	"unicode/utf8"
+
+	"aurora/internal/repository"
+)
+
+// expandContext — «от малого к большому»: найденный фрагмент заменяется
+// целым родительским разделом, если тот не длиннее ParentChars, иначе к нему
+// подклеиваются Neighbors соседей по chunk_index. Фрагменты, уже попавшие в
+// выдачу через соседа, выбрасываются. Если из одного документа найдено не
+// меньше SummaryAfter фрагментов, перед первым из них ставится его краткое
+// содержание — сверх Limit.
+func (s *Service) expandContext(ctx context.Context, hits []repository.VectorDocument, queryVector []float32, options RetrievalOptions) []repository.VectorDocument {
+	expand := options.ParentChars > 0 || options.Neighbors > 0
+	if !expand && options.SummaryAfter <= 0 {
+		return hits
+	}
+
+	siblings := make(map[string][]repository.VectorDocument)
+	partsOf := func(parent string) []repository.VectorDocument {
+		if parts, ok := siblings[parent]; ok {
+			return parts
+		}
+		docs, err := s.vectorRepo.GetByParent(ctx, parent)
+		if err != nil {
+			log.Printf("rag %s: load chunks of %s: %v", options.Label, parent, err)
+		}
+		// краткое содержание лежит рядом с разделами, но в текст не входит
+		var parts []repository.VectorDocument
+		for _, doc := range docs {
+			if !isSummary(doc) {
+				parts = append(parts, doc)
+			}
+		}
+		siblings[parent] = parts
+		return parts
+	}
+
+	covered := make(map[string]bool)
+	wholeParent := make(map[string]bool)
+	perDocument := make(map[string]int)
+	out := make([]repository.VectorDocument, 0, len(hits))
+	var parents, neighbors int
+	for _, hit := range hits {
+		parent := hit.Metadata["parent"]
+		if parent != "" && !isSummary(hit) {
+			perDocument[documentID(hit)]++
+		}
+		if covered[hit.ID] {
+			continue
+		}
+		if !expand || parent == "" || isSummary(hit) {
+			covered[hit.ID] = true
+			out = append(out, hit)
+			continue
+		}
+
+		parts := partsOf(parent)
+		if options.ParentChars > 0 {
+			if text, ids := joinParts(parts, covered); len(ids) > 0 && utf8.RuneCountInString(text) <= options.ParentChars {
+				markCovered(covered, ids)
+				covered[hit.ID] = true
+				wholeParent[parent] = true
+				hit.Content = text
+				out = append(out, hit)
+				parents++
+				continue
+			}
+		}
+		if options.Neighbors > 0 {
+			if index, err := strconv.Atoi(hit.Metadata["chunk_index"]); err == nil {
+				var window []repository.VectorDocument
+				for _, part := range parts {
+					if i, err := strconv.Atoi(part.Metadata["chunk_index"]); err == nil && i >= index-options.Neighbors && i <= index+options.Neighbors {
+						window = append(window, part)
+					}
+				}
+				if text, ids := joinParts(window, covered); len(ids) > 1 {
+					markCovered(covered, ids)
+					hit.Content = text
+					neighbors++
+				}
+			}
+		}
+		covered[hit.ID] = true
+		out = append(out, hit)
+	}
+
+	var summaries []repository.VectorDocument
+	if options.SummaryAfter > 0 {
+		var ids []string
+		for doc, n := range perDocument {
+			if n >= options.SummaryAfter && !wholeParent[doc] && !covered[doc+"_summary"] {
+				ids = append(ids, doc+"_summary")
+			}
+		}
+		sort.Strings(ids)
+		if len(ids) > 0 {
+			docs, err := s.vectorRepo.GetDocuments(ctx, ids)
+			if err != nil {
+				log.Printf("rag %s: load summaries: %v", options.Label, err)
+			}
+			summaries = docs
+		}
+	}
+	for _, summary := range summaries {
+		summary.Score = cosine(queryVector, summary.Vector)
+		doc := documentID(summary)
+		for i, hit := range out {
+			if !isSummary(hit) && hit.Metadata["parent"] != "" && documentID(hit) == doc {
+				out = append(out[:i], append([]repository.VectorDocument{summary}, out[i:]...)...)
+				break
+			}
+		}
+	}
+
+	if parents+neighbors+len(summaries) > 0 || len(out) < len(hits) {
+		log.Printf("rag %s: context %d -> %d (parents %d, neighbors %d, summaries %d)",
+			options.Label, len(hits), len(out), parents, neighbors, len(summaries))
+	}
+	return out
+}
+
+// joinParts склеивает фрагменты, которых ещё нет в выдаче, и возвращает их id.
+func joinParts(parts []repository.VectorDocument, covered map[string]bool) (string, []string) {
+	var text string
+	var ids []string
+	for _, part := range parts {
+		if covered[part.ID] {
+			continue
+		}
+		text = joinChunks(text, part.Content)
+		ids = append(ids, part.ID)
+	}
+	return text, ids
+}
+
+func markCovered(covered map[string]bool, ids []string) {
+	for _, id := range ids {
+		covered[id] = true
+	}
+}
+
+func isSummary(doc repository.VectorDocument) bool {
+	return doc.Metadata["is_summary"] == "true" || doc.Metadata["chunk_type"] == "summary"
+}
+
+// documentID — исходный документ фрагмента; у проиндексированных до появления
+// metadata.document он выводится из parent.
+func documentID(doc repository.VectorDocument) string {
+	if id := doc.Metadata["document"]; id != "" {
+		return id
+	}
+	return documentOf(doc.Metadata["parent"])
+}
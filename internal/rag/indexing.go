@@ -0,0 +1,204 @@
+package rag
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"aurora/internal/embeddings"
+	"aurora/internal/repository"
+)
+
+// indexGroup — сколько фрагментов считается и записывается за раз. Записанное
+// при повторном запуске не пересчитывается, так что прерванная индексация
+// продолжается с последней группы.
+const indexGroup = 500
+
+// FailedChunk — фрагмент, эмбеддинг которого так и не посчитался.
+type FailedChunk struct {
+	ID    string `json:"id"`
+	Title string `json:"title"`
+	Error string `json:"error"`
+}
+
+// EmbedFailures — остальное проиндексировано, а эти фрагменты нет; повторный
+// запуск индексации попробует только их.
+type EmbedFailures struct {
+	Failed []FailedChunk
+}
+
+func (e *EmbedFailures) Error() string {
+	return fmt.Sprintf("%d chunks failed to embed (first: %s: %s)", len(e.Failed), e.Failed[0].ID, e.Failed[0].Error)
+}
+
+// SetEmbedWorkers задаёт число параллельных запросов к API эмбеддингов.
+func (s *Service) SetEmbedWorkers(n int) {
+	s.workers = n
+}
+
+// SetProgress задаёт функцию, которой индексация сообщает, сколько
+// фрагментов из скольких уже обработано.
+func (s *Service) SetProgress(fn func(done, total int)) {
+	s.progress = fn
+}
+
+// embeddingText — что эмбеддится для документа. Фрагменты больших документов
+// и базовый лор — только текстом, остальное — с заголовком. Правило одно для
+// индексации и для Reembed, иначе векторы новой модели разойдутся со старыми.
+func embeddingText(doc repository.VectorDocument) string {
+	if doc.Metadata["chunk_type"] != "" || doc.Metadata["type"] == "core" {
+		return doc.Content
+	}
+	return fmt.Sprintf("%s\n\n%s", doc.Title, doc.Content)
+}
+
+// indexChunks индексирует фрагменты документа, пропуская уже лежащие в
+// индексе без изменений.
+func (s *Service) indexChunks(ctx context.Context, docs []repository.VectorDocument) error {
+	failed, err := s.indexNew(ctx, docs)
+	if err != nil {
+		return err
+	}
+	if len(failed) > 0 {
+		return &EmbedFailures{Failed: failed}
+	}
+	return nil
+}
+
+// indexDocument индексирует все фрагменты документа docID и удаляет фрагменты
+// прошлой индексации, которых среди них нет: при другой нарезке (или когда
+// документ был одним куском) их id не совпадают с новыми. Если что-то не
+// посчиталось, старые фрагменты остаются до следующей попытки.
+func (s *Service) indexDocument(ctx context.Context, docID string, docs []repository.VectorDocument) error {
+	if err := s.indexChunks(ctx, docs); err != nil {
+		return err
+	}
+	existing, err := s.vectorRepo.ListByDocument(ctx, docID)
+	if err != nil {
+		return fmt.Errorf("list chunks of %s: %w", docID, err)
+	}
+	keep := make(map[string]bool, len(docs))
+	for _, doc := range docs {
+		keep[doc.ID] = true
+	}
+	var stale []string
+	for _, info := range existing {
+		if !keep[info.ID] {
+			stale = append(stale, info.ID)
+		}
+	}
+	if len(stale) == 0 {
+		return nil
+	}
+	log.Printf("rag index: %s: removing %d stale chunks", docID, len(stale))
+	if err := s.vectorRepo.DeleteDocuments(ctx, stale); err != nil {
+		return fmt.Errorf("delete stale chunks of %s: %w", docID, err)
+	}
+	return nil
+}
+
+// indexNew эмбеддит и пишет те из docs, которых нет в индексе или которые
+// изменились; возвращает фрагменты, которые посчитать не удалось.
+func (s *Service) indexNew(ctx context.Context, docs []repository.VectorDocument) ([]FailedChunk, error) {
+	ids := make([]string, len(docs))
+	for i := range docs {
+		docs[i].Metadata["content_hash"] = contentHash(docs[i])
+		ids[i] = docs[i].ID
+	}
+	indexed, err := s.vectorRepo.GetDocuments(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("load indexed chunks: %w", err)
+	}
+	hashes := make(map[string]string, len(indexed))
+	for _, doc := range indexed {
+		hashes[doc.ID] = doc.Metadata["content_hash"]
+	}
+
+	var todo []repository.VectorDocument
+	for _, doc := range docs {
+		if hashes[doc.ID] != doc.Metadata["content_hash"] {
+			todo = append(todo, doc)
+		}
+	}
+	if skipped := len(docs) - len(todo); skipped > 0 {
+		log.Printf("rag index: %d/%d chunks already indexed", skipped, len(docs))
+	}
+
+	return s.embedAndIndex(ctx, todo)
+}
+
+// embedAndIndex считает эмбеддинги и пишет документы группами по indexGroup.
+// Возвращает фрагменты, которые посчитать не удалось.
+func (s *Service) embedAndIndex(ctx context.Context, docs []repository.VectorDocument) ([]FailedChunk, error) {
+	// проверка до запросов к API: хранилище всё равно не примет чужие векторы
+	if err := s.CheckModel(); err != nil {
+		return nil, err
+	}
+	texts := make([]string, len(docs))
+	for i, doc := range docs {
+		texts[i] = embeddingText(doc)
+	}
+	var failed []FailedChunk
+	for start := 0; start < len(docs); start += indexGroup {
+		end := min(start+indexGroup, len(docs))
+		var progress func(done, total int)
+		if s.progress != nil {
+			progress = func(done, _ int) { s.progress(start+done, len(docs)) }
+		}
+		vectors, errs := embeddings.EmbedAll(ctx, s.embedder, texts[start:end], s.workers, progress)
+
+		ready := make([]repository.VectorDocument, 0, end-start)
+		for i, doc := range docs[start:end] {
+			err := errs[i]
+			if err == nil && len(vectors[i]) == 0 {
+				err = fmt.Errorf("empty embedding returned")
+			}
+			if err != nil {
+				failed = append(failed, FailedChunk{ID: doc.ID, Title: doc.Title, Error: err.Error()})
+				continue
+			}
+			doc.Vector = vectors[i]
+			doc.Model = s.embedder.Model()
+			ready = append(ready, doc)
+		}
+		if len(ready) > 0 {
+			if err := s.vectorRepo.IndexBatch(ctx, ready); err != nil {
+				return failed, fmt.Errorf("index chunks: %w", err)
+			}
+		}
+		if err := ctx.Err(); err != nil {
+			return failed, err
+		}
+	}
+	return failed, nil
+}
+
+// Reembed пересчитывает корпус из source — того же лора в векторах другой
+// модели — моделью сервиса и пишет рядом, в его хранилище. Заголовки, тексты
+// и метаданные переносятся как есть. Перенесённое раньше без изменений
+// пропускается, так что прерванный перенос продолжается. Возвращает число
+// документов в source; не посчитанные попадают в *EmbedFailures.
+func (s *Service) Reembed(ctx context.Context, source repository.VectorRepository) (int, error) {
+	var total int
+	var failed []FailedChunk
+	err := source.Scan(ctx, indexGroup, func(docs []repository.VectorDocument) error {
+		for i := range docs {
+			docs[i].Vector = nil
+			docs[i].Model = ""
+			if docs[i].Metadata == nil {
+				docs[i].Metadata = make(map[string]string)
+			}
+		}
+		f, err := s.indexNew(ctx, docs)
+		total += len(docs)
+		failed = append(failed, f...)
+		return err
+	})
+	if err != nil {
+		return total, err
+	}
+	if len(failed) > 0 {
+		return total, &EmbedFailures{Failed: failed}
+	}
+	return total, nil
+}
@@ -0,0 +1,60 @@
+package rag
+
+import "aurora/internal/repository"
+
+// mmrPool — во сколько раз больше кандидатов берётся, когда включена
+// диверсификация: из них MMR выбирает Limit.
+const mmrPool = 3
+
+// diversify выбирает limit документов по maximal marginal relevance: каждый
+// следующий — с лучшим (1-diversity)·релевантность − diversity·близость к
+// самому похожему из уже выбранных. Релевантность — Score относительно
+// лучшего, чтобы косинус и оценка слияния были в одной шкале с близостью
+// документов. docs отсортированы по убыванию Score.
+func diversify(docs []repository.VectorDocument, limit int, diversity float32) []repository.VectorDocument {
+	if diversity <= 0 || len(docs) <= 1 {
+		if len(docs) > limit {
+			return docs[:limit]
+		}
+		return docs
+	}
+
+	lambda := float64(diversity)
+	top := float64(docs[0].Score)
+	relevance := make([]float64, len(docs))
+	nearest := make([]float64, len(docs))
+	for i, doc := range docs {
+		if top > 0 {
+			relevance[i] = float64(doc.Score) / top
+		} else {
+			relevance[i] = 1 - float64(i)/float64(len(docs))
+		}
+		nearest[i] = -1
+	}
+
+	taken := make([]bool, len(docs))
+	out := make([]repository.VectorDocument, 0, min(limit, len(docs)))
+	for len(out) < limit && len(out) < len(docs) {
+		best, bestScore := -1, 0.0
+		for i := range docs {
+			if taken[i] {
+				continue
+			}
+			score := (1 - lambda) * relevance[i]
+			if len(out) > 0 {
+				score -= lambda * nearest[i]
+			}
+			if best < 0 || score > bestScore {
+				best, bestScore = i, score
+			}
+		}
+		taken[best] = true
+		out = append(out, docs[best])
+		for i := range docs {
+			if !taken[i] {
+				nearest[i] = max(nearest[i], float64(cosine(docs[i].Vector, docs[best].Vector)))
+			}
+		}
+	}
+	return out
+}
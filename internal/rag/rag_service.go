@@ -2,7 +2,12 @@ package rag
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"log"
+	"math"
+	"sort"
 	"strings"
 
 	"aurora/internal/embeddings"
@@ -14,14 +19,51 @@ type Service struct {
 	embedder   embeddings.Service
 	vectorRepo repository.VectorRepository
 	loreRepo   lore.Repository
+	worldMap   *lore.WorldMap
+	workers    int
+	progress   func(done, total int)
 }
 
 type RetrievalOptions struct {
-	Limit    int
-	Filters  map[string]string
+	Limit int
+	// Filters — zone, tags и faction, значения через запятую. Зона
+	// расширяется до своих предков по дереву зон карты мира.
+	Filters map[string]string
+	// MinScore — нижняя граница косинусной близости фрагмента.
 	MinScore float32
+	// RelativeScore — доля от близости лучшего фрагмента, ниже которой
+	// остальные отбрасываются (0.8 — не хуже 80% лучшего); 0 — без отсечки.
+	RelativeScore float32
+	// Label — тип вызова для лога оценок (lapidarius, quest, combat...).
+	Label string
+	// VectorWeight и KeywordWeight — веса векторного и полнотекстового поиска
+	// в слиянии (reciprocal rank fusion). Оба 0 — только векторный поиск.
+	VectorWeight  float32
+	KeywordWeight float32
+	// Diversity — вес новизны в MMR: чем больше, тем сильнее штраф за
+	// сходство с уже выбранными фрагментами; 0 — без диверсификации.
+	Diversity float32
+	// ParentChars — найденный фрагмент заменяется всем родительским разделом,
+	// если тот не длиннее стольких символов; 0 — не заменять.
+	ParentChars int
+	// Neighbors — сколько соседних фрагментов с каждой стороны подклеить к
+	// найденному, если раздел целиком не влез.
+	Neighbors int
+	// SummaryAfter — со скольких найденных фрагментов одного документа
+	// добавлять его краткое содержание; 0 — не добавлять.
+	SummaryAfter int
+	// ZoneBoost — прибавка к близости документов из самой зоны запроса;
+	// у предков она линейно убывает до 0 у мира.
+	ZoneBoost float32
 }
 
+// rrfK — сглаживающая константа reciprocal rank fusion из исходной статьи.
+const rrfK = 60
+
+// hybridPool — во сколько раз больше кандидатов берётся из каждого поиска
+// перед слиянием.
+const hybridPool = 2
+
 func NewService(
 	embedder embeddings.Service,
 	vectorRepo repository.VectorRepository,
@@ -34,42 +76,221 @@ func NewService(
 	}
 }
 
+// SetWorldMap задаёт дерево зон для фильтра по зоне; без карты зона
+// расширяется только до мира.
+func (s *Service) SetWorldMap(m *lore.WorldMap) {
+	s.worldMap = m
+}
+
+// CheckModel — векторы запроса и индекса должны быть от одной модели, иначе
+// их близость ничего не значит. Ошибка оборачивает repository.ErrModelMismatch.
+func (s *Service) CheckModel() error {
+	if s.embedder.Model() != s.vectorRepo.Model() || s.embedder.Dims() != s.vectorRepo.Dims() {
+		return fmt.Errorf("%w: embedder %s (%d), vector store %s (%d)", repository.ErrModelMismatch,
+			s.embedder.Model(), s.embedder.Dims(), s.vectorRepo.Model(), s.vectorRepo.Dims())
+	}
+	return nil
+}
+
+// RetrieveRelevant отказывает, если эмбеддер и хранилище настроены на разные
+// модели: подмешивать лор по несравнимым векторам хуже, чем обойтись тегами.
 func (s *Service) RetrieveRelevant(ctx context.Context, query string, options RetrievalOptions) ([]lore.Chunk, error) {
 	if query == "" {
 		return nil, fmt.Errorf("empty query")
 	}
+	if err := s.CheckModel(); err != nil {
+		return nil, err
+	}
 
 	if options.Limit <= 0 {
 		options.Limit = 5
 	}
+	filters, zones := s.zoneFilters(options.Filters)
 
+	vectorWeight, keywordWeight := options.VectorWeight, options.KeywordWeight
+	if vectorWeight <= 0 && keywordWeight <= 0 {
+		vectorWeight = 1
+	}
+	pool := options.Limit
+	if keywordWeight > 0 {
+		pool *= hybridPool
+	}
+	if options.Diversity > 0 {
+		pool *= mmrPool
+	}
+
+	var vectorDocs, keywordDocs []repository.VectorDocument
+	var found bool
 	queryVector, err := s.embedder.Embed(ctx, query)
 	if err != nil {
-		return s.fallbackToTagSearch(ctx, options)
+		queryVector = nil
+	} else if vectorWeight > 0 {
+		docs, err := s.vectorRepo.SearchSimilar(ctx, queryVector, pool, filters)
+		if err == nil && len(docs) > 0 {
+			found = true
+			// порог — только для векторной выдачи: совпадения по словам
+			// (имена, названия) ценны и при слабой близости
+			vectorDocs = filterByScore(docs, options)
+			logScores(options, docs, len(vectorDocs))
+			boostZones(vectorDocs, zones, options.ZoneBoost)
+		}
+	}
+	if keywordWeight > 0 {
+		docs, err := s.vectorRepo.SearchKeyword(ctx, query, pool, filters)
+		if err != nil {
+			log.Printf("rag %s: keyword search error: %v", options.Label, err)
+		} else if len(docs) > 0 {
+			found = true
+			keywordDocs = docs
+		}
 	}
 
-	docs, err := s.vectorRepo.SearchSimilar(ctx, queryVector, options.Limit, options.Filters)
-	if err != nil {
+	if !found {
 		return s.fallbackToTagSearch(ctx, options)
 	}
 
-	if len(docs) == 0 {
-		return s.fallbackToTagSearch(ctx, options)
+	// если порог отсеял всё, тегового поиска нет: лучше без лора, чем со слабым совпадением
+	kept := vectorDocs
+	if keywordWeight > 0 {
+		kept = fuse(vectorDocs, keywordDocs, vectorWeight, keywordWeight, pool)
+		log.Printf("rag %s: hybrid %d vector + %d keyword -> %d", options.Label, len(vectorDocs), len(keywordDocs), len(kept))
 	}
+	kept = diversify(kept, options.Limit, options.Diversity)
+	kept = s.expandContext(ctx, kept, queryVector, options)
 
-	chunks := make([]lore.Chunk, 0, len(docs))
-	for _, doc := range docs {
+	chunks := make([]lore.Chunk, 0, len(kept))
+	for _, doc := range kept {
+		// Score здесь уже с прибавкой за зону или оценка слияния; в чанк идёт
+		// чистая косинусная близость
 		chunks = append(chunks, lore.Chunk{
 			Title:   doc.Title,
 			Content: doc.Content,
 			Zone:    doc.Zone,
 			Tags:    doc.Tags,
+			Score:   cosine(queryVector, doc.Vector),
 		})
 	}
 
 	return chunks, nil
 }
 
+// zoneFilters заменяет зону фильтра цепочкой «зона, предки, мир».
+func (s *Service) zoneFilters(filters map[string]string) (map[string]string, []string) {
+	zone := strings.TrimSpace(filters["zone"])
+	if zone == "" {
+		return filters, nil
+	}
+	zones := s.worldMap.ZoneChain(zone)
+	out := make(map[string]string, len(filters))
+	for k, v := range filters {
+		out[k] = v
+	}
+	out["zone"] = strings.Join(zones, ",")
+	return out, zones
+}
+
+// boostZones прибавляет к близости документов бонус за зону: полный для
+// zones[0], убывающий к предкам и нулевой для мира, — и пересортировывает.
+func boostZones(docs []repository.VectorDocument, zones []string, boost float32) {
+	if boost <= 0 || len(zones) < 2 {
+		return
+	}
+	steps := float32(len(zones) - 1)
+	for i := range docs {
+		for depth, zone := range zones {
+			if docs[i].Zone == zone {
+				docs[i].Score += boost * (steps - float32(depth)) / steps
+				break
+			}
+		}
+	}
+	sort.SliceStable(docs, func(i, j int) bool { return docs[i].Score > docs[j].Score })
+}
+
+// filterByScore отбрасывает документы ниже MinScore и ниже RelativeScore от
+// лучшего. docs отсортированы по убыванию близости.
+func filterByScore(docs []repository.VectorDocument, options RetrievalOptions) []repository.VectorDocument {
+	if len(docs) == 0 {
+		return docs
+	}
+	cutoff := options.MinScore
+	if options.RelativeScore > 0 && docs[0].Score > 0 {
+		cutoff = max(cutoff, docs[0].Score*options.RelativeScore)
+	}
+	for i, doc := range docs {
+		if doc.Score < cutoff {
+			return docs[:i]
+		}
+	}
+	return docs
+}
+
+// fuse сливает две выдачи по reciprocal rank fusion: документ получает
+// weight / (rrfK + место) за каждую выдачу, в которой он есть. Эта сумма
+// становится его Score.
+func fuse(vectorDocs, keywordDocs []repository.VectorDocument, vectorWeight, keywordWeight float32, limit int) []repository.VectorDocument {
+	type fused struct {
+		doc   repository.VectorDocument
+		score float64
+	}
+	byID := make(map[string]*fused, len(vectorDocs)+len(keywordDocs))
+	var order []*fused
+	add := func(docs []repository.VectorDocument, weight float32) {
+		for rank, doc := range docs {
+			f, ok := byID[doc.ID]
+			if !ok {
+				f = &fused{doc: doc}
+				byID[doc.ID] = f
+				order = append(order, f)
+			}
+			f.score += float64(weight) / float64(rrfK+rank+1)
+		}
+	}
+	add(vectorDocs, vectorWeight)
+	add(keywordDocs, keywordWeight)
+
+	sort.SliceStable(order, func(i, j int) bool { return order[i].score > order[j].score })
+	if len(order) > limit {
+		order = order[:limit]
+	}
+	out := make([]repository.VectorDocument, len(order))
+	for i, f := range order {
+		out[i] = f.doc
+		out[i].Score = float32(f.score)
+	}
+	return out
+}
+
+func cosine(a, b []float32) float32 {
+	if len(a) == 0 || len(a) != len(b) {
+		return 0
+	}
+	var dot, na, nb float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		na += float64(a[i]) * float64(a[i])
+		nb += float64(b[i]) * float64(b[i])
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return float32(dot / math.Sqrt(na*nb))
+}
+
+// logScores пишет оценки найденного, чтобы по логам подбирать пороги.
+func logScores(options RetrievalOptions, docs []repository.VectorDocument, kept int) {
+	label := options.Label
+	if label == "" {
+		label = "default"
+	}
+	scores := make([]string, len(docs))
+	for i, doc := range docs {
+		scores[i] = fmt.Sprintf("%.3f", doc.Score)
+	}
+	log.Printf("rag %s: kept %d/%d, min %.2f, rel %.2f, scores [%s], zone %q",
+		label, kept, len(docs), options.MinScore, options.RelativeScore, strings.Join(scores, " "), options.Filters["zone"])
+}
+
 func (s *Service) fallbackToTagSearch(ctx context.Context, options RetrievalOptions) ([]lore.Chunk, error) {
 	locationTag := ""
 	factionTag := ""
@@ -88,79 +309,170 @@ func (s *Service) fallbackToTagSearch(ctx context.Context, options RetrievalOpti
 	return s.loreRepo.SelectRelevant(locationTag, factionTag, extraTags), nil
 }
 
-func (s *Service) IndexLore(ctx context.Context) error {
-	coreLore := s.loreRepo.GetCoreLore()
-	if coreLore != "" {
-		coreDoc := repository.VectorDocument{
-			ID:      "core",
-			Title:   "Базовый лор мира Аврора",
-			Content: coreLore,
-			Zone:    "world",
-			Tags:    []string{"world", "core", "основа"},
-			Metadata: map[string]string{
-				"type": "core",
-			},
-		}
+// loreSource — metadata.source документов из файлов лора: по нему IndexLore
+// находит в индексе свои документы.
+const loreSource = "lore_file"
+
+// IndexReport — итог индексации лора. В списках — «файл: заголовок».
+type IndexReport struct {
+	Added     []string
+	Updated   []string
+	Removed   []string
+	Failed    []string
+	Unchanged int
+}
 
-		vector, err := s.embedder.Embed(ctx, coreLore)
-		if err != nil {
-			return fmt.Errorf("embed core lore: %w", err)
-		}
-		coreDoc.Vector = vector
+// IndexLore сверяет лор с индексом: эмбеддинги считаются только для новых и
+// изменённых фрагментов, пропавшие из файлов удаляются. id фрагмента
+// выводится из файла и заголовка, а не из места в списке, так что правка
+// одного файла не задевает остальные; изменение текста видно по
+// metadata.content_hash. Фрагмент, который не удалось пересчитать, остаётся
+// в старом виде и попадает в Failed.
+func (s *Service) IndexLore(ctx context.Context) (IndexReport, error) {
+	var report IndexReport
+	existing, err := s.vectorRepo.ListBySource(ctx, loreSource)
+	if err != nil {
+		return report, fmt.Errorf("list indexed lore: %w", err)
+	}
+	known := make(map[string]repository.DocumentInfo, len(existing))
+	for _, info := range existing {
+		known[info.ID] = info
+	}
 
-		if err := s.vectorRepo.IndexDocument(ctx, coreDoc); err != nil {
-			return fmt.Errorf("index core lore: %w", err)
+	var entries []repository.VectorDocument
+	if coreLore := s.loreRepo.GetCoreLore(); coreLore != "" {
+		entries = append(entries, repository.VectorDocument{
+			ID:       "core",
+			Title:    "Базовый лор мира Аврора",
+			Content:  coreLore,
+			Zone:     lore.RootZone,
+			Tags:     []string{"world", "core", "основа"},
+			Metadata: map[string]string{"type": "core", "path": "core.json"},
+		})
+	}
+	chunks := s.loreRepo.AllChunks()
+	titles := make(map[string]int, len(chunks))
+	for _, chunk := range chunks {
+		titles[loreChunkID(chunk.Source, chunk.Title)]++
+	}
+	seen := make(map[string]bool, len(chunks))
+	for _, chunk := range chunks {
+		docID := loreChunkID(chunk.Source, chunk.Title)
+		// одинаковые заголовки в одном файле различаются отпечатком текста, а
+		// не местом: новый раздел выше по файлу не сдвигает id остальных
+		if titles[docID] > 1 {
+			sum := sha256.Sum256([]byte(chunk.Content))
+			docID += "_" + hex.EncodeToString(sum[:4])
+		}
+		// полные повторы неразличимы, и номер среди них ничего не сдвигает
+		for base, n := docID, 2; seen[docID]; n++ {
+			docID = fmt.Sprintf("%s_%d", base, n)
 		}
+		seen[docID] = true
+		entries = append(entries, repository.VectorDocument{
+			ID:       docID,
+			Title:    chunk.Title,
+			Content:  chunk.Content,
+			Zone:     chunk.Zone,
+			Tags:     chunk.Tags,
+			Metadata: map[string]string{"path": chunk.Source},
+		})
 	}
 
-	chunks := s.loreRepo.SelectRelevant("", "", []string{})
-
+	wanted := make(map[string]bool, len(entries))
 	var docs []repository.VectorDocument
-	for i, chunk := range chunks {
-		docID := fmt.Sprintf("chunk_%d_%s", i, chunk.Zone)
-
-		combinedText := fmt.Sprintf("%s\n\n%s", chunk.Title, chunk.Content)
-		vector, err := s.embedder.Embed(ctx, combinedText)
-		if err != nil {
+	labels := make(map[string]string)
+	isNew := make(map[string]bool)
+	for _, doc := range entries {
+		wanted[doc.ID] = true
+		doc.Metadata["source"] = loreSource
+		doc.Metadata["content_hash"] = contentHash(doc)
+
+		old, ok := known[doc.ID]
+		if ok && old.Metadata["content_hash"] == doc.Metadata["content_hash"] {
+			report.Unchanged++
 			continue
 		}
+		labels[doc.ID] = doc.Metadata["path"] + ": " + doc.Title
+		isNew[doc.ID] = !ok
+		docs = append(docs, doc)
+	}
 
-		doc := repository.VectorDocument{
-			ID:      docID,
-			Title:   chunk.Title,
-			Content: chunk.Content,
-			Zone:    chunk.Zone,
-			Tags:    chunk.Tags,
-			Vector:  vector,
-			Metadata: map[string]string{
-				"source": "lore_file",
-			},
+	failed, err := s.embedAndIndex(ctx, docs)
+	failedIDs := make(map[string]bool, len(failed))
+	for _, f := range failed {
+		log.Printf("embed lore %s: %s", labels[f.ID], f.Error)
+		failedIDs[f.ID] = true
+		report.Failed = append(report.Failed, labels[f.ID])
+	}
+	for _, doc := range docs {
+		switch {
+		case failedIDs[doc.ID]:
+		case isNew[doc.ID]:
+			report.Added = append(report.Added, labels[doc.ID])
+		default:
+			report.Updated = append(report.Updated, labels[doc.ID])
 		}
-
-		docs = append(docs, doc)
+	}
+	if err != nil {
+		return report, err
 	}
 
-	if len(docs) > 0 {
-		if err := s.vectorRepo.IndexBatch(ctx, docs); err != nil {
-			return fmt.Errorf("index chunks: %w", err)
+	var stale []string
+	for _, info := range existing {
+		if !wanted[info.ID] {
+			stale = append(stale, info.ID)
+			path := info.Metadata["path"]
+			if path == "" {
+				// документ старой индексации, с id по месту в списке
+				path = info.ID
+			}
+			report.Removed = append(report.Removed, path+": "+info.Title)
 		}
 	}
+	if err := s.vectorRepo.DeleteDocuments(ctx, stale); err != nil {
+		report.Removed = nil
+		return report, fmt.Errorf("delete removed chunks: %w", err)
+	}
 
-	return nil
+	return report, nil
+}
+
+// loreChunkID — id фрагмента лора по файлу и заголовку.
+func loreChunkID(source, title string) string {
+	sum := sha256.Sum256([]byte(source + "\x00" + title))
+	return "lore_" + hex.EncodeToString(sum[:8])
+}
+
+// contentHash — отпечаток всего, что попадает в индекс: при его смене
+// фрагмент эмбеддится заново.
+func contentHash(doc repository.VectorDocument) string {
+	h := sha256.New()
+	for _, part := range []string{doc.Title, doc.Content, doc.Zone, strings.Join(doc.Tags, ",")} {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil)[:16])
 }
 
 func (s *Service) GetStats(ctx context.Context) (repository.VectorStats, error) {
 	return s.vectorRepo.GetStats(ctx)
 }
 
-func (s *Service) ReindexAll(ctx context.Context) error {
+// ReindexAll удаляет индекс модели эмбеддера, включая локации и большие
+// документы, и заново индексирует лор. Векторы других моделей остаются.
+func (s *Service) ReindexAll(ctx context.Context) (IndexReport, error) {
 	if err := s.vectorRepo.DeleteAll(ctx); err != nil {
-		return fmt.Errorf("delete old vectors: %w", err)
+		return IndexReport{}, fmt.Errorf("delete old vectors: %w", err)
 	}
 
 	return s.IndexLore(ctx)
 }
 
+// IndexLargeDocument режет документ на фрагменты и индексирует их.
+// Фрагменты, уже лежащие в индексе без изменений, не пересчитываются; те, что
+// не удалось посчитать, возвращаются в *EmbedFailures. Фрагменты прежней
+// версии документа, которых больше нет, удаляются.
 func (s *Service) IndexLargeDocument(ctx context.Context, docID, title, content, zone string, tags []string, strategy ChunkingStrategy) error {
 	opts := NewDefaultChunkerOptions()
 	opts.Strategy = strategy
@@ -171,45 +483,31 @@ func (s *Service) IndexLargeDocument(ctx context.Context, docID, title, content,
 
 	docChunks := ChunkDocument(docID, title, content, opts)
 
-	var vectorDocs []repository.VectorDocument
+	vectorDocs := make([]repository.VectorDocument, 0, len(docChunks))
 	for _, chunk := range docChunks {
-		vector, err := s.embedder.Embed(ctx, chunk.Content)
-		if err != nil {
-			continue
-		}
-
 		vectorDocs = append(vectorDocs, repository.VectorDocument{
 			ID:      chunk.ID,
 			Title:   fmt.Sprintf("%s (часть %d)", title, chunk.Index+1),
 			Content: chunk.Content,
 			Zone:    zone,
 			Tags:    tags,
-			Vector:  vector,
 			Metadata: map[string]string{
 				"parent":      chunk.Parent,
+				"document":    docID,
 				"chunk_type":  chunk.Type,
 				"chunk_index": fmt.Sprintf("%d", chunk.Index),
 			},
 		})
 	}
 
-	if len(vectorDocs) > 0 {
-		return s.vectorRepo.IndexBatch(ctx, vectorDocs)
-	}
-
-	return nil
+	return s.indexDocument(ctx, docID, vectorDocs)
 }
 
 func (s *Service) indexHierarchical(ctx context.Context, docID, title, content, zone string, tags []string, opts ChunkerOptions) error {
 	docChunks := ChunkDocument(docID, title, content, opts)
 
-	var vectorDocs []repository.VectorDocument
+	vectorDocs := make([]repository.VectorDocument, 0, len(docChunks))
 	for _, chunk := range docChunks {
-		vector, err := s.embedder.Embed(ctx, chunk.Content)
-		if err != nil {
-			continue
-		}
-
 		chunkTitle := title
 		if chunk.Type == "summary" {
 			chunkTitle = fmt.Sprintf("%s (краткое содержание)", title)
@@ -219,6 +517,7 @@ func (s *Service) indexHierarchical(ctx context.Context, docID, title, content,
 
 		metadata := map[string]string{
 			"parent":      chunk.Parent,
+			"document":    docID,
 			"chunk_type":  chunk.Type,
 			"chunk_index": fmt.Sprintf("%d", chunk.Index),
 		}
@@ -233,16 +532,11 @@ func (s *Service) indexHierarchical(ctx context.Context, docID, title, content,
 			Content:  chunk.Content,
 			Zone:     zone,
 			Tags:     tags,
-			Vector:   vector,
 			Metadata: metadata,
 		})
 	}
 
-	if len(vectorDocs) > 0 {
-		return s.vectorRepo.IndexBatch(ctx, vectorDocs)
-	}
-
-	return nil
+	return s.indexDocument(ctx, docID, vectorDocs)
 }
 
 func (s *Service) IndexFromText(ctx context.Context, docID, title, content, zone string, tags []string) error {
@@ -254,24 +548,19 @@ func (s *Service) IndexFromText(ctx context.Context, docID, title, content, zone
 	} else if contentLength > 2000 {
 		strategy = StrategyParagraph
 	} else {
-		vector, err := s.embedder.Embed(ctx, fmt.Sprintf("%s\n\n%s", title, content))
-		if err != nil {
-			return err
-		}
-
 		doc := repository.VectorDocument{
 			ID:      docID,
 			Title:   title,
 			Content: content,
 			Zone:    zone,
 			Tags:    tags,
-			Vector:  vector,
 			Metadata: map[string]string{
-				"source": "direct",
+				"source":   "direct",
+				"document": docID,
 			},
 		}
 
-		return s.vectorRepo.IndexDocument(ctx, doc)
+		return s.indexDocument(ctx, docID, []repository.VectorDocument{doc})
 	}
 
 	return s.IndexLargeDocument(ctx, docID, title, content, zone, tags, strategy)
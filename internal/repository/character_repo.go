@@ -3,6 +3,8 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"fmt"
+	"strings"
 	"time"
 
 	"aurora/internal/models"
@@ -16,42 +18,138 @@ func NewCharacterRepository(db *sql.DB) *CharacterRepository {
 	return &CharacterRepository{db: db}
 }
 
+const effectColumns = `id, character_id, name, IFNULL(description,''), duration_turns, is_hidden,
+       hp_per_turn, power_mod, blocked_abilities, stacks`
+
 // GetEffects загружает список эффектов персонажа из БД
 func (r *CharacterRepository) GetEffects(ctx context.Context, charID int64) ([]models.Effect, error) {
-	query := `SELECT id, character_id, name, description, duration_turns, is_hidden 
-              FROM character_effects WHERE character_id = ?`
+	rows, err := r.db.QueryContext(ctx, `SELECT `+effectColumns+`
+FROM character_effects WHERE character_id = ? ORDER BY id`, charID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanEffects(rows)
+}
 
-	rows, err := r.db.QueryContext(ctx, query, charID)
+// GetAllEffects — эффекты всех персонажей, для хода мировых часов.
+func (r *CharacterRepository) GetAllEffects(ctx context.Context) ([]models.Effect, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT `+effectColumns+`
+FROM character_effects ORDER BY character_id, id`)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
+	return scanEffects(rows)
+}
 
+func scanEffects(rows *sql.Rows) ([]models.Effect, error) {
 	var effects []models.Effect
 	for rows.Next() {
 		var e models.Effect
-		if err := rows.Scan(&e.ID, &e.CharacterID, &e.Name, &e.Description, &e.Duration, &e.IsHidden); err != nil {
-			continue
+		var blocked string
+		if err := rows.Scan(&e.ID, &e.CharacterID, &e.Name, &e.Description, &e.Duration, &e.IsHidden,
+			&e.HPPerTurn, &e.PowerMod, &blocked, &e.Stacks); err != nil {
+			return nil, err
+		}
+		if blocked != "" {
+			e.Blocked = strings.Split(blocked, ",")
 		}
 		effects = append(effects, e)
 	}
-	return effects, nil
+	return effects, rows.Err()
 }
 
-func (r *CharacterRepository) GetByVKID(ctx context.Context, vkUserID int64) (*models.Character, error) {
-	row := r.db.QueryRowContext(ctx, `
-SELECT
+func (r *CharacterRepository) AddEffect(ctx context.Context, e models.Effect) (int64, error) {
+	return addEffect(ctx, r.db, e)
+}
+
+func addEffect(ctx context.Context, q dbtx, e models.Effect) (int64, error) {
+	if e.Stacks < 1 {
+		e.Stacks = 1
+	}
+	res, err := q.ExecContext(ctx, `
+INSERT INTO character_effects (character_id, name, description, duration_turns, is_hidden, hp_per_turn, power_mod, blocked_abilities, stacks)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		e.CharacterID, e.Name, e.Description, e.Duration, e.IsHidden, e.HPPerTurn, e.PowerMod, strings.Join(e.Blocked, ","), e.Stacks,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+func (r *CharacterRepository) UpdateEffect(ctx context.Context, e models.Effect) error {
+	return updateEffect(ctx, r.db, e)
+}
+
+func updateEffect(ctx context.Context, q dbtx, e models.Effect) error {
+	_, err := q.ExecContext(ctx, `
+UPDATE character_effects
+SET description = ?, duration_turns = ?, is_hidden = ?, hp_per_turn = ?, power_mod = ?, blocked_abilities = ?, stacks = ?
+WHERE id = ?`,
+		e.Description, e.Duration, e.IsHidden, e.HPPerTurn, e.PowerMod, strings.Join(e.Blocked, ","), e.Stacks, e.ID,
+	)
+	return err
+}
+
+func (r *CharacterRepository) DeleteEffect(ctx context.Context, id int64) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM character_effects WHERE id = ?`, id)
+	return err
+}
+
+// ApplyEffectTick одной транзакцией меняет здоровье персонажей (в пределах 0..100),
+// сохраняет длительности эффектов и удаляет истёкшие. Возвращает новое здоровье.
+func (r *CharacterRepository) ApplyEffectTick(ctx context.Context, hp map[int64]int, updated []models.Effect, expired []int64) (map[int64]int, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	after := make(map[int64]int, len(hp))
+	for charID, delta := range hp {
+		if _, err := tx.ExecContext(ctx, `
+UPDATE characters SET combat_health = MAX(0, MIN(100, IFNULL(combat_health, 100) + ?)) WHERE id = ?`, delta, charID); err != nil {
+			return nil, err
+		}
+		var v int
+		if err := tx.QueryRowContext(ctx, `SELECT combat_health FROM characters WHERE id = ?`, charID).Scan(&v); err != nil {
+			return nil, err
+		}
+		after[charID] = v
+	}
+	for _, e := range updated {
+		if _, err := tx.ExecContext(ctx, `UPDATE character_effects SET duration_turns = ? WHERE id = ?`, e.Duration, e.ID); err != nil {
+			return nil, err
+		}
+	}
+	for _, id := range expired {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM character_effects WHERE id = ?`, id); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit transaction: %w", err)
+	}
+	return after, nil
+}
+
+const characterColumns = `
   id, vk_user_id, name, IFNULL(race, ''), IFNULL(class, ''), IFNULL(faction_id, 0), IFNULL(faction_name, ''),
   IFNULL(traits, ''), IFNULL(goal, ''), IFNULL(location_id, 0), IFNULL(location_name, ''),
   IFNULL(status, ''), IFNULL(abilities, ''), IFNULL(bio, ''), IFNULL(combat_power, 10),
-  IFNULL(combat_health, 100), IFNULL(gold, 0), IFNULL(gender, ''), IFNULL(country, ''), IFNULL(sheet_json, ''), created_at
-FROM characters WHERE vk_user_id = ? LIMIT 1`, vkUserID)
+  IFNULL(combat_health, 100), IFNULL(gold, 0), IFNULL(gender, ''), IFNULL(country, ''), IFNULL(sheet_json, ''),
+  IFNULL(xp, 0), IFNULL(level, 1), dying_until, archived_at, created_at`
 
+func scanCharacter(row *sql.Row) (*models.Character, error) {
 	var ch models.Character
 	err := row.Scan(
 		&ch.ID, &ch.VKUserID, &ch.Name, &ch.Race, &ch.Class, &ch.FactionID, &ch.FactionName,
 		&ch.Traits, &ch.Goal, &ch.LocationID, &ch.LocationName, &ch.Status, &ch.Abilities,
-		&ch.Bio, &ch.CombatPower, &ch.CombatHealth, &ch.Gold, &ch.Gender, &ch.Country, &ch.SheetJSON, &ch.CreatedAt,
+		&ch.Bio, &ch.CombatPower, &ch.CombatHealth, &ch.Gold, &ch.Gender, &ch.Country, &ch.SheetJSON,
+		&ch.XP, &ch.Level, &ch.DyingUntil, &ch.ArchivedAt, &ch.CreatedAt,
 	)
 	if err != nil {
 		return nil, err
@@ -59,62 +157,304 @@ FROM characters WHERE vk_user_id = ? LIMIT 1`, vkUserID)
 	return &ch, nil
 }
 
+// GetByVKID возвращает активного персонажа аккаунта.
+func (r *CharacterRepository) GetByVKID(ctx context.Context, vkUserID int64) (*models.Character, error) {
+	return scanCharacter(r.db.QueryRowContext(ctx, `SELECT `+characterColumns+`
+FROM characters
+WHERE id = (SELECT active_character_id FROM player_accounts WHERE vk_user_id = ?) AND archived_at IS NULL`, vkUserID))
+}
+
+// ListByVKID — все персонажи аккаунта, включая архивных, от новых к старым.
+func (r *CharacterRepository) ListByVKID(ctx context.Context, vkUserID int64) ([]models.Character, error) {
+	rows, err := r.db.QueryContext(ctx, `
+SELECT id, name, IFNULL(race, ''), IFNULL(status, ''), IFNULL(level, 1), IFNULL(location_name, ''), archived_at
+FROM characters WHERE vk_user_id = ? ORDER BY archived_at IS NOT NULL, id DESC`, vkUserID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []models.Character
+	for rows.Next() {
+		ch := models.Character{VKUserID: vkUserID}
+		if err := rows.Scan(&ch.ID, &ch.Name, &ch.Race, &ch.Status, &ch.Level, &ch.LocationName, &ch.ArchivedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, ch)
+	}
+	return out, rows.Err()
+}
+
+// CountActive — сколько неархивных персонажей у аккаунта.
+func (r *CharacterRepository) CountActive(ctx context.Context, vkUserID int64) (int, error) {
+	var n int
+	err := r.db.QueryRowContext(ctx, `
+SELECT COUNT(*) FROM characters WHERE vk_user_id = ? AND archived_at IS NULL`, vkUserID).Scan(&n)
+	return n, err
+}
+
+// SetActive делает персонажа активным для его аккаунта.
+func (r *CharacterRepository) SetActive(ctx context.Context, vkUserID, charID int64) error {
+	_, err := r.db.ExecContext(ctx, `
+INSERT INTO player_accounts (vk_user_id, active_character_id, updated_at) VALUES (?, ?, ?)
+ON CONFLICT(vk_user_id) DO UPDATE SET active_character_id = excluded.active_character_id, updated_at = excluded.updated_at`,
+		vkUserID, charID, time.Now())
+	return err
+}
+
+func (r *CharacterRepository) GetByID(ctx context.Context, id int64) (*models.Character, error) {
+	return scanCharacter(r.db.QueryRowContext(ctx, `SELECT `+characterColumns+`
+FROM characters WHERE id = ?`, id))
+}
+
+// Create заводит персонажа и делает его активным для аккаунта. Стартовое
+// золото проходит через журнал, как любое другое.
 func (r *CharacterRepository) Create(ctx context.Context, apiChar *models.Character) (int64, error) {
-	res, err := r.db.ExecContext(ctx, `
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `
 INSERT INTO characters (vk_user_id, name, status, location_name, combat_power, combat_health, gold, created_at)
-VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
-		apiChar.VKUserID, apiChar.Name, apiChar.Status, apiChar.LocationName, apiChar.CombatPower, apiChar.CombatHealth, apiChar.Gold, time.Now(),
+VALUES (?, ?, ?, ?, ?, ?, 0, ?)`,
+		apiChar.VKUserID, apiChar.Name, apiChar.Status, apiChar.LocationName, apiChar.CombatPower, apiChar.CombatHealth, time.Now(),
 	)
 	if err != nil {
 		return 0, err
 	}
-	return res.LastInsertId()
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	if apiChar.Gold != 0 {
+		if _, err := adjustGold(ctx, tx, models.LedgerEntry{
+			CharacterID: id,
+			Amount:      apiChar.Gold,
+			Reason:      "Начальный баланс",
+			Source:      models.LedgerSourceGM,
+		}); err != nil {
+			return 0, fmt.Errorf("opening balance: %w", err)
+		}
+	}
+	if _, err := tx.ExecContext(ctx, `
+INSERT INTO player_accounts (vk_user_id, active_character_id, updated_at) VALUES (?, ?, ?)
+ON CONFLICT(vk_user_id) DO UPDATE SET active_character_id = excluded.active_character_id, updated_at = excluded.updated_at`,
+		apiChar.VKUserID, id, time.Now()); err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("commit transaction: %w", err)
+	}
+	return id, nil
 }
 
+// Update сохраняет анкету персонажа. Здоровье, опыт и золото здесь не
+// пишутся: их меняют точечные запросы ниже и LedgerRepository, чтобы запись
+// анкеты не затирала лечение или урон, случившиеся после её чтения.
 func (r *CharacterRepository) Update(ctx context.Context, ch *models.Character) error {
-	_, err := r.db.ExecContext(ctx, `
+	return updateCharacter(ctx, r.db, ch)
+}
+
+func updateCharacter(ctx context.Context, q dbtx, ch *models.Character) error {
+	_, err := q.ExecContext(ctx, `
 UPDATE characters
-SET name=?, gender=?, race=?, country=?, traits=?, goal=?, abilities=?, bio=?, sheet_json=?, 
-    location_name=?, combat_health=?, gold=?
+SET name=?, gender=?, race=?, class=?, country=?, traits=?, goal=?, abilities=?, bio=?, sheet_json=?, 
+    location_name=?
 WHERE id=?`,
-		ch.Name, ch.Gender, ch.Race, ch.Country, ch.Traits, ch.Goal, ch.Abilities, ch.Bio, ch.SheetJSON,
-		ch.LocationName, ch.CombatHealth, ch.Gold, ch.ID,
+		ch.Name, ch.Gender, ch.Race, ch.Class, ch.Country, ch.Traits, ch.Goal, ch.Abilities, ch.Bio, ch.SheetJSON,
+		ch.LocationName, ch.ID,
 	)
 	return err
 }
 
-func (r *CharacterRepository) DecrementEffectDurations(ctx context.Context, charID int64) error {
+func (r *CharacterRepository) UpdateLocation(ctx context.Context, charID, locID int64, locName string) error {
 	_, err := r.db.ExecContext(ctx, `
-		UPDATE character_effects
-		SET duration_turns = duration_turns - 1
-		WHERE character_id = ? AND duration_turns > 0
-	`, charID)
+UPDATE characters SET location_id = ?, location_name = ? WHERE id = ?`, locID, locName, charID)
 	return err
 }
 
-func (r *CharacterRepository) DeleteExpiredEffects(ctx context.Context, charID int64) error {
-	_, err := r.db.ExecContext(ctx, `
-		DELETE FROM character_effects
-		WHERE character_id = ? AND duration_turns = 0
-	`, charID)
+// ChangeHealth сдвигает здоровье на delta от того, что сейчас в базе, в
+// пределах 0..maxHP. Возвращает новое здоровье.
+func (r *CharacterRepository) ChangeHealth(ctx context.Context, charID int64, delta, maxHP int) (int, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `
+UPDATE characters SET combat_health = MAX(0, MIN(?, IFNULL(combat_health, 100) + ?)) WHERE id = ?`,
+		maxHP, delta, charID,
+	); err != nil {
+		return 0, err
+	}
+	var hp int
+	if err := tx.QueryRowContext(ctx, `SELECT combat_health FROM characters WHERE id = ?`, charID).Scan(&hp); err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("commit transaction: %w", err)
+	}
+	return hp, nil
+}
+
+// Revive поднимает мёртвого персонажа с здоровьем hp. sql.ErrNoRows — он
+// уже не мёртв.
+func (r *CharacterRepository) Revive(ctx context.Context, charID int64, hp int) error {
+	res, err := r.db.ExecContext(ctx, `
+UPDATE characters SET combat_health = ? WHERE id = ? AND status = ?`, hp, charID, models.LifeDead)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// AddXP прибавляет опыт к текущему в базе. Возвращает опыт и уровень после.
+func (r *CharacterRepository) AddXP(ctx context.Context, charID int64, amount int) (xp, level int, err error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, 0, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `UPDATE characters SET xp = xp + ? WHERE id = ?`, amount, charID); err != nil {
+		return 0, 0, err
+	}
+	if err := tx.QueryRowContext(ctx, `SELECT xp, level FROM characters WHERE id = ?`, charID).Scan(&xp, &level); err != nil {
+		return 0, 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, 0, fmt.Errorf("commit transaction: %w", err)
+	}
+	return xp, level, nil
+}
+
+// LevelUp переводит персонажа с уровня from на to, прибавляет боевой
+// потенциал и лечит до hp. sql.ErrNoRows — уровень уже сменился.
+func (r *CharacterRepository) LevelUp(ctx context.Context, charID int64, from, to, powerGain, hp int) error {
+	res, err := r.db.ExecContext(ctx, `
+UPDATE characters
+SET level = ?, combat_power = combat_power + ?, combat_health = MAX(IFNULL(combat_health, 100), ?)
+WHERE id = ? AND level = ?`,
+		to, powerGain, hp, charID, from,
+	)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// AddAbility записывает полученную способность и дописывает её в анкету.
+// Возвращает false, если она уже была.
+func (r *CharacterRepository) AddAbility(ctx context.Context, a models.CharacterAbility) (bool, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `
+INSERT OR IGNORE INTO character_abilities (character_id, name, description, source)
+VALUES (?, ?, ?, ?)`,
+		a.CharacterID, a.Name, a.Description, a.Source,
+	)
+	if err != nil {
+		return false, err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return false, nil
+	}
+	if _, err := tx.ExecContext(ctx, `
+UPDATE characters
+SET abilities = CASE WHEN TRIM(IFNULL(abilities, '')) = '' THEN ?
+                     ELSE RTRIM(abilities, ' ,.;') || ? || ? END
+WHERE id = ?`,
+		a.Name, models.AbilitySeparator, a.Name, a.CharacterID,
+	); err != nil {
+		return false, err
+	}
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("commit transaction: %w", err)
+	}
+	return true, nil
+}
+
+func (r *CharacterRepository) GetAbilities(ctx context.Context, charID int64) ([]models.CharacterAbility, error) {
+	rows, err := r.db.QueryContext(ctx, `
+SELECT id, character_id, name, description, source, unlocked_at
+FROM character_abilities WHERE character_id = ? ORDER BY id`, charID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []models.CharacterAbility
+	for rows.Next() {
+		var a models.CharacterAbility
+		if err := rows.Scan(&a.ID, &a.CharacterID, &a.Name, &a.Description, &a.Source, &a.UnlockedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, a)
+	}
+	return out, nil
+}
+
+// GetByName ищет персонажа по имени; активные важнее архивных.
+func (r *CharacterRepository) GetByName(ctx context.Context, name string) (*models.Character, error) {
+	return scanCharacter(r.db.QueryRowContext(ctx, `SELECT `+characterColumns+`
+FROM characters WHERE name = ? ORDER BY archived_at IS NOT NULL, id DESC LIMIT 1`, name))
+}
+
+func (r *CharacterRepository) GetVKUserID(ctx context.Context, charID int64) (int64, error) {
+	var vkID int64
+	err := r.db.QueryRowContext(ctx, `SELECT vk_user_id FROM characters WHERE id = ?`, charID).Scan(&vkID)
+	return vkID, err
+}
+
+// SetLife сохраняет состояние жизни и таймер кровотечения.
+func (r *CharacterRepository) SetLife(ctx context.Context, charID int64, status string, dyingUntil int64) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE characters SET status = ?, dying_until = ? WHERE id = ?`, status, dyingUntil, charID)
 	return err
 }
 
-func (r *CharacterRepository) GetExpiringEffects(ctx context.Context, charID int64) ([]string, error) {
+// ListLifeCandidates — персонажи, чьё состояние жизни может смениться без
+// их участия: умирающие и живые с нулевым здоровьем.
+func (r *CharacterRepository) ListLifeCandidates(ctx context.Context) ([]models.Character, error) {
 	rows, err := r.db.QueryContext(ctx, `
-		SELECT name FROM character_effects 
-		WHERE character_id = ? AND duration_turns = 1`, charID)
+SELECT id, vk_user_id, name, IFNULL(status, ''), IFNULL(combat_health, 100), dying_until, archived_at
+FROM characters
+WHERE status = ? OR (IFNULL(combat_health, 100) <= 0 AND IFNULL(status, '') <> ?)`, models.LifeDying, models.LifeDead)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var names []string
+	var out []models.Character
 	for rows.Next() {
-		var name string
-		if err := rows.Scan(&name); err == nil {
-			names = append(names, name)
+		var ch models.Character
+		if err := rows.Scan(&ch.ID, &ch.VKUserID, &ch.Name, &ch.Status, &ch.CombatHealth, &ch.DyingUntil, &ch.ArchivedAt); err != nil {
+			return nil, err
 		}
+		out = append(out, ch)
 	}
-	return names, nil
+	return out, rows.Err()
+}
+
+// Archive убирает персонажа из активных; игрок сможет завести нового.
+func (r *CharacterRepository) Archive(ctx context.Context, charID int64) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE characters SET archived_at = ? WHERE id = ? AND archived_at IS NULL`, time.Now(), charID)
+	return err
+}
+
+func (r *CharacterRepository) Unarchive(ctx context.Context, charID int64) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE characters SET archived_at = NULL WHERE id = ?`, charID)
+	return err
 }
@@ -0,0 +1,131 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"aurora/internal/models"
+)
+
+func TestChangeHealthIsRelative(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+	chars := NewCharacterRepository(db)
+	charID := createTestCharacter(t, db, 1, 50, 0)
+
+	// ход прочитал 50 HP и насчитал 40, а тем временем персонажа подлечили до 70
+	if _, err := db.Exec(`UPDATE characters SET combat_health = 70 WHERE id = ?`, charID); err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []struct{ delta, want int }{
+		{-10, 60},
+		{100, 100},
+		{-500, 0},
+	} {
+		hp, err := chars.ChangeHealth(ctx, charID, c.delta, 100)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if hp != c.want {
+			t.Fatalf("%+d: hp %d, want %d", c.delta, hp, c.want)
+		}
+	}
+}
+
+func TestProgressWritesDoNotOverwrite(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+	chars := NewCharacterRepository(db)
+	charID := createTestCharacter(t, db, 1, 30, 0)
+
+	for _, amount := range []int{40, 70} {
+		if _, _, err := chars.AddXP(ctx, charID, amount); err != nil {
+			t.Fatal(err)
+		}
+	}
+	xp, level, err := chars.AddXP(ctx, charID, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if xp != 110 || level != 1 {
+		t.Fatalf("xp %d level %d, want 110 and 1", xp, level)
+	}
+
+	// второй, опоздавший переход с того же уровня не проходит
+	if err := chars.LevelUp(ctx, charID, 1, 2, 5, 100); err != nil {
+		t.Fatal(err)
+	}
+	if err := chars.LevelUp(ctx, charID, 1, 2, 5, 100); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("second level up: err = %v, want sql.ErrNoRows", err)
+	}
+	ch, err := chars.GetByID(ctx, charID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ch.Level != 2 || ch.CombatPower != 15 || ch.CombatHealth != 100 {
+		t.Fatalf("level %d power %d hp %d", ch.Level, ch.CombatPower, ch.CombatHealth)
+	}
+
+	// анкета, записанная после, не трогает здоровье
+	ch.CombatHealth = 1
+	if err := chars.Update(ctx, ch); err != nil {
+		t.Fatal(err)
+	}
+	ch, err = chars.GetByID(ctx, charID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ch.CombatHealth != 100 {
+		t.Fatalf("Update overwrote health: %d", ch.CombatHealth)
+	}
+}
+
+func TestAddAbilityAppends(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+	chars := NewCharacterRepository(db)
+	charID := createTestCharacter(t, db, 1, 100, 0)
+
+	for _, c := range []struct {
+		name  string
+		added bool
+	}{
+		{"Огненный шар", true},
+		{"Щит", true},
+		{"Огненный шар", false},
+	} {
+		added, err := chars.AddAbility(ctx, models.CharacterAbility{CharacterID: charID, Name: c.name, Source: models.AbilitySourceLevel})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if added != c.added {
+			t.Fatalf("%s: added = %v", c.name, added)
+		}
+	}
+	ch, err := chars.GetByID(ctx, charID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ch.Abilities != "Огненный шар; Щит" {
+		t.Fatalf("abilities = %q", ch.Abilities)
+	}
+}
+
+func TestReviveOnlyDead(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+	chars := NewCharacterRepository(db)
+	charID := createTestCharacter(t, db, 1, 0, 0)
+
+	if err := chars.Revive(ctx, charID, 20); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("revive alive: err = %v", err)
+	}
+	if err := chars.SetLife(ctx, charID, models.LifeDead, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := chars.Revive(ctx, charID, 20); err != nil {
+		t.Fatal(err)
+	}
+}
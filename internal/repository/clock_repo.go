@@ -0,0 +1,34 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+)
+
+type ClockRepository struct {
+	db *sql.DB
+}
+
+func NewClockRepository(db *sql.DB) *ClockRepository {
+	return &ClockRepository{db: db}
+}
+
+// Get возвращает сохранённое игровое время в минутах; false, если часы ещё не заводились.
+func (r *ClockRepository) Get(ctx context.Context) (int64, bool, error) {
+	var m int64
+	err := r.db.QueryRowContext(ctx, `SELECT minutes FROM world_clock WHERE id = 1`).Scan(&m)
+	if err == sql.ErrNoRows {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	return m, true, nil
+}
+
+func (r *ClockRepository) Set(ctx context.Context, minutes int64) error {
+	_, err := r.db.ExecContext(ctx, `
+INSERT INTO world_clock (id, minutes, updated_at) VALUES (1, ?, CURRENT_TIMESTAMP)
+ON CONFLICT(id) DO UPDATE SET minutes = excluded.minutes, updated_at = excluded.updated_at`, minutes)
+	return err
+}
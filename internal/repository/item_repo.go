@@ -0,0 +1,302 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"aurora/internal/models"
+)
+
+var (
+	ErrNotEnoughItems = errors.New("not enough items")
+	ErrHealingLimit   = errors.New("healing limit reached this turn")
+	ErrFullHealth     = errors.New("character is at full health")
+)
+
+// ItemUse — механика использования предмета, которая пишется одной транзакцией:
+// лечение в пределах лимита хода, эффект предмета и списание расходника.
+type ItemUse struct {
+	CharacterID int64
+	ItemID      int64
+	Consume     bool
+	Heal        int
+	// Turn — ход мира, за который копится лечение; HealCap — сколько можно
+	// вылечить за ход, MaxHP — предел здоровья персонажа.
+	Turn    int64
+	HealCap int
+	MaxHP   int
+	// Effect — эффект предмета: с ID обновляет наложенный, без ID
+	// добавляется, и тогда Use проставляет ему ID.
+	Effect *models.Effect
+}
+
+type ItemRepository struct {
+	db *sql.DB
+}
+
+func NewItemRepository(db *sql.DB) *ItemRepository {
+	return &ItemRepository{db: db}
+}
+
+// ItemNameKey нормализует название предмета для поиска без учёта регистра
+// (SQLite lower() не работает с кириллицей).
+func ItemNameKey(name string) string {
+	return strings.ToLower(strings.Join(strings.Fields(name), " "))
+}
+
+func (r *ItemRepository) GetByName(ctx context.Context, name string) (*models.Item, error) {
+	row := r.db.QueryRowContext(ctx, `
+SELECT id, name, IFNULL(description,''), rarity, value, equip_slot, properties, created_at
+FROM items
+WHERE name_key = ?
+LIMIT 1`, ItemNameKey(name))
+
+	return scanItem(row)
+}
+
+func (r *ItemRepository) Create(ctx context.Context, it *models.Item) (int64, error) {
+	if it.Properties == nil {
+		it.Properties = map[string]string{}
+	}
+	props, err := json.Marshal(it.Properties)
+	if err != nil {
+		return 0, fmt.Errorf("marshal properties: %w", err)
+	}
+
+	res, err := r.db.ExecContext(ctx, `
+INSERT OR IGNORE INTO items (name, name_key, description, rarity, value, equip_slot, properties)
+VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		it.Name, ItemNameKey(it.Name), it.Description, it.Rarity, it.Value, it.EquipSlot, string(props),
+	)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+func (r *ItemRepository) ListForCharacter(ctx context.Context, charID int64) ([]models.InventoryItem, error) {
+	rows, err := r.db.QueryContext(ctx, `
+SELECT ci.id, ci.character_id, ci.quantity, ci.is_equipped,
+       i.id, i.name, IFNULL(i.description,''), i.rarity, i.value, i.equip_slot, i.properties, i.created_at
+FROM character_items ci
+JOIN items i ON i.id = ci.item_id
+WHERE ci.character_id = ? AND ci.quantity > 0
+ORDER BY ci.is_equipped DESC, i.name`, charID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []models.InventoryItem
+	for rows.Next() {
+		var inv models.InventoryItem
+		var props string
+		if err := rows.Scan(
+			&inv.ID, &inv.CharacterID, &inv.Quantity, &inv.IsEquipped,
+			&inv.Item.ID, &inv.Item.Name, &inv.Item.Description, &inv.Item.Rarity,
+			&inv.Item.Value, &inv.Item.EquipSlot, &props, &inv.Item.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		inv.Item.Properties = decodeItemProperties(props)
+		out = append(out, inv)
+	}
+	return out, rows.Err()
+}
+
+// LegacyInventories — текст из старой колонки characters.inventory, который
+// ещё не перенесён в character_items.
+func (r *ItemRepository) LegacyInventories(ctx context.Context) (map[int64]string, error) {
+	rows, err := r.db.QueryContext(ctx, `
+SELECT id, inventory FROM characters
+WHERE IFNULL(TRIM(inventory), '') != ''`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make(map[int64]string)
+	for rows.Next() {
+		var id int64
+		var text string
+		if err := rows.Scan(&id, &text); err != nil {
+			return nil, err
+		}
+		out[id] = text
+	}
+	return out, rows.Err()
+}
+
+// ClearLegacyInventory отмечает старый инвентарь персонажа перенесённым.
+func (r *ItemRepository) ClearLegacyInventory(ctx context.Context, charID int64) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE characters SET inventory = '' WHERE id = ?`, charID)
+	return err
+}
+
+func (r *ItemRepository) AddToCharacter(ctx context.Context, charID, itemID int64, qty int) error {
+	return addItem(ctx, r.db, charID, itemID, qty)
+}
+
+// RemoveFromCharacter списывает qty штук предмета и удаляет пустую запись.
+func (r *ItemRepository) RemoveFromCharacter(ctx context.Context, charID, itemID int64, qty int) error {
+	return removeItem(ctx, r.db, charID, itemID, qty)
+}
+
+// Equip надевает предмет, снимая всё остальное из того же слота.
+func (r *ItemRepository) Equip(ctx context.Context, charID, itemID int64, slot string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `
+UPDATE character_items SET is_equipped = 0
+WHERE character_id = ? AND item_id IN (SELECT id FROM items WHERE equip_slot = ?)`,
+		charID, slot,
+	); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+UPDATE character_items SET is_equipped = 1
+WHERE character_id = ? AND item_id = ?`,
+		charID, itemID,
+	); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *ItemRepository) Unequip(ctx context.Context, charID, itemID int64) error {
+	_, err := r.db.ExecContext(ctx, `
+UPDATE character_items SET is_equipped = 0
+WHERE character_id = ? AND item_id = ?`,
+		charID, itemID,
+	)
+	return err
+}
+
+// Use лечит персонажа, накладывает эффект и списывает расходник атомарно:
+// при ошибке не остаётся ни действия без списания, ни списания без действия.
+// Возвращает здоровье до и после. Если лечить нечего, а эффекта нет, предмет
+// не тратится: ErrFullHealth — здоровье и так полное, ErrHealingLimit —
+// лимит лечения этого хода исчерпан.
+func (r *ItemRepository) Use(ctx context.Context, u ItemUse) (int, int, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, 0, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var hp, healed int
+	var turn int64
+	if err := tx.QueryRowContext(ctx, `
+SELECT IFNULL(combat_health, 100), healed_turn, healed_amount FROM characters WHERE id = ?`, u.CharacterID,
+	).Scan(&hp, &turn, &healed); err != nil {
+		return 0, 0, err
+	}
+	before := hp
+
+	if u.Heal > 0 {
+		if turn != u.Turn {
+			healed = 0
+		}
+		heal := min(u.Heal, u.HealCap-healed, u.MaxHP-hp)
+		switch {
+		case heal > 0:
+			hp += heal
+			healed += heal
+			if _, err := tx.ExecContext(ctx, `
+UPDATE characters SET combat_health = ?, healed_turn = ?, healed_amount = ? WHERE id = ?`,
+				hp, u.Turn, healed, u.CharacterID,
+			); err != nil {
+				return 0, 0, err
+			}
+		case u.Effect != nil:
+			// лечение пропадает, но эффект предмета сработает
+		case hp >= u.MaxHP:
+			return before, before, ErrFullHealth
+		default:
+			return before, before, ErrHealingLimit
+		}
+	}
+
+	if e := u.Effect; e != nil {
+		if e.ID != 0 {
+			err = updateEffect(ctx, tx, *e)
+		} else {
+			e.ID, err = addEffect(ctx, tx, *e)
+		}
+		if err != nil {
+			return 0, 0, fmt.Errorf("apply item effect: %w", err)
+		}
+	}
+
+	if u.Consume {
+		if err := removeItem(ctx, tx, u.CharacterID, u.ItemID, 1); err != nil {
+			return 0, 0, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, 0, fmt.Errorf("commit transaction: %w", err)
+	}
+	return before, hp, nil
+}
+
+func addItem(ctx context.Context, q dbtx, charID, itemID int64, qty int) error {
+	_, err := q.ExecContext(ctx, `
+INSERT INTO character_items (character_id, item_id, quantity)
+VALUES (?, ?, ?)
+ON CONFLICT(character_id, item_id) DO UPDATE SET quantity = quantity + excluded.quantity`,
+		charID, itemID, qty,
+	)
+	return err
+}
+
+func removeItem(ctx context.Context, q dbtx, charID, itemID int64, qty int) error {
+	res, err := q.ExecContext(ctx, `
+UPDATE character_items SET quantity = quantity - ?
+WHERE character_id = ? AND item_id = ? AND quantity >= ?`,
+		qty, charID, itemID, qty,
+	)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotEnoughItems
+	}
+
+	_, err = q.ExecContext(ctx, `
+DELETE FROM character_items WHERE character_id = ? AND item_id = ? AND quantity <= 0`,
+		charID, itemID,
+	)
+	return err
+}
+
+func scanItem(row *sql.Row) (*models.Item, error) {
+	var it models.Item
+	var props string
+	if err := row.Scan(&it.ID, &it.Name, &it.Description, &it.Rarity, &it.Value, &it.EquipSlot, &props, &it.CreatedAt); err != nil {
+		return nil, err
+	}
+	it.Properties = decodeItemProperties(props)
+	return &it, nil
+}
+
+func decodeItemProperties(raw string) map[string]string {
+	props := make(map[string]string)
+	if raw == "" {
+		return props
+	}
+	if err := json.Unmarshal([]byte(raw), &props); err != nil {
+		return make(map[string]string)
+	}
+	return props
+}
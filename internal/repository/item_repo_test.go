@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"aurora/internal/models"
+)
+
+var potion = models.Item{
+	Name:       "Малое зелье",
+	Properties: map[string]string{models.PropHeal: "30", models.PropConsumable: "true"},
+}
+
+func TestItemUseHealCap(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+	items := NewItemRepository(db)
+	charID := createTestCharacter(t, db, 1, 20, 0)
+	itemID := giveTestItem(t, db, charID, potion, 5)
+
+	use := ItemUse{CharacterID: charID, ItemID: itemID, Consume: true, Heal: 30, Turn: 1, HealCap: 40, MaxHP: 100}
+	for _, c := range []struct {
+		turn           int64
+		before, after  int
+		err            error
+		remainingItems int
+	}{
+		{turn: 1, before: 20, after: 50, remainingItems: 4},
+		// в этом ходу осталось 10 из 40
+		{turn: 1, before: 50, after: 60, remainingItems: 3},
+		// лимит исчерпан — зелье не тратится
+		{turn: 1, before: 60, after: 60, err: ErrHealingLimit, remainingItems: 3},
+		// новый ход — лимит снова полный
+		{turn: 2, before: 60, after: 90, remainingItems: 2},
+		// предел здоровья
+		{turn: 3, before: 90, after: 100, remainingItems: 1},
+		{turn: 3, before: 100, after: 100, err: ErrFullHealth, remainingItems: 1},
+		{turn: 4, before: 100, after: 100, err: ErrFullHealth, remainingItems: 1},
+	} {
+		use.Turn = c.turn
+		before, after, err := items.Use(ctx, use)
+		if !errors.Is(err, c.err) {
+			t.Fatalf("turn %d at %d HP: err = %v, want %v", c.turn, c.before, err, c.err)
+		}
+		if before != c.before || after != c.after {
+			t.Fatalf("turn %d: HP %d → %d, want %d → %d", c.turn, before, after, c.before, c.after)
+		}
+		if got := itemQuantity(t, db, charID, itemID); got != c.remainingItems {
+			t.Fatalf("turn %d: %d potions left, want %d", c.turn, got, c.remainingItems)
+		}
+	}
+}
+
+func TestItemUseConsumesLastAndAppliesEffect(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+	items := NewItemRepository(db)
+	chars := NewCharacterRepository(db)
+	charID := createTestCharacter(t, db, 1, 100, 0)
+	itemID := giveTestItem(t, db, charID, potion, 1)
+
+	// на полном здоровье зелье с эффектом всё равно срабатывает
+	e := &models.Effect{CharacterID: charID, Name: "Бодрость", Duration: 3}
+	use := ItemUse{CharacterID: charID, ItemID: itemID, Consume: true, Heal: 30, Turn: 1, HealCap: 40, MaxHP: 100, Effect: e}
+	if _, _, err := items.Use(ctx, use); err != nil {
+		t.Fatal(err)
+	}
+	if e.ID == 0 {
+		t.Fatal("effect ID not set")
+	}
+	effects, err := chars.GetEffects(ctx, charID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(effects) != 1 || effects[0].Name != "Бодрость" {
+		t.Fatalf("effects = %+v", effects)
+	}
+	if got := itemQuantity(t, db, charID, itemID); got != 0 {
+		t.Fatalf("%d potions left, want 0", got)
+	}
+
+	// последнего зелья больше нет — эффект не записан повторно
+	use.Effect = &models.Effect{CharacterID: charID, Name: "Сила", Duration: 3}
+	if _, _, err := items.Use(ctx, use); !errors.Is(err, ErrNotEnoughItems) {
+		t.Fatalf("err = %v, want ErrNotEnoughItems", err)
+	}
+	if effects, _ := chars.GetEffects(ctx, charID); len(effects) != 1 {
+		t.Fatalf("effect written without the item: %+v", effects)
+	}
+}
@@ -0,0 +1,169 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"aurora/internal/models"
+)
+
+var ErrNotEnoughGold = errors.New("not enough gold")
+
+// dbtx — общее подмножество *sql.DB и *sql.Tx, чтобы операции с золотом
+// и инвентарём можно было собирать в одну транзакцию.
+type dbtx interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// adjustGold меняет баланс и пишет запись в журнал. Списание в минус
+// запрещено и возвращает ErrNotEnoughGold.
+func adjustGold(ctx context.Context, q dbtx, e models.LedgerEntry) (int, error) {
+	res, err := q.ExecContext(ctx, `
+UPDATE characters SET gold = gold + ?
+WHERE id = ? AND gold + ? >= 0`,
+		e.Amount, e.CharacterID, e.Amount,
+	)
+	if err != nil {
+		return 0, err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return 0, ErrNotEnoughGold
+	}
+
+	var balance int
+	if err := q.QueryRowContext(ctx, `SELECT gold FROM characters WHERE id = ?`, e.CharacterID).Scan(&balance); err != nil {
+		return 0, err
+	}
+
+	if _, err := q.ExecContext(ctx, `
+INSERT INTO gold_ledger (character_id, amount, balance_after, reason, source, ref_id)
+VALUES (?, ?, ?, ?, ?, ?)`,
+		e.CharacterID, e.Amount, balance, e.Reason, e.Source, e.RefID,
+	); err != nil {
+		return 0, err
+	}
+	return balance, nil
+}
+
+type LedgerRepository struct {
+	db *sql.DB
+}
+
+func NewLedgerRepository(db *sql.DB) *LedgerRepository {
+	return &LedgerRepository{db: db}
+}
+
+// BalanceMismatch — персонаж, у которого characters.gold разошёлся с журналом.
+type BalanceMismatch struct {
+	CharacterID int64
+	Name        string
+	Gold        int
+	LedgerSum   int
+}
+
+// Record атомарно меняет баланс и пишет запись. Возвращает новый баланс.
+func (r *LedgerRepository) Record(ctx context.Context, e models.LedgerEntry) (int, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	balance, err := adjustGold(ctx, tx, e)
+	if err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("commit transaction: %w", err)
+	}
+	return balance, nil
+}
+
+func (r *LedgerRepository) ListForCharacter(ctx context.Context, charID int64, limit int) ([]models.LedgerEntry, error) {
+	rows, err := r.db.QueryContext(ctx, `
+SELECT id, character_id, amount, balance_after, reason, source, ref_id, created_at
+FROM gold_ledger
+WHERE character_id = ?
+ORDER BY id DESC
+LIMIT ?`, charID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanLedger(rows)
+}
+
+// Anomalies возвращает доходы, превышающие лимиты для своего источника.
+func (r *LedgerRepository) Anomalies(ctx context.Context, questCap, combatCap, limit int) ([]models.LedgerEntry, error) {
+	rows, err := r.db.QueryContext(ctx, `
+SELECT id, character_id, amount, balance_after, reason, source, ref_id, created_at
+FROM gold_ledger
+WHERE (source = ? AND amount > ?) OR (source = ? AND amount > ?)
+ORDER BY id DESC
+LIMIT ?`,
+		models.LedgerSourceQuest, questCap, models.LedgerSourceCombat, combatCap, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanLedger(rows)
+}
+
+func (r *LedgerRepository) Sum(ctx context.Context, charID int64) (int, error) {
+	var sum int
+	err := r.db.QueryRowContext(ctx, `
+SELECT IFNULL(SUM(amount), 0) FROM gold_ledger WHERE character_id = ?`, charID).Scan(&sum)
+	return sum, err
+}
+
+func (r *LedgerRepository) Mismatches(ctx context.Context) ([]BalanceMismatch, error) {
+	rows, err := r.db.QueryContext(ctx, `
+SELECT c.id, c.name, c.gold, IFNULL(SUM(l.amount), 0) AS total
+FROM characters c
+LEFT JOIN gold_ledger l ON l.character_id = c.id
+GROUP BY c.id
+HAVING c.gold != total`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []BalanceMismatch
+	for rows.Next() {
+		var m BalanceMismatch
+		if err := rows.Scan(&m.CharacterID, &m.Name, &m.Gold, &m.LedgerSum); err != nil {
+			return nil, err
+		}
+		out = append(out, m)
+	}
+	return out, rows.Err()
+}
+
+// SetBalanceFromLedger выставляет characters.gold равным сумме журнала.
+func (r *LedgerRepository) SetBalanceFromLedger(ctx context.Context, charID int64) (int, error) {
+	if _, err := r.db.ExecContext(ctx, `
+UPDATE characters
+SET gold = (SELECT IFNULL(SUM(amount), 0) FROM gold_ledger WHERE character_id = ?)
+WHERE id = ?`, charID, charID); err != nil {
+		return 0, err
+	}
+	var gold int
+	err := r.db.QueryRowContext(ctx, `SELECT gold FROM characters WHERE id = ?`, charID).Scan(&gold)
+	return gold, err
+}
+
+func scanLedger(rows *sql.Rows) ([]models.LedgerEntry, error) {
+	var out []models.LedgerEntry
+	for rows.Next() {
+		var e models.LedgerEntry
+		if err := rows.Scan(&e.ID, &e.CharacterID, &e.Amount, &e.BalanceAfter, &e.Reason, &e.Source, &e.RefID, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, e)
+	}
+	return out, rows.Err()
+}
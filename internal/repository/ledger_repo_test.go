@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"aurora/internal/models"
+)
+
+func TestLedgerRecordAndReconcile(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+	ledger := NewLedgerRepository(db)
+	charID := createTestCharacter(t, db, 1, 100, 50)
+
+	// стартовое золото уже в журнале
+	if sum, err := ledger.Sum(ctx, charID); err != nil || sum != 50 {
+		t.Fatalf("opening sum = %d, %v; want 50", sum, err)
+	}
+
+	for _, c := range []struct {
+		amount  int
+		balance int
+		err     error
+	}{
+		{amount: 20, balance: 70},
+		{amount: -70, balance: 0},
+		{amount: -1, err: ErrNotEnoughGold},
+		{amount: 15, balance: 15},
+	} {
+		balance, err := ledger.Record(ctx, models.LedgerEntry{
+			CharacterID: charID, Amount: c.amount, Reason: "тест", Source: models.LedgerSourceGM,
+		})
+		if !errors.Is(err, c.err) {
+			t.Fatalf("%+d: err = %v, want %v", c.amount, err, c.err)
+		}
+		if err == nil && balance != c.balance {
+			t.Fatalf("%+d: balance %d, want %d", c.amount, balance, c.balance)
+		}
+	}
+
+	entries, err := ledger.ListForCharacter(ctx, charID, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// отказ в списании не оставляет записи
+	if len(entries) != 4 {
+		t.Fatalf("%d ledger entries, want 4", len(entries))
+	}
+	if entries[0].BalanceAfter != 15 || entries[len(entries)-1].Amount != 50 {
+		t.Fatalf("entries = %+v", entries)
+	}
+	if list, err := ledger.Mismatches(ctx); err != nil || len(list) != 0 {
+		t.Fatalf("mismatches = %+v, %v", list, err)
+	}
+
+	// золото, записанное мимо журнала, находится сверкой и откатывается
+	if _, err := db.Exec(`UPDATE characters SET gold = 999 WHERE id = ?`, charID); err != nil {
+		t.Fatal(err)
+	}
+	list, err := ledger.Mismatches(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 1 || list[0].CharacterID != charID || list[0].Gold != 999 || list[0].LedgerSum != 15 {
+		t.Fatalf("mismatches = %+v", list)
+	}
+	if gold, err := ledger.SetBalanceFromLedger(ctx, charID); err != nil || gold != 15 {
+		t.Fatalf("SetBalanceFromLedger = %d, %v; want 15", gold, err)
+	}
+	if list, _ := ledger.Mismatches(ctx); len(list) != 0 {
+		t.Fatalf("still mismatched: %+v", list)
+	}
+}
+
+func TestCreateWithoutGoldHasNoOpeningEntry(t *testing.T) {
+	db := openTestDB(t)
+	charID := createTestCharacter(t, db, 1, 100, 0)
+	entries, err := NewLedgerRepository(db).ListForCharacter(context.Background(), charID, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Fatalf("entries = %+v", entries)
+	}
+}
@@ -3,6 +3,8 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"fmt"
 
 	"aurora/internal/models"
 )
@@ -24,18 +26,22 @@ VALUES (?, ?, ?, ?)`,
 	if err != nil {
 		return 0, err
 	}
+	// при IGNORE sqlite возвращает прошлый LastInsertId соединения
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return 0, err
+	}
 	return res.LastInsertId()
 }
 
 func (r *LocationRepository) GetByName(ctx context.Context, name string) (*models.Location, error) {
 	row := r.db.QueryRowContext(ctx, `
-SELECT id, name, IFNULL(description,''), IFNULL(tags,''), created_by, created_at
+SELECT id, name, IFNULL(description,''), IFNULL(tags,''), is_active, created_by, created_at
 FROM locations
 WHERE name = ?
 LIMIT 1`, name)
 
-	var l models.Location
-	if err := row.Scan(&l.ID, &l.Name, &l.Description, &l.Tags, &l.CreatedBy, &l.CreatedAt); err != nil {
+	l, err := scanLocation(row)
+	if err != nil {
 		return nil, err
 	}
 	return &l, nil
@@ -43,13 +49,13 @@ LIMIT 1`, name)
 
 func (r *LocationRepository) GetByID(ctx context.Context, id int64) (*models.Location, error) {
 	row := r.db.QueryRowContext(ctx, `
-SELECT id, name, IFNULL(description,''), IFNULL(tags,''), created_by, created_at
+SELECT id, name, IFNULL(description,''), IFNULL(tags,''), is_active, created_by, created_at
 FROM locations
 WHERE id = ?
 LIMIT 1`, id)
 
-	var l models.Location
-	if err := row.Scan(&l.ID, &l.Name, &l.Description, &l.Tags, &l.CreatedBy, &l.CreatedAt); err != nil {
+	l, err := scanLocation(row)
+	if err != nil {
 		return nil, err
 	}
 	return &l, nil
@@ -57,7 +63,7 @@ LIMIT 1`, id)
 
 func (r *LocationRepository) List(ctx context.Context, limit int) ([]models.Location, error) {
 	rows, err := r.db.QueryContext(ctx, `
-SELECT id, name, IFNULL(description,''), IFNULL(tags,''), created_by, created_at
+SELECT id, name, IFNULL(description,''), IFNULL(tags,''), is_active, created_by, created_at
 FROM locations
 ORDER BY id DESC
 LIMIT ?`, limit)
@@ -68,10 +74,108 @@ LIMIT ?`, limit)
 
 	var out []models.Location
 	for rows.Next() {
-		var l models.Location
-		if err := rows.Scan(&l.ID, &l.Name, &l.Description, &l.Tags, &l.CreatedBy, &l.CreatedAt); err != nil {
+		l, err := scanLocation(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, l)
+	}
+	return out, nil
+}
+
+func (r *LocationRepository) All(ctx context.Context) ([]models.Location, error) {
+	rows, err := r.db.QueryContext(ctx, `
+SELECT id, name, IFNULL(description,''), IFNULL(tags,''), is_active, created_by, created_at
+FROM locations
+ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []models.Location
+	for rows.Next() {
+		l, err := scanLocation(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, l)
+	}
+	return out, nil
+}
+
+func (r *LocationRepository) Update(ctx context.Context, id int64, desc, tags string) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE locations SET description = ?, tags = ? WHERE id = ?`, desc, tags, id)
+	return err
+}
+
+func (r *LocationRepository) SetActive(ctx context.Context, id int64, active bool) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE locations SET is_active = ? WHERE id = ?`, active, id)
+	return err
+}
+
+func scanLocation(row interface{ Scan(...any) error }) (models.Location, error) {
+	var l models.Location
+	err := row.Scan(&l.ID, &l.Name, &l.Description, &l.Tags, &l.IsActive, &l.CreatedBy, &l.CreatedAt)
+	return l, err
+}
+
+// Link соединяет две локации дорогой в обе стороны.
+func (r *LocationRepository) Link(ctx context.Context, aID, bID int64, hours, danger int, encounters []string) error {
+	enc, err := json.Marshal(encounters)
+	if err != nil {
+		return err
+	}
+	if encounters == nil {
+		enc = []byte("[]")
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, pair := range [][2]int64{{aID, bID}, {bID, aID}} {
+		if _, err := tx.ExecContext(ctx, `
+INSERT INTO location_links (from_id, to_id, travel_hours, danger, encounters)
+VALUES (?, ?, ?, ?, ?)
+ON CONFLICT(from_id, to_id) DO UPDATE
+SET travel_hours = excluded.travel_hours, danger = excluded.danger, encounters = excluded.encounters`,
+			pair[0], pair[1], hours, danger, string(enc),
+		); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit transaction: %w", err)
+	}
+	return nil
+}
+
+// Links возвращает рёбра графа локаций. В закрытые локации дороги не ведут,
+// но выбраться из них можно.
+func (r *LocationRepository) Links(ctx context.Context) ([]models.LocationLink, error) {
+	rows, err := r.db.QueryContext(ctx, `
+SELECT l.from_id, l.to_id, t.name, l.travel_hours, l.danger, l.encounters
+FROM location_links l
+JOIN locations t ON t.id = l.to_id
+WHERE t.is_active = 1
+ORDER BY l.from_id, l.travel_hours`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []models.LocationLink
+	for rows.Next() {
+		var l models.LocationLink
+		var enc string
+		if err := rows.Scan(&l.FromID, &l.ToID, &l.ToName, &l.TravelHours, &l.Danger, &enc); err != nil {
 			return nil, err
 		}
+		_ = json.Unmarshal([]byte(enc), &l.Encounters)
 		out = append(out, l)
 	}
 	return out, nil
@@ -0,0 +1,472 @@
+package repository
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/lib/pq"
+)
+
+const (
+	// pgBatchRows — строк в одном INSERT при пакетной записи.
+	pgBatchRows = 100
+	// pgFilteredEfSearch — ширина HNSW-поиска, когда есть фильтры: pgvector
+	// отбрасывает неподходящие строки уже после обхода графа.
+	pgFilteredEfSearch = 200
+)
+
+// Модель, которой посчитаны векторы, лежавшие в таблице до появления колонки
+// model (та же, что в миграции 027 для SQLite).
+const legacyVectorModel = "text-embedding-004"
+
+type pgVectorRepo struct {
+	db    *sql.DB
+	model string
+	dim   int
+	// modelSQL и embeddingSQL — модель литералом и вектор с размерностью:
+	// HNSW-индекс у каждой модели свой, частичный, и планировщик берёт его,
+	// только если запрос повторяет его выражение и условие дословно.
+	modelSQL     string
+	embeddingSQL string
+}
+
+// NewPgVectorRepository — хранилище векторов модели model в PostgreSQL с
+// расширением pgvector. Индекс HNSW строится на размерность модели, поэтому
+// схема создаётся здесь, а не в migrations.
+func NewPgVectorRepository(ctx context.Context, db *sql.DB, model string, dim int) (VectorRepository, error) {
+	r := &pgVectorRepo{
+		db:           db,
+		model:        model,
+		dim:          dim,
+		modelSQL:     pq.QuoteLiteral(model),
+		embeddingSQL: fmt.Sprintf("embedding::vector(%d)", dim),
+	}
+	if err := r.ensureSchema(ctx); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *pgVectorRepo) Model() string { return r.model }
+func (r *pgVectorRepo) Dims() int     { return r.dim }
+
+func (r *pgVectorRepo) ensureSchema(ctx context.Context) error {
+	// векторы разных моделей лежат в одной таблице, поэтому колонка без размерности
+	for _, stmt := range []string{
+		`CREATE EXTENSION IF NOT EXISTS vector`,
+		`CREATE TABLE IF NOT EXISTS lore_vectors (
+    id         TEXT NOT NULL,
+    model      TEXT NOT NULL,
+    dims       INTEGER NOT NULL,
+    title      TEXT NOT NULL,
+    content    TEXT NOT NULL,
+    zone       TEXT NOT NULL,
+    tags       TEXT[] NOT NULL DEFAULT '{}',
+    embedding  vector NOT NULL,
+    metadata   JSONB NOT NULL DEFAULT '{}',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (id, model)
+)`,
+	} {
+		if _, err := r.db.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("pgvector schema: %w", err)
+		}
+	}
+	if err := r.upgradeSchema(ctx); err != nil {
+		return fmt.Errorf("pgvector schema upgrade: %w", err)
+	}
+
+	sum := sha256.Sum256([]byte(r.model))
+	stmts := []string{
+		fmt.Sprintf(`CREATE INDEX IF NOT EXISTS idx_lore_vectors_hnsw_%s_%d ON lore_vectors
+    USING hnsw ((%s) vector_cosine_ops) WHERE model = %s`, hex.EncodeToString(sum[:4]), r.dim, r.embeddingSQL, r.modelSQL),
+		`CREATE INDEX IF NOT EXISTS idx_lore_vectors_model_zone ON lore_vectors(model, zone)`,
+		`CREATE INDEX IF NOT EXISTS idx_lore_vectors_tags ON lore_vectors USING gin (tags)`,
+		// полнотекстовый поиск со стеммингом из русской конфигурации PostgreSQL
+		`ALTER TABLE lore_vectors ADD COLUMN IF NOT EXISTS fts tsvector
+    GENERATED ALWAYS AS (to_tsvector('russian', title || ' ' || content)) STORED`,
+		`CREATE INDEX IF NOT EXISTS idx_lore_vectors_fts ON lore_vectors USING gin (fts)`,
+		`CREATE INDEX IF NOT EXISTS idx_lore_vectors_parent ON lore_vectors ((metadata->>'parent'))`,
+	}
+	for _, stmt := range stmts {
+		if _, err := r.db.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("pgvector schema: %w", err)
+		}
+	}
+	return nil
+}
+
+// upgradeSchema переводит таблицу старого вида (ключ id, колонка vector(N)
+// с общим HNSW-индексом) на ключ (id, model); все старые строки относятся к
+// legacyVectorModel.
+func (r *pgVectorRepo) upgradeSchema(ctx context.Context) error {
+	var upgraded bool
+	err := r.db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM information_schema.columns
+WHERE table_schema = current_schema() AND table_name = 'lore_vectors' AND column_name = 'model')`).Scan(&upgraded)
+	if err != nil || upgraded {
+		return err
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for _, stmt := range []string{
+		`ALTER TABLE lore_vectors ADD COLUMN model TEXT NOT NULL DEFAULT ` + pq.QuoteLiteral(legacyVectorModel),
+		`ALTER TABLE lore_vectors ALTER COLUMN model DROP DEFAULT`,
+		`ALTER TABLE lore_vectors ADD COLUMN dims INTEGER`,
+		`UPDATE lore_vectors SET dims = vector_dims(embedding)`,
+		`ALTER TABLE lore_vectors ALTER COLUMN dims SET NOT NULL`,
+		`DROP INDEX IF EXISTS idx_lore_vectors_embedding`,
+		`ALTER TABLE lore_vectors ALTER COLUMN embedding TYPE vector`,
+		`ALTER TABLE lore_vectors DROP CONSTRAINT lore_vectors_pkey, ADD PRIMARY KEY (id, model)`,
+	} {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (r *pgVectorRepo) IndexDocument(ctx context.Context, doc VectorDocument) error {
+	return r.IndexBatch(ctx, []VectorDocument{doc})
+}
+
+// IndexBatch пишет документы многострочными upsert-ами в одной транзакции.
+func (r *pgVectorRepo) IndexBatch(ctx context.Context, docs []VectorDocument) error {
+	if len(docs) == 0 {
+		return nil
+	}
+	for _, doc := range docs {
+		if err := checkDocument(r.model, r.dim, doc); err != nil {
+			return err
+		}
+	}
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for start := 0; start < len(docs); start += pgBatchRows {
+		chunk := docs[start:min(start+pgBatchRows, len(docs))]
+		var sb strings.Builder
+		sb.WriteString(`INSERT INTO lore_vectors (id, model, dims, title, content, zone, tags, embedding, metadata) VALUES `)
+		args := make([]any, 0, len(chunk)*9)
+		for i, doc := range chunk {
+			metadataJSON, err := json.Marshal(doc.Metadata)
+			if err != nil {
+				return fmt.Errorf("marshal metadata for %s: %w", doc.ID, err)
+			}
+			if doc.Metadata == nil {
+				metadataJSON = []byte("{}")
+			}
+			tags := doc.Tags
+			if tags == nil {
+				tags = []string{}
+			}
+			if i > 0 {
+				sb.WriteString(", ")
+			}
+			n := len(args)
+			fmt.Fprintf(&sb, "($%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d::vector, $%d::jsonb)", n+1, n+2, n+3, n+4, n+5, n+6, n+7, n+8, n+9)
+			args = append(args, doc.ID, r.model, len(doc.Vector), doc.Title, doc.Content, doc.Zone, pq.Array(tags), pgVector(doc.Vector), string(metadataJSON))
+		}
+		sb.WriteString(` ON CONFLICT (id, model) DO UPDATE SET
+    title = EXCLUDED.title, content = EXCLUDED.content, zone = EXCLUDED.zone,
+    tags = EXCLUDED.tags, embedding = EXCLUDED.embedding, metadata = EXCLUDED.metadata`)
+		if _, err := tx.ExecContext(ctx, sb.String(), args...); err != nil {
+			return fmt.Errorf("upsert documents: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit transaction: %w", err)
+	}
+	return nil
+}
+
+// SearchSimilar сортирует по косинусному расстоянию (<=>), чтобы работал
+// HNSW-индекс модели; зоны, теги и фракции фильтруются в том же запросе.
+func (r *pgVectorRepo) SearchSimilar(ctx context.Context, queryVector []float32, limit int, filters map[string]string) ([]VectorDocument, error) {
+	if err := checkVector(r.model, r.dim, queryVector); err != nil {
+		return nil, err
+	}
+	if limit <= 0 {
+		return []VectorDocument{}, nil
+	}
+	distance := fmt.Sprintf("%s <=> $1::vector(%d)", r.embeddingSQL, r.dim)
+	query := `SELECT id, title, content, zone, tags, embedding::text, metadata, 1 - (` + distance + `) FROM lore_vectors`
+	where, args := pgFilters(filters, []any{pgVector(queryVector)})
+	filtered := len(where) > 0
+	where = append([]string{"model = " + r.modelSQL}, where...)
+	query += ` WHERE ` + strings.Join(where, " AND ")
+	args = append(args, limit)
+	query += ` ORDER BY ` + distance + ` LIMIT $` + strconv.Itoa(len(args))
+
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+	if filtered {
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf(`SET LOCAL hnsw.ef_search = %d`, pgFilteredEfSearch)); err != nil {
+			return nil, fmt.Errorf("set ef_search: %w", err)
+		}
+	}
+
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query documents: %w", err)
+	}
+	defer rows.Close()
+
+	return scanPgDocuments(rows, limit, r.model)
+}
+
+// pgFilters дописывает в args значения фильтров zone, tags и faction и
+// возвращает условия WHERE с их номерами; смысл тот же, что у sqliteFilters.
+func pgFilters(filters map[string]string, args []any) ([]string, []any) {
+	var where []string
+	if zones := filterValues(filters, "zone"); len(zones) > 0 {
+		args = append(args, pq.Array(zones))
+		where = append(where, "zone = ANY($"+strconv.Itoa(len(args))+")")
+	}
+	for _, key := range []string{"tags", "faction"} {
+		if tags := filterValues(filters, key); len(tags) > 0 {
+			args = append(args, pq.Array(tags))
+			where = append(where, "tags && $"+strconv.Itoa(len(args)))
+		}
+	}
+	return where, args
+}
+
+// scanPgDocuments читает строки модели model вида id, title, content, zone,
+// tags, embedding::text, metadata, score.
+func scanPgDocuments(rows *sql.Rows, limit int, model string) ([]VectorDocument, error) {
+	docs := make([]VectorDocument, 0, limit)
+	for rows.Next() {
+		doc := VectorDocument{Model: model}
+		var tags pq.StringArray
+		var embedding string
+		var metadataJSON []byte
+		if err := rows.Scan(&doc.ID, &doc.Title, &doc.Content, &doc.Zone, &tags, &embedding, &metadataJSON, &doc.Score); err != nil {
+			return nil, err
+		}
+		doc.Tags = []string(tags)
+		if err := json.Unmarshal(metadataJSON, &doc.Metadata); err != nil {
+			doc.Metadata = make(map[string]string)
+		}
+		// текстовый вид pgvector — «[1,2,3]», тот же JSON
+		vec, err := decodeVector([]byte(embedding), nil)
+		if err != nil {
+			return nil, err
+		}
+		doc.Vector = vec
+		docs = append(docs, doc)
+	}
+	return docs, rows.Err()
+}
+
+// SearchKeyword ищет документы хотя бы с одним словом запроса и ранжирует
+// их ts_rank_cd.
+func (r *pgVectorRepo) SearchKeyword(ctx context.Context, query string, limit int, filters map[string]string) ([]VectorDocument, error) {
+	words := strings.FieldsFunc(strings.ToLower(query), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	if limit <= 0 || len(words) == 0 {
+		return []VectorDocument{}, nil
+	}
+	where, args := pgFilters(filters, []any{strings.Join(words, " | "), r.model})
+	where = append([]string{"fts @@ q", "model = $2"}, where...)
+	args = append(args, limit)
+	rows, err := r.db.QueryContext(ctx, `SELECT id, title, content, zone, tags, embedding::text, metadata, ts_rank_cd(fts, q)
+FROM lore_vectors, to_tsquery('russian', $1) q
+WHERE `+strings.Join(where, " AND ")+`
+ORDER BY ts_rank_cd(fts, q) DESC LIMIT $`+strconv.Itoa(len(args)), args...)
+	if err != nil {
+		return nil, fmt.Errorf("keyword search: %w", err)
+	}
+	defer rows.Close()
+	return scanPgDocuments(rows, limit, r.model)
+}
+
+func (r *pgVectorRepo) GetDocuments(ctx context.Context, ids []string) ([]VectorDocument, error) {
+	if len(ids) == 0 {
+		return []VectorDocument{}, nil
+	}
+	rows, err := r.db.QueryContext(ctx, `SELECT id, title, content, zone, tags, embedding::text, metadata, 0::real
+FROM lore_vectors WHERE model = $1 AND id = ANY($2)`, r.model, pq.Array(ids))
+	if err != nil {
+		return nil, fmt.Errorf("query documents: %w", err)
+	}
+	defer rows.Close()
+	found, err := scanPgDocuments(rows, len(ids), r.model)
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[string]VectorDocument, len(found))
+	for _, doc := range found {
+		byID[doc.ID] = doc
+	}
+	docs := make([]VectorDocument, 0, len(found))
+	for _, id := range ids {
+		if doc, ok := byID[id]; ok {
+			docs = append(docs, doc)
+		}
+	}
+	return docs, nil
+}
+
+func (r *pgVectorRepo) GetByParent(ctx context.Context, parent string) ([]VectorDocument, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT id, title, content, zone, tags, embedding::text, metadata, 0::real
+FROM lore_vectors WHERE metadata->>'parent' = $1 AND model = $2
+ORDER BY (metadata->>'chunk_index')::int, id`, parent, r.model)
+	if err != nil {
+		return nil, fmt.Errorf("query children: %w", err)
+	}
+	defer rows.Close()
+	return scanPgDocuments(rows, 0, r.model)
+}
+
+func (r *pgVectorRepo) DeleteByZone(ctx context.Context, zone string) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM lore_vectors WHERE model = $1 AND zone = $2`, r.model, zone)
+	return err
+}
+
+func (r *pgVectorRepo) ListBySource(ctx context.Context, source string) ([]DocumentInfo, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT id, title, metadata FROM lore_vectors WHERE model = $1 AND metadata->>'source' = $2 ORDER BY id`, r.model, source)
+	if err != nil {
+		return nil, fmt.Errorf("query documents: %w", err)
+	}
+	return scanPgDocumentInfos(rows)
+}
+
+func (r *pgVectorRepo) ListByDocument(ctx context.Context, document string) ([]DocumentInfo, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT id, title, metadata FROM lore_vectors
+WHERE model = $1 AND (id = $2 OR metadata->>'document' = $2) ORDER BY id`, r.model, document)
+	if err != nil {
+		return nil, fmt.Errorf("query documents: %w", err)
+	}
+	return scanPgDocumentInfos(rows)
+}
+
+func scanPgDocumentInfos(rows *sql.Rows) ([]DocumentInfo, error) {
+	defer rows.Close()
+
+	infos := []DocumentInfo{}
+	for rows.Next() {
+		var info DocumentInfo
+		var metadataJSON []byte
+		if err := rows.Scan(&info.ID, &info.Title, &metadataJSON); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(metadataJSON, &info.Metadata); err != nil {
+			info.Metadata = make(map[string]string)
+		}
+		infos = append(infos, info)
+	}
+	return infos, rows.Err()
+}
+
+func (r *pgVectorRepo) DeleteDocuments(ctx context.Context, ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	_, err := r.db.ExecContext(ctx, `DELETE FROM lore_vectors WHERE model = $1 AND id = ANY($2)`, r.model, pq.Array(ids))
+	return err
+}
+
+// DeleteAll удаляет документы модели хранилища; векторы других моделей остаются.
+func (r *pgVectorRepo) DeleteAll(ctx context.Context) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM lore_vectors WHERE model = $1`, r.model)
+	return err
+}
+
+func (r *pgVectorRepo) Scan(ctx context.Context, batch int, fn func([]VectorDocument) error) error {
+	last := ""
+	for {
+		rows, err := r.db.QueryContext(ctx, `SELECT id, title, content, zone, tags, embedding::text, metadata, 0::real
+FROM lore_vectors WHERE model = $1 AND id > $2 ORDER BY id LIMIT $3`, r.model, last, batch)
+		if err != nil {
+			return fmt.Errorf("query documents: %w", err)
+		}
+		docs, err := scanPgDocuments(rows, batch, r.model)
+		rows.Close()
+		if err != nil {
+			return err
+		}
+		if len(docs) == 0 {
+			return nil
+		}
+		last = docs[len(docs)-1].ID
+		if err := fn(docs); err != nil {
+			return err
+		}
+	}
+}
+
+// Flush ничего не делает: всё уже в базе.
+func (r *pgVectorRepo) Flush(ctx context.Context) error {
+	return nil
+}
+
+func (r *pgVectorRepo) GetStats(ctx context.Context) (VectorStats, error) {
+	stats := VectorStats{DocumentsByZone: make(map[string]int), Models: make(map[string]int)}
+	models, err := r.db.QueryContext(ctx, `SELECT model, COUNT(*) FROM lore_vectors GROUP BY model`)
+	if err != nil {
+		return stats, fmt.Errorf("count by model: %w", err)
+	}
+	defer models.Close()
+	for models.Next() {
+		var model string
+		var count int
+		if err := models.Scan(&model, &count); err != nil {
+			return stats, err
+		}
+		stats.Models[model] = count
+	}
+	if err := models.Err(); err != nil {
+		return stats, err
+	}
+
+	rows, err := r.db.QueryContext(ctx, `SELECT zone, COUNT(*) FROM lore_vectors WHERE model = $1 GROUP BY zone`, r.model)
+	if err != nil {
+		return stats, fmt.Errorf("count by zone: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var zone string
+		var count int
+		if err := rows.Scan(&zone, &count); err != nil {
+			return stats, err
+		}
+		stats.DocumentsByZone[zone] = count
+		stats.TotalDocuments += count
+	}
+	return stats, rows.Err()
+}
+
+// pgVector — текстовый литерал pgvector.
+func pgVector(vec []float32) string {
+	var sb strings.Builder
+	sb.Grow(len(vec) * 10)
+	sb.WriteByte('[')
+	for i, x := range vec {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+		sb.WriteString(strconv.FormatFloat(float64(x), 'g', -1, 32))
+	}
+	sb.WriteByte(']')
+	return sb.String()
+}
@@ -0,0 +1,15 @@
+package repository
+
+import (
+	"database/sql"
+
+	_ "github.com/lib/pq"
+)
+
+func NewPostgres(dsn string) (*sql.DB, error) {
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, err
+	}
+	return db, db.Ping()
+}
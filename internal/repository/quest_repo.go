@@ -3,6 +3,8 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"fmt"
+	"time"
 
 	"aurora/internal/models"
 )
@@ -16,8 +18,10 @@ func NewQuestRepository(db *sql.DB) *QuestRepository {
 }
 
 func (r *QuestRepository) GetActiveForCharacter(ctx context.Context, charID int64) ([]models.Quest, error) {
-	rows, err := r.db.QueryContext(ctx, `SELECT id,character_id,title,description,stage,status,from_source,difficulty,reward_value,created_at,updated_at 
-FROM quests WHERE character_id=? AND status='active'`, charID)
+	rows, err := r.db.QueryContext(ctx, `SELECT q.id,q.character_id,q.title,q.description,q.stage,q.status,q.from_source,q.difficulty,q.reward_value,
+       IFNULL(q.faction_id,0),IFNULL(f.name,''),q.deadline_at,q.kind,q.created_at,q.updated_at
+FROM quests q LEFT JOIN factions f ON f.id = q.faction_id
+WHERE q.character_id=? AND q.status='active'`, charID)
 	if err != nil {
 		return nil, err
 	}
@@ -29,7 +33,7 @@ FROM quests WHERE character_id=? AND status='active'`, charID)
 		if err := rows.Scan(
 			&q.ID, &q.CharacterID, &q.Title, &q.Description, &q.Stage,
 			&q.Status, &q.From, &q.Difficulty, &q.RewardValue,
-			&q.CreatedAt, &q.UpdatedAt,
+			&q.FactionID, &q.FactionName, &q.DeadlineAt, &q.Kind, &q.CreatedAt, &q.UpdatedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -39,13 +43,15 @@ FROM quests WHERE character_id=? AND status='active'`, charID)
 }
 
 func (r *QuestRepository) GetByID(ctx context.Context, id int64) (models.Quest, error) {
-	row := r.db.QueryRowContext(ctx, `SELECT id,character_id,title,description,stage,status,from_source,difficulty,reward_value,created_at,updated_at 
-FROM quests WHERE id=?`, id)
+	row := r.db.QueryRowContext(ctx, `SELECT q.id,q.character_id,q.title,q.description,q.stage,q.status,q.from_source,q.difficulty,q.reward_value,
+       IFNULL(q.faction_id,0),IFNULL(f.name,''),q.deadline_at,q.kind,q.created_at,q.updated_at
+FROM quests q LEFT JOIN factions f ON f.id = q.faction_id
+WHERE q.id=?`, id)
 	var q models.Quest
 	err := row.Scan(
 		&q.ID, &q.CharacterID, &q.Title, &q.Description, &q.Stage,
 		&q.Status, &q.From, &q.Difficulty, &q.RewardValue,
-		&q.CreatedAt, &q.UpdatedAt,
+		&q.FactionID, &q.FactionName, &q.DeadlineAt, &q.Kind, &q.CreatedAt, &q.UpdatedAt,
 	)
 	return q, err
 }
@@ -56,12 +62,79 @@ func (r *QuestRepository) Update(ctx context.Context, q models.Quest) error {
 	return err
 }
 
+// Progress сохраняет стадию и статус квеста, только пока он активен: завершить
+// или провалить квест можно один раз. Закрытый квест не трогает и возвращает
+// sql.ErrNoRows.
+func (r *QuestRepository) Progress(ctx context.Context, q models.Quest) error {
+	res, err := r.db.ExecContext(ctx, `UPDATE quests SET stage=?,status=?,updated_at=? WHERE id=? AND status='active'`,
+		q.Stage, q.Status, q.UpdatedAt, q.ID)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
 func (r *QuestRepository) Create(ctx context.Context, q *models.Quest) (int64, error) {
-	res, err := r.db.ExecContext(ctx, `INSERT INTO quests (character_id,title,description,stage,status,from_source,difficulty,reward_value,created_at,updated_at)
-VALUES (?,?,?,?,?,?,?,?,?,?)`,
-		q.CharacterID, q.Title, q.Description, q.Stage, q.Status, q.From, q.Difficulty, q.RewardValue, q.CreatedAt, q.UpdatedAt)
+	var factionID sql.NullInt64
+	if q.FactionID != 0 {
+		factionID = sql.NullInt64{Int64: q.FactionID, Valid: true}
+	}
+	res, err := r.db.ExecContext(ctx, `INSERT INTO quests (character_id,title,description,stage,status,from_source,difficulty,reward_value,faction_id,deadline_at,kind,created_at,updated_at)
+VALUES (?,?,?,?,?,?,?,?,?,?,?,?,?)`,
+		q.CharacterID, q.Title, q.Description, q.Stage, q.Status, q.From, q.Difficulty, q.RewardValue, factionID, q.DeadlineAt, q.Kind, q.CreatedAt, q.UpdatedAt)
 	if err != nil {
 		return 0, err
 	}
 	return res.LastInsertId()
 }
+
+// FailOverdue проваливает активные квесты, срок которых (в игровых минутах)
+// наступил к now, и возвращает их.
+func (r *QuestRepository) FailOverdue(ctx context.Context, now int64) ([]models.Quest, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `SELECT id,character_id,title,deadline_at FROM quests
+WHERE status='active' AND deadline_at > 0 AND deadline_at <= ?`, now)
+	if err != nil {
+		return nil, err
+	}
+	var res []models.Quest
+	for rows.Next() {
+		var q models.Quest
+		if err := rows.Scan(&q.ID, &q.CharacterID, &q.Title, &q.DeadlineAt); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		q.Status = "failed"
+		res = append(res, q)
+	}
+	rows.Close()
+
+	if _, err := tx.ExecContext(ctx, `UPDATE quests SET status='failed', updated_at=?
+WHERE status='active' AND deadline_at > 0 AND deadline_at <= ?`, time.Now(), now); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit transaction: %w", err)
+	}
+	return res, nil
+}
+
+// FailActiveForCharacter проваливает обычные активные квесты персонажа
+// (например, после его смерти). Квесты на воскрешение не трогаются.
+func (r *QuestRepository) FailActiveForCharacter(ctx context.Context, charID int64) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE quests SET status='failed', updated_at=?
+WHERE character_id=? AND status='active' AND kind=''`, time.Now(), charID)
+	return err
+}
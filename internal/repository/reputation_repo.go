@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"aurora/internal/models"
+)
+
+// Границы шкалы репутации.
+const (
+	MinReputation = -100
+	MaxReputation = 100
+)
+
+type ReputationRepository struct {
+	db *sql.DB
+}
+
+func NewReputationRepository(db *sql.DB) *ReputationRepository {
+	return &ReputationRepository{db: db}
+}
+
+// UpsertFaction синхронизирует фракцию из лора и возвращает её id.
+func (r *ReputationRepository) UpsertFaction(ctx context.Context, f models.Faction) (int64, error) {
+	if _, err := r.db.ExecContext(ctx, `
+INSERT INTO factions (key, name, description) VALUES (?, ?, ?)
+ON CONFLICT(key) DO UPDATE SET name = excluded.name, description = excluded.description`,
+		f.Key, f.Name, f.Description,
+	); err != nil {
+		return 0, err
+	}
+	var id int64
+	err := r.db.QueryRowContext(ctx, `SELECT id FROM factions WHERE key = ?`, f.Key).Scan(&id)
+	return id, err
+}
+
+func (r *ReputationRepository) ListFactions(ctx context.Context) ([]models.Faction, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT id, key, name, description FROM factions ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []models.Faction
+	for rows.Next() {
+		var f models.Faction
+		if err := rows.Scan(&f.ID, &f.Key, &f.Name, &f.Description); err != nil {
+			return nil, err
+		}
+		out = append(out, f)
+	}
+	return out, nil
+}
+
+func (r *ReputationRepository) Score(ctx context.Context, charID, factionID int64) (int, error) {
+	var score int
+	err := r.db.QueryRowContext(ctx, `
+SELECT score FROM character_reputation WHERE character_id = ? AND faction_id = ?`,
+		charID, factionID).Scan(&score)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	return score, err
+}
+
+// Adjust меняет репутацию на delta в пределах шкалы и возвращает новое значение.
+func (r *ReputationRepository) Adjust(ctx context.Context, charID, factionID int64, delta int) (int, error) {
+	if _, err := r.db.ExecContext(ctx, `
+INSERT INTO character_reputation (character_id, faction_id, score, updated_at)
+VALUES (?, ?, MAX(?, MIN(?, ?)), CURRENT_TIMESTAMP)
+ON CONFLICT(character_id, faction_id) DO UPDATE
+SET score = MAX(?, MIN(?, score + ?)), updated_at = CURRENT_TIMESTAMP`,
+		charID, factionID, MinReputation, MaxReputation, delta,
+		MinReputation, MaxReputation, delta,
+	); err != nil {
+		return 0, err
+	}
+	return r.Score(ctx, charID, factionID)
+}
+
+// ListForCharacter возвращает только фракции, с которыми у персонажа есть история.
+func (r *ReputationRepository) ListForCharacter(ctx context.Context, charID int64) ([]models.Reputation, error) {
+	rows, err := r.db.QueryContext(ctx, `
+SELECT cr.character_id, cr.faction_id, f.name, cr.score
+FROM character_reputation cr
+JOIN factions f ON f.id = cr.faction_id
+WHERE cr.character_id = ?
+ORDER BY cr.score DESC, f.name`, charID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []models.Reputation
+	for rows.Next() {
+		var rep models.Reputation
+		if err := rows.Scan(&rep.CharacterID, &rep.FactionID, &rep.FactionName, &rep.Score); err != nil {
+			return nil, err
+		}
+		out = append(out, rep)
+	}
+	return out, nil
+}
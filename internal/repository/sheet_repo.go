@@ -0,0 +1,141 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"aurora/internal/models"
+)
+
+type SheetRepository struct {
+	db *sql.DB
+}
+
+func NewSheetRepository(db *sql.DB) *SheetRepository {
+	return &SheetRepository{db: db}
+}
+
+const sheetColumns = `id, character_id, vk_user_id, peer_id, sheet_json, status, comment, created_at, reviewed_at`
+
+type sheetScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanSheet(row sheetScanner) (*models.CharacterSheet, error) {
+	var s models.CharacterSheet
+	var raw string
+	if err := row.Scan(&s.ID, &s.CharacterID, &s.VKUserID, &s.PeerID, &raw, &s.Status, &s.Comment, &s.CreatedAt, &s.ReviewedAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(raw), &s.Form); err != nil {
+		return nil, fmt.Errorf("parse sheet %d: %w", s.ID, err)
+	}
+	return &s, nil
+}
+
+// Submit ставит анкету в очередь. Прежняя непроверенная анкета того же
+// персонажа снимается: ГМ видит только последнюю.
+func (r *SheetRepository) Submit(ctx context.Context, s *models.CharacterSheet) (int64, error) {
+	raw, err := json.Marshal(s.Form)
+	if err != nil {
+		return 0, err
+	}
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `
+UPDATE character_sheets SET status = ?, reviewed_at = ? WHERE character_id = ? AND status = ?`,
+		models.SheetWithdrawn, time.Now(), s.CharacterID, models.SheetPending); err != nil {
+		return 0, err
+	}
+	res, err := tx.ExecContext(ctx, `
+INSERT INTO character_sheets (character_id, vk_user_id, peer_id, sheet_json, status, created_at)
+VALUES (?, ?, ?, ?, ?, ?)`,
+		s.CharacterID, s.VKUserID, s.PeerID, string(raw), models.SheetPending, time.Now())
+	if err != nil {
+		return 0, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	return id, tx.Commit()
+}
+
+func (r *SheetRepository) GetByID(ctx context.Context, id int64) (*models.CharacterSheet, error) {
+	return scanSheet(r.db.QueryRowContext(ctx, `SELECT `+sheetColumns+` FROM character_sheets WHERE id = ?`, id))
+}
+
+// Latest — последняя поданная анкета персонажа.
+func (r *SheetRepository) Latest(ctx context.Context, charID int64) (*models.CharacterSheet, error) {
+	return scanSheet(r.db.QueryRowContext(ctx, `SELECT `+sheetColumns+`
+FROM character_sheets WHERE character_id = ? AND status != ? ORDER BY id DESC LIMIT 1`, charID, models.SheetWithdrawn))
+}
+
+// ListPending — очередь на проверку, от старых к новым.
+func (r *SheetRepository) ListPending(ctx context.Context) ([]models.CharacterSheet, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT `+sheetColumns+`
+FROM character_sheets WHERE status = ? ORDER BY id`, models.SheetPending)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []models.CharacterSheet
+	for rows.Next() {
+		s, err := scanSheet(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *s)
+	}
+	return out, rows.Err()
+}
+
+// Review закрывает анкету решением ГМа. Уже проверенную анкету не трогает
+// и возвращает sql.ErrNoRows.
+func (r *SheetRepository) Review(ctx context.Context, id int64, status, comment string) error {
+	return review(ctx, r.db, id, status, comment)
+}
+
+func review(ctx context.Context, q dbtx, id int64, status, comment string) error {
+	res, err := q.ExecContext(ctx, `
+UPDATE character_sheets SET status = ?, comment = ?, reviewed_at = ? WHERE id = ? AND status = ?`,
+		status, comment, time.Now(), id, models.SheetPending)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// Approve одобряет анкету и записывает персонажа ch одной транзакцией: статус
+// меняется первым, так что уже проверенную анкету (sql.ErrNoRows) повторно в
+// персонажа не перенести.
+func (r *SheetRepository) Approve(ctx context.Context, id int64, ch *models.Character) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := review(ctx, tx, id, models.SheetApproved, ""); err != nil {
+		return err
+	}
+	if err := updateCharacter(ctx, tx, ch); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
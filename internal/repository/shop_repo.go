@@ -0,0 +1,151 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"aurora/internal/models"
+)
+
+var ErrOutOfStock = errors.New("out of stock")
+
+type ShopRepository struct {
+	db *sql.DB
+}
+
+func NewShopRepository(db *sql.DB) *ShopRepository {
+	return &ShopRepository{db: db}
+}
+
+type StockRow struct {
+	ItemKey     string
+	Quantity    int
+	RestockedAt time.Time
+	// RestockedMin — игровое время последнего завоза в минутах.
+	RestockedMin int64
+}
+
+// ShopDeal — одна сделка с торговцем. Total всегда положительный,
+// направление задаётся методом (Buy/Sell).
+type ShopDeal struct {
+	ShopID      string
+	ItemKey     string
+	CharacterID int64
+	ItemID      int64
+	Quantity    int
+	Total       int
+	Reason      string
+}
+
+func (r *ShopRepository) GetStock(ctx context.Context, shopID string) (map[string]StockRow, error) {
+	rows, err := r.db.QueryContext(ctx, `
+SELECT item_key, quantity, restocked_at, restocked_min FROM shop_stock WHERE shop_id = ?`, shopID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make(map[string]StockRow)
+	for rows.Next() {
+		var s StockRow
+		if err := rows.Scan(&s.ItemKey, &s.Quantity, &s.RestockedAt, &s.RestockedMin); err != nil {
+			return nil, err
+		}
+		out[s.ItemKey] = s
+	}
+	return out, rows.Err()
+}
+
+func (r *ShopRepository) SetStock(ctx context.Context, shopID, itemKey string, qty int, restockedAt time.Time, restockedMin int64) error {
+	_, err := r.db.ExecContext(ctx, `
+INSERT INTO shop_stock (shop_id, item_key, quantity, restocked_at, restocked_min)
+VALUES (?, ?, ?, ?, ?)
+ON CONFLICT(shop_id, item_key) DO UPDATE
+SET quantity = excluded.quantity, restocked_at = excluded.restocked_at, restocked_min = excluded.restocked_min`,
+		shopID, itemKey, qty, restockedAt, restockedMin,
+	)
+	return err
+}
+
+// Buy атомарно списывает золото (с записью в журнал), уменьшает остаток
+// лавки и кладёт предмет в инвентарь. Возвращает новый баланс.
+func (r *ShopRepository) Buy(ctx context.Context, d ShopDeal) (int, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `
+UPDATE shop_stock SET quantity = quantity - ?
+WHERE shop_id = ? AND item_key = ? AND quantity >= ?`,
+		d.Quantity, d.ShopID, d.ItemKey, d.Quantity,
+	)
+	if err != nil {
+		return 0, err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return 0, ErrOutOfStock
+	}
+
+	balance, err := adjustGold(ctx, tx, models.LedgerEntry{
+		CharacterID: d.CharacterID,
+		Amount:      -d.Total,
+		Reason:      d.Reason,
+		Source:      models.LedgerSourceShop,
+		RefID:       d.ShopID,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	if err := addItem(ctx, tx, d.CharacterID, d.ItemID, d.Quantity); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("commit transaction: %w", err)
+	}
+	return balance, nil
+}
+
+// Sell атомарно забирает предмет, начисляет золото и, если лавка торгует
+// этим товаром, возвращает его на полку.
+func (r *ShopRepository) Sell(ctx context.Context, d ShopDeal) (int, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := removeItem(ctx, tx, d.CharacterID, d.ItemID, d.Quantity); err != nil {
+		return 0, err
+	}
+
+	balance, err := adjustGold(ctx, tx, models.LedgerEntry{
+		CharacterID: d.CharacterID,
+		Amount:      d.Total,
+		Reason:      d.Reason,
+		Source:      models.LedgerSourceShop,
+		RefID:       d.ShopID,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+UPDATE shop_stock SET quantity = quantity + ?
+WHERE shop_id = ? AND item_key = ?`,
+		d.Quantity, d.ShopID, d.ItemKey,
+	); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("commit transaction: %w", err)
+	}
+	return balance, nil
+}
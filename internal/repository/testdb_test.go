@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"aurora/internal/models"
+)
+
+// openTestDB — временная SQLite со всеми миграциями.
+func openTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := NewSQLite(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	files, err := filepath.Glob("../../migrations/*.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(files)
+	for _, f := range files {
+		b, err := os.ReadFile(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := db.Exec(string(b)); err != nil {
+			t.Fatalf("%s: %v", filepath.Base(f), err)
+		}
+	}
+	return db
+}
+
+// createTestCharacter заводит персонажа с заданным здоровьем и золотом.
+func createTestCharacter(t *testing.T, db *sql.DB, vkID int64, hp, gold int) int64 {
+	t.Helper()
+	id, err := NewCharacterRepository(db).Create(context.Background(), &models.Character{
+		VKUserID:     vkID,
+		Name:         "Тестовый",
+		Status:       models.LifeAlive,
+		CombatPower:  10,
+		CombatHealth: hp,
+		Gold:         gold,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return id
+}
+
+// giveTestItem заводит предмет и кладёт qty штук персонажу.
+func giveTestItem(t *testing.T, db *sql.DB, charID int64, it models.Item, qty int) int64 {
+	t.Helper()
+	ctx := context.Background()
+	items := NewItemRepository(db)
+	id, err := items.Create(ctx, &it)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := items.AddToCharacter(ctx, charID, id, qty); err != nil {
+		t.Fatal(err)
+	}
+	return id
+}
+
+func itemQuantity(t *testing.T, db *sql.DB, charID, itemID int64) int {
+	t.Helper()
+	var qty int
+	err := db.QueryRow(`SELECT quantity FROM character_items WHERE character_id = ? AND item_id = ?`, charID, itemID).Scan(&qty)
+	if err == sql.ErrNoRows {
+		return 0
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return qty
+}
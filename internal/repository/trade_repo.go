@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"aurora/internal/models"
+)
+
+type TradeRepository struct {
+	db *sql.DB
+}
+
+func NewTradeRepository(db *sql.DB) *TradeRepository {
+	return &TradeRepository{db: db}
+}
+
+type TradeLine struct {
+	ItemID   int64
+	Quantity int
+}
+
+// TradeTransfer — то, что одна сторона отдаёт другой.
+type TradeTransfer struct {
+	From  int64
+	To    int64
+	Items []TradeLine
+	Gold  int
+}
+
+// CommitTrade атомарно выполняет обе половины обмена: если у любой стороны
+// не хватает предметов или золота, не меняется ничего.
+func (r *TradeRepository) CommitTrade(ctx context.Context, refID, reason string, transfers ...TradeTransfer) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, t := range transfers {
+		for _, l := range t.Items {
+			if err := removeItem(ctx, tx, t.From, l.ItemID, l.Quantity); err != nil {
+				return err
+			}
+			if err := addItem(ctx, tx, t.To, l.ItemID, l.Quantity); err != nil {
+				return err
+			}
+		}
+		if t.Gold <= 0 {
+			continue
+		}
+		if _, err := adjustGold(ctx, tx, models.LedgerEntry{
+			CharacterID: t.From,
+			Amount:      -t.Gold,
+			Reason:      reason,
+			Source:      models.LedgerSourceTrade,
+			RefID:       refID,
+		}); err != nil {
+			return err
+		}
+		if _, err := adjustGold(ctx, tx, models.LedgerEntry{
+			CharacterID: t.To,
+			Amount:      t.Gold,
+			Reason:      reason,
+			Source:      models.LedgerSourceTrade,
+			RefID:       refID,
+		}); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit transaction: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"aurora/internal/models"
+)
+
+func TestCommitTradeRollsBack(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+	trades := NewTradeRepository(db)
+	ledger := NewLedgerRepository(db)
+
+	alice := createTestCharacter(t, db, 1, 100, 30)
+	bob := createTestCharacter(t, db, 2, 100, 10)
+	sword := giveTestItem(t, db, alice, models.Item{Name: "Меч"}, 1)
+	shield := giveTestItem(t, db, bob, models.Item{Name: "Щит"}, 2)
+
+	gold := func(id int64) int {
+		t.Helper()
+		var g int
+		if err := db.QueryRow(`SELECT gold FROM characters WHERE id = ?`, id).Scan(&g); err != nil {
+			t.Fatal(err)
+		}
+		return g
+	}
+	unchanged := func() {
+		t.Helper()
+		if gold(alice) != 30 || gold(bob) != 10 {
+			t.Fatalf("gold changed: %d, %d", gold(alice), gold(bob))
+		}
+		if itemQuantity(t, db, alice, sword) != 1 || itemQuantity(t, db, bob, sword) != 0 ||
+			itemQuantity(t, db, bob, shield) != 2 || itemQuantity(t, db, alice, shield) != 0 {
+			t.Fatal("items moved")
+		}
+		for _, id := range []int64{alice, bob} {
+			entries, err := ledger.ListForCharacter(ctx, id, 10)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(entries) != 1 {
+				t.Fatalf("character %d: %d ledger entries, want only the opening one", id, len(entries))
+			}
+		}
+	}
+
+	for name, c := range map[string]struct {
+		transfers []TradeTransfer
+		err       error
+	}{
+		// меч уходит первым, а щитов у Боба только два
+		"items": {
+			transfers: []TradeTransfer{
+				{From: alice, To: bob, Items: []TradeLine{{ItemID: sword, Quantity: 1}}, Gold: 5},
+				{From: bob, To: alice, Items: []TradeLine{{ItemID: shield, Quantity: 3}}},
+			},
+			err: ErrNotEnoughItems,
+		},
+		// золото Алисы уже списано, а у Боба его не хватает
+		"gold": {
+			transfers: []TradeTransfer{
+				{From: alice, To: bob, Items: []TradeLine{{ItemID: sword, Quantity: 1}}, Gold: 30},
+				{From: bob, To: alice, Items: []TradeLine{{ItemID: shield, Quantity: 1}}, Gold: 41},
+			},
+			err: ErrNotEnoughGold,
+		},
+	} {
+		if err := trades.CommitTrade(ctx, "trade-"+name, "Обмен", c.transfers...); !errors.Is(err, c.err) {
+			t.Fatalf("%s: err = %v, want %v", name, err, c.err)
+		}
+		unchanged()
+	}
+
+	err := trades.CommitTrade(ctx, "trade-ok", "Обмен",
+		TradeTransfer{From: alice, To: bob, Items: []TradeLine{{ItemID: sword, Quantity: 1}}, Gold: 30},
+		TradeTransfer{From: bob, To: alice, Items: []TradeLine{{ItemID: shield, Quantity: 2}}, Gold: 40},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gold(alice) != 40 || gold(bob) != 0 {
+		t.Fatalf("gold after trade: %d, %d", gold(alice), gold(bob))
+	}
+	if itemQuantity(t, db, bob, sword) != 1 || itemQuantity(t, db, alice, shield) != 2 || itemQuantity(t, db, bob, shield) != 0 {
+		t.Fatal("items not moved")
+	}
+	if list, err := ledger.Mismatches(ctx); err != nil || len(list) != 0 {
+		t.Fatalf("mismatches = %+v, %v", list, err)
+	}
+}
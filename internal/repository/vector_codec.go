@@ -0,0 +1,286 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+)
+
+// VectorEncoding — формат вектора в lore_vectors.vector. Значение — первый
+// байт блоба; старые строки в JSON начинаются с '[' и читаются как раньше.
+type VectorEncoding byte
+
+const (
+	// VectorFloat32 — float32 little-endian, без потерь.
+	VectorFloat32 VectorEncoding = 1
+	// VectorFloat16 — половинная точность, вдвое компактнее.
+	VectorFloat16 VectorEncoding = 2
+	// VectorInt8 — int8 с общим масштабом, вчетверо компактнее.
+	VectorInt8 VectorEncoding = 3
+)
+
+var ErrVectorFormat = errors.New("unknown vector format")
+
+// ParseVectorEncoding разбирает настройку VECTOR_ENCODING: f32, f16 или i8.
+func ParseVectorEncoding(s string) (VectorEncoding, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "f32", "float32":
+		return VectorFloat32, nil
+	case "f16", "float16":
+		return VectorFloat16, nil
+	case "i8", "int8":
+		return VectorInt8, nil
+	}
+	return 0, fmt.Errorf("%w: %q", ErrVectorFormat, s)
+}
+
+func (e VectorEncoding) String() string {
+	switch e {
+	case VectorFloat32:
+		return "f32"
+	case VectorFloat16:
+		return "f16"
+	case VectorInt8:
+		return "i8"
+	}
+	return fmt.Sprintf("VectorEncoding(%d)", byte(e))
+}
+
+// encodeVector упаковывает вектор и возвращает норму уже упакованных
+// значений: для f16 и i8 она чуть отличается от нормы исходного.
+func encodeVector(vec []float32, enc VectorEncoding) ([]byte, float64, error) {
+	var out []byte
+	switch enc {
+	case VectorFloat32:
+		out = make([]byte, 1+4*len(vec))
+		for i, x := range vec {
+			binary.LittleEndian.PutUint32(out[1+4*i:], math.Float32bits(x))
+		}
+	case VectorFloat16:
+		out = make([]byte, 1+2*len(vec))
+		for i, x := range vec {
+			binary.LittleEndian.PutUint16(out[1+2*i:], float32ToHalf(x))
+		}
+	case VectorInt8:
+		var maxAbs float32
+		for _, x := range vec {
+			maxAbs = max(maxAbs, float32(math.Abs(float64(x))))
+		}
+		scale := maxAbs / 127
+		out = make([]byte, 5+len(vec))
+		binary.LittleEndian.PutUint32(out[1:], math.Float32bits(scale))
+		for i, x := range vec {
+			var q float64
+			if scale > 0 {
+				q = math.Round(float64(x / scale))
+			}
+			out[5+i] = byte(int8(min(max(q, -127), 127)))
+		}
+	default:
+		return nil, 0, fmt.Errorf("%w: %d", ErrVectorFormat, byte(enc))
+	}
+	out[0] = byte(enc)
+
+	decoded, err := decodeVector(out, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	return out, vectorNorm(decoded), nil
+}
+
+// decodeVector распаковывает вектор в dst (переиспользуя его память) и
+// понимает все форматы, включая старый JSON.
+func decodeVector(data []byte, dst []float32) ([]float32, error) {
+	if len(data) == 0 {
+		return nil, fmt.Errorf("%w: empty", ErrVectorFormat)
+	}
+	body := data[1:]
+	switch VectorEncoding(data[0]) {
+	case VectorFloat32:
+		if len(body)%4 != 0 {
+			return nil, fmt.Errorf("%w: f32 length %d", ErrVectorFormat, len(body))
+		}
+		dst = grow(dst, len(body)/4)
+		for i := range dst {
+			dst[i] = math.Float32frombits(binary.LittleEndian.Uint32(body[4*i:]))
+		}
+	case VectorFloat16:
+		if len(body)%2 != 0 {
+			return nil, fmt.Errorf("%w: f16 length %d", ErrVectorFormat, len(body))
+		}
+		dst = grow(dst, len(body)/2)
+		table := halfTable()
+		for i := range dst {
+			dst[i] = table[binary.LittleEndian.Uint16(body[2*i:])]
+		}
+	case VectorInt8:
+		if len(body) < 4 {
+			return nil, fmt.Errorf("%w: i8 without scale", ErrVectorFormat)
+		}
+		scale := math.Float32frombits(binary.LittleEndian.Uint32(body))
+		body = body[4:]
+		dst = grow(dst, len(body))
+		for i, b := range body {
+			dst[i] = float32(int8(b)) * scale
+		}
+	case '[':
+		var vec []float32
+		if err := json.Unmarshal(data, &vec); err != nil {
+			return nil, err
+		}
+		return vec, nil
+	default:
+		return nil, fmt.Errorf("%w: %d", ErrVectorFormat, data[0])
+	}
+	return dst, nil
+}
+
+func grow(dst []float32, n int) []float32 {
+	if cap(dst) < n {
+		return make([]float32, n)
+	}
+	return dst[:n]
+}
+
+func vectorNorm(v []float32) float64 {
+	var s float64
+	for _, x := range v {
+		s += float64(x) * float64(x)
+	}
+	return math.Sqrt(s)
+}
+
+// float32ToHalf — IEEE 754 binary16 с округлением к ближайшему.
+func float32ToHalf(f float32) uint16 {
+	b := math.Float32bits(f)
+	sign := uint16(b>>16) & 0x8000
+	exp := int(b>>23&0xff) - 127 + 15
+	mant := b & 0x7fffff
+	switch {
+	case b>>23&0xff == 0xff:
+		if mant != 0 {
+			return sign | 0x7e00
+		}
+		return sign | 0x7c00
+	case exp >= 0x1f:
+		// вне диапазона half — насыщаем до ±65504, бесконечность сломала бы косинус
+		return sign | 0x7bff
+	case exp <= 0:
+		if exp < -10 {
+			return sign
+		}
+		mant |= 0x800000
+		shift := uint(14 - exp)
+		h := uint16(mant >> shift)
+		if mant>>(shift-1)&1 != 0 {
+			h++
+		}
+		return sign | h
+	}
+	h := sign | uint16(exp)<<10 | uint16(mant>>13)
+	if mant&0x1000 != 0 && h&0x7fff != 0x7bff {
+		// перенос в порядок здесь корректен: мантисса обнулится, порядок вырастет
+		h++
+	}
+	return h
+}
+
+// halfTable — все 65536 значений half заранее: таблица быстрее разбора битов.
+var halfTable = sync.OnceValue(func() *[1 << 16]float32 {
+	var t [1 << 16]float32
+	for i := range t {
+		t[i] = halfToFloat32(uint16(i))
+	}
+	return &t
+})
+
+func halfToFloat32(h uint16) float32 {
+	sign := uint32(h&0x8000) << 16
+	exp := uint32(h>>10) & 0x1f
+	mant := uint32(h & 0x3ff)
+	switch exp {
+	case 0:
+		f := float32(mant) / (1 << 24)
+		if sign != 0 {
+			return -f
+		}
+		return f
+	case 0x1f:
+		return math.Float32frombits(sign | 0x7f800000 | mant<<13)
+	}
+	return math.Float32frombits(sign | (exp+127-15)<<23 | mant<<13)
+}
+
+// upgradeBatch — сколько строк перекодируется за одну транзакцию.
+const upgradeBatch = 500
+
+// UpgradeVectors перекодирует в enc строки lore_vectors, записанные в другом
+// формате (в том числе старым JSON), и заполняет norm. rowid при UPDATE не
+// меняется, поэтому проход идёт по нему пачками. Возвращает число строк.
+func UpgradeVectors(ctx context.Context, db *sql.DB, enc VectorEncoding) (int, error) {
+	if _, _, err := encodeVector(nil, enc); err != nil {
+		return 0, err
+	}
+	type row struct {
+		rowid int64
+		blob  []byte
+	}
+
+	var total int
+	var last int64
+	for {
+		rows, err := db.QueryContext(ctx, `SELECT rowid, vector FROM lore_vectors
+WHERE rowid > ? AND substr(vector, 1, 1) <> ? ORDER BY rowid LIMIT ?`, last, []byte{byte(enc)}, upgradeBatch)
+		if err != nil {
+			return total, fmt.Errorf("select vectors: %w", err)
+		}
+		var batch []row
+		for rows.Next() {
+			var r row
+			if err := rows.Scan(&r.rowid, &r.blob); err != nil {
+				rows.Close()
+				return total, err
+			}
+			batch = append(batch, r)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return total, err
+		}
+		if len(batch) == 0 {
+			return total, nil
+		}
+
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			return total, fmt.Errorf("begin transaction: %w", err)
+		}
+		for _, r := range batch {
+			vec, err := decodeVector(r.blob, nil)
+			if err != nil {
+				// битую строку оставляем как есть: поиск её и так пропускает
+				continue
+			}
+			blob, norm, err := encodeVector(vec, enc)
+			if err != nil {
+				tx.Rollback()
+				return total, err
+			}
+			if _, err := tx.ExecContext(ctx, `UPDATE lore_vectors SET vector = ?, norm = ? WHERE rowid = ?`, blob, norm, r.rowid); err != nil {
+				tx.Rollback()
+				return total, fmt.Errorf("update vector: %w", err)
+			}
+			total++
+		}
+		if err := tx.Commit(); err != nil {
+			return total, fmt.Errorf("commit transaction: %w", err)
+		}
+		last = batch[len(batch)-1].rowid
+	}
+}
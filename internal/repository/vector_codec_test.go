@@ -0,0 +1,312 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"path/filepath"
+	"testing"
+)
+
+var encodings = []VectorEncoding{VectorFloat32, VectorFloat16, VectorInt8}
+
+// testVector — вектор, похожий на эмбеддинг: значения порядка 0.01–0.1.
+func testVector(rng *rand.Rand, dim int) []float32 {
+	v := make([]float32, dim)
+	for i := range v {
+		v[i] = float32(rng.NormFloat64() * 0.05)
+	}
+	return v
+}
+
+// maxError — допустимая поэлементная ошибка формата для вектора v.
+func maxError(enc VectorEncoding, v []float32) float64 {
+	switch enc {
+	case VectorFloat16:
+		var m float64
+		for _, x := range v {
+			m = max(m, math.Abs(float64(x)))
+		}
+		// 10 бит мантиссы: половина шага у самого большого значения
+		return m / 1024
+	case VectorInt8:
+		var m float64
+		for _, x := range v {
+			m = max(m, math.Abs(float64(x)))
+		}
+		return m / 127 / 2 * 1.0001
+	}
+	return 0
+}
+
+func TestVectorRoundTrip(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for _, enc := range encodings {
+		t.Run(enc.String(), func(t *testing.T) {
+			for _, dim := range []int{0, 1, 3, 768} {
+				v := testVector(rng, dim)
+				blob, norm, err := encodeVector(v, enc)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if VectorEncoding(blob[0]) != enc {
+					t.Fatalf("dim %d: format byte %d", dim, blob[0])
+				}
+				got, err := decodeVector(blob, nil)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(got) != dim {
+					t.Fatalf("dim %d: decoded %d values", dim, len(got))
+				}
+				tol := maxError(enc, v)
+				for i := range v {
+					if d := math.Abs(float64(got[i] - v[i])); d > tol {
+						t.Fatalf("dim %d [%d]: %v → %v, error %g > %g", dim, i, v[i], got[i], d, tol)
+					}
+				}
+				// норма — у упакованных значений, по ней считается косинус
+				if want := vectorNorm(got); norm != want {
+					t.Fatalf("dim %d: norm %v, want %v", dim, norm, want)
+				}
+			}
+		})
+	}
+}
+
+func TestDecodeVectorReusesDst(t *testing.T) {
+	blob, _, err := encodeVector([]float32{1, 2, 3}, VectorFloat32)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dst := make([]float32, 0, 8)
+	got, err := decodeVector(blob, dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if &got[0] != &dst[:1][0] {
+		t.Fatal("dst with enough capacity was not reused")
+	}
+}
+
+func TestDecodeLegacyJSON(t *testing.T) {
+	want := []float32{0.25, -1.5, 3, 1e-7}
+	raw, err := json.Marshal(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := decodeVector(raw, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	if _, err := decodeVector([]byte("[1, 2,"), nil); err == nil {
+		t.Fatal("broken JSON decoded")
+	}
+}
+
+func TestDecodeVectorErrors(t *testing.T) {
+	for name, data := range map[string][]byte{
+		"empty":       nil,
+		"unknown":     {9, 0, 0, 0, 0},
+		"f32 length":  {byte(VectorFloat32), 0, 0, 0},
+		"f16 length":  {byte(VectorFloat16), 0},
+		"i8 no scale": {byte(VectorInt8), 0, 0},
+	} {
+		if _, err := decodeVector(data, nil); !errors.Is(err, ErrVectorFormat) {
+			t.Errorf("%s: err = %v, want ErrVectorFormat", name, err)
+		}
+	}
+	if _, _, err := encodeVector([]float32{1}, 9); !errors.Is(err, ErrVectorFormat) {
+		t.Errorf("encode unknown: err = %v", err)
+	}
+}
+
+func TestFloat16Edges(t *testing.T) {
+	for _, c := range []struct {
+		in, want float32
+	}{
+		{0, 0},
+		{1, 1},
+		{-2.5, -2.5},
+		{65504, 65504},
+		{1e6, 65504}, // насыщение вместо бесконечности
+		{-1e6, -65504},
+		{6e-8, 5.9604645e-08},
+		{1e-9, 0},
+		{float32(math.Inf(1)), float32(math.Inf(1))},
+	} {
+		if got := halfToFloat32(float32ToHalf(c.in)); got != c.want {
+			t.Errorf("%v: got %v, want %v", c.in, got, c.want)
+		}
+	}
+	if got := halfToFloat32(float32ToHalf(float32(math.NaN()))); !math.IsNaN(float64(got)) {
+		t.Errorf("NaN: got %v", got)
+	}
+	// таблица совпадает с разбором битов
+	table := halfTable()
+	for h := 0; h < 1<<16; h++ {
+		want := halfToFloat32(uint16(h))
+		if got := table[h]; got != want && !(math.IsNaN(float64(got)) && math.IsNaN(float64(want))) {
+			t.Fatalf("table[%#x] = %v, want %v", h, got, want)
+		}
+	}
+}
+
+func TestUpgradeVectors(t *testing.T) {
+	ctx := context.Background()
+	db, err := NewSQLite(filepath.Join(t.TempDir(), "vectors.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, err := db.Exec(`CREATE TABLE lore_vectors (id TEXT PRIMARY KEY, vector BLOB NOT NULL, norm REAL NOT NULL DEFAULT 0)`); err != nil {
+		t.Fatal(err)
+	}
+
+	rng := rand.New(rand.NewSource(2))
+	want := make(map[string][]float32)
+	insert := func(id string, blob []byte) {
+		if _, err := db.Exec(`INSERT INTO lore_vectors (id, vector) VALUES (?, ?)`, id, blob); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// больше пачки, чтобы проход по rowid перешёл через границу
+	for i := 0; i < upgradeBatch+20; i++ {
+		id := fmt.Sprintf("doc-%d", i)
+		v := testVector(rng, 16)
+		want[id] = v
+		var blob []byte
+		if i%2 == 0 {
+			blob, _ = json.Marshal(v)
+		} else {
+			blob, _, _ = encodeVector(v, VectorFloat32)
+		}
+		insert(id, blob)
+	}
+	insert("broken", []byte{9, 1, 2})
+
+	n, err := UpgradeVectors(ctx, db, VectorFloat16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != len(want) {
+		t.Fatalf("upgraded %d rows, want %d", n, len(want))
+	}
+
+	rows, err := db.Query(`SELECT id, vector, norm FROM lore_vectors`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id string
+		var blob []byte
+		var norm float64
+		if err := rows.Scan(&id, &blob, &norm); err != nil {
+			t.Fatal(err)
+		}
+		if id == "broken" {
+			if blob[0] != 9 || norm != 0 {
+				t.Fatalf("broken row was rewritten: %v %v", blob, norm)
+			}
+			continue
+		}
+		if VectorEncoding(blob[0]) != VectorFloat16 {
+			t.Fatalf("%s: format %d", id, blob[0])
+		}
+		got, err := decodeVector(blob, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tol := maxError(VectorFloat16, want[id])
+		for i, x := range want[id] {
+			if math.Abs(float64(got[i]-x)) > tol {
+				t.Fatalf("%s[%d]: %v → %v", id, i, x, got[i])
+			}
+		}
+		if norm != vectorNorm(got) {
+			t.Fatalf("%s: norm %v, want %v", id, norm, vectorNorm(got))
+		}
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	// повторный запуск ничего не трогает
+	if n, err := UpgradeVectors(ctx, db, VectorFloat16); err != nil || n != 0 {
+		t.Fatalf("second run: %d, %v", n, err)
+	}
+}
+
+func BenchmarkEncodeVector(b *testing.B) {
+	v := testVector(rand.New(rand.NewSource(1)), 768)
+	for _, enc := range encodings {
+		b.Run(enc.String(), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, _, err := encodeVector(v, enc); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkDecodeVector(b *testing.B) {
+	v := testVector(rand.New(rand.NewSource(1)), 768)
+	legacy, _ := json.Marshal(v)
+	blobs := map[string][]byte{"json": legacy}
+	for _, enc := range encodings {
+		blobs[enc.String()], _, _ = encodeVector(v, enc)
+	}
+	for _, name := range []string{"f32", "f16", "i8", "json"} {
+		blob := blobs[name]
+		b.Run(name, func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(len(blob)))
+			dst := make([]float32, 0, len(v))
+			for i := 0; i < b.N; i++ {
+				var err error
+				if dst, err = decodeVector(blob, dst); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkUpgradeVectors(b *testing.B) {
+	ctx := context.Background()
+	rng := rand.New(rand.NewSource(1))
+	legacy := make([][]byte, 1000)
+	for i := range legacy {
+		legacy[i], _ = json.Marshal(testVector(rng, 768))
+	}
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		db, err := NewSQLite(filepath.Join(b.TempDir(), fmt.Sprintf("bench%d.db", i)))
+		if err != nil {
+			b.Fatal(err)
+		}
+		if _, err := db.Exec(`CREATE TABLE lore_vectors (id TEXT PRIMARY KEY, vector BLOB NOT NULL, norm REAL NOT NULL DEFAULT 0)`); err != nil {
+			b.Fatal(err)
+		}
+		for j, blob := range legacy {
+			if _, err := db.Exec(`INSERT INTO lore_vectors (id, vector) VALUES (?, ?)`, fmt.Sprint(j), blob); err != nil {
+				b.Fatal(err)
+			}
+		}
+		b.StartTimer()
+		if _, err := UpgradeVectors(ctx, db, VectorFloat32); err != nil {
+			b.Fatal(err)
+		}
+		b.StopTimer()
+		db.Close()
+	}
+}
@@ -0,0 +1,311 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"aurora/internal/vectorindex"
+)
+
+const (
+	// annSaveInterval — как часто изменённый индекс сбрасывается на диск.
+	annSaveInterval = time.Minute
+	// annMaxGarbage — доля удалённых узлов, после которой граф строится заново.
+	annMaxGarbage = 0.3
+)
+
+// annIndex — HNSW-индекс поверх строк lore_vectors одной модели. Таблица
+// остаётся источником истины: индекс хранит только id и векторы, документы
+// читаются из базы.
+type annIndex struct {
+	idx  *vectorindex.HNSW
+	path string
+
+	mu      sync.RWMutex
+	zones   map[string]string
+	dirty   bool
+	savedAt time.Time
+	// fp — отметка таблицы, которой соответствует граф. Пустая — таблицу
+	// меняли в обход этого индекса, и сохранять его нельзя.
+	fp string
+}
+
+// NewIndexedVectorRepository — хранилище векторов модели model с
+// HNSW-индексом в памяти. Векторы в таблице сначала перекодируются в enc.
+// Индекс читается из path, если файл совпадает с таблицей, иначе строится
+// заново из lore_vectors и сохраняется.
+func NewIndexedVectorRepository(ctx context.Context, db *sql.DB, path string, enc VectorEncoding, model string, dims int) (VectorRepository, error) {
+	r := &sqliteVectorRepo{db: db, model: model, dims: dims, enc: enc}
+	ann := &annIndex{path: path}
+	upgraded, err := UpgradeVectors(ctx, db, enc)
+	if err != nil {
+		return nil, fmt.Errorf("upgrade vectors: %w", err)
+	}
+	if upgraded > 0 {
+		log.Printf("vectors converted to %s: %d", enc, upgraded)
+	}
+	if err := r.ensureKeywordIndex(ctx); err != nil {
+		return nil, err
+	}
+	fp, err := r.fingerprint(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	ann.fp = fp
+
+	idx, meta, err := vectorindex.LoadFile(path)
+	switch {
+	// после перекодирования векторы могли чуть сдвинуться — граф строится заново
+	case err == nil && meta == fp && upgraded == 0:
+		ann.idx = idx
+		if err := ann.loadZones(ctx, r); err != nil {
+			return nil, err
+		}
+		ann.savedAt = time.Now()
+		log.Printf("vector index loaded: %d documents", idx.Len())
+	default:
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			log.Printf("vector index %s unreadable, rebuilding: %v", path, err)
+		}
+		ann.idx = vectorindex.New(vectorindex.DefaultConfig())
+		if err := ann.build(ctx, r); err != nil {
+			return nil, err
+		}
+		if err := ann.save(ctx, r); err != nil {
+			log.Printf("vector index save failed: %v", err)
+		}
+		log.Printf("vector index built: %d documents", ann.idx.Len())
+	}
+	r.ann = ann
+	return r, nil
+}
+
+// fingerprint — отметка состояния строк модели. REPLACE выдаёт строке новый
+// rowid, поэтому любая вставка или удаление её меняет; модель в отметке не
+// даёт подхватить индекс, построенный по другой модели.
+func (r *sqliteVectorRepo) fingerprint(ctx context.Context, q dbtx) (string, error) {
+	var n, maxRow int64
+	err := q.QueryRowContext(ctx, `SELECT COUNT(*), IFNULL(MAX(rowid), 0) FROM lore_vectors WHERE model = ?`, r.model).Scan(&n, &maxRow)
+	if err != nil {
+		return "", fmt.Errorf("vector fingerprint: %w", err)
+	}
+	return fmt.Sprintf("%s:%d:%d", r.model, n, maxRow), nil
+}
+
+func (a *annIndex) build(ctx context.Context, r *sqliteVectorRepo) error {
+	rows, err := r.db.QueryContext(ctx, `SELECT id, zone, vector FROM lore_vectors WHERE model = ?`, r.model)
+	if err != nil {
+		return fmt.Errorf("load vectors: %w", err)
+	}
+	defer rows.Close()
+
+	zones := make(map[string]string)
+	for rows.Next() {
+		var id, zone string
+		var blob []byte
+		if err := rows.Scan(&id, &zone, &blob); err != nil {
+			return err
+		}
+		vec, err := decodeVector(blob, nil)
+		if err != nil {
+			continue
+		}
+		if a.idx.Add(id, vec) {
+			zones[id] = zone
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	a.mu.Lock()
+	a.zones = zones
+	a.dirty = true
+	a.mu.Unlock()
+	return nil
+}
+
+func (a *annIndex) loadZones(ctx context.Context, r *sqliteVectorRepo) error {
+	rows, err := r.db.QueryContext(ctx, `SELECT id, zone FROM lore_vectors WHERE model = ?`, r.model)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	zones := make(map[string]string)
+	for rows.Next() {
+		var id, zone string
+		if err := rows.Scan(&id, &zone); err != nil {
+			return err
+		}
+		zones[id] = zone
+	}
+	a.mu.Lock()
+	a.zones = zones
+	a.mu.Unlock()
+	return rows.Err()
+}
+
+func (a *annIndex) add(doc VectorDocument) {
+	if !a.idx.Add(doc.ID, doc.Vector) {
+		// вектор не подошёл индексу — старая версия документа тоже не должна находиться
+		a.idx.Remove(doc.ID)
+	}
+	a.mu.Lock()
+	a.zones[doc.ID] = doc.Zone
+	a.dirty = true
+	a.mu.Unlock()
+}
+
+func (a *annIndex) removeZone(zone string) {
+	a.mu.Lock()
+	var ids []string
+	for id, z := range a.zones {
+		if z == zone {
+			ids = append(ids, id)
+			delete(a.zones, id)
+		}
+	}
+	a.dirty = true
+	a.mu.Unlock()
+	for _, id := range ids {
+		a.idx.Remove(id)
+	}
+}
+
+func (a *annIndex) remove(ids []string) {
+	a.mu.Lock()
+	for _, id := range ids {
+		delete(a.zones, id)
+	}
+	a.dirty = true
+	a.mu.Unlock()
+	for _, id := range ids {
+		a.idx.Remove(id)
+	}
+}
+
+// reset очищает индекс; fp — отметка опустевшей таблицы.
+func (a *annIndex) reset(fp string) {
+	a.idx.Reset()
+	a.mu.Lock()
+	a.zones = make(map[string]string)
+	a.fp = fp
+	a.dirty = true
+	a.mu.Unlock()
+}
+
+// advance переводит индекс на отметку after после записи этого процесса.
+// before — отметка в той же транзакции до записи: если она не совпала с
+// индексом, таблицу успели поменять мимо него.
+func (a *annIndex) advance(before, after string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.fp != "" && a.fp != before {
+		log.Printf("vector index out of sync with lore_vectors, it will not be saved until rebuilt")
+		a.fp = ""
+	}
+	if a.fp != "" {
+		a.fp = after
+	}
+}
+
+// compact перестраивает граф, когда в нём накопилось много удалённых или
+// заменённых узлов. Перестроенный граф снова совпадает с таблицей.
+func (a *annIndex) compact(ctx context.Context, r *sqliteVectorRepo) error {
+	if a.idx.Garbage() > annMaxGarbage {
+		fp, err := r.fingerprint(ctx, r.db)
+		if err != nil {
+			return err
+		}
+		a.idx.Reset()
+		if err := a.build(ctx, r); err != nil {
+			return err
+		}
+		a.mu.Lock()
+		a.fp = fp
+		a.mu.Unlock()
+	}
+	a.maybeSave(ctx, r)
+	return nil
+}
+
+func (a *annIndex) zone(id string) string {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.zones[id]
+}
+
+// maybeSave сохраняет индекс не чаще annSaveInterval; ошибки только в лог,
+// чтобы запись в таблицу не зависела от диска под индексом.
+func (a *annIndex) maybeSave(ctx context.Context, r *sqliteVectorRepo) {
+	a.mu.RLock()
+	due := a.dirty && time.Since(a.savedAt) >= annSaveInterval
+	a.mu.RUnlock()
+	if !due {
+		return
+	}
+	if err := a.save(ctx, r); err != nil {
+		log.Printf("vector index save failed: %v", err)
+	}
+}
+
+// save пишет граф под отметкой, которой он соответствует, а не под текущей
+// отметкой таблицы: иначе отставший граф сохранился бы как свежий.
+func (a *annIndex) save(ctx context.Context, r *sqliteVectorRepo) error {
+	a.mu.RLock()
+	fp := a.fp
+	a.mu.RUnlock()
+	if a.path == "" || fp == "" {
+		return nil
+	}
+	if err := a.idx.SaveFile(a.path, fp); err != nil {
+		return fmt.Errorf("save vector index: %w", err)
+	}
+	a.mu.Lock()
+	a.dirty = false
+	a.savedAt = time.Now()
+	a.mu.Unlock()
+	return nil
+}
+
+// searchANN ищет через индекс. ok=false — индекс не смог ответить (другая
+// размерность запроса, пустой индекс, фильтр по тегам), и нужен полный перебор.
+func (r *sqliteVectorRepo) searchANN(ctx context.Context, queryVector []float32, limit int, filters map[string]string) ([]VectorDocument, bool, error) {
+	if r.ann.idx.Len() == 0 || len(queryVector) != r.ann.idx.Dim() {
+		return nil, false, nil
+	}
+	if len(filterValues(filters, "tags")) > 0 || len(filterValues(filters, "faction")) > 0 {
+		// теги в индексе не хранятся — такие запросы идут полным перебором
+		return nil, false, nil
+	}
+	var accept func(string) bool
+	if zones := filterValues(filters, "zone"); len(zones) > 0 {
+		accept = func(id string) bool { return slices.Contains(zones, r.ann.zone(id)) }
+	}
+	found := r.ann.idx.Search(queryVector, limit, 0, accept)
+	// фильтр отсеивает кандидатов уже при обходе графа, и из маленькой зоны
+	// может дойти меньше limit — тогда отвечает полный перебор
+	if len(found) < limit && (accept != nil || len(found) < r.ann.idx.Len()) {
+		return nil, false, nil
+	}
+	if len(found) == 0 {
+		return nil, true, nil
+	}
+
+	hits := make([]scoredID, len(found))
+	for i, f := range found {
+		hits[i] = scoredID{id: f.ID, score: float64(f.Score)}
+	}
+	docs, err := r.loadDocuments(ctx, hits)
+	if err != nil {
+		return nil, false, err
+	}
+	return docs, true, nil
+}
@@ -0,0 +1,142 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"path/filepath"
+	"testing"
+
+	"aurora/internal/vectorindex"
+)
+
+const annTestDim = 16
+
+func annTestDocs(rng *rand.Rand, prefix, zone string, n int) []VectorDocument {
+	docs := make([]VectorDocument, n)
+	for i := range docs {
+		docs[i] = VectorDocument{
+			ID:     fmt.Sprintf("%s-%d", prefix, i),
+			Title:  "t",
+			Zone:   zone,
+			Tags:   []string{},
+			Vector: testVector(rng, annTestDim),
+		}
+	}
+	return docs
+}
+
+// openANN — хранилище с HNSW-индексом над чистой базой; path "" — без файла.
+func openANN(t *testing.T, path string) *sqliteVectorRepo {
+	t.Helper()
+	repo, err := NewIndexedVectorRepository(context.Background(), openTestDB(t), path, VectorFloat32, "m", annTestDim)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return repo.(*sqliteVectorRepo)
+}
+
+// Из маленькой зоны граф может отдать меньше limit — ответ тогда даёт полный перебор.
+func TestSearchANNSmallZoneFallsBack(t *testing.T) {
+	ctx := context.Background()
+	r := openANN(t, "")
+	rng := rand.New(rand.NewSource(1))
+	if err := r.IndexBatch(ctx, annTestDocs(rng, "world", "world", 500)); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.IndexBatch(ctx, annTestDocs(rng, "tiny", "tiny", 3)); err != nil {
+		t.Fatal(err)
+	}
+
+	filters := map[string]string{"zone": "tiny"}
+	for q := 0; q < 20; q++ {
+		query := testVector(rng, annTestDim)
+		got, err := r.SearchSimilar(ctx, query, 5, filters)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want, err := r.searchExact(ctx, query, 5, filters)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != 3 || docIDs(got) != docIDs(want) {
+			t.Fatalf("query %d: got %s, want %s", q, docIDs(got), docIDs(want))
+		}
+	}
+}
+
+func docIDs(docs []VectorDocument) string {
+	var s string
+	for _, d := range docs {
+		s += d.ID + " "
+	}
+	return s
+}
+
+// Переиндексация тех же документов оставляет в графе мёртвые узлы; IndexBatch
+// перестраивает граф, когда их становится слишком много.
+func TestIndexBatchCompacts(t *testing.T) {
+	ctx := context.Background()
+	r := openANN(t, "")
+	rng := rand.New(rand.NewSource(2))
+	for round := 0; round < 3; round++ {
+		if err := r.IndexBatch(ctx, annTestDocs(rng, "doc", "world", 100)); err != nil {
+			t.Fatal(err)
+		}
+		if g := r.ann.idx.Garbage(); g > annMaxGarbage {
+			t.Fatalf("round %d: garbage %.2f after IndexBatch", round, g)
+		}
+		if n := r.ann.idx.Len(); n != 100 {
+			t.Fatalf("round %d: %d documents in index", round, n)
+		}
+	}
+}
+
+func TestIndexSavedUnderOwnFingerprint(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "lore.hnsw")
+	r := openANN(t, path)
+	rng := rand.New(rand.NewSource(3))
+
+	// свои записи двигают отметку индекса вместе с таблицей
+	if err := r.IndexBatch(ctx, annTestDocs(rng, "a", "world", 50)); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.DeleteDocuments(ctx, []string{"a-1"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Flush(ctx); err != nil {
+		t.Fatal(err)
+	}
+	fp, err := r.fingerprint(ctx, r.db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, meta, err := vectorindex.LoadFile(path); err != nil || meta != fp {
+		t.Fatalf("saved under %q (%v), table is %q", meta, err, fp)
+	}
+
+	// запись мимо индекса: граф отстал и не должен сохраниться как свежий
+	other := NewVectorRepository(r.db, "m", annTestDim)
+	if err := other.IndexBatch(ctx, annTestDocs(rng, "b", "world", 5)); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.IndexBatch(ctx, annTestDocs(rng, "c", "world", 5)); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Flush(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, meta, err := vectorindex.LoadFile(path); err != nil || meta != fp {
+		t.Fatalf("stale graph saved under %q (%v)", meta, err)
+	}
+
+	// при следующем запуске отметка не совпадёт, и граф построится заново
+	reopened, err := NewIndexedVectorRepository(ctx, r.db, path, VectorFloat32, "m", annTestDim)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := reopened.(*sqliteVectorRepo).ann.idx.Len(); n != 59 {
+		t.Fatalf("reopened index has %d documents, want 59", n)
+	}
+}
@@ -0,0 +1,158 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+
+	"aurora/internal/textsearch"
+)
+
+// execer — *sql.DB или *sql.Tx.
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// ensureKeywordIndex включает полнотекстовый поиск: таблица FTS5 lore_fts с
+// теми же rowid, что у lore_vectors, хранит основы слов (textsearch.Terms) —
+// своего русского стеммера у FTS5 нет. Без FTS5 в сборке SQLite (тег
+// sqlite_fts5) поиск по словам просто выключается.
+func (r *sqliteVectorRepo) ensureKeywordIndex(ctx context.Context) error {
+	_, err := r.db.ExecContext(ctx, `CREATE VIRTUAL TABLE IF NOT EXISTS lore_fts USING fts5(terms, tokenize = 'unicode61 remove_diacritics 0')`)
+	if err != nil {
+		if strings.Contains(err.Error(), "no such module") {
+			log.Printf("keyword search disabled: SQLite built without FTS5 (build with -tags sqlite_fts5)")
+			return nil
+		}
+		return fmt.Errorf("create lore_fts: %w", err)
+	}
+	r.fts = true
+
+	var stale bool
+	err = r.db.QueryRowContext(ctx, `SELECT (SELECT COUNT(*) FROM lore_fts) <> (SELECT COUNT(*) FROM lore_vectors)
+    OR EXISTS (SELECT 1 FROM lore_vectors v WHERE NOT EXISTS (SELECT 1 FROM lore_fts f WHERE f.rowid = v.rowid))`).Scan(&stale)
+	if err != nil {
+		return fmt.Errorf("check lore_fts: %w", err)
+	}
+	if stale {
+		return r.rebuildKeywords(ctx)
+	}
+	return nil
+}
+
+func (r *sqliteVectorRepo) rebuildKeywords(ctx context.Context) error {
+	rows, err := r.db.QueryContext(ctx, `SELECT rowid, title, content, tags FROM lore_vectors`)
+	if err != nil {
+		return fmt.Errorf("load documents: %w", err)
+	}
+	type entry struct {
+		rowid int64
+		doc   VectorDocument
+	}
+	var entries []entry
+	for rows.Next() {
+		var e entry
+		var tagsJSON string
+		if err := rows.Scan(&e.rowid, &e.doc.Title, &e.doc.Content, &tagsJSON); err != nil {
+			rows.Close()
+			return err
+		}
+		json.Unmarshal([]byte(tagsJSON), &e.doc.Tags)
+		entries = append(entries, e)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+	if _, err := tx.ExecContext(ctx, `DELETE FROM lore_fts`); err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if err := r.addKeywords(ctx, tx, e.rowid, e.doc); err != nil {
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit transaction: %w", err)
+	}
+	log.Printf("keyword index rebuilt: %d documents", len(entries))
+	return nil
+}
+
+// dropKeywords убирает из FTS текущую версию документа id модели хранилища —
+// до того, как INSERT OR REPLACE выдаст ему новый rowid.
+func (r *sqliteVectorRepo) dropKeywords(ctx context.Context, ex execer, id string) error {
+	if !r.fts {
+		return nil
+	}
+	_, err := ex.ExecContext(ctx, `DELETE FROM lore_fts WHERE rowid = (SELECT rowid FROM lore_vectors WHERE id = ? AND model = ?)`, id, r.model)
+	return err
+}
+
+func (r *sqliteVectorRepo) addKeywords(ctx context.Context, ex execer, rowid int64, doc VectorDocument) error {
+	if !r.fts {
+		return nil
+	}
+	text := strings.Join(textsearch.Terms(doc.Title+"\n"+doc.Content+"\n"+strings.Join(doc.Tags, " ")), " ")
+	_, err := ex.ExecContext(ctx, `INSERT INTO lore_fts (rowid, terms) VALUES (?, ?)`, rowid, text)
+	return err
+}
+
+// SearchKeyword — BM25 по основам слов запроса (любое из слов). Score —
+// BM25 с обратным знаком: больше — лучше.
+func (r *sqliteVectorRepo) SearchKeyword(ctx context.Context, query string, limit int, filters map[string]string) ([]VectorDocument, error) {
+	expr := keywordMatch(textsearch.Terms(query))
+	if !r.fts || expr == "" || limit <= 0 {
+		return []VectorDocument{}, nil
+	}
+	where, args := sqliteFilters(filters, "v.")
+	q := `SELECT v.id, -bm25(lore_fts) FROM lore_fts JOIN lore_vectors v ON v.rowid = lore_fts.rowid WHERE lore_fts MATCH ? AND v.model = ?`
+	args = append([]any{expr, r.model}, args...)
+	for _, w := range where {
+		q += ` AND ` + w
+	}
+	q += ` ORDER BY bm25(lore_fts) LIMIT ?`
+	args = append(args, limit)
+
+	rows, err := r.db.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, fmt.Errorf("keyword search: %w", err)
+	}
+	var hits []scoredID
+	for rows.Next() {
+		var hit scoredID
+		if err := rows.Scan(&hit.id, &hit.score); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		hits = append(hits, hit)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return r.loadDocuments(ctx, hits)
+}
+
+// keywordMatch — выражение FTS5 «любое из слов»; слова состоят только из
+// букв и цифр, кавычки внутри не встречаются.
+func keywordMatch(terms []string) string {
+	seen := make(map[string]bool, len(terms))
+	var parts []string
+	for _, t := range terms {
+		if !seen[t] {
+			seen[t] = true
+			parts = append(parts, `"`+t+`"`)
+		}
+	}
+	return strings.Join(parts, " OR ")
+}
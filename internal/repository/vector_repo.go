@@ -1,11 +1,13 @@
 package repository
 
 import (
+	"container/heap"
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"math"
+	"strings"
 )
 
 type VectorDocument struct {
@@ -16,78 +18,156 @@ type VectorDocument struct {
 	Zone     string
 	Tags     []string
 	Metadata map[string]string
+	// Model — модель эмбеддинга; при записи пусто значит модель хранилища.
+	Model string
+	// Score — косинусная близость к запросу; заполняется только SearchSimilar.
+	Score float32
+}
+
+// ErrModelMismatch — вектор посчитан не той моделью или не той размерности,
+// что у хранилища: сравнивать его с лежащими там бессмысленно.
+var ErrModelMismatch = errors.New("embedding model mismatch")
+
+// DocumentInfo — проиндексированный документ без текста и вектора.
+type DocumentInfo struct {
+	ID       string
+	Title    string
+	Metadata map[string]string
 }
 
 type VectorStats struct {
 	TotalDocuments  int
 	DocumentsByZone map[string]int
+	// Models — сколько документов у каждой модели во всей таблице, включая
+	// чужие для хранилища.
+	Models map[string]int
 }
 
+// VectorRepository работает с векторами одной модели эмбеддингов (Model):
+// строки других моделей в той же таблице для него не видны.
 type VectorRepository interface {
+	// Model и Dims — модель хранилища и размерность её векторов.
+	Model() string
+	Dims() int
 	IndexDocument(ctx context.Context, doc VectorDocument) error
 	IndexBatch(ctx context.Context, docs []VectorDocument) error
 	SearchSimilar(ctx context.Context, queryVector []float32, limit int, filters map[string]string) ([]VectorDocument, error)
+	// SearchKeyword — полнотекстовый поиск по словам запроса; Score — оценка
+	// релевантности хранилища (BM25 или ts_rank), сравнимая только внутри одной выдачи.
+	SearchKeyword(ctx context.Context, query string, limit int, filters map[string]string) ([]VectorDocument, error)
+	// GetDocuments возвращает документы в порядке ids; отсутствующие пропускаются.
+	GetDocuments(ctx context.Context, ids []string) ([]VectorDocument, error)
+	// GetByParent — все фрагменты с metadata.parent = parent по возрастанию chunk_index.
+	GetByParent(ctx context.Context, parent string) ([]VectorDocument, error)
+	// ListBySource — документы с metadata.source = source, для сверки при
+	// инкрементальной индексации.
+	ListBySource(ctx context.Context, source string) ([]DocumentInfo, error)
+	// ListByDocument — фрагменты документа: с id = document или metadata.document = document.
+	ListByDocument(ctx context.Context, document string) ([]DocumentInfo, error)
+	DeleteDocuments(ctx context.Context, ids []string) error
 	DeleteByZone(ctx context.Context, zone string) error
 	GetStats(ctx context.Context) (VectorStats, error)
 	DeleteAll(ctx context.Context) error
+	// Scan отдаёт все документы модели пачками по batch в порядке id.
+	Scan(ctx context.Context, batch int, fn func([]VectorDocument) error) error
+	// Flush сохраняет на диск то, что хранилище держит в памяти.
+	Flush(ctx context.Context) error
 }
 
 type sqliteVectorRepo struct {
-	db *sql.DB
+	db    *sql.DB
+	model string
+	dims  int
+	enc   VectorEncoding
+	ann   *annIndex
+	fts   bool
 }
 
-func NewVectorRepository(db *sql.DB) VectorRepository {
-	return &sqliteVectorRepo{db: db}
-}
-
-func (r *sqliteVectorRepo) IndexDocument(ctx context.Context, doc VectorDocument) error {
-	tagsJSON, err := json.Marshal(doc.Tags)
-	if err != nil {
-		return fmt.Errorf("marshal tags: %w", err)
-	}
+// Хранилища векторов для VectorOptions.Backend.
+const (
+	VectorBackendSQLite   = "sqlite"
+	VectorBackendPgvector = "pgvector"
+)
 
-	metadataJSON, err := json.Marshal(doc.Metadata)
-	if err != nil {
-		return fmt.Errorf("marshal metadata: %w", err)
-	}
+// VectorOptions — где и как хранить векторы лора.
+type VectorOptions struct {
+	Backend     string
+	PostgresDSN string
+	// IndexPath и Encoding — для SQLite.
+	IndexPath string
+	Encoding  VectorEncoding
+	// Model и Dim — модель эмбеддингов и её размерность (embeddings.Service).
+	Model string
+	Dim   int
+}
 
-	vectorBlob, err := serializeVector(doc.Vector)
-	if err != nil {
-		return fmt.Errorf("serialize vector: %w", err)
+// OpenVectorRepository открывает хранилище, выбранное в opts. db — основная
+// база SQLite; соединение с PostgreSQL живёт до конца процесса.
+func OpenVectorRepository(ctx context.Context, db *sql.DB, opts VectorOptions) (VectorRepository, error) {
+	switch opts.Backend {
+	case "", VectorBackendSQLite:
+		return NewIndexedVectorRepository(ctx, db, opts.IndexPath, opts.Encoding, opts.Model, opts.Dim)
+	case VectorBackendPgvector:
+		pg, err := NewPostgres(opts.PostgresDSN)
+		if err != nil {
+			return nil, fmt.Errorf("connect postgres: %w", err)
+		}
+		return NewPgVectorRepository(ctx, pg, opts.Model, opts.Dim)
 	}
+	return nil, fmt.Errorf("unknown vector backend %q", opts.Backend)
+}
 
-	query := `
-		INSERT OR REPLACE INTO lore_vectors (id, title, content, zone, tags, vector, metadata)
-		VALUES (?, ?, ?, ?, ?, ?, ?)
-	`
+// NewVectorRepository — хранилище векторов модели model с поиском полным
+// перебором; новые векторы пишутся в float32.
+func NewVectorRepository(db *sql.DB, model string, dims int) VectorRepository {
+	return &sqliteVectorRepo{db: db, model: model, dims: dims, enc: VectorFloat32}
+}
 
-	_, err = r.db.ExecContext(ctx, query,
-		doc.ID,
-		doc.Title,
-		doc.Content,
-		doc.Zone,
-		string(tagsJSON),
-		vectorBlob,
-		string(metadataJSON),
-	)
+func (r *sqliteVectorRepo) Model() string { return r.model }
+func (r *sqliteVectorRepo) Dims() int     { return r.dims }
 
-	if err != nil {
-		return fmt.Errorf("insert document: %w", err)
+// checkVector — вектор должен быть размерности хранилища.
+func checkVector(model string, dims int, vec []float32) error {
+	if len(vec) != dims {
+		return fmt.Errorf("%w: dimension %d, %s stores %d", ErrModelMismatch, len(vec), model, dims)
 	}
+	return nil
+}
 
+// checkDocument — документ чужой модели в хранилище не пишется.
+func checkDocument(model string, dims int, doc VectorDocument) error {
+	if doc.Model != "" && doc.Model != model {
+		return fmt.Errorf("%w: document %s from %s, store holds %s", ErrModelMismatch, doc.ID, doc.Model, model)
+	}
+	if err := checkVector(model, dims, doc.Vector); err != nil {
+		return fmt.Errorf("document %s: %w", doc.ID, err)
+	}
 	return nil
 }
 
+func (r *sqliteVectorRepo) IndexDocument(ctx context.Context, doc VectorDocument) error {
+	return r.IndexBatch(ctx, []VectorDocument{doc})
+}
+
 func (r *sqliteVectorRepo) IndexBatch(ctx context.Context, docs []VectorDocument) error {
+	for _, doc := range docs {
+		if err := checkDocument(r.model, r.dims, doc); err != nil {
+			return err
+		}
+	}
 	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
 		return fmt.Errorf("begin transaction: %w", err)
 	}
 	defer tx.Rollback()
 
+	before, err := r.annFingerprint(ctx, tx)
+	if err != nil {
+		return err
+	}
 	stmt, err := tx.PrepareContext(ctx, `
-		INSERT OR REPLACE INTO lore_vectors (id, title, content, zone, tags, vector, metadata)
-		VALUES (?, ?, ?, ?, ?, ?, ?)
+		INSERT OR REPLACE INTO lore_vectors (id, model, dims, title, content, zone, tags, vector, norm, metadata)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`)
 	if err != nil {
 		return fmt.Errorf("prepare statement: %w", err)
@@ -105,40 +185,91 @@ func (r *sqliteVectorRepo) IndexBatch(ctx context.Context, docs []VectorDocument
 			return fmt.Errorf("marshal metadata for %s: %w", doc.ID, err)
 		}
 
-		vectorBlob, err := serializeVector(doc.Vector)
+		vectorBlob, norm, err := encodeVector(doc.Vector, r.enc)
 		if err != nil {
 			return fmt.Errorf("serialize vector for %s: %w", doc.ID, err)
 		}
 
-		_, err = stmt.ExecContext(ctx,
+		if err := r.dropKeywords(ctx, tx, doc.ID); err != nil {
+			return fmt.Errorf("drop keywords for %s: %w", doc.ID, err)
+		}
+		res, err := stmt.ExecContext(ctx,
 			doc.ID,
+			r.model,
+			len(doc.Vector),
 			doc.Title,
 			doc.Content,
 			doc.Zone,
 			string(tagsJSON),
 			vectorBlob,
+			norm,
 			string(metadataJSON),
 		)
 		if err != nil {
 			return fmt.Errorf("insert document %s: %w", doc.ID, err)
 		}
+		if r.fts {
+			rowid, err := res.LastInsertId()
+			if err != nil {
+				return err
+			}
+			if err := r.addKeywords(ctx, tx, rowid, doc); err != nil {
+				return fmt.Errorf("index keywords for %s: %w", doc.ID, err)
+			}
+		}
 	}
 
+	after, err := r.annFingerprint(ctx, tx)
+	if err != nil {
+		return err
+	}
 	if err := tx.Commit(); err != nil {
 		return fmt.Errorf("commit transaction: %w", err)
 	}
 
+	if r.ann != nil {
+		for _, doc := range docs {
+			r.ann.add(doc)
+		}
+		r.ann.advance(before, after)
+		// замена документа оставляет в графе мёртвый узел, как и удаление
+		return r.ann.compact(ctx, r)
+	}
 	return nil
 }
 
+// annFingerprint — отметка таблицы внутри транзакции записи, если у
+// хранилища есть индекс; см. annIndex.advance.
+func (r *sqliteVectorRepo) annFingerprint(ctx context.Context, tx *sql.Tx) (string, error) {
+	if r.ann == nil {
+		return "", nil
+	}
+	return r.fingerprint(ctx, tx)
+}
+
 func (r *sqliteVectorRepo) SearchSimilar(ctx context.Context, queryVector []float32, limit int, filters map[string]string) ([]VectorDocument, error) {
-	query := `SELECT id, title, content, zone, tags, vector, metadata FROM lore_vectors`
-	args := []interface{}{}
+	if err := checkVector(r.model, r.dims, queryVector); err != nil {
+		return nil, err
+	}
+	if r.ann != nil {
+		if docs, ok, err := r.searchANN(ctx, queryVector, limit, filters); err != nil || ok {
+			return docs, err
+		}
+	}
+	return r.searchExact(ctx, queryVector, limit, filters)
+}
 
-	if zone, ok := filters["zone"]; ok && zone != "" {
-		query += ` WHERE zone = ?`
-		args = append(args, zone)
+// searchExact сравнивает запрос с каждым документом таблицы. Читаются только
+// id, вектор и его норма; лучшие limit держатся в куче, полные документы
+// загружаются в конце.
+func (r *sqliteVectorRepo) searchExact(ctx context.Context, queryVector []float32, limit int, filters map[string]string) ([]VectorDocument, error) {
+	if limit <= 0 {
+		return nil, nil
 	}
+	where, args := sqliteFilters(filters, "lore_vectors.")
+	where = append([]string{"model = ?"}, where...)
+	args = append([]any{r.model}, args...)
+	query := `SELECT id, vector, norm FROM lore_vectors WHERE ` + strings.Join(where, " AND ")
 
 	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
@@ -146,89 +277,377 @@ func (r *sqliteVectorRepo) SearchSimilar(ctx context.Context, queryVector []floa
 	}
 	defer rows.Close()
 
-	type docWithScore struct {
-		doc   VectorDocument
-		score float64
+	queryNorm := vectorNorm(queryVector)
+	top := newTopK(limit)
+	var buf []float32
+	for rows.Next() {
+		var id string
+		var blob sql.RawBytes
+		var norm float64
+		if err := rows.Scan(&id, &blob, &norm); err != nil {
+			continue
+		}
+		vec, err := decodeVector(blob, buf)
+		if err != nil {
+			continue
+		}
+		buf = vec
+		if norm == 0 {
+			// строка ещё в старом формате
+			norm = vectorNorm(vec)
+		}
+		top.push(id, cosine(queryVector, vec, queryNorm, norm))
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
 	}
+	return r.loadDocuments(ctx, top.sorted())
+}
 
-	var candidates []docWithScore
+// sqliteFilters — условия WHERE для фильтров zone, tags и faction; prefix —
+// имя таблицы lore_vectors в запросе с точкой.
+func sqliteFilters(filters map[string]string, prefix string) ([]string, []any) {
+	var where []string
+	var args []any
+	if zones := filterValues(filters, "zone"); len(zones) > 0 {
+		where = append(where, prefix+`zone IN (?`+strings.Repeat(",?", len(zones)-1)+`)`)
+		for _, zone := range zones {
+			args = append(args, zone)
+		}
+	}
+	// фракция в лоре — такой же тег, но условие на неё отдельное: нужны и тег, и фракция
+	for _, key := range []string{"tags", "faction"} {
+		if tags := filterValues(filters, key); len(tags) > 0 {
+			where = append(where, `EXISTS (SELECT 1 FROM json_each(`+prefix+`tags) WHERE value IN (?`+strings.Repeat(",?", len(tags)-1)+`))`)
+			for _, tag := range tags {
+				args = append(args, tag)
+			}
+		}
+	}
+	return where, args
+}
+
+// filterValues — значения фильтра key через запятую. Фильтры zone, tags и
+// faction независимы друг от друга (И), а внутри одного подходит документ
+// хотя бы с одним из значений (ИЛИ).
+func filterValues(filters map[string]string, key string) []string {
+	var values []string
+	for _, v := range strings.Split(filters[key], ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+// loadDocuments читает документы найденных id и возвращает их в том же
+// порядке, с оценками близости.
+func (r *sqliteVectorRepo) loadDocuments(ctx context.Context, hits []scoredID) ([]VectorDocument, error) {
+	if len(hits) == 0 {
+		return []VectorDocument{}, nil
+	}
+	args := make([]any, 0, len(hits)+1)
+	args = append(args, r.model)
+	for _, hit := range hits {
+		args = append(args, hit.id)
+	}
+	rows, err := r.db.QueryContext(ctx, `SELECT id, title, content, zone, tags, vector, metadata
+FROM lore_vectors WHERE model = ? AND id IN (?`+strings.Repeat(",?", len(hits)-1)+`)`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query documents: %w", err)
+	}
+	defer rows.Close()
 
+	byID := make(map[string]VectorDocument, len(hits))
 	for rows.Next() {
-		var doc VectorDocument
-		var tagsJSON, metadataJSON string
-		var vectorBlob []byte
-
-		err := rows.Scan(
-			&doc.ID,
-			&doc.Title,
-			&doc.Content,
-			&doc.Zone,
-			&tagsJSON,
-			&vectorBlob,
-			&metadataJSON,
-		)
+		doc, err := scanVectorDocument(rows)
 		if err != nil {
 			continue
 		}
+		doc.Model = r.model
+		byID[doc.ID] = doc
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
 
-		if err := json.Unmarshal([]byte(tagsJSON), &doc.Tags); err != nil {
-			continue
+	docs := make([]VectorDocument, 0, len(hits))
+	for _, hit := range hits {
+		if doc, ok := byID[hit.id]; ok {
+			doc.Score = float32(hit.score)
+			docs = append(docs, doc)
 		}
+	}
+	return docs, nil
+}
 
-		if err := json.Unmarshal([]byte(metadataJSON), &doc.Metadata); err != nil {
-			doc.Metadata = make(map[string]string)
-		}
+func (r *sqliteVectorRepo) GetDocuments(ctx context.Context, ids []string) ([]VectorDocument, error) {
+	hits := make([]scoredID, len(ids))
+	for i, id := range ids {
+		hits[i] = scoredID{id: id}
+	}
+	return r.loadDocuments(ctx, hits)
+}
 
-		vector, err := deserializeVector(vectorBlob)
+// GetByParent опирается на индекс по выражению из миграции 026: условие
+// должно совпадать с ним дословно.
+func (r *sqliteVectorRepo) GetByParent(ctx context.Context, parent string) ([]VectorDocument, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT id, title, content, zone, tags, vector, metadata
+FROM lore_vectors WHERE json_extract(metadata, '$.parent') = ? AND model = ?
+ORDER BY CAST(json_extract(metadata, '$.chunk_index') AS INTEGER), id`, parent, r.model)
+	if err != nil {
+		return nil, fmt.Errorf("query children: %w", err)
+	}
+	defer rows.Close()
+
+	docs := []VectorDocument{}
+	for rows.Next() {
+		doc, err := scanVectorDocument(rows)
 		if err != nil {
 			continue
 		}
-		doc.Vector = vector
+		doc.Model = r.model
+		docs = append(docs, doc)
+	}
+	return docs, rows.Err()
+}
+
+func scanVectorDocument(row interface{ Scan(...any) error }) (VectorDocument, error) {
+	var doc VectorDocument
+	var tagsJSON, metadataJSON string
+	var vectorBlob []byte
 
-		similarity := cosineSimilarity(queryVector, vector)
-		candidates = append(candidates, docWithScore{doc: doc, score: similarity})
+	if err := row.Scan(&doc.ID, &doc.Title, &doc.Content, &doc.Zone, &tagsJSON, &vectorBlob, &metadataJSON); err != nil {
+		return doc, err
+	}
+	if err := json.Unmarshal([]byte(tagsJSON), &doc.Tags); err != nil {
+		return doc, err
 	}
+	if err := json.Unmarshal([]byte(metadataJSON), &doc.Metadata); err != nil {
+		doc.Metadata = make(map[string]string)
+	}
+	vector, err := decodeVector(vectorBlob, nil)
+	if err != nil {
+		return doc, err
+	}
+	doc.Vector = vector
+	return doc, nil
+}
 
-	for i := 0; i < len(candidates); i++ {
-		for j := i + 1; j < len(candidates); j++ {
-			if candidates[j].score > candidates[i].score {
-				candidates[i], candidates[j] = candidates[j], candidates[i]
-			}
+func (r *sqliteVectorRepo) DeleteByZone(ctx context.Context, zone string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	before, err := r.annFingerprint(ctx, tx)
+	if err != nil {
+		return err
+	}
+	if r.fts {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM lore_fts WHERE rowid IN (SELECT rowid FROM lore_vectors WHERE model = ? AND zone = ?)`, r.model, zone); err != nil {
+			return err
 		}
 	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM lore_vectors WHERE model = ? AND zone = ?`, r.model, zone); err != nil {
+		return err
+	}
+	after, err := r.annFingerprint(ctx, tx)
+	if err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit transaction: %w", err)
+	}
+	if r.ann != nil {
+		r.ann.removeZone(zone)
+		r.ann.advance(before, after)
+		return r.ann.compact(ctx, r)
+	}
+	return nil
+}
 
-	if limit > len(candidates) {
-		limit = len(candidates)
+func (r *sqliteVectorRepo) ListBySource(ctx context.Context, source string) ([]DocumentInfo, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT id, title, metadata FROM lore_vectors
+WHERE model = ? AND json_extract(metadata, '$.source') = ? ORDER BY id`, r.model, source)
+	if err != nil {
+		return nil, fmt.Errorf("query documents: %w", err)
 	}
+	return scanDocumentInfos(rows)
+}
 
-	results := make([]VectorDocument, limit)
-	for i := 0; i < limit; i++ {
-		results[i] = candidates[i].doc
+func (r *sqliteVectorRepo) ListByDocument(ctx context.Context, document string) ([]DocumentInfo, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT id, title, metadata FROM lore_vectors
+WHERE model = ? AND (id = ? OR json_extract(metadata, '$.document') = ?) ORDER BY id`, r.model, document, document)
+	if err != nil {
+		return nil, fmt.Errorf("query documents: %w", err)
 	}
+	return scanDocumentInfos(rows)
+}
+
+func scanDocumentInfos(rows *sql.Rows) ([]DocumentInfo, error) {
+	defer rows.Close()
 
-	return results, nil
+	infos := []DocumentInfo{}
+	for rows.Next() {
+		var info DocumentInfo
+		var metadataJSON string
+		if err := rows.Scan(&info.ID, &info.Title, &metadataJSON); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(metadataJSON), &info.Metadata); err != nil {
+			info.Metadata = make(map[string]string)
+		}
+		infos = append(infos, info)
+	}
+	return infos, rows.Err()
 }
 
-func (r *sqliteVectorRepo) DeleteByZone(ctx context.Context, zone string) error {
-	_, err := r.db.ExecContext(ctx, `DELETE FROM lore_vectors WHERE zone = ?`, zone)
-	return err
+func (r *sqliteVectorRepo) DeleteDocuments(ctx context.Context, ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	before, err := r.annFingerprint(ctx, tx)
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		if err := r.dropKeywords(ctx, tx, id); err != nil {
+			return fmt.Errorf("drop keywords for %s: %w", id, err)
+		}
+		if _, err := tx.ExecContext(ctx, `DELETE FROM lore_vectors WHERE id = ? AND model = ?`, id, r.model); err != nil {
+			return fmt.Errorf("delete document %s: %w", id, err)
+		}
+	}
+	after, err := r.annFingerprint(ctx, tx)
+	if err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit transaction: %w", err)
+	}
+	if r.ann != nil {
+		r.ann.remove(ids)
+		r.ann.advance(before, after)
+		return r.ann.compact(ctx, r)
+	}
+	return nil
 }
 
+// DeleteAll удаляет документы модели хранилища; векторы других моделей остаются.
 func (r *sqliteVectorRepo) DeleteAll(ctx context.Context) error {
-	_, err := r.db.ExecContext(ctx, `DELETE FROM lore_vectors`)
-	return err
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if r.fts {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM lore_fts WHERE rowid IN (SELECT rowid FROM lore_vectors WHERE model = ?)`, r.model); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM lore_vectors WHERE model = ?`, r.model); err != nil {
+		return err
+	}
+	// пустой индекс совпадает с пустой таблицей, что бы в ней ни было до этого
+	after, err := r.annFingerprint(ctx, tx)
+	if err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit transaction: %w", err)
+	}
+	if r.ann != nil {
+		r.ann.reset(after)
+		r.ann.maybeSave(ctx, r)
+	}
+	return nil
+}
+
+func (r *sqliteVectorRepo) Flush(ctx context.Context) error {
+	if r.ann == nil {
+		return nil
+	}
+	return r.ann.save(ctx, r)
+}
+
+func (r *sqliteVectorRepo) Scan(ctx context.Context, batch int, fn func([]VectorDocument) error) error {
+	return ExportVectors(ctx, r.db, r.model, batch, fn)
+}
+
+// ExportVectors отдаёт документы модели model из lore_vectors в SQLite
+// пачками по batch в порядке id — для переноса в другое хранилище.
+func ExportVectors(ctx context.Context, db *sql.DB, model string, batch int, fn func([]VectorDocument) error) error {
+	last := ""
+	for {
+		rows, err := db.QueryContext(ctx, `SELECT id, title, content, zone, tags, vector, metadata
+FROM lore_vectors WHERE model = ? AND id > ? ORDER BY id LIMIT ?`, model, last, batch)
+		if err != nil {
+			return fmt.Errorf("query documents: %w", err)
+		}
+		docs := make([]VectorDocument, 0, batch)
+		var n int
+		for rows.Next() {
+			n++
+			doc, err := scanVectorDocument(rows)
+			if doc.ID == "" && err != nil {
+				rows.Close()
+				return fmt.Errorf("scan document: %w", err)
+			}
+			last = doc.ID
+			if err != nil {
+				continue
+			}
+			doc.Model = model
+			docs = append(docs, doc)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		if n == 0 {
+			return nil
+		}
+		if len(docs) > 0 {
+			if err := fn(docs); err != nil {
+				return err
+			}
+		}
+	}
 }
 
 func (r *sqliteVectorRepo) GetStats(ctx context.Context) (VectorStats, error) {
 	var stats VectorStats
 	stats.DocumentsByZone = make(map[string]int)
+	stats.Models = make(map[string]int)
 
-	row := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM lore_vectors`)
-	if err := row.Scan(&stats.TotalDocuments); err != nil {
-		return stats, fmt.Errorf("count total: %w", err)
+	models, err := r.db.QueryContext(ctx, `SELECT model, COUNT(*) FROM lore_vectors GROUP BY model`)
+	if err != nil {
+		return stats, fmt.Errorf("count by model: %w", err)
+	}
+	defer models.Close()
+	for models.Next() {
+		var model string
+		var count int
+		if err := models.Scan(&model, &count); err != nil {
+			return stats, err
+		}
+		stats.Models[model] = count
+	}
+	if err := models.Err(); err != nil {
+		return stats, err
 	}
+	stats.TotalDocuments = stats.Models[r.model]
 
-	rows, err := r.db.QueryContext(ctx, `SELECT zone, COUNT(*) FROM lore_vectors GROUP BY zone`)
+	rows, err := r.db.QueryContext(ctx, `SELECT zone, COUNT(*) FROM lore_vectors WHERE model = ? GROUP BY zone`, r.model)
 	if err != nil {
 		return stats, fmt.Errorf("count by zone: %w", err)
 	}
@@ -246,33 +665,71 @@ func (r *sqliteVectorRepo) GetStats(ctx context.Context) (VectorStats, error) {
 	return stats, nil
 }
 
-func serializeVector(vec []float32) ([]byte, error) {
-	return json.Marshal(vec)
+// cosine — косинусная близость при заранее посчитанных нормах. Векторы
+// другой размерности считаются непохожими.
+func cosine(a, b []float32, normA, normB float64) float64 {
+	if len(a) != len(b) || normA == 0 || normB == 0 {
+		return 0
+	}
+	var dot float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+	}
+	return dot / (normA * normB)
 }
 
-func deserializeVector(data []byte) ([]float32, error) {
-	var vec []float32
-	if err := json.Unmarshal(data, &vec); err != nil {
-		return nil, err
-	}
-	return vec, nil
+type scoredID struct {
+	id    string
+	score float64
+	seq   int
 }
 
-func cosineSimilarity(a, b []float32) float64 {
-	if len(a) != len(b) {
-		return 0.0
-	}
+// topK — куча из limit лучших результатов; в корне худший из них. При
+// равной близости выигрывает документ, прочитанный раньше.
+type topK struct {
+	limit int
+	seq   int
+	items []scoredID
+}
+
+func newTopK(limit int) *topK {
+	return &topK{limit: limit, items: make([]scoredID, 0, limit)}
+}
 
-	var dotProduct, normA, normB float64
-	for i := 0; i < len(a); i++ {
-		dotProduct += float64(a[i]) * float64(b[i])
-		normA += float64(a[i]) * float64(a[i])
-		normB += float64(b[i]) * float64(b[i])
+func (t *topK) Len() int { return len(t.items) }
+func (t *topK) Less(i, j int) bool {
+	a, b := t.items[i], t.items[j]
+	if a.score != b.score {
+		return a.score < b.score
 	}
+	return a.seq > b.seq
+}
+func (t *topK) Swap(i, j int) { t.items[i], t.items[j] = t.items[j], t.items[i] }
+func (t *topK) Push(x any)    { t.items = append(t.items, x.(scoredID)) }
+func (t *topK) Pop() any {
+	x := t.items[len(t.items)-1]
+	t.items = t.items[:len(t.items)-1]
+	return x
+}
 
-	if normA == 0 || normB == 0 {
-		return 0.0
+func (t *topK) push(id string, score float64) {
+	t.seq++
+	if len(t.items) < t.limit {
+		heap.Push(t, scoredID{id: id, score: score, seq: t.seq})
+		return
 	}
+	if score <= t.items[0].score {
+		return
+	}
+	t.items[0] = scoredID{id: id, score: score, seq: t.seq}
+	heap.Fix(t, 0)
+}
 
-	return dotProduct / (math.Sqrt(normA) * math.Sqrt(normB))
+// sorted — найденное по убыванию близости.
+func (t *topK) sorted() []scoredID {
+	out := make([]scoredID, len(t.items))
+	for i := len(out) - 1; i >= 0; i-- {
+		out[i] = heap.Pop(t).(scoredID)
+	}
+	return out
 }
@@ -4,6 +4,9 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
+	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
@@ -11,36 +14,88 @@ import (
 	"aurora/internal/repository"
 )
 
+// defaultMaxCharacters — сколько персонажей в игре может быть у одного аккаунта.
+const defaultMaxCharacters = 3
+
+var (
+	ErrNoCharacter       = errors.New("no active character")
+	ErrCharacterLimit    = errors.New("character limit reached")
+	ErrCharacterNotFound = errors.New("character not found")
+)
+
 type CharacterService struct {
-	repo *repository.CharacterRepository
+	repo          *repository.CharacterRepository
+	inventory     *InventoryService
+	reputation    *ReputationService
+	maxCharacters int
 }
 
-func NewCharacterService(repo *repository.CharacterRepository) *CharacterService {
-	return &CharacterService{repo: repo}
+func NewCharacterService(repo *repository.CharacterRepository, inventory *InventoryService) *CharacterService {
+	return &CharacterService{repo: repo, inventory: inventory, maxCharacters: defaultMaxCharacters}
+}
+
+// SetMaxCharacters задаёт лимит персонажей на аккаунт; n <= 0 оставляет прежний.
+func (s *CharacterService) SetMaxCharacters(n int) {
+	if n > 0 {
+		s.maxCharacters = n
+	}
+}
+
+func (s *CharacterService) MaxCharacters() int {
+	return s.maxCharacters
+}
+
+func (s *CharacterService) SetReputationService(rs *ReputationService) {
+	s.reputation = rs
+}
+
+// loadDetails подгружает эффекты, инвентарь и репутацию персонажа.
+func (s *CharacterService) loadDetails(ctx context.Context, ch *models.Character) {
+	effects, _ := s.repo.GetEffects(ctx, ch.ID)
+	ch.Effects = effects
+	items, _ := s.inventory.List(ctx, ch.ID)
+	ch.Items = items
+	if s.reputation != nil {
+		reps, _ := s.reputation.Standings(ctx, ch.ID)
+		ch.Reputation = reps
+	}
 }
 
 func (s *CharacterService) GetEffects(ctx context.Context, charID int64) ([]models.Effect, error) {
 	return s.repo.GetEffects(ctx, charID)
 }
 
-func (s *CharacterService) GetOrCreateByVK(ctx context.Context, vkUserID int64) (*models.Character, error) {
+// GetActive возвращает активного персонажа аккаунта с эффектами, инвентарём
+// и репутацией. ErrNoCharacter — у аккаунта нет ни одного персонажа в игре.
+func (s *CharacterService) GetActive(ctx context.Context, vkUserID int64) (*models.Character, error) {
 	ch, err := s.repo.GetByVKID(ctx, vkUserID)
-	if err == nil {
-		effects, _ := s.repo.GetEffects(ctx, ch.ID)
-		ch.Effects = effects
-		return ch, nil
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNoCharacter
+	}
+	if err != nil {
+		return nil, err
 	}
+	s.loadDetails(ctx, ch)
+	return ch, nil
+}
 
-	if err != sql.ErrNoRows {
+// Create заводит аккаунту нового персонажа и делает его активным.
+func (s *CharacterService) Create(ctx context.Context, vkUserID int64) (*models.Character, error) {
+	n, err := s.repo.CountActive(ctx, vkUserID)
+	if err != nil {
 		return nil, err
 	}
+	if n >= s.maxCharacters {
+		return nil, ErrCharacterLimit
+	}
 
 	newChar := &models.Character{
 		VKUserID:     vkUserID,
 		Name:         "Безымянный",
-		Status:       "жив",
+		Status:       models.LifeAlive,
 		LocationName: "Столица Авроры",
 		CombatPower:  10,
+		Level:        1,
 		CombatHealth: 100,
 		Gold:         0,
 		CreatedAt:    time.Now(),
@@ -54,18 +109,150 @@ func (s *CharacterService) GetOrCreateByVK(ctx context.Context, vkUserID int64)
 	return newChar, nil
 }
 
-func (s *CharacterService) UpdateCombatAndGold(ctx context.Context, ch *models.Character) error {
-	return s.repo.Update(ctx, ch)
+// Roster — все персонажи аккаунта, архивные в конце.
+func (s *CharacterService) Roster(ctx context.Context, vkUserID int64) ([]models.Character, error) {
+	return s.repo.ListByVKID(ctx, vkUserID)
+}
+
+// Switch делает активным персонажа аккаунта с подходящим именем.
+func (s *CharacterService) Switch(ctx context.Context, vkUserID int64, name string) (*models.Character, error) {
+	roster, err := s.repo.ListByVKID(ctx, vkUserID)
+	if err != nil {
+		return nil, err
+	}
+	var playable []models.Character
+	var names []string
+	for _, ch := range roster {
+		if !ch.IsArchived() {
+			playable = append(playable, ch)
+			names = append(names, ch.Name)
+		}
+	}
+	idx := MatchName(names, name)
+	if idx < 0 {
+		return nil, ErrCharacterNotFound
+	}
+	if err := s.repo.SetActive(ctx, vkUserID, playable[idx].ID); err != nil {
+		return nil, err
+	}
+	return s.GetActive(ctx, vkUserID)
+}
+
+// Restore возвращает архивного персонажа в игру, если позволяет лимит аккаунта.
+func (s *CharacterService) Restore(ctx context.Context, ch *models.Character) error {
+	if !ch.IsArchived() {
+		return nil
+	}
+	n, err := s.repo.CountActive(ctx, ch.VKUserID)
+	if err != nil {
+		return err
+	}
+	if n >= s.maxCharacters {
+		return ErrCharacterLimit
+	}
+	if err := s.repo.Unarchive(ctx, ch.ID); err != nil {
+		return err
+	}
+	ch.ArchivedAt.Valid = false
+	return nil
+}
+
+// activeOrCreate — персонаж, к которому подаётся анкета: активный или новый.
+func (s *CharacterService) activeOrCreate(ctx context.Context, vkUserID int64) (*models.Character, error) {
+	ch, err := s.GetActive(ctx, vkUserID)
+	if errors.Is(err, ErrNoCharacter) {
+		return s.Create(ctx, vkUserID)
+	}
+	return ch, err
+}
+
+// NameTaken сообщает, носит ли имя другой персонаж — чужой или неактивный
+// персонаж того же игрока.
+func (s *CharacterService) NameTaken(ctx context.Context, vkUserID int64, name string) (bool, error) {
+	var activeID int64
+	active, err := s.GetActive(ctx, vkUserID)
+	switch {
+	case err == nil:
+		activeID = active.ID
+	case !errors.Is(err, ErrNoCharacter):
+		return false, err
+	}
+	return s.nameTakenBy(ctx, name, activeID)
+}
+
+// nameTakenBy — носит ли имя кто-то, кроме персонажа charID.
+func (s *CharacterService) nameTakenBy(ctx context.Context, name string, charID int64) (bool, error) {
+	other, err := s.repo.GetByName(ctx, name)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return other.ID != charID, nil
 }
 
-func (s *CharacterService) UpdateFromNormalizedForm(ctx context.Context, vkID int64, f *models.NormalizedCharacterForm) (*models.Character, error) {
-	ch, err := s.GetOrCreateByVK(ctx, vkID)
+// ChangeHealth сдвигает здоровье персонажа на delta от текущего в базе: урон
+// хода не затирает лечение и эффекты, пришедшие, пока ход обдумывался.
+func (s *CharacterService) ChangeHealth(ctx context.Context, ch *models.Character, delta int) error {
+	hp, err := s.repo.ChangeHealth(ctx, ch.ID, delta, ch.MaxHealth())
 	if err != nil {
+		return err
+	}
+	ch.CombatHealth = hp
+	return nil
+}
+
+var mentionRe = regexp.MustCompile(`^\[id(\d+)\|[^\]]*\]$`)
+
+// FindPlayer ищет персонажа по упоминанию [id123|Имя], vk id или имени.
+func (s *CharacterService) FindPlayer(ctx context.Context, ref string) (*models.Character, error) {
+	ref = strings.TrimSpace(ref)
+	if m := mentionRe.FindStringSubmatch(ref); m != nil {
+		ref = m[1]
+	}
+	var ch *models.Character
+	var err error
+	if id, perr := strconv.ParseInt(ref, 10, 64); perr == nil {
+		ch, err = s.repo.GetByVKID(ctx, id)
+	} else {
+		ch, err = s.repo.GetByName(ctx, ref)
+	}
+	if err != nil {
+		return nil, err
+	}
+	s.loadDetails(ctx, ch)
+	return ch, nil
+}
+
+// ApplySheet переносит одобренную ГМом анкету в персонажа. Способности,
+// полученные в игре, при этом сохраняются.
+func (s *CharacterService) ApplySheet(ctx context.Context, charID int64, f *models.NormalizedCharacterForm) (*models.Character, error) {
+	ch, err := s.sheetCharacter(ctx, charID, f)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.Update(ctx, ch); err != nil {
 		return nil, err
 	}
+	return ch, s.importSheetItems(ctx, ch, f)
+}
 
-	if strings.TrimSpace(f.Name) != "" {
-		ch.Name = strings.TrimSpace(f.Name)
+// sheetCharacter — персонаж charID с полями анкеты f; в базу не пишется.
+func (s *CharacterService) sheetCharacter(ctx context.Context, charID int64, f *models.NormalizedCharacterForm) (*models.Character, error) {
+	ch, err := s.repo.GetByID(ctx, charID)
+	if err != nil {
+		return nil, err
+	}
+	if name := strings.TrimSpace(f.Name); name != "" {
+		taken, err := s.nameTakenBy(ctx, name, ch.ID)
+		if err != nil {
+			return nil, err
+		}
+		if taken {
+			return nil, ErrNameTaken
+		}
+		ch.Name = name
 	}
 	if strings.TrimSpace(f.Gender) != "" {
 		ch.Gender = strings.TrimSpace(f.Gender)
@@ -76,8 +263,21 @@ func (s *CharacterService) UpdateFromNormalizedForm(ctx context.Context, vkID in
 	if strings.TrimSpace(f.Country) != "" {
 		ch.Country = strings.TrimSpace(f.Country)
 	}
+	if strings.TrimSpace(f.Class) != "" {
+		ch.Class = strings.TrimSpace(f.Class)
+	}
 	if len(f.Abilities) > 0 {
-		ch.Abilities = strings.Join(f.Abilities, "; ")
+		abilities := append([]string(nil), f.Abilities...)
+		earned, err := s.repo.GetAbilities(ctx, ch.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, a := range earned {
+			if !containsFold(abilities, a.Name) {
+				abilities = append(abilities, a.Name)
+			}
+		}
+		ch.Abilities = models.JoinAbilities(abilities)
 	}
 	if strings.TrimSpace(f.Bio) != "" {
 		ch.Bio = strings.TrimSpace(f.Bio)
@@ -100,12 +300,20 @@ func (s *CharacterService) UpdateFromNormalizedForm(ctx context.Context, vkID in
 
 	sheetJSON, _ := json.Marshal(f)
 	ch.SheetJSON = string(sheetJSON)
+	return ch, nil
+}
 
-	if err := s.repo.Update(ctx, ch); err != nil {
-		return nil, err
+// importSheetItems выдаёт предметы из анкеты. Уже имеющиеся не дублируются,
+// так что повтор после сбоя безопасен.
+func (s *CharacterService) importSheetItems(ctx context.Context, ch *models.Character, f *models.NormalizedCharacterForm) error {
+	if len(f.Inventory) == 0 {
+		return nil
 	}
-
-	return ch, nil
+	if err := s.inventory.ImportFromForm(ctx, ch.ID, f.Inventory); err != nil {
+		return err
+	}
+	ch.Items, _ = s.inventory.List(ctx, ch.ID)
+	return nil
 }
 
 type Form struct {
@@ -114,12 +322,11 @@ type Form struct {
 	Traits       string
 	Goal         string
 	LocationName string
-	Abilities    string
 	Bio          string
 }
 
 func (s *CharacterService) UpdateFromForm(ctx context.Context, vkUserID int64, f Form) (*models.Character, error) {
-	ch, err := s.GetOrCreateByVK(ctx, vkUserID)
+	ch, err := s.activeOrCreate(ctx, vkUserID)
 	if err != nil {
 		return nil, err
 	}
@@ -139,9 +346,6 @@ func (s *CharacterService) UpdateFromForm(ctx context.Context, vkUserID int64, f
 	if f.LocationName != "" {
 		ch.LocationName = f.LocationName
 	}
-	if f.Abilities != "" {
-		ch.Abilities = f.Abilities
-	}
 	if f.Bio != "" {
 		ch.Bio = f.Bio
 	}
@@ -153,18 +357,11 @@ func (s *CharacterService) UpdateFromForm(ctx context.Context, vkUserID int64, f
 	return ch, nil
 }
 
-func (s *CharacterService) TickTurn(ctx context.Context, charID int64) ([]string, error) {
-	expired, err := s.repo.GetExpiringEffects(ctx, charID)
-	if err != nil {
-		return nil, err
-	}
-
-	if err := s.repo.DecrementEffectDurations(ctx, charID); err != nil {
-		return nil, err
+func containsFold(list []string, s string) bool {
+	for _, v := range list {
+		if strings.EqualFold(strings.TrimSpace(v), strings.TrimSpace(s)) {
+			return true
+		}
 	}
-	if err := s.repo.DeleteExpiredEffects(ctx, charID); err != nil {
-		return nil, err
-	}
-
-	return expired, nil
+	return false
 }
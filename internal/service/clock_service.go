@@ -0,0 +1,393 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"aurora/internal/lore"
+	"aurora/internal/models"
+	"aurora/internal/repository"
+)
+
+const minutesPerDay = 24 * 60
+
+var ErrInvalidDuration = errors.New("invalid duration")
+
+// GameClock отдаёт текущее игровое время в минутах от начала летоисчисления.
+type GameClock interface {
+	Now() int64
+}
+
+// WorldDate — игровое время, разложенное по календарю лора.
+type WorldDate struct {
+	Year      int
+	Month     int
+	Day       int
+	Hour      int
+	Minute    int
+	MonthName string
+	Season    string
+	Weather   string
+	Holiday   *lore.Holiday
+}
+
+// ClockTick — что произошло в мире за один сдвиг часов. Notices собраны
+// по vk_user_id игроков, которым стоит об этом сообщить.
+type ClockTick struct {
+	From    int64
+	To      int64
+	Turns   int
+	Expired []models.Effect
+	Failed  []models.Quest
+	Life    []LifeChange
+	Notices map[int64][]string
+}
+
+// ClockService ведёт мировые часы. Время двигается действиями игроков,
+// командой ГМа или по таймеру; при каждом сдвиге истекают эффекты и сроки квестов.
+type ClockService struct {
+	repo    *repository.ClockRepository
+	cal     *lore.Calendar
+	chars   *repository.CharacterRepository
+	quests  *repository.QuestRepository
+	effects *EffectService
+	life    *LifeService
+
+	mu       sync.Mutex
+	minutes  int64
+	notifier func(*ClockTick)
+	// tick держится весь сдвиг, от записи времени до сроков квестов: иначе
+	// два сдвига подряд (таймер и действие игрока) прочтут одни и те же
+	// эффекты и применят их урон дважды.
+	tick sync.Mutex
+	// passed — личные минуты PassTime, не набравшие целого хода; под tick.
+	passed map[int64]int64
+}
+
+func NewClockService(repo *repository.ClockRepository, cal *lore.Calendar, chars *repository.CharacterRepository, quests *repository.QuestRepository, effects *EffectService) *ClockService {
+	if cal == nil {
+		cal = &lore.Calendar{}
+	}
+	if cal.DaysPerMonth <= 0 {
+		cal.DaysPerMonth = 30
+	}
+	if cal.TurnMinutes <= 0 {
+		cal.TurnMinutes = 60
+	}
+	if len(cal.Months) == 0 {
+		for i := 1; i <= 12; i++ {
+			cal.Months = append(cal.Months, lore.CalendarMonth{Name: fmt.Sprintf("%d-й месяц", i)})
+		}
+	}
+	return &ClockService{repo: repo, cal: cal, chars: chars, quests: quests, effects: effects, passed: make(map[int64]int64)}
+}
+
+// SetNotifier задаёт, кому отдавать итоги сдвига часов (обычно — доставке сообщений).
+func (s *ClockService) SetNotifier(fn func(*ClockTick)) {
+	s.mu.Lock()
+	s.notifier = fn
+	s.mu.Unlock()
+}
+
+// SetLife подключает правила смерти: после хода эффектов проверяются
+// упавшие без сознания и истёкшие кровью.
+func (s *ClockService) SetLife(life *LifeService) {
+	s.life = life
+}
+
+// Load поднимает время из БД; при первом запуске заводит часы с даты начала календаря.
+func (s *ClockService) Load(ctx context.Context) error {
+	m, ok, err := s.repo.Get(ctx)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		st := s.cal.Start
+		m = s.toMinutes(st.Year, st.Month, st.Day, st.Hour)
+		if err := s.repo.Set(ctx, m); err != nil {
+			return err
+		}
+	}
+	s.mu.Lock()
+	s.minutes = m
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *ClockService) Now() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.minutes
+}
+
+// Turn — номер текущего хода мира.
+func (s *ClockService) Turn() int64 {
+	return s.Now() / int64(s.cal.TurnMinutes)
+}
+
+// Advance сдвигает часы вперёд, проматывает эффекты на прошедшие ходы
+// и проваливает квесты с истёкшим сроком.
+func (s *ClockService) Advance(ctx context.Context, minutes int64) (*ClockTick, error) {
+	if minutes <= 0 {
+		return nil, ErrInvalidDuration
+	}
+	tick, err := s.advance(ctx, minutes)
+	if err != nil {
+		return tick, err
+	}
+	s.notify(tick)
+	return tick, nil
+}
+
+func (s *ClockService) advance(ctx context.Context, minutes int64) (*ClockTick, error) {
+	s.tick.Lock()
+	defer s.tick.Unlock()
+
+	s.mu.Lock()
+	from := s.minutes
+	to := from + minutes
+	if err := s.repo.Set(ctx, to); err != nil {
+		s.mu.Unlock()
+		return nil, err
+	}
+	s.minutes = to
+	s.mu.Unlock()
+
+	turn := int64(s.cal.TurnMinutes)
+	tick := &ClockTick{From: from, To: to, Turns: int(to/turn - from/turn), Notices: make(map[int64][]string)}
+	if err := s.runEffects(ctx, tick, 0); err != nil {
+		return tick, err
+	}
+
+	failed, err := s.quests.FailOverdue(ctx, to)
+	if err != nil {
+		return tick, fmt.Errorf("fail overdue quests: %w", err)
+	}
+	tick.Failed = failed
+	for _, q := range failed {
+		s.addNotice(ctx, tick, q.CharacterID, "⌛ Срок квеста «"+q.Title+"» истёк — квест провален.")
+	}
+	return tick, nil
+}
+
+// PassTime проматывает minutes для одного персонажа, не трогая мировые часы:
+// его эффекты отрабатывают прошедшие ходы, у остальных ничего не меняется.
+// Так долгая дорога одного игрока не сжигает таймеры всех прочих. Минуты,
+// не набравшие хода, копятся до следующего вызова.
+func (s *ClockService) PassTime(ctx context.Context, charID, minutes int64) (*ClockTick, error) {
+	if minutes <= 0 {
+		return nil, ErrInvalidDuration
+	}
+	tick, err := s.passTime(ctx, charID, minutes)
+	if err != nil {
+		return tick, err
+	}
+	s.notify(tick)
+	return tick, nil
+}
+
+func (s *ClockService) passTime(ctx context.Context, charID, minutes int64) (*ClockTick, error) {
+	s.tick.Lock()
+	defer s.tick.Unlock()
+
+	now := s.Now()
+	turn := int64(s.cal.TurnMinutes)
+	total := s.passed[charID] + minutes
+	if rest := total % turn; rest > 0 {
+		s.passed[charID] = rest
+	} else {
+		delete(s.passed, charID)
+	}
+
+	tick := &ClockTick{From: now, To: now, Turns: int(total / turn), Notices: make(map[int64][]string)}
+	return tick, s.runEffects(ctx, tick, charID)
+}
+
+// notify отдаёт итоги сдвига получателю уже без tick: доставка сообщений
+// медленная и не должна держать часы.
+func (s *ClockService) notify(tick *ClockTick) {
+	s.mu.Lock()
+	fn := s.notifier
+	s.mu.Unlock()
+	if fn != nil && len(tick.Notices) > 0 {
+		fn(tick)
+	}
+}
+
+// runEffects проматывает эффекты на tick.Turns ходов (charID 0 — у всех) и
+// проверяет, кто от них упал.
+func (s *ClockService) runEffects(ctx context.Context, tick *ClockTick, charID int64) error {
+	ticks, err := s.effects.Tick(ctx, charID, tick.Turns)
+	if err != nil {
+		return fmt.Errorf("tick effects: %w", err)
+	}
+	for _, t := range ticks {
+		tick.Expired = append(tick.Expired, t.Expired...)
+		if text := FormatEffectTick(t); text != "" {
+			s.addNotice(ctx, tick, t.CharacterID, text)
+		}
+	}
+
+	if s.life != nil {
+		changes, err := s.life.Sweep(ctx)
+		tick.Life = changes
+		for _, c := range changes {
+			if text := c.Text(); text != "" {
+				s.addNotice(ctx, tick, c.CharacterID, text)
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("life sweep: %w", err)
+		}
+	}
+	return nil
+}
+
+func (s *ClockService) addNotice(ctx context.Context, tick *ClockTick, charID int64, text string) {
+	vkID, err := s.chars.GetVKUserID(ctx, charID)
+	if err != nil || vkID <= 0 {
+		return
+	}
+	tick.Notices[vkID] = append(tick.Notices[vkID], text)
+}
+
+func (s *ClockService) daysPerYear() int64 {
+	return int64(len(s.cal.Months) * s.cal.DaysPerMonth)
+}
+
+func (s *ClockService) toMinutes(year, month, day, hour int) int64 {
+	if year < 1 {
+		year = 1
+	}
+	if month < 1 {
+		month = 1
+	}
+	if day < 1 {
+		day = 1
+	}
+	days := int64(year-1)*s.daysPerYear() + int64((month-1)*s.cal.DaysPerMonth+day-1)
+	return days*minutesPerDay + int64(hour)*60
+}
+
+func (s *ClockService) Date(m int64) WorldDate {
+	days := m / minutesPerDay
+	inDay := int(m % minutesPerDay)
+	dayOfYear := int(days % s.daysPerYear())
+
+	d := WorldDate{
+		Year:   int(days/s.daysPerYear()) + 1,
+		Month:  dayOfYear/s.cal.DaysPerMonth + 1,
+		Day:    dayOfYear%s.cal.DaysPerMonth + 1,
+		Hour:   inDay / 60,
+		Minute: inDay % 60,
+	}
+	month := s.cal.Months[d.Month-1]
+	d.MonthName = month.Name
+	d.Season = month.Season
+	if weather := s.cal.Seasons[d.Season]; len(weather) > 0 {
+		// погода держится весь день: выбор зависит только от номера дня
+		d.Weather = weather[int(uint64(days)*2654435761>>16)%len(weather)]
+	}
+	for i, h := range s.cal.Holidays {
+		if h.Month == d.Month && h.Day == d.Day {
+			d.Holiday = &s.cal.Holidays[i]
+			break
+		}
+	}
+	return d
+}
+
+func dayPart(hour int) string {
+	switch {
+	case hour < 5:
+		return "ночь"
+	case hour < 11:
+		return "утро"
+	case hour < 17:
+		return "день"
+	case hour < 22:
+		return "вечер"
+	default:
+		return "ночь"
+	}
+}
+
+// Describe — текущая дата, время и погода одной строкой для игроков и промптов.
+func (s *ClockService) Describe() string {
+	return s.FormatDate(s.Date(s.Now()))
+}
+
+func (s *ClockService) FormatDate(d WorldDate) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d-й день месяца %s, %d г.", d.Day, d.MonthName, d.Year)
+	if s.cal.Era != "" {
+		b.WriteString(" " + s.cal.Era)
+	}
+	fmt.Fprintf(&b, ", %02d:%02d (%s)", d.Hour, d.Minute, dayPart(d.Hour))
+	if d.Season != "" {
+		b.WriteString(", " + d.Season)
+	}
+	if d.Weather != "" {
+		b.WriteString(". Погода: " + d.Weather)
+	}
+	if d.Holiday != nil {
+		b.WriteString(". Праздник: " + d.Holiday.Name)
+		if d.Holiday.Description != "" {
+			b.WriteString(" — " + d.Holiday.Description)
+		}
+	}
+	return b.String()
+}
+
+var durationPartRe = regexp.MustCompile(`(\d+)\s*([a-zа-яё]+)`)
+
+// ParseGameDuration разбирает сдвиг вида «3ч», «2д 6ч», «+45m» в игровые минуты.
+func ParseGameDuration(s string) (int64, error) {
+	s = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(s), "+"))
+	parts := durationPartRe.FindAllStringSubmatch(s, -1)
+	if len(parts) == 0 {
+		return 0, ErrInvalidDuration
+	}
+	var total int64
+	for _, p := range parts {
+		n, err := strconv.ParseInt(p[1], 10, 64)
+		if err != nil {
+			return 0, ErrInvalidDuration
+		}
+		switch []rune(p[2])[0] {
+		case 'm', 'м':
+			total += n
+		case 'h', 'ч':
+			total += n * 60
+		case 'd', 'д':
+			total += n * minutesPerDay
+		default:
+			return 0, ErrInvalidDuration
+		}
+	}
+	if total <= 0 {
+		return 0, ErrInvalidDuration
+	}
+	return total, nil
+}
+
+// FormatGameDuration — длительность в днях и часах для ответов игрокам.
+func FormatGameDuration(m int64) string {
+	days, hours, mins := m/minutesPerDay, m%minutesPerDay/60, m%60
+	var parts []string
+	if days > 0 {
+		parts = append(parts, fmt.Sprintf("%d д", days))
+	}
+	if hours > 0 {
+		parts = append(parts, fmt.Sprintf("%d ч", hours))
+	}
+	if mins > 0 || len(parts) == 0 {
+		parts = append(parts, fmt.Sprintf("%d мин", mins))
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,70 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"aurora/internal/lore"
+	"aurora/internal/models"
+	"aurora/internal/repository"
+)
+
+func TestPassTimeCarriesRemainder(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+	chars := repository.NewCharacterRepository(db)
+	clk := NewClockService(repository.NewClockRepository(db), &lore.Calendar{TurnMinutes: 60},
+		chars, repository.NewQuestRepository(db), NewEffectService(chars, nil))
+	if err := clk.Load(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	charID, err := chars.Create(ctx, &models.Character{VKUserID: 1, Name: "Путник", Status: models.LifeAlive, CombatHealth: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := chars.AddEffect(ctx, models.Effect{CharacterID: charID, Name: "Яд", Duration: 10, HPPerTurn: -5}); err != nil {
+		t.Fatal(err)
+	}
+
+	var notified int
+	clk.SetNotifier(func(*ClockTick) {
+		// итоги отдаются уже после того, как часы отпущены
+		if !clk.tick.TryLock() {
+			t.Error("notifier called with the tick lock held")
+			return
+		}
+		clk.tick.Unlock()
+		notified++
+	})
+
+	for _, c := range []struct {
+		minutes int64
+		turns   int
+		hp      int
+	}{
+		{40, 0, 100},
+		{40, 1, 95}, // 80 минут: ход и 20 в остатке
+		{100, 2, 85},
+		{59, 0, 85},
+		{1, 1, 80},
+	} {
+		tick, err := clk.PassTime(ctx, charID, c.minutes)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tick.Turns != c.turns {
+			t.Fatalf("+%d min: %d turns, want %d", c.minutes, tick.Turns, c.turns)
+		}
+		ch, err := chars.GetByID(ctx, charID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ch.CombatHealth != c.hp {
+			t.Fatalf("+%d min: hp %d, want %d", c.minutes, ch.CombatHealth, c.hp)
+		}
+	}
+	if notified != 3 {
+		t.Fatalf("notified %d times, want 3", notified)
+	}
+}
@@ -0,0 +1,210 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"aurora/internal/llm"
+	"aurora/internal/models"
+)
+
+// combatTTL — бой без ходов дольше этого срока считается брошенным.
+const combatTTL = 30 * time.Minute
+
+var (
+	ErrInCombat    = errors.New("already in combat")
+	ErrNotInCombat = errors.New("not in combat")
+	ErrRoundBusy   = errors.New("combat round in progress")
+)
+
+// Encounter — текущая схватка персонажа. Здоровье персонажа хранится в БД,
+// состояние противника живёт только в памяти.
+type Encounter struct {
+	CharacterID int64
+	Enemy       string
+	// Spawned — противника выставила сцена (дорога) или ГМ. Опыт дают только
+	// такие бои: врага, которого назвал сам игрок, можно звать без конца.
+	Spawned     bool
+	EnemyHP     int
+	EnemyStatus string
+	Round       int
+	UpdatedAt   time.Time
+
+	// busy — ход уже разыгрывается: второй параллельный ход не начнётся и
+	// не завершит бой повторно.
+	busy bool
+}
+
+type CombatRound struct {
+	Result    llm.CombatResult
+	Encounter Encounter
+	Won       bool
+	Reward    *RewardSummary
+	Life      *LifeChange
+}
+
+type CombatService struct {
+	llm     llm.Client
+	chars   *CharacterService
+	rewards *RewardService
+	life    *LifeService
+
+	mu     sync.Mutex
+	active map[int64]*Encounter
+}
+
+func NewCombatService(llm llm.Client, chars *CharacterService, rewards *RewardService) *CombatService {
+	return &CombatService{
+		llm:     llm,
+		chars:   chars,
+		rewards: rewards,
+		active:  make(map[int64]*Encounter),
+	}
+}
+
+// SetLife подключает правила смерти: упавший до нуля персонаж выбывает из боя.
+func (s *CombatService) SetLife(life *LifeService) {
+	s.life = life
+}
+
+func (s *CombatService) Active(charID int64) (Encounter, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e := s.activeLocked(charID)
+	if e == nil {
+		return Encounter{}, false
+	}
+	return *e, true
+}
+
+func (s *CombatService) activeLocked(charID int64) *Encounter {
+	e := s.active[charID]
+	if e != nil && time.Since(e.UpdatedAt) > combatTTL {
+		delete(s.active, charID)
+		return nil
+	}
+	return e
+}
+
+// Start начинает бой с противником, которого назвал сам игрок. Такой бой
+// опыта не даёт.
+func (s *CombatService) Start(charID int64, enemy string) (Encounter, error) {
+	return s.start(charID, enemy, false)
+}
+
+// Spawn выставляет против персонажа противника от лица сцены или ГМа.
+func (s *CombatService) Spawn(charID int64, enemy string) (Encounter, error) {
+	return s.start(charID, enemy, true)
+}
+
+func (s *CombatService) start(charID int64, enemy string, spawned bool) (Encounter, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.activeLocked(charID) != nil {
+		return Encounter{}, ErrInCombat
+	}
+	e := &Encounter{
+		CharacterID: charID,
+		Enemy:       strings.TrimSpace(enemy),
+		Spawned:     spawned,
+		EnemyHP:     100,
+		UpdatedAt:   time.Now(),
+	}
+	s.active[charID] = e
+	return *e, nil
+}
+
+// Flee завершает бой без награды.
+func (s *CombatService) Flee(charID int64) (Encounter, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e := s.activeLocked(charID)
+	if e == nil {
+		return Encounter{}, ErrNotInCombat
+	}
+	delete(s.active, charID)
+	return *e, nil
+}
+
+// Round разыгрывает один ход: модель описывает его, лимиты урона
+// применяются в клиенте, здоровье персонажа сохраняется, а за победу над
+// выставленным противником начисляется опыт. Ход захватывает схватку, так
+// что награду получает только тот ход, который её завершил.
+func (s *CombatService) Round(ctx context.Context, ch *models.Character, sc models.Scene, quest *models.Quest, history, action string) (*CombatRound, error) {
+	s.mu.Lock()
+	e := s.activeLocked(ch.ID)
+	if e == nil {
+		s.mu.Unlock()
+		return nil, ErrNotInCombat
+	}
+	if e.busy {
+		s.mu.Unlock()
+		return nil, ErrRoundBusy
+	}
+	e.busy = true
+	snapshot := *e
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		e.busy = false
+		s.mu.Unlock()
+	}()
+
+	res, err := s.llm.GenerateCombatTurn(ctx, llm.CombatContext{
+		Character:    *ch,
+		Scene:        sc,
+		Quest:        quest,
+		History:      history,
+		PlayerAction: action,
+		EnemyName:    snapshot.Enemy,
+		EnemyHP:      snapshot.EnemyHP,
+		EnemyStatus:  snapshot.EnemyStatus,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.chars.ChangeHealth(ctx, ch, res.PlayerHP-ch.CombatHealth); err != nil {
+		return nil, err
+	}
+	res.PlayerHP = ch.CombatHealth
+	var change *LifeChange
+	if s.life != nil {
+		if change, err = s.life.Sync(ctx, ch); err != nil {
+			return nil, err
+		}
+		if ch.Status == models.LifeDying || ch.Status == models.LifeDead {
+			res.IsFinished = true
+			res.Winner = "enemy"
+		}
+	}
+
+	s.mu.Lock()
+	e.EnemyHP = res.EnemyHP
+	if res.EnemyStatus != "" {
+		e.EnemyStatus = res.EnemyStatus
+	}
+	e.Round++
+	e.UpdatedAt = time.Now()
+	out := &CombatRound{Result: res, Encounter: *e, Life: change}
+	// пока шёл ход, от схватки могли сбежать: такой бой уже не выиграть
+	claimed := s.active[ch.ID] == e
+	if res.IsFinished && claimed {
+		delete(s.active, ch.ID)
+	}
+	s.mu.Unlock()
+
+	if res.IsFinished && res.Winner == "player" && claimed {
+		out.Won = true
+		if snapshot.Spawned {
+			out.Reward, err = s.rewards.ApplyCombat(ctx, ch)
+			if err != nil {
+				return out, err
+			}
+		}
+	}
+	return out, nil
+}
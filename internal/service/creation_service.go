@@ -0,0 +1,440 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"aurora/internal/lore"
+	"aurora/internal/models"
+)
+
+// creationTTL — сколько ждёт незаконченная анкета без ответов игрока.
+const creationTTL = 30 * time.Minute
+
+const (
+	maxNameLen       = 40
+	maxFormAbilities = 5
+	maxAbilityLen    = 60
+	minBioLen        = 30
+	maxBioLen        = 3000
+)
+
+var (
+	ErrNoDraft          = errors.New("no character draft")
+	ErrDraftIncomplete  = errors.New("character draft is not finished")
+	ErrUnknownField     = errors.New("unknown form field")
+	ErrNameInvalid      = errors.New("invalid character name")
+	ErrNameTaken        = errors.New("character name is taken")
+	ErrUnknownOption    = errors.New("option not found in lore")
+	ErrAbilitiesInvalid = errors.New("invalid abilities list")
+	ErrBioInvalid       = errors.New("invalid bio")
+)
+
+// CreationStep — шаг мастера анкеты.
+type CreationStep string
+
+const (
+	StepName      CreationStep = "name"
+	StepRace      CreationStep = "race"
+	StepCountry   CreationStep = "country"
+	StepClass     CreationStep = "class"
+	StepAbilities CreationStep = "abilities"
+	StepBio       CreationStep = "bio"
+	StepPreview   CreationStep = "preview"
+)
+
+var creationSteps = []CreationStep{StepName, StepRace, StepCountry, StepClass, StepAbilities, StepBio, StepPreview}
+
+// creationFields — как игрок называет поля в «изменить <поле>».
+var creationFields = map[string]CreationStep{
+	"имя":         StepName,
+	"раса":        StepRace,
+	"расу":        StepRace,
+	"страна":      StepCountry,
+	"страну":      StepCountry,
+	"родина":      StepCountry,
+	"родину":      StepCountry,
+	"класс":       StepClass,
+	"способности": StepAbilities,
+	"биография":   StepBio,
+	"биографию":   StepBio,
+	"био":         StepBio,
+}
+
+// CreationDraft — анкета, которую игрок заполняет по шагам. Editing значит,
+// что после ответа мастер вернётся к предпросмотру, а не к следующему шагу.
+type CreationDraft struct {
+	VKUserID  int64
+	PeerID    int
+	Step      CreationStep
+	Editing   bool
+	Form      models.NormalizedCharacterForm
+	ExpiresAt time.Time
+}
+
+// CreationPrompt — вопрос мастера и варианты ответа для клавиатуры.
+type CreationPrompt struct {
+	Text    string
+	Options []string
+}
+
+// CreationService ведёт пошаговое заполнение анкеты. Черновики живут в памяти;
+// раса, страна и класс проверяются по справочнику лора.
+type CreationService struct {
+	book   *lore.CreationBook
+	chars  *CharacterService
+	sheets *SheetService
+
+	mu     sync.Mutex
+	drafts map[int64]*CreationDraft
+}
+
+func NewCreationService(book *lore.CreationBook, chars *CharacterService, sheets *SheetService) *CreationService {
+	if book == nil {
+		book = &lore.CreationBook{}
+	}
+	return &CreationService{book: book, chars: chars, sheets: sheets, drafts: make(map[int64]*CreationDraft)}
+}
+
+// Start начинает анкету заново, отбрасывая прежний черновик.
+func (s *CreationService) Start(vkUserID int64, peerID int) CreationDraft {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	d := &CreationDraft{VKUserID: vkUserID, PeerID: peerID, Step: StepName, ExpiresAt: time.Now().Add(creationTTL)}
+	s.drafts[vkUserID] = d
+	return *d
+}
+
+// Resume открывает прежнюю анкету сразу на предпросмотре — например, когда
+// ГМ вернул её на доработку.
+func (s *CreationService) Resume(vkUserID int64, peerID int, f models.NormalizedCharacterForm) CreationDraft {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	d := &CreationDraft{VKUserID: vkUserID, PeerID: peerID, Step: StepPreview, Form: f, ExpiresAt: time.Now().Add(creationTTL)}
+	s.drafts[vkUserID] = d
+	return *d
+}
+
+// Draft — незаконченная анкета игрока, если она ещё не истекла.
+func (s *CreationService) Draft(vkUserID int64) (CreationDraft, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	d := s.draft(vkUserID)
+	if d == nil {
+		return CreationDraft{}, false
+	}
+	return *d, true
+}
+
+func (s *CreationService) draft(vkUserID int64) *CreationDraft {
+	d, ok := s.drafts[vkUserID]
+	if !ok {
+		return nil
+	}
+	if time.Now().After(d.ExpiresAt) {
+		delete(s.drafts, vkUserID)
+		return nil
+	}
+	return d
+}
+
+func (s *CreationService) Cancel(vkUserID int64) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	d := s.draft(vkUserID)
+	delete(s.drafts, vkUserID)
+	return d != nil
+}
+
+// Answer проверяет ответ на текущий шаг и переходит к следующему. При ошибке
+// черновик не меняется, и вопрос нужно задать снова.
+func (s *CreationService) Answer(ctx context.Context, vkUserID int64, text string) (CreationDraft, error) {
+	s.mu.Lock()
+	d := s.draft(vkUserID)
+	if d == nil {
+		s.mu.Unlock()
+		return CreationDraft{}, ErrNoDraft
+	}
+	step := d.Step
+	s.mu.Unlock()
+
+	form, err := s.apply(ctx, vkUserID, step, text)
+	if err != nil {
+		return CreationDraft{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	d = s.draft(vkUserID)
+	if d == nil || d.Step != step {
+		return CreationDraft{}, ErrNoDraft
+	}
+	form(&d.Form)
+	if d.Editing {
+		d.Step = StepPreview
+		d.Editing = false
+	} else {
+		d.Step = nextStep(step)
+	}
+	d.ExpiresAt = time.Now().Add(creationTTL)
+	return *d, nil
+}
+
+// apply проверяет ответ и возвращает, как записать его в анкету. Проверки
+// с походом в базу идут без блокировки черновиков.
+func (s *CreationService) apply(ctx context.Context, vkUserID int64, step CreationStep, text string) (func(*models.NormalizedCharacterForm), error) {
+	text = strings.TrimSpace(text)
+	switch step {
+	case StepName:
+		name, err := normalizeName(text)
+		if err != nil {
+			return nil, err
+		}
+		taken, err := s.chars.NameTaken(ctx, vkUserID, name)
+		if err != nil {
+			return nil, err
+		}
+		if taken {
+			return nil, ErrNameTaken
+		}
+		return func(f *models.NormalizedCharacterForm) { f.Name = name }, nil
+	case StepRace:
+		v, err := matchOption(s.book.Races, text)
+		return func(f *models.NormalizedCharacterForm) { f.Race = v }, err
+	case StepCountry:
+		v, err := matchOption(s.book.Countries, text)
+		return func(f *models.NormalizedCharacterForm) { f.Country = v }, err
+	case StepClass:
+		v, err := matchOption(s.book.Classes, text)
+		return func(f *models.NormalizedCharacterForm) { f.Class = v }, err
+	case StepAbilities:
+		list, err := parseAbilities(text)
+		return func(f *models.NormalizedCharacterForm) { f.Abilities = list }, err
+	case StepBio:
+		if n := utf8.RuneCountInString(text); n < minBioLen || n > maxBioLen {
+			return nil, ErrBioInvalid
+		}
+		return func(f *models.NormalizedCharacterForm) { f.Bio = text }, nil
+	}
+	return nil, ErrDraftIncomplete
+}
+
+func nextStep(step CreationStep) CreationStep {
+	for i, s := range creationSteps[:len(creationSteps)-1] {
+		if s == step {
+			return creationSteps[i+1]
+		}
+	}
+	return StepPreview
+}
+
+// Edit возвращает заполненную анкету к одному полю; после ответа мастер
+// снова покажет предпросмотр.
+func (s *CreationService) Edit(vkUserID int64, field string) (CreationDraft, error) {
+	step, ok := creationFields[strings.ToLower(strings.TrimSpace(field))]
+	if !ok {
+		return CreationDraft{}, ErrUnknownField
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	d := s.draft(vkUserID)
+	if d == nil {
+		return CreationDraft{}, ErrNoDraft
+	}
+	if d.Step != StepPreview {
+		return CreationDraft{}, ErrDraftIncomplete
+	}
+	d.Step = step
+	d.Editing = true
+	d.ExpiresAt = time.Now().Add(creationTTL)
+	return *d, nil
+}
+
+// Save отправляет готовую анкету ГМу на проверку. Персонаж изменится,
+// только когда ГМ её одобрит.
+func (s *CreationService) Save(ctx context.Context, vkUserID int64) (*models.CharacterSheet, error) {
+	d, ok := s.Draft(vkUserID)
+	if !ok {
+		return nil, ErrNoDraft
+	}
+	if d.Step != StepPreview {
+		return nil, ErrDraftIncomplete
+	}
+	taken, err := s.chars.NameTaken(ctx, vkUserID, d.Form.Name)
+	if err != nil {
+		return nil, err
+	}
+	if taken {
+		return nil, ErrNameTaken
+	}
+	sheet, err := s.sheets.Submit(ctx, vkUserID, d.PeerID, d.Form)
+	if err != nil {
+		return nil, err
+	}
+	s.Cancel(vkUserID)
+	return sheet, nil
+}
+
+// Prompt — вопрос для текущего шага черновика.
+func (s *CreationService) Prompt(d CreationDraft) CreationPrompt {
+	var p CreationPrompt
+	switch d.Step {
+	case StepName:
+		p.Text = "Как зовут персонажа? Имя и, если есть, фамилия."
+		if d.Form.Name != "" {
+			p.Text += "\nСейчас: " + d.Form.Name
+		}
+	case StepRace:
+		p = optionPrompt("Какой он расы?", s.book.Races, d.Form.Race)
+	case StepCountry:
+		p = optionPrompt("Откуда он родом?", s.book.Countries, d.Form.Country)
+	case StepClass:
+		p = optionPrompt("Кто он по призванию?", s.book.Classes, d.Form.Class)
+	case StepAbilities:
+		p.Text = fmt.Sprintf("Перечисли способности через запятую (до %d).", maxFormAbilities)
+		if len(d.Form.Abilities) > 0 {
+			p.Text += "\nСейчас: " + strings.Join(d.Form.Abilities, ", ")
+		}
+	case StepBio:
+		p.Text = fmt.Sprintf("Расскажи биографию: прошлое, цели, чем живёт персонаж (от %d символов).", minBioLen)
+	case StepPreview:
+		p.Text = FormatCreationPreview(d.Form) +
+			"\n\nВсё верно — «Сохранить», анкета уйдёт ГМу на проверку. Исправить поле — «Изменить <поле>»: имя, раса, страна, класс, способности, биография."
+		p.Options = []string{"Сохранить", "Изменить имя", "Изменить расу", "Изменить страну", "Изменить класс", "Изменить способности", "Изменить биографию"}
+	}
+	return p
+}
+
+func optionPrompt(question string, opts []lore.CreationOption, current string) CreationPrompt {
+	p := CreationPrompt{Text: question}
+	if current != "" {
+		p.Text += " Сейчас: " + current
+	}
+	for _, o := range opts {
+		p.Text += "\n• " + o.Name
+		if o.Description != "" {
+			p.Text += " — " + o.Description
+		}
+		p.Options = append(p.Options, o.Name)
+	}
+	return p
+}
+
+// FormatCreationPreview показывает анкету так, как она будет сохранена.
+func FormatCreationPreview(f models.NormalizedCharacterForm) string {
+	bio := f.Bio
+	if r := []rune(bio); len(r) > 500 {
+		bio = string(r[:500]) + "…"
+	}
+	var b strings.Builder
+	b.WriteString("📜 Анкета персонажа\n")
+	b.WriteString("Имя: " + f.Name + "\n")
+	b.WriteString("Раса: " + f.Race + "\n")
+	b.WriteString("Страна: " + f.Country + "\n")
+	b.WriteString("Класс: " + f.Class + "\n")
+	b.WriteString("Способности: " + strings.Join(f.Abilities, "; ") + "\n")
+	b.WriteString("Биография: " + bio)
+	return b.String()
+}
+
+// normalizeName проверяет имя и приводит каждое слово к заглавной букве.
+func normalizeName(s string) (string, error) {
+	words := strings.Fields(s)
+	name := strings.Join(words, " ")
+	if n := utf8.RuneCountInString(name); n < 2 || n > maxNameLen {
+		return "", ErrNameInvalid
+	}
+	for _, r := range name {
+		if !unicode.IsLetter(r) && r != ' ' && r != '-' && r != '\'' {
+			return "", ErrNameInvalid
+		}
+	}
+	for i, w := range words {
+		words[i] = capitalize(w)
+	}
+	return strings.Join(words, " "), nil
+}
+
+func capitalize(s string) string {
+	r, size := utf8.DecodeRuneInString(s)
+	return string(unicode.ToUpper(r)) + s[size:]
+}
+
+// matchOption находит вариант по названию, синониму или однозначному началу
+// слова из них. Пустой справочник принимает любой ответ.
+func matchOption(opts []lore.CreationOption, s string) (string, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return "", ErrUnknownOption
+	}
+	if len(opts) == 0 {
+		return capitalize(s), nil
+	}
+	for _, o := range opts {
+		if o.Matches(s) {
+			return o.Name, nil
+		}
+	}
+	var found []string
+	low := strings.ToLower(s)
+	for _, o := range opts {
+		words := append(strings.Fields(o.Name), o.Aliases...)
+		for _, w := range words {
+			if strings.HasPrefix(strings.ToLower(w), low) {
+				found = append(found, o.Name)
+				break
+			}
+		}
+	}
+	if len(found) == 1 {
+		return found[0], nil
+	}
+	return "", ErrUnknownOption
+}
+
+func parseAbilities(s string) ([]string, error) {
+	var list []string
+	for _, a := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ';' || r == '\n' }) {
+		a = strings.TrimSpace(a)
+		if a == "" {
+			continue
+		}
+		if utf8.RuneCountInString(a) > maxAbilityLen {
+			return nil, ErrAbilitiesInvalid
+		}
+		list = append(list, capitalize(a))
+	}
+	if len(list) == 0 || len(list) > maxFormAbilities {
+		return nil, ErrAbilitiesInvalid
+	}
+	return list, nil
+}
+
+// CreationErrorText — ответ игроку на отклонённый ответ мастеру анкеты.
+func CreationErrorText(err error) string {
+	switch {
+	case errors.Is(err, ErrNameInvalid):
+		return fmt.Sprintf("Имя — это буквы (от 2 до %d), можно с пробелом, дефисом или апострофом.", maxNameLen)
+	case errors.Is(err, ErrNameTaken):
+		return "Это имя уже носит другой персонаж. Придумай другое."
+	case errors.Is(err, ErrUnknownOption):
+		return "Такого нет в мире Авроры. Выбери из списка."
+	case errors.Is(err, ErrAbilitiesInvalid):
+		return fmt.Sprintf("Нужно от 1 до %d способностей через запятую, каждая не длиннее %d символов.", maxFormAbilities, maxAbilityLen)
+	case errors.Is(err, ErrBioInvalid):
+		return fmt.Sprintf("Биография должна быть от %d до %d символов.", minBioLen, maxBioLen)
+	case errors.Is(err, ErrUnknownField):
+		return "Такого поля нет. Можно изменить: имя, раса, страна, класс, способности, биография."
+	case errors.Is(err, ErrDraftIncomplete):
+		return "Сначала ответь на вопросы анкеты до конца."
+	case errors.Is(err, ErrNoDraft):
+		return "Анкета не начата или истекла. Начни заново: !анкета"
+	}
+	return ""
+}
@@ -0,0 +1,368 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"aurora/internal/lore"
+	"aurora/internal/models"
+	"aurora/internal/repository"
+)
+
+var (
+	ErrNoShopHere      = errors.New("no shop at location")
+	ErrNotInShop       = errors.New("item not sold here")
+	ErrNoBuyer         = errors.New("merchant not interested")
+	ErrMerchantRefuses = errors.New("merchant refuses to trade")
+	ErrInvalidQuantity = errors.New("invalid quantity")
+)
+
+// hostileTradingScore — репутация, при которой торговцы фракции отказываются торговать.
+const hostileTradingScore = -50
+
+// ReputationSource отдаёт репутацию персонажа у фракции (-100..100).
+type ReputationSource interface {
+	ReputationScore(ctx context.Context, charID int64, faction string) (int, error)
+}
+
+type EconomyService struct {
+	shops      []lore.Shop
+	repo       *repository.ShopRepository
+	inventory  *InventoryService
+	reputation ReputationSource
+	clock      GameClock
+}
+
+func NewEconomyService(shops []lore.Shop, repo *repository.ShopRepository, inventory *InventoryService) *EconomyService {
+	return &EconomyService{shops: shops, repo: repo, inventory: inventory}
+}
+
+func (s *EconomyService) SetReputationSource(rs ReputationSource) {
+	s.reputation = rs
+}
+
+// SetClock переводит завоз товаров на игровое время. Без часов лавки
+// пополняются по реальному времени.
+func (s *EconomyService) SetClock(c GameClock) {
+	s.clock = c
+}
+
+type ShopOffer struct {
+	Item    lore.ShopItem
+	Price   int
+	InStock int
+}
+
+type ShopReceipt struct {
+	Shop      lore.Shop
+	ItemName  string
+	Quantity  int
+	UnitPrice int
+	Total     int
+	Balance   int
+}
+
+func (s *EconomyService) ShopsAt(location string) []lore.Shop {
+	var out []lore.Shop
+	for _, sh := range s.shops {
+		if strings.EqualFold(sh.Location, location) {
+			out = append(out, sh)
+		}
+	}
+	return out
+}
+
+// FindShop выбирает лавку в локации по названию или имени торговца.
+// Пустое название допустимо, если лавка в локации одна.
+func (s *EconomyService) FindShop(location, name string) (*lore.Shop, error) {
+	shops := s.ShopsAt(location)
+	if len(shops) == 0 {
+		return nil, ErrNoShopHere
+	}
+	if strings.TrimSpace(name) == "" {
+		if len(shops) == 1 {
+			return &shops[0], nil
+		}
+		return nil, ErrNotInShop
+	}
+
+	names := make([]string, 0, len(shops)*2)
+	for _, sh := range shops {
+		names = append(names, sh.Name, sh.Merchant)
+	}
+	idx := MatchName(names, name)
+	if idx < 0 {
+		return nil, ErrNotInShop
+	}
+	return &shops[idx/2], nil
+}
+
+// Offers возвращает ассортимент с ценами для персонажа, попутно пополняя
+// полки, если с последнего завоза прошло RestockHours.
+func (s *EconomyService) Offers(ctx context.Context, ch *models.Character, shop lore.Shop) ([]ShopOffer, error) {
+	stock, err := s.restock(ctx, shop)
+	if err != nil {
+		return nil, err
+	}
+	mult, err := s.priceMultiplier(ctx, ch.ID, shop.Faction)
+	if err != nil {
+		return nil, err
+	}
+
+	offers := make([]ShopOffer, 0, len(shop.Items))
+	for _, it := range shop.Items {
+		offers = append(offers, ShopOffer{
+			Item:    it,
+			Price:   buyPrice(it.Price, mult),
+			InStock: stock[repository.ItemNameKey(it.Name)].Quantity,
+		})
+	}
+	return offers, nil
+}
+
+func (s *EconomyService) Buy(ctx context.Context, ch *models.Character, location, target string, qty int) (*ShopReceipt, error) {
+	if qty <= 0 {
+		return nil, ErrInvalidQuantity
+	}
+	shops := s.ShopsAt(location)
+	if len(shops) == 0 {
+		return nil, ErrNoShopHere
+	}
+
+	var names []string
+	var owners []int
+	for si, sh := range shops {
+		for _, it := range sh.Items {
+			names = append(names, it.Name)
+			owners = append(owners, si)
+		}
+	}
+	idx := MatchName(names, target)
+	if idx < 0 {
+		return nil, ErrNotInShop
+	}
+	shop := shops[owners[idx]]
+	var offer lore.ShopItem
+	for _, it := range shop.Items {
+		if it.Name == names[idx] {
+			offer = it
+			break
+		}
+	}
+
+	if _, err := s.restock(ctx, shop); err != nil {
+		return nil, err
+	}
+	mult, err := s.priceMultiplier(ctx, ch.ID, shop.Faction)
+	if err != nil {
+		return nil, err
+	}
+
+	item, err := s.inventory.EnsureItem(ctx, models.Item{
+		Name:        offer.Name,
+		Description: offer.Description,
+		Rarity:      offer.Rarity,
+		Value:       offer.Price,
+		EquipSlot:   offer.EquipSlot,
+		Properties:  offer.Properties,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	unit := buyPrice(offer.Price, mult)
+	receipt := &ShopReceipt{Shop: shop, ItemName: item.Name, Quantity: qty, UnitPrice: unit, Total: unit * qty}
+	receipt.Balance, err = s.repo.Buy(ctx, repository.ShopDeal{
+		ShopID:      shop.ID,
+		ItemKey:     repository.ItemNameKey(offer.Name),
+		CharacterID: ch.ID,
+		ItemID:      item.ID,
+		Quantity:    qty,
+		Total:       receipt.Total,
+		Reason:      fmt.Sprintf("Покупка: %s ×%d (%s)", item.Name, qty, shop.Name),
+	})
+	if err != nil {
+		return nil, err
+	}
+	ch.Gold = receipt.Balance
+	return receipt, nil
+}
+
+// Sell продаёт предмет из инвентаря торговцу в текущей локации. Если
+// shopName пуст, выбирается лавка, которая торгует этим товаром, иначе первая.
+func (s *EconomyService) Sell(ctx context.Context, ch *models.Character, location, shopName, target string, qty int) (*ShopReceipt, error) {
+	if qty <= 0 {
+		return nil, ErrInvalidQuantity
+	}
+	inv := FindOwnedItem(ch.Items, target)
+	if inv == nil {
+		return nil, ErrItemNotOwned
+	}
+	if inv.Quantity < qty {
+		return nil, repository.ErrNotEnoughItems
+	}
+
+	shops := s.ShopsAt(location)
+	if len(shops) == 0 {
+		return nil, ErrNoShopHere
+	}
+	shop := shops[0]
+	if shopName != "" {
+		found, err := s.FindShop(location, shopName)
+		if err != nil {
+			return nil, err
+		}
+		shop = *found
+	} else {
+		for _, sh := range shops {
+			if shopItem(sh, inv.Item.Name) != nil {
+				shop = sh
+				break
+			}
+		}
+	}
+
+	base := inv.Item.Value
+	if it := shopItem(shop, inv.Item.Name); it != nil {
+		base = it.Price
+	}
+	mult, err := s.priceMultiplier(ctx, ch.ID, shop.Faction)
+	if err != nil {
+		return nil, err
+	}
+	unit := sellPrice(base, shop.BuyRate, mult)
+	if unit <= 0 {
+		return nil, ErrNoBuyer
+	}
+
+	receipt := &ShopReceipt{Shop: shop, ItemName: inv.Item.Name, Quantity: qty, UnitPrice: unit, Total: unit * qty}
+	receipt.Balance, err = s.repo.Sell(ctx, repository.ShopDeal{
+		ShopID:      shop.ID,
+		ItemKey:     repository.ItemNameKey(inv.Item.Name),
+		CharacterID: ch.ID,
+		ItemID:      inv.Item.ID,
+		Quantity:    qty,
+		Total:       receipt.Total,
+		Reason:      fmt.Sprintf("Продажа: %s ×%d (%s)", inv.Item.Name, qty, shop.Name),
+	})
+	if err != nil {
+		return nil, err
+	}
+	ch.Gold = receipt.Balance
+	return receipt, nil
+}
+
+func (s *EconomyService) restock(ctx context.Context, shop lore.Shop) (map[string]repository.StockRow, error) {
+	stock, err := s.repo.GetStock(ctx, shop.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	var gameNow int64
+	if s.clock != nil {
+		gameNow = s.clock.Now()
+	}
+	for _, it := range shop.Items {
+		key := repository.ItemNameKey(it.Name)
+		row, ok := stock[key]
+		due := false
+		switch {
+		case shop.RestockHours <= 0:
+		case s.clock != nil:
+			due = gameNow-row.RestockedMin >= int64(shop.RestockHours)*60
+		default:
+			due = now.Sub(row.RestockedAt) >= time.Duration(shop.RestockHours)*time.Hour
+		}
+		if ok && !due {
+			continue
+		}
+		qty := it.Stock
+		if ok && row.Quantity > qty {
+			qty = row.Quantity
+		}
+		if err := s.repo.SetStock(ctx, shop.ID, key, qty, now, gameNow); err != nil {
+			return nil, err
+		}
+		stock[key] = repository.StockRow{ItemKey: key, Quantity: qty, RestockedAt: now, RestockedMin: gameNow}
+	}
+	return stock, nil
+}
+
+func (s *EconomyService) priceMultiplier(ctx context.Context, charID int64, faction string) (float64, error) {
+	if s.reputation == nil || faction == "" {
+		return 1, nil
+	}
+	score, err := s.reputation.ReputationScore(ctx, charID, faction)
+	if err != nil {
+		return 0, fmt.Errorf("reputation with %s: %w", faction, err)
+	}
+	if score <= hostileTradingScore {
+		return 0, ErrMerchantRefuses
+	}
+	return PriceMultiplier(score), nil
+}
+
+// PriceMultiplier переводит репутацию (-100..100) в множитель цены:
+// враждебным торговец накидывает до 30%, почитаемым уступает до 20%.
+func PriceMultiplier(score int) float64 {
+	if score > 100 {
+		score = 100
+	}
+	if score < -100 {
+		score = -100
+	}
+	if score < 0 {
+		return 1 + float64(-score)/100*0.3
+	}
+	return 1 - float64(score)/100*0.2
+}
+
+func buyPrice(base int, mult float64) int {
+	p := int(math.Ceil(float64(base) * mult))
+	if p < 1 {
+		p = 1
+	}
+	return p
+}
+
+// sellPrice всегда ниже buyPrice той же вещи: иначе скидка почитаемым
+// при высоком buy_rate превратила бы куплю-продажу в бесконечное золото.
+func sellPrice(base int, rate, mult float64) int {
+	p := int(math.Floor(float64(base) * rate / mult))
+	return max(0, min(p, buyPrice(base, mult)-1))
+}
+
+func shopItem(shop lore.Shop, name string) *lore.ShopItem {
+	key := repository.ItemNameKey(name)
+	for i := range shop.Items {
+		if repository.ItemNameKey(shop.Items[i].Name) == key {
+			return &shop.Items[i]
+		}
+	}
+	return nil
+}
+
+func FormatShop(shop lore.Shop, offers []ShopOffer, gold int) string {
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("🏪 %s", shop.Name))
+	if shop.Merchant != "" {
+		b.WriteString(" — " + shop.Merchant)
+	}
+	b.WriteString("\n")
+	for _, o := range offers {
+		line := fmt.Sprintf("— %s: %d ₸", o.Item.Name, o.Price)
+		if o.InStock <= 0 {
+			line += " (нет в наличии)"
+		} else {
+			line += fmt.Sprintf(" (в наличии: %d)", o.InStock)
+		}
+		b.WriteString(line + "\n")
+	}
+	b.WriteString(fmt.Sprintf("\nТвоё золото: %d ₸. Купить: !купить <предмет> [кол-во]", gold))
+	return b.String()
+}
@@ -0,0 +1,45 @@
+package service
+
+import "testing"
+
+// Ни при какой репутации и ставке выкупа нельзя продать вещь дороже, чем
+// купить её у того же торговца.
+func TestSellPriceBelowBuyPrice(t *testing.T) {
+	for score := -100; score <= 100; score++ {
+		mult := PriceMultiplier(score)
+		for _, rate := range []float64{0.01, 0.4, 0.75, 0.8, 0.9, 0.99, 1} {
+			for base := 0; base <= 500; base++ {
+				buy, sell := buyPrice(base, mult), sellPrice(base, rate, mult)
+				if sell < 0 || sell >= buy {
+					t.Fatalf("score %d, rate %v, base %d: buy %d, sell %d", score, rate, base, buy, sell)
+				}
+			}
+		}
+	}
+}
+
+func TestPriceMultiplierBounds(t *testing.T) {
+	for _, c := range []struct {
+		score int
+		want  float64
+	}{
+		{-1000, 1.3},
+		{-100, 1.3},
+		{0, 1},
+		{100, 0.8},
+		{1000, 0.8},
+	} {
+		if got := PriceMultiplier(c.score); got != c.want {
+			t.Errorf("PriceMultiplier(%d) = %v, want %v", c.score, got, c.want)
+		}
+	}
+}
+
+func TestBuyPriceAtLeastOne(t *testing.T) {
+	if got := buyPrice(0, 0.8); got != 1 {
+		t.Fatalf("buyPrice(0) = %d, want 1", got)
+	}
+	if got := buyPrice(10, 1.3); got != 13 {
+		t.Fatalf("buyPrice(10, 1.3) = %d, want 13", got)
+	}
+}
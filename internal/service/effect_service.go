@@ -0,0 +1,293 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"aurora/internal/lore"
+	"aurora/internal/models"
+	"aurora/internal/repository"
+)
+
+// maxEffectDuration — предел длительности, чтобы опечатка не повесила эффект навсегда.
+const maxEffectDuration = 100
+
+var (
+	ErrEffectActive   = errors.New("effect already active")
+	ErrEffectNotFound = errors.New("effect not found")
+)
+
+// EffectTick — итог хода эффектов для одного персонажа.
+type EffectTick struct {
+	CharacterID int64
+	HPDelta     int
+	HPAfter     int
+	Expired     []models.Effect
+}
+
+// EffectService накладывает, снимает и проматывает эффекты. Шаблоны из
+// справочника дают модификаторы и правило наложения; без шаблона эффект
+// обновляется при повторном наложении.
+type EffectService struct {
+	repo *repository.CharacterRepository
+	book *lore.EffectBook
+
+	byKey map[string]lore.EffectTemplate
+}
+
+func NewEffectService(repo *repository.CharacterRepository, book *lore.EffectBook) *EffectService {
+	if book == nil {
+		book = &lore.EffectBook{}
+	}
+	s := &EffectService{repo: repo, book: book, byKey: make(map[string]lore.EffectTemplate)}
+	for _, t := range book.Effects {
+		for _, name := range append([]string{t.Name}, t.Aliases...) {
+			s.byKey[repository.ItemNameKey(name)] = t
+		}
+	}
+	return s
+}
+
+func (s *EffectService) Template(name string) (lore.EffectTemplate, bool) {
+	t, ok := s.byKey[repository.ItemNameKey(name)]
+	return t, ok
+}
+
+// Apply накладывает эффект на персонажа. Нулевые поля e дополняются из
+// шаблона справочника. Обновляет ch.Effects.
+func (s *EffectService) Apply(ctx context.Context, ch *models.Character, e models.Effect) (models.Effect, error) {
+	e, err := s.resolve(ch, e)
+	if err != nil {
+		return e, err
+	}
+	if e.ID != 0 {
+		err = s.repo.UpdateEffect(ctx, e)
+	} else {
+		e.ID, err = s.repo.AddEffect(ctx, e)
+	}
+	if err != nil {
+		return models.Effect{}, err
+	}
+	setEffect(ch, e)
+	return e, nil
+}
+
+// resolve считает, каким станет эффект e на ch по правилу наложения, ничего
+// не записывая: с ID — обновлённый уже наложенный, с нулевым ID — новый.
+func (s *EffectService) resolve(ch *models.Character, e models.Effect) (models.Effect, error) {
+	stacking, maxStacks := models.EffectStackRefresh, 1
+	if t, ok := s.Template(e.Name); ok {
+		e.Name = t.Name
+		if e.Description == "" {
+			e.Description = t.Description
+		}
+		if e.Duration == 0 {
+			e.Duration = t.Duration
+		}
+		if e.HPPerTurn == 0 {
+			e.HPPerTurn = t.HPPerTurn
+		}
+		if e.PowerMod == 0 {
+			e.PowerMod = t.Power
+		}
+		if len(e.Blocked) == 0 {
+			e.Blocked = t.BlockedAbilities
+		}
+		e.IsHidden = e.IsHidden || t.Hidden
+		if t.Stacking != "" {
+			stacking = t.Stacking
+		}
+		if t.MaxStacks > 1 {
+			maxStacks = t.MaxStacks
+		}
+	}
+	e.Name = strings.TrimSpace(e.Name)
+	if e.Name == "" {
+		return models.Effect{}, ErrEffectNotFound
+	}
+	if e.Duration < 0 {
+		e.Duration = 0
+	}
+	if e.Duration > maxEffectDuration {
+		e.Duration = maxEffectDuration
+	}
+	e.CharacterID = ch.ID
+	e.Stacks = 1
+
+	for _, cur := range ch.Effects {
+		if !strings.EqualFold(cur.Name, e.Name) {
+			continue
+		}
+		switch stacking {
+		case models.EffectStackIgnore:
+			return cur, ErrEffectActive
+		case models.EffectStackAdd:
+			if cur.Stacks < maxStacks {
+				cur.Stacks++
+			}
+		}
+		if cur.Duration != 0 && (e.Duration == 0 || e.Duration > cur.Duration) {
+			cur.Duration = e.Duration
+		}
+		cur.IsHidden = e.IsHidden
+		return cur, nil
+	}
+	e.ID = 0
+	return e, nil
+}
+
+// setEffect записывает в ch.Effects сохранённый эффект e.
+func setEffect(ch *models.Character, e models.Effect) {
+	for i := range ch.Effects {
+		if ch.Effects[i].ID == e.ID {
+			ch.Effects[i] = e
+			return
+		}
+	}
+	ch.Effects = append(ch.Effects, e)
+}
+
+// Remove снимает эффект по названию (допускается неточное совпадение).
+func (s *EffectService) Remove(ctx context.Context, ch *models.Character, name string) (models.Effect, error) {
+	names := make([]string, len(ch.Effects))
+	for i, e := range ch.Effects {
+		names[i] = e.Name
+	}
+	idx := MatchName(names, name)
+	if idx < 0 {
+		return models.Effect{}, ErrEffectNotFound
+	}
+	e := ch.Effects[idx]
+	if err := s.repo.DeleteEffect(ctx, e.ID); err != nil {
+		return models.Effect{}, err
+	}
+	ch.Effects = append(ch.Effects[:idx], ch.Effects[idx+1:]...)
+	return e, nil
+}
+
+// Tick проматывает turns ходов: применяет изменение здоровья за каждый ход,
+// пока эффект действовал, и снимает истёкшие. charID 0 — все персонажи.
+func (s *EffectService) Tick(ctx context.Context, charID int64, turns int) ([]EffectTick, error) {
+	if turns <= 0 {
+		return nil, nil
+	}
+	var effects []models.Effect
+	var err error
+	if charID == 0 {
+		effects, err = s.repo.GetAllEffects(ctx)
+	} else {
+		effects, err = s.repo.GetEffects(ctx, charID)
+	}
+	if err != nil || len(effects) == 0 {
+		return nil, err
+	}
+
+	hp := make(map[int64]int)
+	byChar := make(map[int64]*EffectTick)
+	var updated []models.Effect
+	var expired []int64
+	for _, e := range effects {
+		t := byChar[e.CharacterID]
+		if t == nil {
+			t = &EffectTick{CharacterID: e.CharacterID}
+			byChar[e.CharacterID] = t
+		}
+		active := turns
+		if e.Duration > 0 && e.Duration < turns {
+			active = e.Duration
+		}
+		if d := e.HPDelta() * active; d != 0 {
+			hp[e.CharacterID] += d
+			t.HPDelta += d
+		}
+		if e.Duration == 0 {
+			continue
+		}
+		e.Duration -= turns
+		if e.Duration <= 0 {
+			expired = append(expired, e.ID)
+			t.Expired = append(t.Expired, e)
+		} else {
+			updated = append(updated, e)
+		}
+	}
+
+	after, err := s.repo.ApplyEffectTick(ctx, hp, updated, expired)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]EffectTick, 0, len(byChar))
+	for id, t := range byChar {
+		if t.HPDelta == 0 && len(t.Expired) == 0 {
+			continue
+		}
+		t.HPAfter = after[id]
+		out = append(out, *t)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].CharacterID < out[j].CharacterID })
+	return out, nil
+}
+
+// FormatEffectTick — что почувствовал персонаж за ход эффектов. Скрытые
+// эффекты не называются, но их влияние на здоровье видно.
+func FormatEffectTick(t EffectTick) string {
+	var lines []string
+	if t.HPDelta != 0 {
+		lines = append(lines, fmt.Sprintf("🩸 Эффекты: здоровье %+d (теперь %d).", t.HPDelta, t.HPAfter))
+	}
+	for _, e := range t.Expired {
+		if !e.IsHidden {
+			lines = append(lines, "⏳ Эффект «"+e.Name+"» развеялся.")
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// FormatEffects — список эффектов; showHidden для ГМа.
+func FormatEffects(name string, effects []models.Effect, showHidden bool) string {
+	var b strings.Builder
+	b.WriteString("🧪 Эффекты: " + name + "\n")
+	n := 0
+	for _, e := range effects {
+		if e.IsHidden && !showHidden {
+			continue
+		}
+		n++
+		b.WriteString("— " + DescribeEffect(e))
+		if e.IsHidden {
+			b.WriteString(" [скрыт]")
+		}
+		b.WriteString("\n")
+	}
+	if n == 0 {
+		return "🧪 " + name + ": эффектов нет."
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+func DescribeEffect(e models.Effect) string {
+	var parts []string
+	if e.HPPerTurn != 0 {
+		parts = append(parts, fmt.Sprintf("%+d HP/ход", e.HPDelta()))
+	}
+	if e.PowerMod != 0 {
+		parts = append(parts, fmt.Sprintf("сила %+d", e.Power()))
+	}
+	if len(e.Blocked) > 0 {
+		parts = append(parts, "блокирует: "+strings.Join(e.Blocked, ", "))
+	}
+	if e.Duration > 0 {
+		parts = append(parts, fmt.Sprintf("%d х.", e.Duration))
+	} else {
+		parts = append(parts, "бессрочно")
+	}
+	name := e.Name
+	if e.Stacks > 1 {
+		name += fmt.Sprintf(" ×%d", e.Stacks)
+	}
+	return name + " (" + strings.Join(parts, ", ") + ")"
+}
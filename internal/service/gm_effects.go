@@ -0,0 +1,101 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"aurora/internal/models"
+)
+
+const gmEffectUsage = "Использование: !gm effect <игрок> <эффект> [ходы=N] [hp=±N] [power=±N] [block=способность,...] [hidden]"
+
+// parseGMEffect разбирает название эффекта и необязательные параметры
+// вида ключ=значение. Незаданные параметры берутся из справочника.
+func parseGMEffect(args []string) (models.Effect, error) {
+	var e models.Effect
+	var name []string
+	for _, a := range args {
+		key, val, ok := strings.Cut(a, "=")
+		if !ok {
+			switch strings.ToLower(a) {
+			case "hidden", "скрыт", "скрытый":
+				e.IsHidden = true
+			default:
+				name = append(name, a)
+			}
+			continue
+		}
+		var err error
+		switch strings.ToLower(key) {
+		case "ходы", "turns", "t":
+			e.Duration, err = strconv.Atoi(val)
+		case "hp":
+			e.HPPerTurn, err = strconv.Atoi(val)
+		case "power", "сила":
+			e.PowerMod, err = strconv.Atoi(val)
+		case "block", "блок":
+			for _, b := range strings.Split(val, ",") {
+				if b = strings.TrimSpace(b); b != "" {
+					e.Blocked = append(e.Blocked, b)
+				}
+			}
+		default:
+			return e, fmt.Errorf("неизвестный параметр %q", key)
+		}
+		if err != nil {
+			return e, fmt.Errorf("неверное значение %q", a)
+		}
+	}
+	e.Name = strings.Join(name, " ")
+	if e.Name == "" {
+		return e, errors.New("не указано название эффекта")
+	}
+	return e, nil
+}
+
+func (s *GMService) handleEffect(ctx context.Context, fields []string) string {
+	if len(fields) < 4 {
+		return gmEffectUsage
+	}
+	ch, err := s.charService.FindPlayer(ctx, fields[2])
+	if err != nil {
+		return "Игрок не найден."
+	}
+	e, err := parseGMEffect(fields[3:])
+	if err != nil {
+		return err.Error() + "\n" + gmEffectUsage
+	}
+	applied, err := s.effects.Apply(ctx, ch, e)
+	if errors.Is(err, ErrEffectActive) {
+		return fmt.Sprintf("%s: эффект «%s» уже действует и не накладывается повторно.", ch.Name, applied.Name)
+	}
+	if err != nil {
+		return "Ошибка: " + err.Error()
+	}
+	reply := ch.Name + ": наложен эффект " + DescribeEffect(applied)
+	if applied.IsHidden {
+		reply += " [скрыт от игрока]"
+	}
+	return reply
+}
+
+func (s *GMService) handleUneffect(ctx context.Context, fields []string) string {
+	if len(fields) < 4 {
+		return "Использование: !gm uneffect <игрок> <эффект>"
+	}
+	ch, err := s.charService.FindPlayer(ctx, fields[2])
+	if err != nil {
+		return "Игрок не найден."
+	}
+	removed, err := s.effects.Remove(ctx, ch, strings.Join(fields[3:], " "))
+	if errors.Is(err, ErrEffectNotFound) {
+		return ch.Name + ": такого эффекта нет."
+	}
+	if err != nil {
+		return "Ошибка: " + err.Error()
+	}
+	return fmt.Sprintf("%s: эффект «%s» снят.", ch.Name, removed.Name)
+}
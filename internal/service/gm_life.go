@@ -0,0 +1,54 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const gmResurrectUsage = "Использование: !gm resurrect <игрок> [| задание] — открыть квест на воскрешение, !gm resurrect <игрок> now — вернуть сразу"
+
+// handleResurrect — одобрение воскрешения: квестом или волей ГМа.
+func (s *GMService) handleResurrect(ctx context.Context, fields []string, text string) string {
+	if len(fields) < 3 {
+		return gmResurrectUsage
+	}
+	ch, err := s.charService.FindPlayer(ctx, fields[2])
+	if err != nil {
+		return "Игрок не найден."
+	}
+	_, rest, _ := strings.Cut(text, fields[2])
+	rest = strings.TrimSpace(rest)
+
+	if strings.EqualFold(rest, "now") || strings.EqualFold(rest, "сейчас") {
+		change, err := s.life.Resurrect(ctx, ch)
+		if errors.Is(err, ErrCharacterNotDead) {
+			return ch.Name + " не мёртв (" + ch.Status + ")."
+		}
+		if err != nil {
+			return "Ошибка: " + err.Error()
+		}
+		reply := change.Text()
+		if err := s.charService.Restore(ctx, ch); errors.Is(err, ErrCharacterLimit) {
+			reply += "\nУ игрока нет свободного места — персонаж остаётся в архиве."
+		} else if err != nil {
+			reply += "\nОшибка возврата из архива: " + err.Error()
+		}
+		return reply
+	}
+
+	if ch.IsArchived() {
+		return ch.Name + " в архиве — квест ему недоступен. Вернуть сразу: !gm resurrect " + fields[2] + " now"
+	}
+	q, err := s.life.StartResurrection(ctx, ch, strings.TrimSpace(strings.TrimPrefix(rest, "|")))
+	switch {
+	case errors.Is(err, ErrCharacterNotDead):
+		return ch.Name + " не мёртв (" + ch.Status + ")."
+	case errors.Is(err, ErrResurrectionTaken):
+		return fmt.Sprintf("%s уже идёт путём назад: «%s» (стадия %d).", ch.Name, q.Title, q.Stage)
+	case err != nil:
+		return "Ошибка: " + err.Error()
+	}
+	return fmt.Sprintf("✨ %s получает квест «%s»: %s\nИгрок продолжает его командой !квест <действие>.", ch.Name, q.Title, q.Description)
+}
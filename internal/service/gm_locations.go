@@ -0,0 +1,127 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const gmLocUsage = "Локации: !gm loc list, !gm loc add <название> | <описание> [| теги], !gm loc edit <название> | [описание] [| теги], !gm loc close <название>, !gm loc open <название>, !gm loc link <откуда> | <куда> | <часы> [| опасность 0-5]"
+
+// handleLocation разбирает !gm loc ... Аргументы разделяются «|», потому что
+// названия локаций содержат пробелы.
+func (s *GMService) handleLocation(ctx context.Context, args string) string {
+	sub, rest, _ := strings.Cut(strings.TrimSpace(args), " ")
+	parts := strings.Split(rest, "|")
+	for i := range parts {
+		parts[i] = strings.TrimSpace(parts[i])
+	}
+	arg := func(i int) string {
+		if i < len(parts) {
+			return parts[i]
+		}
+		return ""
+	}
+
+	switch strings.ToLower(sub) {
+	case "list":
+		locs, err := s.locations.List(ctx, 50)
+		if err != nil {
+			return "Ошибка: " + err.Error()
+		}
+		if len(locs) == 0 {
+			return "Локаций пока нет."
+		}
+		var b strings.Builder
+		b.WriteString("🗺 Локации:\n")
+		for _, l := range locs {
+			fmt.Fprintf(&b, "• %s", l.Name)
+			if l.Tags != "" {
+				fmt.Fprintf(&b, " [%s]", l.Tags)
+			}
+			if !l.IsActive {
+				b.WriteString(" — закрыта")
+			}
+			b.WriteString("\n")
+		}
+		return strings.TrimRight(b.String(), "\n")
+
+	case "add":
+		if arg(0) == "" || arg(1) == "" {
+			return "Использование: !gm loc add <название> | <описание> [| теги]"
+		}
+		if _, err := s.locations.GetByName(ctx, arg(0)); err == nil {
+			return "Локация «" + arg(0) + "» уже есть. Изменить: !gm loc edit"
+		}
+		loc, err := s.locations.Create(ctx, arg(0), arg(1), arg(2), "gm")
+		if err != nil {
+			return "Ошибка: " + err.Error()
+		}
+		reply := "Локация «" + loc.Name + "» создана. Соедините её дорогой: !gm loc link"
+		if err := s.locations.Index(ctx, loc); err != nil {
+			reply += "\n⚠️ Описание не проиндексировано: " + err.Error()
+		}
+		return reply
+
+	case "edit":
+		if arg(0) == "" || (arg(1) == "" && arg(2) == "") {
+			return "Использование: !gm loc edit <название> | [описание] [| теги]"
+		}
+		loc, err := s.locations.Update(ctx, arg(0), arg(1), arg(2))
+		if errors.Is(err, ErrUnknownLocation) {
+			return "Локация не найдена."
+		}
+		if err != nil {
+			return "Ошибка: " + err.Error()
+		}
+		reply := "Локация «" + loc.Name + "» обновлена."
+		if err := s.locations.Index(ctx, loc); err != nil {
+			reply += "\n⚠️ Описание не проиндексировано: " + err.Error()
+		}
+		return reply
+
+	case "close", "open":
+		if arg(0) == "" {
+			return "Использование: !gm loc " + sub + " <название>"
+		}
+		open := strings.ToLower(sub) == "open"
+		loc, err := s.locations.SetActive(ctx, arg(0), open)
+		if errors.Is(err, ErrUnknownLocation) {
+			return "Локация не найдена."
+		}
+		if err != nil {
+			return "Ошибка: " + err.Error()
+		}
+		if open {
+			return "Локация «" + loc.Name + "» снова открыта."
+		}
+		return "Локация «" + loc.Name + "» закрыта: дороги туда не ведут, но находящиеся там могут уйти."
+
+	case "link":
+		hours, err := strconv.Atoi(arg(2))
+		if arg(0) == "" || arg(1) == "" || err != nil || hours <= 0 {
+			return "Использование: !gm loc link <откуда> | <куда> | <часы> [| опасность 0-5]"
+		}
+		danger := 0
+		if arg(3) != "" {
+			danger, err = strconv.Atoi(arg(3))
+			if err != nil || danger < 0 || danger > 5 {
+				return "Опасность — число от 0 до 5."
+			}
+		}
+		from, to, err := s.locations.Link(ctx, arg(0), arg(1), hours, danger)
+		if errors.Is(err, ErrUnknownLocation) {
+			return "Локация не найдена."
+		}
+		if errors.Is(err, ErrAlreadyThere) {
+			return "Нельзя соединить локацию саму с собой."
+		}
+		if err != nil {
+			return "Ошибка: " + err.Error()
+		}
+		return fmt.Sprintf("Дорога «%s» — «%s»: %d ч, опасность %d.", from.Name, to.Name, hours, danger)
+	}
+	return gmLocUsage
+}
@@ -3,11 +3,14 @@ package service
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"strconv"
 	"strings"
 
 	"aurora/internal/llm"
+	"aurora/internal/models"
+	"aurora/internal/repository"
 	"aurora/pkg/config"
 
 	"github.com/SevereCloud/vksdk/v2/api"
@@ -20,9 +23,18 @@ type GMService struct {
 	llm          llm.Client
 	vk           *api.VK
 	db           *sql.DB
+	ledger       *LedgerService
+	progression  *ProgressionService
+	reputation   *ReputationService
+	locations    *LocationService
+	clock        *ClockService
+	effects      *EffectService
+	life         *LifeService
+	sheets       *SheetService
+	combat       *CombatService
 }
 
-func NewGMService(cfg *config.Config, ss *SceneService, cs *CharacterService, llm llm.Client, vk *api.VK, db *sql.DB) *GMService {
+func NewGMService(cfg *config.Config, ss *SceneService, cs *CharacterService, llm llm.Client, vk *api.VK, db *sql.DB, ledger *LedgerService, progression *ProgressionService, reputation *ReputationService, locations *LocationService, clock *ClockService, effects *EffectService, life *LifeService, sheets *SheetService) *GMService {
 	return &GMService{
 		cfg:          cfg,
 		sceneService: ss,
@@ -30,9 +42,22 @@ func NewGMService(cfg *config.Config, ss *SceneService, cs *CharacterService, ll
 		llm:          llm,
 		vk:           vk,
 		db:           db,
+		ledger:       ledger,
+		progression:  progression,
+		reputation:   reputation,
+		locations:    locations,
+		clock:        clock,
+		effects:      effects,
+		life:         life,
+		sheets:       sheets,
 	}
 }
 
+// SetCombat включает !gm fight: ГМ выставляет противника, победа над которым даёт опыт.
+func (s *GMService) SetCombat(combat *CombatService) {
+	s.combat = combat
+}
+
 func (s *GMService) IsGM(vkUserID int64) bool {
 	return s.cfg.GMUserID != 0 && int(vkUserID) == s.cfg.GMUserID
 }
@@ -43,7 +68,7 @@ func (s *GMService) HandleCommand(ctx context.Context, peerID int64, fromID int6
 	}
 	fields := strings.Fields(text)
 	if len(fields) == 1 {
-		return true, "Команды: !gm mode <human|ai_assist|ai_full>, !gm ask <вопрос>, !gm say <текст>, !gm setgm <vk_id>."
+		return true, "Команды: !gm mode <human|ai_assist|ai_full>, !gm ask <вопрос>, !gm say <текст>, !gm setgm <vk_id>, !gm ledger <игрок> [N], !gm gold <игрок> <±сумма> <причина>, !gm reconcile [игрок], !gm anomalies, !gm xp <игрок> <кол-во>, !gm ability <игрок> <название> [| описание], !gm rep <игрок> [<фракция> <±N>], !gm loc <list|add|edit|close|open|link>, !gm time [+N<м|ч|д>], !gm effect <игрок> <эффект> [ходы=N hp=±N power=±N block=… hidden], !gm uneffect <игрок> <эффект>, !gm effects <игрок>, !gm resurrect <игрок> [| задание|now], !gm fight <игрок> <противник>, !gm sheets, !gm sheet <номер>, !gm approve <номер>, !gm reject <номер> <причина>, !gm changes <номер> <что исправить>."
 	}
 	cmd := fields[1]
 
@@ -54,7 +79,7 @@ func (s *GMService) HandleCommand(ctx context.Context, peerID int64, fromID int6
 		}
 		mode := fields[2]
 
-		ch, err := s.charService.GetOrCreateByVK(ctx, fromID)
+		ch, err := s.charService.GetActive(ctx, fromID)
 		if err != nil {
 			return true, "Ошибка: персонаж ГМ не найден."
 		}
@@ -97,6 +122,225 @@ func (s *GMService) HandleCommand(ctx context.Context, peerID int64, fromID int6
 		}
 		s.cfg.GMUserID = int(id)
 		return true, fmt.Sprintf("GM_USER_ID установлен на %d", id)
+
+	case "ledger":
+		if len(fields) < 3 {
+			return true, "Использование: !gm ledger <игрок> [N]"
+		}
+		ch, err := s.charService.FindPlayer(ctx, fields[2])
+		if err != nil {
+			return true, "Игрок не найден."
+		}
+		limit := 20
+		if len(fields) > 3 {
+			if n, err := strconv.Atoi(fields[3]); err == nil && n > 0 {
+				limit = n
+			}
+		}
+		entries, err := s.ledger.History(ctx, ch.ID, limit)
+		if err != nil {
+			return true, "Ошибка журнала: " + err.Error()
+		}
+		return true, FormatLedger(ch.Name, ch.Gold, entries)
+
+	case "gold":
+		if len(fields) < 5 {
+			return true, "Использование: !gm gold <игрок> <±сумма> <причина>"
+		}
+		ch, err := s.charService.FindPlayer(ctx, fields[2])
+		if err != nil {
+			return true, "Игрок не найден."
+		}
+		amount, err := strconv.Atoi(fields[3])
+		if err != nil || amount == 0 {
+			return true, "Неверная сумма."
+		}
+		reason := strings.Join(fields[4:], " ")
+		if err := s.ledger.Adjust(ctx, ch, amount, reason); err != nil {
+			if errors.Is(err, repository.ErrNotEnoughGold) {
+				return true, "У игрока недостаточно золота."
+			}
+			return true, "Ошибка: " + err.Error()
+		}
+		return true, fmt.Sprintf("%s: %+d золота (%s). Баланс: %d", ch.Name, amount, reason, ch.Gold)
+
+	case "reconcile":
+		if len(fields) < 3 {
+			list, err := s.ledger.Mismatches(ctx)
+			if err != nil {
+				return true, "Ошибка сверки: " + err.Error()
+			}
+			if len(list) == 0 {
+				return true, "Балансы всех персонажей совпадают с журналом."
+			}
+			var b strings.Builder
+			b.WriteString("Расхождения с журналом:\n")
+			for _, m := range list {
+				fmt.Fprintf(&b, "• %s (id %d): в анкете %d, по журналу %d\n", m.Name, m.CharacterID, m.Gold, m.LedgerSum)
+			}
+			return true, strings.TrimRight(b.String(), "\n")
+		}
+		ch, err := s.charService.FindPlayer(ctx, fields[2])
+		if err != nil {
+			return true, "Игрок не найден."
+		}
+		before, after, err := s.ledger.Reconcile(ctx, ch)
+		if err != nil {
+			return true, "Ошибка сверки: " + err.Error()
+		}
+		if before == after {
+			return true, fmt.Sprintf("%s: баланс %d совпадает с журналом.", ch.Name, after)
+		}
+		return true, fmt.Sprintf("%s: баланс исправлен %d → %d по журналу.", ch.Name, before, after)
+
+	case "xp":
+		if len(fields) < 4 {
+			return true, "Использование: !gm xp <игрок> <кол-во>"
+		}
+		ch, err := s.charService.FindPlayer(ctx, fields[2])
+		if err != nil {
+			return true, "Игрок не найден."
+		}
+		amount, err := strconv.Atoi(fields[3])
+		if err != nil {
+			return true, "Неверное количество опыта."
+		}
+		res, err := s.progression.GrantXP(ctx, ch, amount)
+		if errors.Is(err, ErrInvalidXP) {
+			return true, fmt.Sprintf("Опыт выдаётся порциями от 1 до %d.", maxGrantXP)
+		}
+		if err != nil {
+			return true, "Ошибка: " + err.Error()
+		}
+		return true, ch.Name + ": " + s.progression.FormatXPResult(res)
+
+	case "ability":
+		if len(fields) < 4 {
+			return true, "Использование: !gm ability <игрок> <название> [| описание]"
+		}
+		ch, err := s.charService.FindPlayer(ctx, fields[2])
+		if err != nil {
+			return true, "Игрок не найден."
+		}
+		name, desc, _ := strings.Cut(strings.Join(fields[3:], " "), "|")
+		name = strings.TrimSpace(name)
+		added, err := s.progression.UnlockAbility(ctx, ch, name, strings.TrimSpace(desc), models.AbilitySourceGM)
+		if err != nil {
+			return true, "Ошибка: " + err.Error()
+		}
+		if !added {
+			return true, fmt.Sprintf("%s уже владеет способностью «%s».", ch.Name, name)
+		}
+		return true, fmt.Sprintf("%s получает способность «%s».", ch.Name, name)
+
+	case "rep":
+		if len(fields) < 3 {
+			return true, "Использование: !gm rep <игрок> [<фракция> <±N>]"
+		}
+		ch, err := s.charService.FindPlayer(ctx, fields[2])
+		if err != nil {
+			return true, "Игрок не найден."
+		}
+		if len(fields) == 3 {
+			return true, FormatReputation(ch.Name, ch.Reputation)
+		}
+		if len(fields) < 5 {
+			return true, "Использование: !gm rep <игрок> <фракция> <±N>"
+		}
+		delta, err := strconv.Atoi(fields[len(fields)-1])
+		if err != nil || delta == 0 {
+			return true, "Неверное изменение репутации."
+		}
+		faction := strings.Join(fields[3:len(fields)-1], " ")
+		rep, err := s.reputation.Adjust(ctx, ch, faction, delta)
+		if errors.Is(err, ErrUnknownFaction) {
+			return true, "Неизвестная фракция. Известны: " + strings.Join(s.reputation.FactionNames(), ", ")
+		}
+		if err != nil {
+			return true, "Ошибка: " + err.Error()
+		}
+		return true, fmt.Sprintf("%s — %s: %+d → %d (%s)", ch.Name, rep.FactionName, delta, rep.Score, rep.Tier)
+
+	case "loc":
+		return true, s.handleLocation(ctx, strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(text), "!gm loc")))
+
+	case "time":
+		if len(fields) == 2 {
+			return true, "🕰 " + s.clock.Describe()
+		}
+		minutes, err := ParseGameDuration(strings.Join(fields[2:], " "))
+		if err != nil {
+			return true, "Использование: !gm time +N<м|ч|д>, например !gm time +2д 6ч"
+		}
+		tick, err := s.clock.Advance(ctx, minutes)
+		if err != nil {
+			return true, "Ошибка: " + err.Error()
+		}
+		reply := fmt.Sprintf("🕰 Прошло %s. Теперь: %s", FormatGameDuration(minutes), s.clock.Describe())
+		if len(tick.Expired) > 0 || len(tick.Failed) > 0 {
+			reply += fmt.Sprintf("\nИстекло эффектов: %d, провалено квестов по сроку: %d.", len(tick.Expired), len(tick.Failed))
+		}
+		return true, reply
+
+	case "effect":
+		return true, s.handleEffect(ctx, fields)
+
+	case "uneffect":
+		return true, s.handleUneffect(ctx, fields)
+
+	case "effects":
+		if len(fields) < 3 {
+			return true, "Использование: !gm effects <игрок>"
+		}
+		ch, err := s.charService.FindPlayer(ctx, fields[2])
+		if err != nil {
+			return true, "Игрок не найден."
+		}
+		return true, FormatEffects(ch.Name, ch.Effects, true)
+
+	case "resurrect":
+		return true, s.handleResurrect(ctx, fields, text)
+
+	case "fight":
+		if len(fields) < 4 || s.combat == nil {
+			return true, "Использование: !gm fight <игрок> <противник>"
+		}
+		ch, err := s.charService.FindPlayer(ctx, fields[2])
+		if err != nil {
+			return true, "Игрок не найден."
+		}
+		enc, err := s.combat.Spawn(ch.ID, strings.Join(fields[3:], " "))
+		if errors.Is(err, ErrInCombat) {
+			return true, ch.Name + " уже в бою."
+		}
+		if err != nil {
+			return true, "Ошибка: " + err.Error()
+		}
+		return true, fmt.Sprintf("⚔️ %s встречает противника «%s». Игрок ходит командой !бой <действие>.", ch.Name, enc.Enemy)
+
+	case "sheets":
+		return true, s.handleSheets(ctx)
+
+	case "sheet":
+		return true, s.handleSheet(ctx, fields)
+
+	case "approve", "reject", "changes":
+		return true, s.handleSheetReview(ctx, fields, text)
+
+	case "anomalies":
+		entries, err := s.ledger.Anomalies(ctx, 30)
+		if err != nil {
+			return true, "Ошибка отчёта: " + err.Error()
+		}
+		if len(entries) == 0 {
+			return true, fmt.Sprintf("Аномалий нет (лимиты: квест %d, бой %d).", llm.MaxQuestGold, llm.MaxCombatGold)
+		}
+		var b strings.Builder
+		fmt.Fprintf(&b, "⚠️ Доходы выше лимитов (квест %d, бой %d):\n", llm.MaxQuestGold, llm.MaxCombatGold)
+		for _, e := range entries {
+			fmt.Fprintf(&b, "• персонаж %d: %s\n", e.CharacterID, FormatLedgerEntry(e))
+		}
+		return true, strings.TrimRight(b.String(), "\n")
 	}
 	return true, "Неизвестная команда GM."
 }
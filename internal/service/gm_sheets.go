@@ -0,0 +1,101 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"aurora/internal/models"
+)
+
+// sheetID разбирает номер анкеты: «12» или «#12».
+func sheetID(s string) (int64, bool) {
+	id, err := strconv.ParseInt(strings.TrimPrefix(s, "#"), 10, 64)
+	return id, err == nil && id > 0
+}
+
+func (s *GMService) handleSheets(ctx context.Context) string {
+	list, err := s.sheets.Pending(ctx)
+	if err != nil {
+		return "Ошибка: " + err.Error()
+	}
+	if len(list) == 0 {
+		return "Анкет на проверке нет."
+	}
+	var b strings.Builder
+	b.WriteString("📜 Анкеты на проверке:\n")
+	for _, sh := range list {
+		fmt.Fprintf(&b, "#%d %s (vk %d) — %s\n", sh.ID, sh.Form.Name, sh.VKUserID, sh.CreatedAt.Format("02.01 15:04"))
+	}
+	b.WriteString("Подробнее: !gm sheet <номер>")
+	return b.String()
+}
+
+func (s *GMService) handleSheet(ctx context.Context, fields []string) string {
+	if len(fields) < 3 {
+		return "Использование: !gm sheet <номер>"
+	}
+	id, ok := sheetID(fields[2])
+	if !ok {
+		return "Неверный номер анкеты."
+	}
+	sheet, err := s.sheets.Get(ctx, id)
+	if errors.Is(err, ErrSheetNotFound) {
+		return "Анкета не найдена."
+	}
+	if err != nil {
+		return "Ошибка: " + err.Error()
+	}
+	ch, changes, err := s.sheets.Diff(ctx, sheet)
+	if err != nil {
+		return "Ошибка: " + err.Error()
+	}
+	return FormatSheetChanges(sheet, ch, changes)
+}
+
+// handleSheetReview — решение ГМа: approve, reject или changes.
+func (s *GMService) handleSheetReview(ctx context.Context, fields []string, text string) string {
+	cmd := fields[1]
+	if len(fields) < 3 {
+		return fmt.Sprintf("Использование: !gm %s <номер> [комментарий]", cmd)
+	}
+	id, ok := sheetID(fields[2])
+	if !ok {
+		return "Неверный номер анкеты."
+	}
+	_, comment, _ := strings.Cut(text, fields[2])
+	comment = strings.TrimSpace(comment)
+	if cmd != "approve" && comment == "" {
+		return fmt.Sprintf("Напиши игроку, что не так: !gm %s %d <комментарий>", cmd, id)
+	}
+
+	var err error
+	var reply string
+	switch cmd {
+	case "approve":
+		var ch *models.Character
+		_, ch, err = s.sheets.Approve(ctx, id)
+		if err == nil {
+			reply = fmt.Sprintf("✅ Анкета #%d одобрена: %s обновлён.", id, ch.Name)
+		}
+	case "reject":
+		_, err = s.sheets.Reject(ctx, id, comment)
+		reply = fmt.Sprintf("❌ Анкета #%d отклонена.", id)
+	default:
+		_, err = s.sheets.RequestChanges(ctx, id, comment)
+		reply = fmt.Sprintf("✏️ Анкета #%d возвращена игроку на доработку.", id)
+	}
+	switch {
+	case errors.Is(err, ErrSheetNotFound):
+		return "Анкета не найдена."
+	case errors.Is(err, ErrSheetReviewed):
+		return fmt.Sprintf("Анкета #%d уже проверена.", id)
+	case errors.Is(err, ErrNameTaken):
+		return fmt.Sprintf("Имя из анкеты #%d уже занято другим персонажем. Верни её на доработку: !gm changes %d <комментарий>", id, id)
+	case err != nil:
+		return "Ошибка: " + err.Error()
+	}
+	return reply
+}
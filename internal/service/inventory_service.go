@@ -0,0 +1,523 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"aurora/internal/llm"
+	"aurora/internal/models"
+	"aurora/internal/repository"
+)
+
+var (
+	ErrItemNotOwned    = errors.New("item not owned")
+	ErrItemNotUsable   = errors.New("item not usable")
+	ErrItemNotEquip    = errors.New("item cannot be equipped")
+	ErrAlreadyEquipped = errors.New("item already equipped")
+)
+
+const defaultBuffDuration = 3
+
+type InventoryService struct {
+	items   *repository.ItemRepository
+	chars   *repository.CharacterRepository
+	effects *EffectService
+	clock   *ClockService
+}
+
+func NewInventoryService(items *repository.ItemRepository, chars *repository.CharacterRepository) *InventoryService {
+	return &InventoryService{items: items, chars: chars}
+}
+
+// SetEffects подключает справочник эффектов: бафы предметов получают его модификаторы и правила наложения.
+func (s *InventoryService) SetEffects(effects *EffectService) {
+	s.effects = effects
+}
+
+// SetClock подключает мировые часы: лимит лечения считается за ход мира.
+// Без них ходом считается реальный час.
+func (s *InventoryService) SetClock(clock *ClockService) {
+	s.clock = clock
+}
+
+func (s *InventoryService) turn() int64 {
+	if s.clock != nil {
+		return s.clock.Turn()
+	}
+	return time.Now().Unix() / 3600
+}
+
+// ItemActionResult описывает механический итог использования или экипировки.
+type ItemActionResult struct {
+	Item       models.Item
+	HPBefore   int
+	HPAfter    int
+	Effect     string
+	Duration   int
+	Consumed   bool
+	Remaining  int
+	Equipped   bool
+	Unequipped []string
+	PowerAfter int
+}
+
+// Summary — сухое описание итога для игрока и для нарратива LLM.
+func (r ItemActionResult) Summary() string {
+	var parts []string
+	if r.Equipped {
+		parts = append(parts, fmt.Sprintf("«%s» экипирован (%s).", r.Item.Name, models.SlotTitle(r.Item.EquipSlot)))
+		if len(r.Unequipped) > 0 {
+			parts = append(parts, "Снято: "+strings.Join(r.Unequipped, ", ")+".")
+		}
+		parts = append(parts, fmt.Sprintf("Боевой потенциал: %d.", r.PowerAfter))
+		return strings.Join(parts, " ")
+	}
+
+	parts = append(parts, fmt.Sprintf("Использован предмет «%s».", r.Item.Name))
+	if r.HPAfter != r.HPBefore {
+		parts = append(parts, fmt.Sprintf("Здоровье: %d → %d.", r.HPBefore, r.HPAfter))
+	}
+	if r.Effect != "" && r.Duration > 0 {
+		parts = append(parts, fmt.Sprintf("Эффект «%s» на %d ход(а).", r.Effect, r.Duration))
+	} else if r.Effect != "" {
+		parts = append(parts, fmt.Sprintf("Эффект «%s».", r.Effect))
+	}
+	if r.Consumed {
+		parts = append(parts, fmt.Sprintf("Предмет израсходован (осталось: %d).", r.Remaining))
+	}
+	if r.HPAfter == r.HPBefore && r.Effect == "" && !r.Consumed {
+		parts = append(parts, "Ощутимого эффекта нет.")
+	}
+	return strings.Join(parts, " ")
+}
+
+// itemQtyPattern ловит количество в записях анкеты: "Зелье лечения x3", "стрелы ×20", "факел (2)".
+var itemQtyPattern = regexp.MustCompile(`^(.*?)\s*(?:[xх×*]\s*(\d+)|\((\d+)\))$`)
+
+func (s *InventoryService) List(ctx context.Context, charID int64) ([]models.InventoryItem, error) {
+	return s.items.ListForCharacter(ctx, charID)
+}
+
+// EnsureItem возвращает предмет из каталога, создавая его при отсутствии.
+// Редкость нормализуется, стоимость ограничивается лимитами редкости.
+func (s *InventoryService) EnsureItem(ctx context.Context, it models.Item) (*models.Item, error) {
+	it.Name = strings.TrimSpace(it.Name)
+	if it.Name == "" {
+		return nil, errors.New("empty item name")
+	}
+
+	existing, err := s.items.GetByName(ctx, it.Name)
+	if err == nil {
+		return existing, nil
+	}
+	if err != sql.ErrNoRows {
+		return nil, err
+	}
+
+	it.Rarity = NormalizeRarity(it.Rarity)
+	it.Value = ClampItemValue(it.Rarity, it.Value)
+
+	if _, err := s.items.Create(ctx, &it); err != nil {
+		return nil, err
+	}
+	return s.items.GetByName(ctx, it.Name)
+}
+
+func (s *InventoryService) AddItem(ctx context.Context, charID int64, it models.Item, qty int) (*models.Item, error) {
+	if qty <= 0 {
+		qty = 1
+	}
+	item, err := s.EnsureItem(ctx, it)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.items.AddToCharacter(ctx, charID, item.ID, qty); err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+// ImportFromForm переносит предметы из нормализованной анкеты в инвентарь.
+// Уже имеющиеся у персонажа предметы не дублируются, поэтому повторная
+// отправка анкеты безопасна.
+func (s *InventoryService) ImportFromForm(ctx context.Context, charID int64, entries []string) error {
+	owned, err := s.items.ListForCharacter(ctx, charID)
+	if err != nil {
+		return err
+	}
+	have := make(map[string]struct{}, len(owned))
+	for _, inv := range owned {
+		have[repository.ItemNameKey(inv.Item.Name)] = struct{}{}
+	}
+
+	for _, entry := range entries {
+		name, qty := ParseItemEntry(entry)
+		if name == "" {
+			continue
+		}
+		if _, ok := have[repository.ItemNameKey(name)]; ok {
+			continue
+		}
+		item := models.Item{Name: name, Rarity: models.RarityCommon}
+		item.EquipSlot, item.Properties = inferItemTraits(name)
+		if _, err := s.AddItem(ctx, charID, item, qty); err != nil {
+			return fmt.Errorf("import %q: %w", name, err)
+		}
+		have[repository.ItemNameKey(name)] = struct{}{}
+	}
+	return nil
+}
+
+// ImportLegacy переносит предметы из старой текстовой колонки
+// characters.inventory в character_items и очищает её. Записи разделены
+// запятыми, точками с запятой или переносами строк. Повторный запуск
+// безопасен: ImportFromForm не дублирует уже имеющиеся предметы.
+func (s *InventoryService) ImportLegacy(ctx context.Context) (int, error) {
+	legacy, err := s.items.LegacyInventories(ctx)
+	if err != nil {
+		return 0, err
+	}
+	imported := 0
+	for charID, text := range legacy {
+		entries := strings.FieldsFunc(text, func(r rune) bool {
+			return r == ',' || r == ';' || r == '\n'
+		})
+		if err := s.ImportFromForm(ctx, charID, entries); err != nil {
+			return imported, fmt.Errorf("character %d: %w", charID, err)
+		}
+		if err := s.items.ClearLegacyInventory(ctx, charID); err != nil {
+			return imported, err
+		}
+		imported++
+	}
+	return imported, nil
+}
+
+// UseItem применяет предмет по правилам: лечение за ход мира ограничено
+// MaxHealingPerTurn и пределом здоровья, бафы становятся эффектами персонажа,
+// расходники списываются вместе с лечением и эффектом одной транзакцией.
+// Предмет, от которого сейчас нет толку, не тратится.
+func (s *InventoryService) UseItem(ctx context.Context, ch *models.Character, target string) (*ItemActionResult, error) {
+	inv := FindOwnedItem(ch.Items, target)
+	if inv == nil {
+		return nil, ErrItemNotOwned
+	}
+	item := inv.Item
+
+	heal := item.IntProp(models.PropHeal)
+	buff := strings.TrimSpace(item.Properties[models.PropBuff])
+	if heal <= 0 && buff == "" && !item.IsConsumable() {
+		return &ItemActionResult{Item: item}, ErrItemNotUsable
+	}
+
+	var effect *models.Effect
+	if buff != "" {
+		e := models.Effect{
+			CharacterID: ch.ID,
+			Name:        buff,
+			Description: "Эффект предмета «" + item.Name + "»",
+			Duration:    item.IntProp(models.PropDuration),
+		}
+		if s.effects == nil {
+			if e.Duration <= 0 {
+				e.Duration = defaultBuffDuration
+			}
+			effect = &e
+		} else {
+			if _, ok := s.effects.Template(buff); ok {
+				e.Description = ""
+			} else if e.Duration <= 0 {
+				e.Duration = defaultBuffDuration
+			}
+			e, err := s.effects.resolve(ch, e)
+			switch {
+			case errors.Is(err, ErrEffectActive):
+				if heal <= 0 {
+					return &ItemActionResult{Item: item, Effect: e.Name}, err
+				}
+			case err != nil:
+				return nil, err
+			default:
+				effect = &e
+			}
+		}
+	}
+
+	res := &ItemActionResult{Item: item, HPBefore: ch.CombatHealth, HPAfter: ch.CombatHealth}
+
+	before, after, err := s.items.Use(ctx, repository.ItemUse{
+		CharacterID: ch.ID,
+		ItemID:      item.ID,
+		Consume:     item.IsConsumable(),
+		Heal:        heal,
+		Turn:        s.turn(),
+		HealCap:     llm.MaxHealingPerTurn,
+		MaxHP:       ch.MaxHealth(),
+		Effect:      effect,
+	})
+	if err != nil {
+		return &ItemActionResult{Item: item}, err
+	}
+	ch.CombatHealth = after
+	res.HPBefore, res.HPAfter = before, after
+	if item.IsConsumable() {
+		res.Consumed = true
+		res.Remaining = inv.Quantity - 1
+	}
+	if effect != nil {
+		setEffect(ch, *effect)
+		res.Effect = effect.Name
+		res.Duration = effect.Duration
+	}
+
+	if ch.Items, err = s.items.ListForCharacter(ctx, ch.ID); err != nil {
+		return res, fmt.Errorf("reload inventory: %w", err)
+	}
+	return res, nil
+}
+
+// Equip экипирует предмет в его слот; прочие предметы этого слота снимаются.
+func (s *InventoryService) Equip(ctx context.Context, ch *models.Character, target string) (*ItemActionResult, error) {
+	inv := FindOwnedItem(ch.Items, target)
+	if inv == nil {
+		return nil, ErrItemNotOwned
+	}
+	if inv.Item.EquipSlot == "" {
+		return &ItemActionResult{Item: inv.Item}, ErrItemNotEquip
+	}
+	if inv.IsEquipped {
+		return &ItemActionResult{Item: inv.Item}, ErrAlreadyEquipped
+	}
+
+	res := &ItemActionResult{Item: inv.Item, Equipped: true}
+	for _, other := range ch.Items {
+		if other.IsEquipped && other.Item.EquipSlot == inv.Item.EquipSlot {
+			res.Unequipped = append(res.Unequipped, other.Item.Name)
+		}
+	}
+
+	if err := s.items.Equip(ctx, ch.ID, inv.Item.ID, inv.Item.EquipSlot); err != nil {
+		return nil, err
+	}
+
+	var err error
+	if ch.Items, err = s.items.ListForCharacter(ctx, ch.ID); err != nil {
+		return res, fmt.Errorf("reload inventory: %w", err)
+	}
+	res.PowerAfter = ch.EffectiveCombatPower()
+	return res, nil
+}
+
+// FindOwnedItem нечетко сопоставляет цель из интента с предметом инвентаря.
+func FindOwnedItem(items []models.InventoryItem, target string) *models.InventoryItem {
+	names := make([]string, len(items))
+	for i, inv := range items {
+		names[i] = inv.Item.Name
+	}
+	idx := MatchName(names, target)
+	if idx < 0 {
+		return nil
+	}
+	return &items[idx]
+}
+
+// MatchName ищет название, наиболее похожее на target: точное совпадение,
+// затем вхождение подстроки, затем похожесть слов. Возвращает -1, если
+// ничего достаточно похожего нет.
+func MatchName(names []string, target string) int {
+	key := repository.ItemNameKey(target)
+	if key == "" {
+		return -1
+	}
+
+	for i, n := range names {
+		if repository.ItemNameKey(n) == key {
+			return i
+		}
+	}
+
+	for i, n := range names {
+		name := repository.ItemNameKey(n)
+		if strings.Contains(name, key) || strings.Contains(key, name) {
+			return i
+		}
+	}
+
+	best, bestScore := -1, 0.0
+	for i, n := range names {
+		if score := wordSimilarity(repository.ItemNameKey(n), key); score > bestScore {
+			best, bestScore = i, score
+		}
+	}
+	if bestScore < 0.6 {
+		return -1
+	}
+	return best
+}
+
+// wordSimilarity — доля слов запроса, для которых в названии нашлось
+// похожее слово (учитывает падежи: "зелья" ~ "зелье").
+func wordSimilarity(name, query string) float64 {
+	qWords := strings.Fields(query)
+	nWords := strings.Fields(name)
+	if len(qWords) == 0 || len(nWords) == 0 {
+		return 0
+	}
+
+	var total float64
+	for _, q := range qWords {
+		best := 0.0
+		for _, n := range nWords {
+			if sim := runeSimilarity(q, n); sim > best {
+				best = sim
+			}
+		}
+		total += best
+	}
+	return total / float64(len(qWords))
+}
+
+func runeSimilarity(a, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+	maxLen := len(ra)
+	if len(rb) > maxLen {
+		maxLen = len(rb)
+	}
+	if maxLen == 0 {
+		return 1
+	}
+	return 1 - float64(levenshtein(ra, rb))/float64(maxLen)
+}
+
+func levenshtein(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+// inferItemTraits угадывает слот и базовые свойства предметов из анкеты,
+// чтобы зелья можно было выпить, а оружие — взять в руки.
+// Предметы из каталога ГМа сохраняют свои свойства.
+func inferItemTraits(name string) (string, map[string]string) {
+	key := repository.ItemNameKey(name)
+	props := map[string]string{}
+	has := func(words ...string) bool {
+		for _, w := range words {
+			if strings.Contains(key, w) {
+				return true
+			}
+		}
+		return false
+	}
+
+	switch {
+	case has("зелье", "эликсир", "настойк", "снадобь", "бинт", "травы"):
+		props[models.PropConsumable] = "true"
+		if has("лечен", "исцел", "здоров", "бинт", "травы") {
+			props[models.PropHeal] = "20"
+		}
+		return "", props
+	case has("меч", "кинжал", "топор", "секир", "лук", "арбалет", "копь", "посох", "булав", "молот", "клинок", "нож"):
+		props[models.PropPower] = "2"
+		return models.SlotWeapon, props
+	case has("щит"):
+		props[models.PropPower] = "1"
+		return models.SlotOffhand, props
+	case has("доспех", "кольчуг", "брон", "латы", "кираса", "куртка"):
+		props[models.PropPower] = "1"
+		return models.SlotArmor, props
+	case has("шлем", "капюшон"):
+		return models.SlotHead, props
+	case has("кольцо", "амулет", "медальон", "оберег"):
+		return models.SlotAccessory, props
+	}
+	return "", props
+}
+
+func ParseItemEntry(entry string) (string, int) {
+	entry = strings.TrimSpace(strings.Trim(entry, "-—•;,. "))
+	if entry == "" {
+		return "", 0
+	}
+
+	m := itemQtyPattern.FindStringSubmatch(entry)
+	if m == nil {
+		return entry, 1
+	}
+	qtyStr := m[2]
+	if qtyStr == "" {
+		qtyStr = m[3]
+	}
+	qty, err := strconv.Atoi(qtyStr)
+	if err != nil || qty <= 0 {
+		return entry, 1
+	}
+	return strings.TrimSpace(m[1]), qty
+}
+
+func NormalizeRarity(rarity string) string {
+	rarity = strings.ToLower(strings.TrimSpace(rarity))
+	if _, ok := llm.ItemRarityLimits[rarity]; ok {
+		return rarity
+	}
+	return models.RarityCommon
+}
+
+func ClampItemValue(rarity string, value int) int {
+	limit, ok := llm.ItemRarityLimits[rarity]
+	if !ok {
+		limit = llm.ItemRarityLimits[models.RarityCommon]
+	}
+	if value > limit {
+		return limit
+	}
+	if value < 0 {
+		return 0
+	}
+	return value
+}
+
+func FormatInventory(ch *models.Character) string {
+	if len(ch.Items) == 0 {
+		return "🎒 Инвентарь пуст."
+	}
+
+	var b strings.Builder
+	b.WriteString("🎒 Инвентарь: " + ch.Name + "\n")
+	for _, inv := range ch.Items {
+		b.WriteString("— " + inv.Item.Name)
+		if inv.Quantity > 1 {
+			b.WriteString(fmt.Sprintf(" ×%d", inv.Quantity))
+		}
+		b.WriteString(fmt.Sprintf(" (%s", inv.Item.RarityTitle()))
+		if inv.Item.Value > 0 {
+			b.WriteString(fmt.Sprintf(", %d ₸", inv.Item.Value))
+		}
+		b.WriteString(")")
+		if inv.IsEquipped {
+			b.WriteString(" [экипировано: " + models.SlotTitle(inv.Item.EquipSlot) + "]")
+		}
+		b.WriteString("\n")
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
@@ -0,0 +1,124 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"aurora/internal/llm"
+	"aurora/internal/models"
+	"aurora/internal/repository"
+)
+
+var ErrZeroAmount = errors.New("zero amount")
+
+type LedgerService struct {
+	repo *repository.LedgerRepository
+}
+
+func NewLedgerService(repo *repository.LedgerRepository) *LedgerService {
+	return &LedgerService{repo: repo}
+}
+
+// Credit начисляет золото и обновляет ch.Gold новым балансом.
+func (s *LedgerService) Credit(ctx context.Context, ch *models.Character, amount int, source, reason, refID string) error {
+	if amount <= 0 {
+		return ErrZeroAmount
+	}
+	return s.apply(ctx, ch, amount, source, reason, refID)
+}
+
+// Debit списывает золото; при нехватке возвращает repository.ErrNotEnoughGold.
+func (s *LedgerService) Debit(ctx context.Context, ch *models.Character, amount int, source, reason, refID string) error {
+	if amount <= 0 {
+		return ErrZeroAmount
+	}
+	return s.apply(ctx, ch, -amount, source, reason, refID)
+}
+
+// Adjust — ручная правка баланса ведущим (amount может быть отрицательным).
+func (s *LedgerService) Adjust(ctx context.Context, ch *models.Character, amount int, reason string) error {
+	if amount == 0 {
+		return ErrZeroAmount
+	}
+	return s.apply(ctx, ch, amount, models.LedgerSourceGM, reason, "")
+}
+
+func (s *LedgerService) apply(ctx context.Context, ch *models.Character, amount int, source, reason, refID string) error {
+	balance, err := s.repo.Record(ctx, models.LedgerEntry{
+		CharacterID: ch.ID,
+		Amount:      amount,
+		Reason:      reason,
+		Source:      source,
+		RefID:       refID,
+	})
+	if err != nil {
+		return err
+	}
+	ch.Gold = balance
+	return nil
+}
+
+func (s *LedgerService) History(ctx context.Context, charID int64, limit int) ([]models.LedgerEntry, error) {
+	return s.repo.ListForCharacter(ctx, charID, limit)
+}
+
+// Anomalies — доходы выше llm.MaxQuestGold за квест и llm.MaxCombatGold за бой.
+func (s *LedgerService) Anomalies(ctx context.Context, limit int) ([]models.LedgerEntry, error) {
+	return s.repo.Anomalies(ctx, llm.MaxQuestGold, llm.MaxCombatGold, limit)
+}
+
+func (s *LedgerService) Mismatches(ctx context.Context) ([]repository.BalanceMismatch, error) {
+	return s.repo.Mismatches(ctx)
+}
+
+// Reconcile приводит characters.gold к сумме журнала. Возвращает старый и новый баланс.
+func (s *LedgerService) Reconcile(ctx context.Context, ch *models.Character) (int, int, error) {
+	before := ch.Gold
+	after, err := s.repo.SetBalanceFromLedger(ctx, ch.ID)
+	if err != nil {
+		return 0, 0, err
+	}
+	ch.Gold = after
+	return before, after, nil
+}
+
+func LedgerSourceTitle(source string) string {
+	switch source {
+	case models.LedgerSourceQuest:
+		return "квест"
+	case models.LedgerSourceCombat:
+		return "бой"
+	case models.LedgerSourceShop:
+		return "лавка"
+	case models.LedgerSourceGM:
+		return "ГМ"
+	case models.LedgerSourceTrade:
+		return "обмен"
+	}
+	return source
+}
+
+func FormatLedgerEntry(e models.LedgerEntry) string {
+	line := fmt.Sprintf("#%d %s %+d → %d (%s) %s",
+		e.ID, e.CreatedAt.Format("02.01 15:04"), e.Amount, e.BalanceAfter, LedgerSourceTitle(e.Source), e.Reason)
+	if e.RefID != "" {
+		line += " [" + e.RefID + "]"
+	}
+	return line
+}
+
+func FormatLedger(name string, gold int, entries []models.LedgerEntry) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "📒 Журнал золота: %s (баланс %d)\n", name, gold)
+	if len(entries) == 0 {
+		b.WriteString("Записей нет.")
+		return b.String()
+	}
+	for _, e := range entries {
+		b.WriteString(FormatLedgerEntry(e))
+		b.WriteString("\n")
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
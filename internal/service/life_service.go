@@ -0,0 +1,251 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"aurora/internal/models"
+	"aurora/internal/repository"
+)
+
+// bleedOut — сколько реального времени умирающий держится без помощи. Игровые
+// часы двигают действия всех игроков, и по ним раненого добивала бы чужая
+// болтовня за считанные секунды.
+const bleedOut = 2 * time.Hour
+
+var (
+	ErrCharacterDead     = errors.New("character is dead")
+	ErrCharacterDying    = errors.New("character is dying")
+	ErrCharacterNotDead  = errors.New("character is not dead")
+	ErrResurrectionTaken = errors.New("resurrection quest already active")
+)
+
+// LifeAction — вид действия игрока для проверки состояния жизни.
+type LifeAction int
+
+const (
+	ActionRP LifeAction = iota
+	ActionQuest
+	ActionCombat
+	ActionTravel
+	ActionTrade
+	ActionItem
+)
+
+// LifeChange — смена состояния жизни персонажа.
+type LifeChange struct {
+	CharacterID int64
+	VKUserID    int64
+	Name        string
+	From        string
+	To          string
+}
+
+func (c LifeChange) Text() string {
+	switch {
+	case c.To == models.LifeDying:
+		return fmt.Sprintf("🩸 %s падает без сил и истекает кровью. Без лечения смерть наступит через %s.", c.Name, FormatGameDuration(int64(bleedOut/time.Minute)))
+	case c.To == models.LifeDead:
+		return fmt.Sprintf("☠️ %s умирает. Смерть окончательна: можно завести нового персонажа (!новый персонаж) или просить ГМа о воскрешении (!воскрешение).", c.Name)
+	case c.From == models.LifeDead:
+		return fmt.Sprintf("✨ %s возвращается из-за Грани. Тело слабо, но живо.", c.Name)
+	case c.From == models.LifeDying:
+		return fmt.Sprintf("🩹 Кровь остановлена: %s больше не при смерти.", c.Name)
+	case c.To == models.LifeWounded:
+		return fmt.Sprintf("🤕 %s тяжело ранен.", c.Name)
+	}
+	return ""
+}
+
+// LifeService ведёт состояния персонажа: жив → ранен → при смерти → мёртв.
+// Здоровье меняют бой, эффекты и предметы; здесь по нему выставляется состояние.
+type LifeService struct {
+	chars  *repository.CharacterRepository
+	quests *repository.QuestRepository
+}
+
+func NewLifeService(chars *repository.CharacterRepository, quests *repository.QuestRepository) *LifeService {
+	return &LifeService{chars: chars, quests: quests}
+}
+
+// nextState — куда переходит персонаж при данном здоровье. Мёртвого
+// возвращает только воскрешение.
+func nextState(cur string, hp int) string {
+	switch {
+	case cur == models.LifeDead:
+		return models.LifeDead
+	case hp <= 0:
+		return models.LifeDying
+	case hp < models.HealthWounded:
+		return models.LifeWounded
+	default:
+		return models.LifeAlive
+	}
+}
+
+// Sync приводит состояние персонажа в соответствие со здоровьем и таймером
+// кровотечения. Возвращает nil, если состояние не изменилось.
+func (s *LifeService) Sync(ctx context.Context, ch *models.Character) (*LifeChange, error) {
+	now := time.Now().Unix()
+	to := nextState(ch.Status, ch.CombatHealth)
+	if to == models.LifeDying && ch.Status == models.LifeDying && ch.DyingUntil > 0 && ch.DyingUntil <= now {
+		to = models.LifeDead
+	}
+	if to == ch.Status {
+		return nil, nil
+	}
+	return s.setState(ctx, ch, to, now)
+}
+
+func (s *LifeService) setState(ctx context.Context, ch *models.Character, to string, now int64) (*LifeChange, error) {
+	change := &LifeChange{CharacterID: ch.ID, VKUserID: ch.VKUserID, Name: ch.Name, From: ch.Status, To: to}
+	var until int64
+	if to == models.LifeDying {
+		until = ch.DyingUntil
+		if ch.Status != models.LifeDying || until == 0 {
+			until = now + int64(bleedOut/time.Second)
+		}
+	}
+	if err := s.chars.SetLife(ctx, ch.ID, to, until); err != nil {
+		return nil, err
+	}
+	if to == models.LifeDead {
+		if err := s.quests.FailActiveForCharacter(ctx, ch.ID); err != nil {
+			return nil, err
+		}
+	}
+	ch.Status = to
+	ch.DyingUntil = until
+	return change, nil
+}
+
+// Sweep проверяет персонажей, которые могут умереть без собственных действий:
+// упавших до нуля от эффектов и истёкших кровью. Вызывается мировыми часами и
+// раз в минуту по реальному времени.
+func (s *LifeService) Sweep(ctx context.Context) ([]LifeChange, error) {
+	list, err := s.chars.ListLifeCandidates(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var out []LifeChange
+	var errs []error
+	for i := range list {
+		ch := &list[i]
+		change, err := s.Sync(ctx, ch)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if change != nil {
+			out = append(out, *change)
+		}
+	}
+	return out, errors.Join(errs...)
+}
+
+// Allow сообщает, может ли персонаж сейчас совершить действие. Мёртвому
+// доступен только квест на воскрешение, умирающему — лишь отыгрыш и предметы.
+func (s *LifeService) Allow(ctx context.Context, ch *models.Character, action LifeAction) error {
+	switch ch.Status {
+	case models.LifeDead:
+		if action == ActionQuest {
+			if q, err := s.ResurrectionQuest(ctx, ch.ID); err == nil && q != nil {
+				return nil
+			}
+		}
+		return ErrCharacterDead
+	case models.LifeDying:
+		if action == ActionRP || action == ActionItem {
+			return nil
+		}
+		return ErrCharacterDying
+	}
+	return nil
+}
+
+// ResurrectionQuest — активный квест на воскрешение персонажа, если он есть.
+func (s *LifeService) ResurrectionQuest(ctx context.Context, charID int64) (*models.Quest, error) {
+	active, err := s.quests.GetActiveForCharacter(ctx, charID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range active {
+		if active[i].Kind == models.QuestKindResurrection {
+			return &active[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// StartResurrection открывает одобренный ГМом квест на воскрешение мёртвого персонажа.
+func (s *LifeService) StartResurrection(ctx context.Context, ch *models.Character, task string) (*models.Quest, error) {
+	if !ch.IsDead() {
+		return nil, ErrCharacterNotDead
+	}
+	if q, err := s.ResurrectionQuest(ctx, ch.ID); err != nil {
+		return nil, err
+	} else if q != nil {
+		return q, ErrResurrectionTaken
+	}
+	task = strings.TrimSpace(task)
+	if task == "" {
+		task = "Душа " + ch.Name + " блуждает за Гранью. Найди путь назад: тех, кто помнит тебя, и цену, которую придётся заплатить за возвращение."
+	}
+	now := time.Now()
+	q := &models.Quest{
+		CharacterID: ch.ID,
+		Title:       "Возвращение из-за Грани",
+		Description: task,
+		Stage:       1,
+		Status:      "active",
+		From:        "gm",
+		Difficulty:  "hard",
+		Kind:        models.QuestKindResurrection,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	id, err := s.quests.Create(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	q.ID = id
+	return q, nil
+}
+
+// Resurrect возвращает мёртвого персонажа к жизни тяжело раненым.
+func (s *LifeService) Resurrect(ctx context.Context, ch *models.Character) (*LifeChange, error) {
+	if !ch.IsDead() {
+		return nil, ErrCharacterNotDead
+	}
+	if err := s.chars.Revive(ctx, ch.ID, models.HealthCritical); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrCharacterNotDead
+		}
+		return nil, err
+	}
+	ch.CombatHealth = models.HealthCritical
+	return s.setState(ctx, ch, models.LifeWounded, time.Now().Unix())
+}
+
+// Archive убирает мёртвого персонажа в архив, чтобы игрок мог начать заново.
+func (s *LifeService) Archive(ctx context.Context, ch *models.Character) error {
+	if !ch.IsDead() {
+		return ErrCharacterNotDead
+	}
+	return s.chars.Archive(ctx, ch.ID)
+}
+
+// BlockedText — ответ игроку на запрещённое состоянием действие.
+func BlockedText(err error) string {
+	switch {
+	case errors.Is(err, ErrCharacterDead):
+		return "☠️ Твой персонаж мёртв. Доступно: !новый персонаж, !воскрешение <просьба к ГМу>."
+	case errors.Is(err, ErrCharacterDying):
+		return "🩸 Ты при смерти и не можешь этого сделать. Лечение или помощь других ещё могут спасти тебя."
+	}
+	return ""
+}
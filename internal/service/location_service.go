@@ -3,15 +3,25 @@ package service
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"fmt"
 	"strings"
 	"time"
 
+	"aurora/internal/lore"
 	"aurora/internal/models"
 	"aurora/internal/repository"
 )
 
+// LocationIndexer кладёт описание локации в векторное хранилище,
+// чтобы RAG находил его по зоне локации.
+type LocationIndexer interface {
+	IndexFromText(ctx context.Context, docID, title, content, zone string, tags []string) error
+}
+
 type LocationService struct {
-	repo *repository.LocationRepository
+	repo    *repository.LocationRepository
+	indexer LocationIndexer
 }
 
 func NewLocationService(repo *repository.LocationRepository) *LocationService {
@@ -41,11 +51,85 @@ func (s *LocationService) Create(ctx context.Context, name, desc, tags, createdB
 		Name:        name,
 		Description: desc,
 		Tags:        tags,
+		IsActive:    true,
 		CreatedBy:   createdBy,
 		CreatedAt:   time.Now(),
 	}, nil
 }
 
+func (s *LocationService) SetIndexer(idx LocationIndexer) {
+	s.indexer = idx
+}
+
+// Index (пере)индексирует описание локации под зоной с её названием.
+// Без подключённого RAG ничего не делает.
+func (s *LocationService) Index(ctx context.Context, loc *models.Location) error {
+	if s.indexer == nil || strings.TrimSpace(loc.Description) == "" {
+		return nil
+	}
+	tags := []string{"location"}
+	for _, t := range strings.Split(loc.Tags, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			tags = append(tags, t)
+		}
+	}
+	docID := fmt.Sprintf("location_%d", loc.ID)
+	if err := s.indexer.IndexFromText(ctx, docID, loc.Name, loc.Description, loc.Name, tags); err != nil {
+		return fmt.Errorf("index location %s: %w", loc.Name, err)
+	}
+	return nil
+}
+
+// Update меняет описание и теги; пустое значение оставляет прежнее.
+func (s *LocationService) Update(ctx context.Context, name, desc, tags string) (*models.Location, error) {
+	loc, err := s.Match(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if desc = strings.TrimSpace(desc); desc != "" {
+		loc.Description = desc
+	}
+	if tags = strings.TrimSpace(tags); tags != "" {
+		loc.Tags = tags
+	}
+	if err := s.repo.Update(ctx, loc.ID, loc.Description, loc.Tags); err != nil {
+		return nil, err
+	}
+	return loc, nil
+}
+
+// SetActive закрывает или снова открывает локацию для путешествий.
+func (s *LocationService) SetActive(ctx context.Context, name string, active bool) (*models.Location, error) {
+	loc, err := s.Match(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.SetActive(ctx, loc.ID, active); err != nil {
+		return nil, err
+	}
+	loc.IsActive = active
+	return loc, nil
+}
+
+// Link соединяет две локации дорогой в обе стороны.
+func (s *LocationService) Link(ctx context.Context, a, b string, hours, danger int) (*models.Location, *models.Location, error) {
+	from, err := s.Match(ctx, a)
+	if err != nil {
+		return nil, nil, err
+	}
+	to, err := s.Match(ctx, b)
+	if err != nil {
+		return nil, nil, err
+	}
+	if from.ID == to.ID {
+		return nil, nil, ErrAlreadyThere
+	}
+	if err := s.repo.Link(ctx, from.ID, to.ID, hours, danger, nil); err != nil {
+		return nil, nil, err
+	}
+	return from, to, nil
+}
+
 func (s *LocationService) GetByName(ctx context.Context, name string) (*models.Location, error) {
 	return s.repo.GetByName(ctx, name)
 }
@@ -60,3 +144,164 @@ func (s *LocationService) List(ctx context.Context, limit int) ([]models.Locatio
 	}
 	return s.repo.List(ctx, limit)
 }
+
+var (
+	ErrUnknownLocation = errors.New("unknown location")
+	ErrNoRoute         = errors.New("no route")
+	ErrAlreadyThere    = errors.New("already at location")
+	ErrOffMap          = errors.New("current location is not on the map")
+	ErrLocationClosed  = errors.New("location is closed")
+)
+
+// RouteLeg — один переход между соседними локациями.
+type RouteLeg struct {
+	From models.Location
+	To   models.Location
+	Link models.LocationLink
+}
+
+type Route struct {
+	Legs       []RouteLeg
+	TotalHours int
+}
+
+// SyncMap заносит локации и дороги из лора. Существующие локации не
+// перезаписываются, дороги обновляются. Новые локации индексируются для RAG;
+// ошибки индексации не мешают построить граф и возвращаются в конце.
+func (s *LocationService) SyncMap(ctx context.Context, m *lore.WorldMap) error {
+	if m == nil {
+		return nil
+	}
+	ids := make(map[string]int64, len(m.Locations))
+	var indexErrs []error
+	for _, l := range m.Locations {
+		_, err := s.repo.GetByName(ctx, l.Name)
+		isNew := err == sql.ErrNoRows
+		loc, err := s.Create(ctx, l.Name, l.Description, l.Tags, "lore")
+		if err != nil {
+			return fmt.Errorf("sync location %s: %w", l.Name, err)
+		}
+		ids[l.Name] = loc.ID
+		if isNew {
+			if err := s.Index(ctx, loc); err != nil {
+				indexErrs = append(indexErrs, err)
+			}
+		}
+	}
+	for _, link := range m.Links {
+		from, ok1 := ids[link.From]
+		to, ok2 := ids[link.To]
+		if !ok1 || !ok2 {
+			return fmt.Errorf("link %s — %s: %w", link.From, link.To, ErrUnknownLocation)
+		}
+		if err := s.repo.Link(ctx, from, to, link.Hours, link.Danger, link.Encounters); err != nil {
+			return fmt.Errorf("link %s — %s: %w", link.From, link.To, err)
+		}
+	}
+	return errors.Join(indexErrs...)
+}
+
+// Match ищет локацию по точному или приблизительному названию.
+func (s *LocationService) Match(ctx context.Context, name string) (*models.Location, error) {
+	all, err := s.repo.All(ctx)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, len(all))
+	for i, l := range all {
+		names[i] = l.Name
+	}
+	idx := MatchName(names, name)
+	if idx < 0 {
+		return nil, ErrUnknownLocation
+	}
+	return &all[idx], nil
+}
+
+func (s *LocationService) Neighbors(ctx context.Context, locID int64) ([]models.LocationLink, error) {
+	links, err := s.repo.Links(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var out []models.LocationLink
+	for _, l := range links {
+		if l.FromID == locID {
+			out = append(out, l)
+		}
+	}
+	return out, nil
+}
+
+// FindRoute строит кратчайший по времени путь (Дейкстра по travel_hours).
+func (s *LocationService) FindRoute(ctx context.Context, fromName, toName string) (*Route, error) {
+	from, err := s.repo.GetByName(ctx, fromName)
+	if err == sql.ErrNoRows {
+		return nil, ErrOffMap
+	}
+	if err != nil {
+		return nil, err
+	}
+	to, err := s.Match(ctx, toName)
+	if err != nil {
+		return nil, err
+	}
+	if from.ID == to.ID {
+		return nil, ErrAlreadyThere
+	}
+	if !to.IsActive {
+		return nil, ErrLocationClosed
+	}
+
+	all, err := s.repo.All(ctx)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[int64]models.Location, len(all))
+	for _, l := range all {
+		byID[l.ID] = l
+	}
+	links, err := s.repo.Links(ctx)
+	if err != nil {
+		return nil, err
+	}
+	adj := make(map[int64][]models.LocationLink)
+	for _, l := range links {
+		adj[l.FromID] = append(adj[l.FromID], l)
+	}
+
+	const inf = int(^uint(0) >> 1)
+	dist := map[int64]int{from.ID: 0}
+	prev := make(map[int64]models.LocationLink)
+	done := make(map[int64]bool)
+	for {
+		cur, best := int64(0), inf
+		for id, d := range dist {
+			if !done[id] && d < best {
+				cur, best = id, d
+			}
+		}
+		if best == inf || cur == to.ID {
+			break
+		}
+		done[cur] = true
+		for _, l := range adj[cur] {
+			nd := best + l.TravelHours
+			if d, ok := dist[l.ToID]; !ok || nd < d {
+				dist[l.ToID] = nd
+				prev[l.ToID] = l
+			}
+		}
+	}
+
+	total, ok := dist[to.ID]
+	if !ok {
+		return nil, ErrNoRoute
+	}
+	var legs []RouteLeg
+	for id := to.ID; id != from.ID; {
+		l := prev[id]
+		legs = append([]RouteLeg{{From: byID[l.FromID], To: byID[l.ToID], Link: l}}, legs...)
+		id = l.FromID
+	}
+	return &Route{Legs: legs, TotalHours: total}, nil
+}
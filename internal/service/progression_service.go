@@ -0,0 +1,180 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"aurora/internal/lore"
+	"aurora/internal/models"
+	"aurora/internal/repository"
+)
+
+// maxGrantXP — потолок разовой выдачи опыта, чтобы опечатка ГМа не дала десяток уровней.
+const maxGrantXP = 1000
+
+var ErrInvalidXP = errors.New("invalid xp amount")
+
+// LevelUp — один полученный уровень и то, что он принёс.
+type LevelUp struct {
+	Level     int
+	PowerGain int
+	Abilities []string
+}
+
+type XPResult struct {
+	Gained   int
+	XP       int
+	Level    int
+	LevelUps []LevelUp
+}
+
+type ProgressionService struct {
+	repo  *repository.CharacterRepository
+	rules *lore.Progression
+}
+
+func NewProgressionService(repo *repository.CharacterRepository, rules *lore.Progression) *ProgressionService {
+	if rules == nil {
+		rules = &lore.Progression{}
+	}
+	return &ProgressionService{repo: repo, rules: rules}
+}
+
+func (s *ProgressionService) QuestXP(difficulty string) int {
+	if xp, ok := s.rules.QuestXP[strings.ToLower(difficulty)]; ok {
+		return xp
+	}
+	return s.rules.QuestXP["normal"]
+}
+
+// CombatXP — опыт за победу; поражение опыта не даёт.
+func (s *ProgressionService) CombatXP() int {
+	return s.rules.CombatXP["win"]
+}
+
+// QuestAbility ищет способность в списке тех, что разрешено давать за квесты.
+func (s *ProgressionService) QuestAbility(name string) (lore.AbilityUnlock, bool) {
+	key := repository.ItemNameKey(name)
+	for _, a := range s.rules.QuestAbilities {
+		if key != "" && repository.ItemNameKey(a.Name) == key {
+			return a, true
+		}
+	}
+	return lore.AbilityUnlock{}, false
+}
+
+// NextLevelXP — порог следующего уровня; false, если уровень максимальный.
+func (s *ProgressionService) NextLevelXP(level int) (int, bool) {
+	for _, l := range s.rules.Levels {
+		if l.Level > level {
+			return l.XP, true
+		}
+	}
+	return 0, false
+}
+
+// GrantXP начисляет опыт, применяет все пройденные пороги уровней и
+// сохраняет персонажа. При повышении здоровье восстанавливается полностью.
+// Опыт прибавляется к тому, что в базе, а уровень меняется условно, так что
+// параллельные начисления не теряются и не дают один уровень дважды.
+func (s *ProgressionService) GrantXP(ctx context.Context, ch *models.Character, amount int) (*XPResult, error) {
+	if amount <= 0 || amount > maxGrantXP {
+		return nil, ErrInvalidXP
+	}
+	xp, level, err := s.repo.AddXP(ctx, ch.ID, amount)
+	if err != nil {
+		return nil, err
+	}
+	ch.XP, ch.Level = xp, max(level, 1)
+
+	res := &XPResult{Gained: amount}
+	var unlocked []lore.AbilityUnlock
+	for _, l := range s.rules.Levels {
+		if l.Level <= ch.Level || ch.XP < l.XP {
+			continue
+		}
+		err := s.repo.LevelUp(ctx, ch.ID, level, l.Level, l.CombatPower, ch.MaxHealth())
+		if errors.Is(err, sql.ErrNoRows) {
+			// уровень уже поднял параллельный вызов, он же выдаст и награды
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		level = l.Level
+		ch.Level = l.Level
+		ch.CombatPower += l.CombatPower
+		ch.CombatHealth = ch.MaxHealth()
+
+		up := LevelUp{Level: l.Level, PowerGain: l.CombatPower}
+		for _, a := range s.rules.Abilities {
+			if a.Level != l.Level {
+				continue
+			}
+			if a.Race != "" && !strings.EqualFold(a.Race, ch.Race) {
+				continue
+			}
+			up.Abilities = append(up.Abilities, a.Name)
+			unlocked = append(unlocked, a)
+		}
+		res.LevelUps = append(res.LevelUps, up)
+	}
+
+	for _, a := range unlocked {
+		if _, err := s.UnlockAbility(ctx, ch, a.Name, a.Description, models.AbilitySourceLevel); err != nil {
+			return nil, err
+		}
+	}
+
+	res.XP = ch.XP
+	res.Level = ch.Level
+	return res, nil
+}
+
+// UnlockAbility — точка, через которую персонаж получает новые способности
+// в игре: уровень, награда за квест или решение ГМа. Способность дописывается
+// в анкету, чтобы её видели промпты и проверка ValidateAbilityUse.
+func (s *ProgressionService) UnlockAbility(ctx context.Context, ch *models.Character, name, description, source string) (bool, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return false, nil
+	}
+	added, err := s.repo.AddAbility(ctx, models.CharacterAbility{
+		CharacterID: ch.ID,
+		Name:        name,
+		Description: description,
+		Source:      source,
+	})
+	if err != nil || !added {
+		return false, err
+	}
+
+	if strings.TrimSpace(ch.Abilities) == "" {
+		ch.Abilities = name
+	} else {
+		ch.Abilities = strings.TrimRight(ch.Abilities, " ,.;") + models.AbilitySeparator + name
+	}
+	return true, nil
+}
+
+func (s *ProgressionService) FormatXPResult(r *XPResult) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "✨ +%d опыта (всего %d", r.Gained, r.XP)
+	if next, ok := s.NextLevelXP(r.Level); ok {
+		fmt.Fprintf(&b, ", до %d-го уровня: %d", r.Level+1, next-r.XP)
+	}
+	b.WriteString(")")
+	for _, up := range r.LevelUps {
+		fmt.Fprintf(&b, "\n🎉 Уровень %d!", up.Level)
+		if up.PowerGain > 0 {
+			fmt.Fprintf(&b, " Боевой потенциал +%d.", up.PowerGain)
+		}
+		if len(up.Abilities) > 0 {
+			b.WriteString(" Новые способности: " + strings.Join(up.Abilities, ", ") + ".")
+		}
+	}
+	return b.String()
+}
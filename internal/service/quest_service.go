@@ -2,6 +2,8 @@ package service
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"strconv"
 	"strings"
 	"time"
@@ -10,14 +12,40 @@ import (
 	"aurora/internal/repository"
 )
 
+var (
+	ErrQuestFactionRefuses = errors.New("faction refuses to give quests")
+	ErrQuestClosed         = errors.New("quest is no longer active")
+)
+
+// QuestFactions связывает квесты с фракциями и решает, доступны ли
+// персонажу поручения фракции.
+type QuestFactions interface {
+	FindFaction(name string) (models.Faction, bool)
+	QuestAllowed(ctx context.Context, charID, factionID int64) (bool, error)
+}
+
+// maxQuestDeadlineDays — самый долгий срок, который модель может назначить квесту.
+const maxQuestDeadlineDays = 30
+
 type QuestService struct {
-	repo *repository.QuestRepository
+	repo     *repository.QuestRepository
+	factions QuestFactions
+	clock    GameClock
 }
 
 func NewQuestService(repo *repository.QuestRepository) *QuestService {
 	return &QuestService{repo: repo}
 }
 
+func (s *QuestService) SetFactions(f QuestFactions) {
+	s.factions = f
+}
+
+// SetClock включает сроки квестов: без часов [QUEST_DEADLINE] игнорируется.
+func (s *QuestService) SetClock(c GameClock) {
+	s.clock = c
+}
+
 func (s *QuestService) GetActiveForCharacter(ctx context.Context, charID int64) ([]models.Quest, error) {
 	return s.repo.GetActiveForCharacter(ctx, charID)
 }
@@ -26,15 +54,21 @@ func (s *QuestService) GetByID(ctx context.Context, id int64) (models.Quest, err
 	return s.repo.GetByID(ctx, id)
 }
 
+// UpdateProgress сохраняет ход активного квеста. ErrQuestClosed — квест уже
+// завершён или провален, награду за него выдавать нельзя.
 func (s *QuestService) UpdateProgress(ctx context.Context, q models.Quest) error {
 	q.UpdatedAt = time.Now()
-	return s.repo.Update(ctx, q)
+	err := s.repo.Progress(ctx, q)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrQuestClosed
+	}
+	return err
 }
 
 func (s *QuestService) CreateFromAI(ctx context.Context, charID int64, raw string) (*models.Quest, error) {
 	lines := strings.Split(raw, "\n")
-	var title, desc, qtype, qdiff string
-	var qvalue int
+	var title, desc, qtype, qdiff, faction string
+	var qvalue, deadlineDays int
 
 	for _, ln := range lines {
 		l := strings.TrimSpace(ln)
@@ -47,6 +81,15 @@ func (s *QuestService) CreateFromAI(ctx context.Context, charID int64, raw strin
 			qtype = strings.TrimSpace(strings.TrimPrefix(l, "[QUEST_TYPE]:"))
 		case strings.HasPrefix(l, "[QUEST_DIFFICULTY]:"):
 			qdiff = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(l, "[QUEST_DIFFICULTY]:")))
+		case strings.HasPrefix(l, "[QUEST_FACTION]:"):
+			faction = strings.TrimSpace(strings.TrimPrefix(l, "[QUEST_FACTION]:"))
+		case strings.HasPrefix(l, "[QUEST_DEADLINE]:"):
+			dStr := strings.Fields(strings.TrimPrefix(l, "[QUEST_DEADLINE]:"))
+			if len(dStr) > 0 {
+				if d, err := strconv.Atoi(dStr[0]); err == nil {
+					deadlineDays = d
+				}
+			}
 		case strings.HasPrefix(l, "[QUEST_VALUE]:"):
 			vStr := strings.TrimSpace(strings.TrimPrefix(l, "[QUEST_VALUE]:"))
 			if v, err := strconv.Atoi(vStr); err == nil {
@@ -67,6 +110,28 @@ func (s *QuestService) CreateFromAI(ctx context.Context, charID int64, raw strin
 		qvalue = 100
 	}
 
+	var qf models.Faction
+	if s.factions != nil && faction != "" {
+		if f, found := s.factions.FindFaction(faction); found {
+			ok, err := s.factions.QuestAllowed(ctx, charID, f.ID)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				return nil, ErrQuestFactionRefuses
+			}
+			qf = f
+		}
+	}
+
+	var deadline int64
+	if s.clock != nil && deadlineDays > 0 {
+		if deadlineDays > maxQuestDeadlineDays {
+			deadlineDays = maxQuestDeadlineDays
+		}
+		deadline = s.clock.Now() + int64(deadlineDays)*minutesPerDay
+	}
+
 	now := time.Now()
 	q := &models.Quest{
 		CharacterID: charID,
@@ -77,6 +142,9 @@ func (s *QuestService) CreateFromAI(ctx context.Context, charID int64, raw strin
 		From:        "ai",
 		Difficulty:  qdiff,
 		RewardValue: qvalue,
+		FactionID:   qf.ID,
+		FactionName: qf.Name,
+		DeadlineAt:  deadline,
 		CreatedAt:   now,
 		UpdatedAt:   now,
 	}
@@ -0,0 +1,158 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"aurora/internal/lore"
+	"aurora/internal/models"
+	"aurora/internal/repository"
+)
+
+// minQuestScore — ниже этой репутации фракция не даёт персонажу поручений.
+const minQuestScore = -15
+
+var ErrUnknownFaction = errors.New("unknown faction")
+
+type ReputationService struct {
+	repo *repository.ReputationRepository
+	book *lore.FactionBook
+
+	mu    sync.RWMutex
+	byKey map[string]models.Faction // ItemNameKey(имя/алиас/id) -> фракция
+}
+
+func NewReputationService(repo *repository.ReputationRepository, book *lore.FactionBook) *ReputationService {
+	if book == nil {
+		book = &lore.FactionBook{}
+	}
+	return &ReputationService{repo: repo, book: book, byKey: make(map[string]models.Faction)}
+}
+
+// SyncFactions заносит фракции из лора в БД и строит индекс по именам.
+func (s *ReputationService) SyncFactions(ctx context.Context) error {
+	index := make(map[string]models.Faction)
+	for _, lf := range s.book.Factions {
+		f := models.Faction{Key: lf.ID, Name: lf.Name, Description: lf.Description}
+		id, err := s.repo.UpsertFaction(ctx, f)
+		if err != nil {
+			return fmt.Errorf("sync faction %s: %w", lf.ID, err)
+		}
+		f.ID = id
+		for _, name := range append([]string{lf.ID, lf.Name}, lf.Aliases...) {
+			index[repository.ItemNameKey(name)] = f
+		}
+	}
+
+	s.mu.Lock()
+	s.byKey = index
+	s.mu.Unlock()
+	return nil
+}
+
+// FindFaction ищет фракцию по имени, алиасу или id из лора.
+func (s *ReputationService) FindFaction(name string) (models.Faction, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	f, ok := s.byKey[repository.ItemNameKey(name)]
+	return f, ok
+}
+
+// ReputationScore реализует ReputationSource для цен в лавках.
+func (s *ReputationService) ReputationScore(ctx context.Context, charID int64, faction string) (int, error) {
+	f, ok := s.FindFaction(faction)
+	if !ok {
+		return 0, nil
+	}
+	return s.repo.Score(ctx, charID, f.ID)
+}
+
+// QuestAllowed сообщает, готова ли фракция давать персонажу поручения.
+func (s *ReputationService) QuestAllowed(ctx context.Context, charID, factionID int64) (bool, error) {
+	score, err := s.repo.Score(ctx, charID, factionID)
+	if err != nil {
+		return false, err
+	}
+	return score >= minQuestScore, nil
+}
+
+// Adjust меняет репутацию персонажа у фракции и обновляет ch.Reputation.
+func (s *ReputationService) Adjust(ctx context.Context, ch *models.Character, faction string, delta int) (models.Reputation, error) {
+	f, ok := s.FindFaction(faction)
+	if !ok {
+		return models.Reputation{}, ErrUnknownFaction
+	}
+	return s.AdjustByID(ctx, ch, f, delta)
+}
+
+func (s *ReputationService) AdjustByID(ctx context.Context, ch *models.Character, f models.Faction, delta int) (models.Reputation, error) {
+	score, err := s.repo.Adjust(ctx, ch.ID, f.ID, delta)
+	if err != nil {
+		return models.Reputation{}, err
+	}
+	rep := models.Reputation{
+		CharacterID: ch.ID,
+		FactionID:   f.ID,
+		FactionName: f.Name,
+		Score:       score,
+		Tier:        s.Tier(score),
+	}
+
+	replaced := false
+	for i := range ch.Reputation {
+		if ch.Reputation[i].FactionID == f.ID {
+			ch.Reputation[i] = rep
+			replaced = true
+		}
+	}
+	if !replaced {
+		ch.Reputation = append(ch.Reputation, rep)
+	}
+	return rep, nil
+}
+
+func (s *ReputationService) Tier(score int) string {
+	name := ""
+	for _, t := range s.book.Tiers {
+		if score >= t.Min {
+			name = t.Name
+		}
+	}
+	return name
+}
+
+// Standings — репутация персонажа с названиями ступеней.
+func (s *ReputationService) Standings(ctx context.Context, charID int64) ([]models.Reputation, error) {
+	list, err := s.repo.ListForCharacter(ctx, charID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range list {
+		list[i].Tier = s.Tier(list[i].Score)
+	}
+	return list, nil
+}
+
+// FactionNames — названия всех фракций лора, для подсказок модели и ГМу.
+func (s *ReputationService) FactionNames() []string {
+	names := make([]string, 0, len(s.book.Factions))
+	for _, f := range s.book.Factions {
+		names = append(names, f.Name)
+	}
+	return names
+}
+
+func FormatReputation(name string, list []models.Reputation) string {
+	if len(list) == 0 {
+		return "🏛 " + name + ": фракции пока не знают о тебе."
+	}
+	var b strings.Builder
+	b.WriteString("🏛 Репутация: " + name + "\n")
+	for _, r := range list {
+		fmt.Fprintf(&b, "— %s: %s (%+d)\n", r.FactionName, r.Tier, r.Score)
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
@@ -0,0 +1,140 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"aurora/internal/llm"
+	"aurora/internal/models"
+)
+
+// RewardSummary — что персонаж фактически получил после проверки лимитов.
+type RewardSummary struct {
+	Gold    int
+	Items   []string
+	XP      *XPResult
+	Ability string
+	// AbilityRequest — способность не из списка квестовых: модель её
+	// предложила, но выдать может только ГМ.
+	AbilityRequest string
+	RepDelta       int
+	Reputation     *models.Reputation // итоговая репутация у фракции-заказчика
+}
+
+// RewardService превращает итоги квестов и боёв в изменения персонажа:
+// золото через журнал, предметы в инвентарь, опыт, способности и репутацию.
+type RewardService struct {
+	ledger      *LedgerService
+	inventory   *InventoryService
+	progression *ProgressionService
+	reputation  *ReputationService
+}
+
+func NewRewardService(ledger *LedgerService, inventory *InventoryService, progression *ProgressionService, reputation *ReputationService) *RewardService {
+	return &RewardService{ledger: ledger, inventory: inventory, progression: progression, reputation: reputation}
+}
+
+// ApplyQuest выдаёт награду за завершённый квест. Золото, предметы и
+// репутация ограничиваются ValidateQuestReward, опыт берётся из правил по сложности,
+// способность — только из списка квестовых способностей.
+func (s *RewardService) ApplyQuest(ctx context.Context, ch *models.Character, q models.Quest, res llm.QuestProgressResult) (*RewardSummary, error) {
+	llm.ValidateQuestReward(&res)
+	ref := fmt.Sprintf("quest:%d", q.ID)
+	sum := &RewardSummary{}
+
+	if res.RewardGold > 0 {
+		if err := s.ledger.Credit(ctx, ch, res.RewardGold, models.LedgerSourceQuest, "Награда за квест «"+q.Title+"»", ref); err != nil {
+			return nil, err
+		}
+		sum.Gold = res.RewardGold
+	}
+
+	for _, entry := range res.RewardItems {
+		name, qty := ParseItemEntry(entry)
+		if name == "" {
+			continue
+		}
+		item := models.Item{Name: name, Rarity: models.RarityCommon}
+		item.EquipSlot, item.Properties = inferItemTraits(name)
+		if _, err := s.inventory.AddItem(ctx, ch.ID, item, qty); err != nil {
+			return nil, err
+		}
+		sum.Items = append(sum.Items, entry)
+	}
+
+	if xp := s.progression.QuestXP(q.Difficulty); xp > 0 {
+		r, err := s.progression.GrantXP(ctx, ch, xp)
+		if err != nil {
+			return nil, err
+		}
+		sum.XP = r
+	}
+
+	if res.RewardReputation != 0 && q.FactionID != 0 {
+		rep, err := s.reputation.AdjustByID(ctx, ch, models.Faction{ID: q.FactionID, Name: q.FactionName}, res.RewardReputation)
+		if err != nil {
+			return nil, err
+		}
+		sum.RepDelta = res.RewardReputation
+		sum.Reputation = &rep
+	}
+
+	// способность приходит из ответа модели, поэтому сама выдаётся только
+	// из списка квестовых в правилах; остальное решает ГМ
+	if name := strings.TrimSpace(res.UnlockAbility); name != "" {
+		a, ok := s.progression.QuestAbility(name)
+		if !ok {
+			sum.AbilityRequest = name
+		} else {
+			desc := a.Description
+			if desc == "" {
+				desc = "Освоено в квесте «" + q.Title + "»"
+			}
+			added, err := s.progression.UnlockAbility(ctx, ch, a.Name, desc, models.AbilitySourceQuest)
+			if err != nil {
+				return nil, err
+			}
+			if added {
+				sum.Ability = a.Name
+			}
+		}
+	}
+	return sum, nil
+}
+
+// ApplyCombat начисляет опыт за победу в бою.
+func (s *RewardService) ApplyCombat(ctx context.Context, ch *models.Character) (*RewardSummary, error) {
+	sum := &RewardSummary{}
+	if xp := s.progression.CombatXP(); xp > 0 {
+		r, err := s.progression.GrantXP(ctx, ch, xp)
+		if err != nil {
+			return nil, err
+		}
+		sum.XP = r
+	}
+	return sum, nil
+}
+
+func (s *RewardService) FormatSummary(r *RewardSummary) string {
+	var lines []string
+	if r.Gold > 0 {
+		lines = append(lines, fmt.Sprintf("🪙 +%d ₸", r.Gold))
+	}
+	if len(r.Items) > 0 {
+		lines = append(lines, "🎒 "+strings.Join(r.Items, ", "))
+	}
+	if r.XP != nil {
+		lines = append(lines, s.progression.FormatXPResult(r.XP))
+	}
+	if r.Ability != "" {
+		lines = append(lines, "📜 Новая способность: "+r.Ability)
+	}
+	if r.AbilityRequest != "" {
+		lines = append(lines, "📜 Способность «"+r.AbilityRequest+"» ждёт решения ГМа")
+	}
+	if r.Reputation != nil {
+		lines = append(lines, fmt.Sprintf("🏛 %s: %+d (%s)", r.Reputation.FactionName, r.RepDelta, r.Reputation.Tier))
+	}
+	return strings.Join(lines, "\n")
+}
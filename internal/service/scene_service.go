@@ -10,13 +10,19 @@ import (
 )
 
 type SceneService struct {
-	repo *repository.SceneRepository
+	repo  *repository.SceneRepository
+	clock *ClockService
 }
 
 func NewSceneService(repo *repository.SceneRepository) *SceneService {
 	return &SceneService{repo: repo}
 }
 
+// SetClock подключает мировые часы: сцены получают текущее время и погоду.
+func (s *SceneService) SetClock(c *ClockService) {
+	s.clock = c
+}
+
 func (s *SceneService) EnsureDefaultScene() error {
 	return nil
 }
@@ -28,7 +34,7 @@ func (s *SceneService) SetGMMode(ctx context.Context, sceneID int64, mode string
 func (s *SceneService) GetOrCreateSceneForCharacter(ctx context.Context, charID int64) (models.Scene, error) {
 	sc, err := s.repo.GetActiveForCharacter(ctx, charID)
 	if err == nil {
-		return *sc, nil
+		return s.withTime(*sc), nil
 	}
 
 	if err == sql.ErrNoRows {
@@ -41,11 +47,18 @@ func (s *SceneService) GetOrCreateSceneForCharacter(ctx context.Context, charID
 		if err != nil {
 			return models.Scene{}, err
 		}
-		return *newSc, nil
+		return s.withTime(*newSc), nil
 	}
 	return models.Scene{}, err
 }
 
+func (s *SceneService) withTime(sc models.Scene) models.Scene {
+	if s.clock != nil {
+		sc.WorldTime = s.clock.Describe()
+	}
+	return sc
+}
+
 func (s *SceneService) AppendMessage(ctx context.Context, msg models.SceneMessage) error {
 	return s.repo.AppendMessage(ctx, msg)
 }
@@ -0,0 +1,251 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"aurora/internal/models"
+	"aurora/internal/repository"
+)
+
+var (
+	ErrSheetNotFound = errors.New("sheet not found")
+	ErrSheetReviewed = errors.New("sheet already reviewed")
+)
+
+// SheetChange — поле, которое поданная анкета меняет у персонажа.
+type SheetChange struct {
+	Field string
+	Old   string
+	New   string
+}
+
+// SheetService ведёт очередь анкет на проверку ГМом. Поданная анкета ничего
+// не меняет в персонаже, пока ГМ её не одобрит.
+type SheetService struct {
+	repo     *repository.SheetRepository
+	chars    *CharacterService
+	notifier func(models.CharacterSheet)
+}
+
+func NewSheetService(repo *repository.SheetRepository, chars *CharacterService) *SheetService {
+	return &SheetService{repo: repo, chars: chars}
+}
+
+// SetNotifier задаёт, кому сообщать игроку о решении ГМа по анкете.
+func (s *SheetService) SetNotifier(fn func(models.CharacterSheet)) {
+	s.notifier = fn
+}
+
+// Submit подаёт анкету активного персонажа игрока (или нового, если его нет).
+func (s *SheetService) Submit(ctx context.Context, vkUserID int64, peerID int, f models.NormalizedCharacterForm) (*models.CharacterSheet, error) {
+	ch, err := s.chars.activeOrCreate(ctx, vkUserID)
+	if err != nil {
+		return nil, err
+	}
+	sheet := &models.CharacterSheet{
+		CharacterID: ch.ID,
+		VKUserID:    vkUserID,
+		PeerID:      peerID,
+		Form:        f,
+		Status:      models.SheetPending,
+	}
+	id, err := s.repo.Submit(ctx, sheet)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.GetByID(ctx, id)
+}
+
+func (s *SheetService) Get(ctx context.Context, id int64) (*models.CharacterSheet, error) {
+	sheet, err := s.repo.GetByID(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrSheetNotFound
+	}
+	return sheet, err
+}
+
+// Latest — последняя анкета персонажа или nil, если он их не подавал.
+func (s *SheetService) Latest(ctx context.Context, charID int64) (*models.CharacterSheet, error) {
+	sheet, err := s.repo.Latest(ctx, charID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return sheet, err
+}
+
+func (s *SheetService) Pending(ctx context.Context) ([]models.CharacterSheet, error) {
+	return s.repo.ListPending(ctx)
+}
+
+// Diff сравнивает анкету с тем, что сейчас записано у персонажа.
+func (s *SheetService) Diff(ctx context.Context, sheet *models.CharacterSheet) (*models.Character, []SheetChange, error) {
+	ch, err := s.chars.repo.GetByID(ctx, sheet.CharacterID)
+	if err != nil {
+		return nil, nil, err
+	}
+	f := sheet.Form
+	var changes []SheetChange
+	add := func(field, old, new string) {
+		if new = strings.TrimSpace(new); new != "" && new != strings.TrimSpace(old) {
+			changes = append(changes, SheetChange{Field: field, Old: old, New: new})
+		}
+	}
+	add("Имя", ch.Name, f.Name)
+	add("Раса", ch.Race, f.Race)
+	add("Страна", ch.Country, f.Country)
+	add("Класс", ch.Class, f.Class)
+	if len(f.Abilities) > 0 {
+		added, removed := diffAbilities(models.ParseAbilities(ch.Abilities), f.Abilities)
+		if len(added) > 0 || len(removed) > 0 {
+			changes = append(changes, SheetChange{Field: "Способности", Old: strings.Join(removed, "; "), New: strings.Join(added, "; ")})
+		}
+	}
+	add("Биография", ch.Bio, f.Bio)
+	return ch, changes, nil
+}
+
+// diffAbilities — способности, которые анкета добавляет и убирает.
+func diffAbilities(cur, next []string) (added, removed []string) {
+	for _, a := range next {
+		if !containsFold(cur, a) {
+			added = append(added, a)
+		}
+	}
+	for _, a := range cur {
+		if !containsFold(next, a) {
+			removed = append(removed, a)
+		}
+	}
+	return added, removed
+}
+
+// Approve переносит анкету в персонажа и сообщает игроку. Статус анкеты и
+// поля персонажа пишутся одной транзакцией: два одобрения подряд не применят
+// анкету дважды.
+func (s *SheetService) Approve(ctx context.Context, id int64) (*models.CharacterSheet, *models.Character, error) {
+	sheet, err := s.pending(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	ch, err := s.chars.sheetCharacter(ctx, sheet.CharacterID, &sheet.Form)
+	if err != nil {
+		return sheet, nil, err
+	}
+	err = s.repo.Approve(ctx, sheet.ID, ch)
+	if errors.Is(err, sql.ErrNoRows) {
+		return sheet, nil, ErrSheetReviewed
+	}
+	if err != nil {
+		return sheet, nil, err
+	}
+	if err := s.chars.importSheetItems(ctx, ch, &sheet.Form); err != nil {
+		return sheet, ch, fmt.Errorf("import sheet items: %w", err)
+	}
+	s.reviewed(sheet, models.SheetApproved, "")
+	return sheet, ch, nil
+}
+
+// Reject отклоняет анкету; персонаж остаётся прежним.
+func (s *SheetService) Reject(ctx context.Context, id int64, comment string) (*models.CharacterSheet, error) {
+	sheet, err := s.pending(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return sheet, s.close(ctx, sheet, models.SheetRejected, comment)
+}
+
+// RequestChanges возвращает анкету игроку на доработку.
+func (s *SheetService) RequestChanges(ctx context.Context, id int64, comment string) (*models.CharacterSheet, error) {
+	sheet, err := s.pending(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return sheet, s.close(ctx, sheet, models.SheetChanges, comment)
+}
+
+func (s *SheetService) pending(ctx context.Context, id int64) (*models.CharacterSheet, error) {
+	sheet, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if sheet.Status != models.SheetPending {
+		return sheet, ErrSheetReviewed
+	}
+	return sheet, nil
+}
+
+func (s *SheetService) close(ctx context.Context, sheet *models.CharacterSheet, status, comment string) error {
+	err := s.repo.Review(ctx, sheet.ID, status, strings.TrimSpace(comment))
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrSheetReviewed
+	}
+	if err != nil {
+		return err
+	}
+	s.reviewed(sheet, status, comment)
+	return nil
+}
+
+// reviewed отмечает решение ГМа в sheet и сообщает о нём игроку.
+func (s *SheetService) reviewed(sheet *models.CharacterSheet, status, comment string) {
+	sheet.Status = status
+	sheet.Comment = strings.TrimSpace(comment)
+	if s.notifier != nil {
+		s.notifier(*sheet)
+	}
+}
+
+// FormatSheetChanges — сравнение анкеты с текущим персонажем для ГМа.
+func FormatSheetChanges(sheet *models.CharacterSheet, ch *models.Character, changes []SheetChange) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "📜 Анкета #%d — %s (персонаж %d, vk %d), подана %s\n", sheet.ID, ch.Name, ch.ID, sheet.VKUserID, sheet.CreatedAt.Format("02.01 15:04"))
+	if len(changes) == 0 {
+		b.WriteString("Изменений нет.")
+	}
+	for _, c := range changes {
+		switch c.Field {
+		case "Способности":
+			if c.New != "" {
+				b.WriteString("Способности + " + c.New + "\n")
+			}
+			if c.Old != "" {
+				b.WriteString("Способности − " + c.Old + "\n")
+			}
+		case "Биография":
+			b.WriteString("Биография: " + c.New + "\n")
+		default:
+			old := c.Old
+			if old == "" {
+				old = "—"
+			}
+			fmt.Fprintf(&b, "%s: %s → %s\n", c.Field, old, c.New)
+		}
+	}
+	if sheet.Status == models.SheetPending {
+		fmt.Fprintf(&b, "\n!gm approve %d | !gm reject %d <причина> | !gm changes %d <что исправить>", sheet.ID, sheet.ID, sheet.ID)
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// SheetReviewText — сообщение игроку о решении ГМа.
+func SheetReviewText(sheet models.CharacterSheet) string {
+	var text string
+	switch sheet.Status {
+	case models.SheetApproved:
+		text = "✅ ГМ одобрил анкету. " + sheet.Form.Name + " обновлён."
+	case models.SheetRejected:
+		text = "❌ ГМ отклонил анкету " + sheet.Form.Name + ". Персонаж остаётся прежним."
+	case models.SheetChanges:
+		text = "✏️ ГМ просит доработать анкету " + sheet.Form.Name + ". Открой !анкета, чтобы исправить и отправить снова."
+	default:
+		return ""
+	}
+	if sheet.Comment != "" {
+		text += "\nКомментарий ГМа: " + sheet.Comment
+	}
+	return text
+}
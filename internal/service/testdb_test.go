@@ -0,0 +1,37 @@
+package service
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"aurora/internal/repository"
+)
+
+// openTestDB — временная SQLite со всеми миграциями.
+func openTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := repository.NewSQLite(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	files, err := filepath.Glob("../../migrations/*.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(files)
+	for _, f := range files {
+		b, err := os.ReadFile(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := db.Exec(string(b)); err != nil {
+			t.Fatalf("%s: %v", filepath.Base(f), err)
+		}
+	}
+	return db
+}
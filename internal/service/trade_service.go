@@ -0,0 +1,325 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"aurora/internal/models"
+	"aurora/internal/repository"
+)
+
+// tradeTTL — сколько живёт предложение обмена без изменений.
+const tradeTTL = 10 * time.Minute
+
+var (
+	ErrNoTrade    = errors.New("no active trade")
+	ErrTradeBusy  = errors.New("character already trading")
+	ErrTradeSelf  = errors.New("cannot trade with yourself")
+	ErrTradeEmpty = errors.New("trade is empty")
+)
+
+type TradeItem struct {
+	ItemID   int64
+	Name     string
+	Quantity int
+}
+
+// TradeSide — половина обмена: что персонаж выкладывает на стол.
+type TradeSide struct {
+	CharacterID int64
+	VKUserID    int64
+	Name        string
+	Items       []TradeItem
+	Gold        int
+	Confirmed   bool
+}
+
+type TradeOffer struct {
+	ID        int64
+	PeerID    int
+	Sides     [2]*TradeSide
+	ExpiresAt time.Time
+}
+
+func (o *TradeOffer) side(vkUserID int64) (*TradeSide, *TradeSide) {
+	if o.Sides[0].VKUserID == vkUserID {
+		return o.Sides[0], o.Sides[1]
+	}
+	return o.Sides[1], o.Sides[0]
+}
+
+func (o *TradeOffer) isEmpty() bool {
+	for _, s := range o.Sides {
+		if s.Gold > 0 || len(s.Items) > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// touch сбрасывает подтверждения и продлевает предложение: любое изменение
+// стола требует повторного согласия обеих сторон.
+func (o *TradeOffer) touch(now time.Time) {
+	o.Sides[0].Confirmed = false
+	o.Sides[1].Confirmed = false
+	o.ExpiresAt = now.Add(tradeTTL)
+}
+
+// TradeService держит открытые предложения в памяти; предметы и золото
+// двигаются только при подтверждении, одной транзакцией.
+type TradeService struct {
+	repo *repository.TradeRepository
+
+	mu     sync.Mutex
+	nextID int64
+	byUser map[int64]*TradeOffer
+}
+
+func NewTradeService(repo *repository.TradeRepository) *TradeService {
+	return &TradeService{
+		repo:   repo,
+		byUser: make(map[int64]*TradeOffer),
+	}
+}
+
+func (s *TradeService) Open(initiator, partner *models.Character, peerID int) (*TradeOffer, error) {
+	if initiator.ID == partner.ID {
+		return nil, ErrTradeSelf
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for _, vk := range []int64{initiator.VKUserID, partner.VKUserID} {
+		if o := s.byUser[vk]; o != nil && now.Before(o.ExpiresAt) {
+			return nil, ErrTradeBusy
+		}
+	}
+
+	s.nextID++
+	o := &TradeOffer{
+		ID:     s.nextID,
+		PeerID: peerID,
+		Sides: [2]*TradeSide{
+			{CharacterID: initiator.ID, VKUserID: initiator.VKUserID, Name: initiator.Name},
+			{CharacterID: partner.ID, VKUserID: partner.VKUserID, Name: partner.Name},
+		},
+		ExpiresAt: now.Add(tradeTTL),
+	}
+	s.byUser[initiator.VKUserID] = o
+	s.byUser[partner.VKUserID] = o
+	return o, nil
+}
+
+// Active возвращает действующее предложение игрока; просроченное удаляется.
+func (s *TradeService) Active(vkUserID int64) (*TradeOffer, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	o := s.activeLocked(vkUserID)
+	return o, o != nil
+}
+
+func (s *TradeService) activeLocked(vkUserID int64) *TradeOffer {
+	o := s.byUser[vkUserID]
+	if o == nil {
+		return nil
+	}
+	if time.Now().After(o.ExpiresAt) {
+		s.dropLocked(o)
+		return nil
+	}
+	return o
+}
+
+func (s *TradeService) dropLocked(o *TradeOffer) {
+	for _, side := range o.Sides {
+		if s.byUser[side.VKUserID] == o {
+			delete(s.byUser, side.VKUserID)
+		}
+	}
+}
+
+// AddItem выкладывает предмет из инвентаря ch на стол.
+func (s *TradeService) AddItem(ch *models.Character, target string, qty int) (*TradeOffer, error) {
+	if qty <= 0 {
+		return nil, ErrInvalidQuantity
+	}
+	inv := FindOwnedItem(ch.Items, target)
+	if inv == nil {
+		return nil, ErrItemNotOwned
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	o := s.activeLocked(ch.VKUserID)
+	if o == nil {
+		return nil, ErrNoTrade
+	}
+	me, _ := o.side(ch.VKUserID)
+
+	for i := range me.Items {
+		if me.Items[i].ItemID == inv.Item.ID {
+			if me.Items[i].Quantity+qty > inv.Quantity {
+				return nil, repository.ErrNotEnoughItems
+			}
+			me.Items[i].Quantity += qty
+			o.touch(time.Now())
+			return o, nil
+		}
+	}
+	if qty > inv.Quantity {
+		return nil, repository.ErrNotEnoughItems
+	}
+	me.Items = append(me.Items, TradeItem{ItemID: inv.Item.ID, Name: inv.Item.Name, Quantity: qty})
+	o.touch(time.Now())
+	return o, nil
+}
+
+// RemoveItem убирает предмет со стола целиком.
+func (s *TradeService) RemoveItem(vkUserID int64, target string) (*TradeOffer, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	o := s.activeLocked(vkUserID)
+	if o == nil {
+		return nil, ErrNoTrade
+	}
+	me, _ := o.side(vkUserID)
+
+	names := make([]string, len(me.Items))
+	for i, it := range me.Items {
+		names[i] = it.Name
+	}
+	idx := MatchName(names, target)
+	if idx < 0 {
+		return nil, ErrItemNotOwned
+	}
+	me.Items = append(me.Items[:idx], me.Items[idx+1:]...)
+	o.touch(time.Now())
+	return o, nil
+}
+
+// SetGold задаёт, сколько золота ch кладёт на стол (0 — убрать).
+func (s *TradeService) SetGold(ch *models.Character, amount int) (*TradeOffer, error) {
+	if amount < 0 {
+		return nil, ErrInvalidQuantity
+	}
+	if amount > ch.Gold {
+		return nil, repository.ErrNotEnoughGold
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	o := s.activeLocked(ch.VKUserID)
+	if o == nil {
+		return nil, ErrNoTrade
+	}
+	me, _ := o.side(ch.VKUserID)
+	me.Gold = amount
+	o.touch(time.Now())
+	return o, nil
+}
+
+// Confirm отмечает согласие стороны. Когда согласны обе, обмен проводится;
+// done сообщает, что сделка завершена и предложение закрыто.
+func (s *TradeService) Confirm(ctx context.Context, vkUserID int64) (o *TradeOffer, done bool, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	o = s.activeLocked(vkUserID)
+	if o == nil {
+		return nil, false, ErrNoTrade
+	}
+	if o.isEmpty() {
+		return o, false, ErrTradeEmpty
+	}
+	me, other := o.side(vkUserID)
+	me.Confirmed = true
+	if !other.Confirmed {
+		return o, false, nil
+	}
+
+	reason := fmt.Sprintf("Обмен: %s ⇄ %s", o.Sides[0].Name, o.Sides[1].Name)
+	err = s.repo.CommitTrade(ctx, fmt.Sprintf("trade:%d", o.ID), reason,
+		transferOf(o.Sides[0], o.Sides[1]),
+		transferOf(o.Sides[1], o.Sides[0]),
+	)
+	if err != nil {
+		o.touch(time.Now())
+		return o, false, err
+	}
+	s.dropLocked(o)
+	return o, true, nil
+}
+
+func (s *TradeService) Cancel(vkUserID int64) (*TradeOffer, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	o := s.activeLocked(vkUserID)
+	if o == nil {
+		return nil, ErrNoTrade
+	}
+	s.dropLocked(o)
+	return o, nil
+}
+
+// Sweep закрывает просроченные предложения и возвращает их для уведомлений.
+func (s *TradeService) Sweep(now time.Time) []*TradeOffer {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var expired []*TradeOffer
+	seen := make(map[*TradeOffer]bool)
+	for _, o := range s.byUser {
+		if seen[o] || now.Before(o.ExpiresAt) {
+			continue
+		}
+		seen[o] = true
+		expired = append(expired, o)
+	}
+	for _, o := range expired {
+		s.dropLocked(o)
+	}
+	return expired
+}
+
+func transferOf(from, to *TradeSide) repository.TradeTransfer {
+	t := repository.TradeTransfer{From: from.CharacterID, To: to.CharacterID, Gold: from.Gold}
+	for _, it := range from.Items {
+		t.Items = append(t.Items, repository.TradeLine{ItemID: it.ItemID, Quantity: it.Quantity})
+	}
+	return t
+}
+
+func FormatTrade(o *TradeOffer) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "🤝 Обмен #%d\n", o.ID)
+	for _, side := range o.Sides {
+		mark := "⏳"
+		if side.Confirmed {
+			mark = "✅"
+		}
+		fmt.Fprintf(&b, "\n%s %s кладёт на стол:\n", mark, side.Name)
+		if len(side.Items) == 0 && side.Gold == 0 {
+			b.WriteString("— ничего\n")
+			continue
+		}
+		for _, it := range side.Items {
+			fmt.Fprintf(&b, "— %s ×%d\n", it.Name, it.Quantity)
+		}
+		if side.Gold > 0 {
+			fmt.Fprintf(&b, "— %d ₸\n", side.Gold)
+		}
+	}
+	left := time.Until(o.ExpiresAt).Round(time.Minute)
+	fmt.Fprintf(&b, "\nПредложение истечёт через %d мин.", int(left.Minutes()))
+	return b.String()
+}
@@ -0,0 +1,145 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+
+	"aurora/internal/lore"
+	"aurora/internal/models"
+	"aurora/internal/repository"
+)
+
+// encounterChancePerDanger — шанс встречи на переходе в процентах за единицу опасности дороги.
+const encounterChancePerDanger = 8
+
+// TravelResult — чем закончилось путешествие. Если по дороге случилась
+// встреча, персонаж останавливается в конце перехода, где она произошла.
+type TravelResult struct {
+	Route     *Route
+	Passed    []RouteLeg
+	Arrived   models.Location
+	Hours     int
+	Encounter *Encounter
+}
+
+type TravelService struct {
+	locations *LocationService
+	scenes    *SceneService
+	chars     *repository.CharacterRepository
+	combat    *CombatService
+	defaults  []string
+
+	roll func(n int) int
+}
+
+func NewTravelService(locations *LocationService, scenes *SceneService, chars *repository.CharacterRepository, combat *CombatService, world *lore.WorldMap) *TravelService {
+	s := &TravelService{
+		locations: locations,
+		scenes:    scenes,
+		chars:     chars,
+		combat:    combat,
+		roll:      rand.Intn,
+	}
+	if world != nil {
+		s.defaults = world.DefaultEncounters
+	}
+	return s
+}
+
+// Travel ведёт персонажа по кратчайшему пути из текущей локации сцены.
+// На каждом переходе бросается шанс встречи; при встрече начинается бой.
+func (s *TravelService) Travel(ctx context.Context, ch *models.Character, sc models.Scene, dest string) (*TravelResult, error) {
+	if _, ok := s.combat.Active(ch.ID); ok {
+		return nil, ErrInCombat
+	}
+	route, err := s.locations.FindRoute(ctx, sc.LocationName, dest)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &TravelResult{Route: route}
+	for _, leg := range route.Legs {
+		res.Passed = append(res.Passed, leg)
+		res.Arrived = leg.To
+		res.Hours += leg.Link.TravelHours
+		if enemy := s.rollEncounter(leg.Link); enemy != "" {
+			enc, err := s.combat.Spawn(ch.ID, enemy)
+			if err != nil {
+				return nil, err
+			}
+			res.Encounter = &enc
+			break
+		}
+	}
+
+	loc := res.Arrived
+	if err := s.scenes.UpdateSceneLocation(ctx, sc.ID, sql.NullInt64{Int64: loc.ID, Valid: true}, loc.Name); err != nil {
+		return nil, err
+	}
+	if err := s.chars.UpdateLocation(ctx, ch.ID, loc.ID, loc.Name); err != nil {
+		return nil, err
+	}
+	ch.LocationID = loc.ID
+	ch.LocationName = loc.Name
+
+	names := make([]string, 0, len(res.Passed))
+	for _, leg := range res.Passed {
+		names = append(names, leg.To.Name)
+	}
+	note := fmt.Sprintf("%s отправляется из «%s» через: %s (%d ч в пути).", ch.Name, sc.LocationName, strings.Join(names, " → "), res.Hours)
+	if res.Encounter != nil {
+		note += " В дороге встреча: " + res.Encounter.Enemy + "."
+	}
+	err = s.scenes.AppendMessage(ctx, models.SceneMessage{
+		SceneID:    sc.ID,
+		SenderType: "system",
+		SenderID:   ch.ID,
+		Content:    note,
+		CreatedAt:  time.Now(),
+	})
+	return res, err
+}
+
+func (s *TravelService) rollEncounter(link models.LocationLink) string {
+	if link.Danger <= 0 || s.roll(100) >= link.Danger*encounterChancePerDanger {
+		return ""
+	}
+	pool := link.Encounters
+	if len(pool) == 0 {
+		pool = s.defaults
+	}
+	if len(pool) == 0 {
+		return ""
+	}
+	return pool[s.roll(len(pool))]
+}
+
+func FormatNeighbors(here string, links []models.LocationLink) string {
+	if len(links) == 0 {
+		return "📍 " + here + ". Дорог отсюда не видно."
+	}
+	var b strings.Builder
+	b.WriteString("📍 " + here + ". Отсюда можно отправиться:\n")
+	for _, l := range links {
+		fmt.Fprintf(&b, "— %s: %d ч, опасность %s\n", l.ToName, l.TravelHours, dangerText(l.Danger))
+	}
+	b.WriteString("Путь: !путь <место>")
+	return b.String()
+}
+
+func dangerText(d int) string {
+	switch {
+	case d <= 0:
+		return "нет"
+	case d == 1:
+		return "низкая"
+	case d <= 3:
+		return "средняя"
+	default:
+		return "высокая"
+	}
+}
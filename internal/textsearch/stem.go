@@ -0,0 +1,157 @@
+// Package textsearch — разбор текста для полнотекстового поиска: токены и
+// русский стеммер (алгоритм Snowball).
+package textsearch
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Terms разбивает текст на слова в нижнем регистре; русские слова
+// приводятся к основе. Однобуквенные слова отбрасываются.
+func Terms(text string) []string {
+	fields := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	out := make([]string, 0, len(fields))
+	for _, f := range fields {
+		if len([]rune(f)) < 2 {
+			continue
+		}
+		out = append(out, Stem(f))
+	}
+	return out
+}
+
+// Stem — основа русского слова по Snowball; слова не на кириллице
+// только приводятся к нижнему регистру.
+func Stem(word string) string {
+	w := []rune(strings.ReplaceAll(strings.ToLower(word), "ё", "е"))
+	for _, r := range w {
+		if !unicode.Is(unicode.Cyrillic, r) {
+			return string(w)
+		}
+	}
+	rv, r2 := regions(w)
+	if rv >= len(w) {
+		return string(w)
+	}
+
+	// шаг 1
+	if n, ok := match(w, rv, perfectiveGerund1, perfectiveGerund2); ok {
+		w = w[:len(w)-n]
+	} else {
+		if n, ok := match(w, rv, nil, reflexive); ok {
+			w = w[:len(w)-n]
+		}
+		if n, ok := match(w, rv, nil, adjective); ok {
+			w = w[:len(w)-n]
+			if n, ok := match(w, rv, participle1, participle2); ok {
+				w = w[:len(w)-n]
+			}
+		} else if n, ok := match(w, rv, verb1, verb2); ok {
+			w = w[:len(w)-n]
+		} else if n, ok := match(w, rv, nil, noun); ok {
+			w = w[:len(w)-n]
+		}
+	}
+
+	// шаг 2
+	if len(w) > rv && w[len(w)-1] == 'и' {
+		w = w[:len(w)-1]
+	}
+
+	// шаг 3
+	if n, ok := match(w, r2, nil, derivational); ok {
+		w = w[:len(w)-n]
+	}
+
+	// шаг 4
+	if n, ok := match(w, rv, nil, superlative); ok {
+		w = w[:len(w)-n]
+	}
+	switch {
+	case hasSuffix(w, rv, "нн"):
+		w = w[:len(w)-1]
+	case hasSuffix(w, rv, "ь"):
+		w = w[:len(w)-1]
+	}
+	return string(w)
+}
+
+var (
+	perfectiveGerund1 = []string{"в", "вши", "вшись"}
+	perfectiveGerund2 = []string{"ив", "ивши", "ившись", "ыв", "ывши", "ывшись"}
+	adjective         = []string{"ее", "ие", "ые", "ое", "ими", "ыми", "ей", "ий", "ый", "ой", "ем", "им", "ым", "ом", "его", "ого", "ему", "ому", "их", "ых", "ую", "юю", "ая", "яя", "ою", "ею"}
+	participle1       = []string{"ем", "нн", "вш", "ющ", "щ"}
+	participle2       = []string{"ивш", "ывш", "ующ"}
+	reflexive         = []string{"ся", "сь"}
+	verb1             = []string{"ла", "на", "ете", "йте", "ли", "й", "л", "ем", "н", "ло", "но", "ет", "ют", "ны", "ть", "ешь", "нно"}
+	verb2             = []string{"ила", "ыла", "ена", "ейте", "уйте", "ите", "или", "ыли", "ей", "уй", "ил", "ыл", "им", "ым", "ен", "ило", "ыло", "ено", "ят", "ует", "уют", "ит", "ыт", "ены", "ить", "ыть", "ишь", "ую", "ю"}
+	noun              = []string{"а", "ев", "ов", "ие", "ье", "е", "иями", "ями", "ами", "еи", "ии", "и", "ией", "ей", "ой", "ий", "й", "иям", "ям", "ием", "ем", "ам", "ом", "о", "у", "ах", "иях", "ях", "ы", "ь", "ию", "ью", "ю", "ия", "ья", "я"}
+	superlative       = []string{"ейш", "ейше"}
+	derivational      = []string{"ост", "ость"}
+)
+
+func isVowel(r rune) bool {
+	return strings.ContainsRune("аеиоуыэюя", r)
+}
+
+// regions — начало RV (после первой гласной) и R2 из алгоритма Snowball.
+func regions(w []rune) (rv, r2 int) {
+	rv, r1 := len(w), len(w)
+	r2 = len(w)
+	for i, r := range w {
+		if isVowel(r) {
+			rv = i + 1
+			break
+		}
+	}
+	for i := 1; i < len(w); i++ {
+		if !isVowel(w[i]) && isVowel(w[i-1]) {
+			r1 = i + 1
+			break
+		}
+	}
+	for i := r1 + 1; i < len(w); i++ {
+		if !isVowel(w[i]) && isVowel(w[i-1]) {
+			r2 = i + 1
+			break
+		}
+	}
+	return rv, r2
+}
+
+// match ищет самое длинное окончание из обеих групп, лежащее в области от
+// start. Окончания первой группы засчитываются, только если перед ними «а»
+// или «я»; если самое длинное совпадение это условие не выполняет, шаг не
+// срабатывает, как в Snowball.
+func match(w []rune, start int, group1, group2 []string) (int, bool) {
+	best1, best2 := longest(w, start, group1), longest(w, start, group2)
+	if best2 >= best1 {
+		return best2, best2 > 0
+	}
+	i := len(w) - best1 - 1
+	if i < start || (w[i] != 'а' && w[i] != 'я') {
+		return 0, false
+	}
+	return best1, true
+}
+
+func longest(w []rune, start int, group []string) int {
+	best := 0
+	for _, s := range group {
+		if n := len([]rune(s)); n > best && hasSuffix(w, start, s) {
+			best = n
+		}
+	}
+	return best
+}
+
+func hasSuffix(w []rune, start int, suffix string) bool {
+	s := []rune(suffix)
+	if len(w)-len(s) < start {
+		return false
+	}
+	return string(w[len(w)-len(s):]) == suffix
+}
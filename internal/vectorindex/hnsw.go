@@ -0,0 +1,412 @@
+// Package vectorindex — приближённый поиск ближайших соседей (HNSW) по
+// косинусной близости в памяти процесса.
+package vectorindex
+
+import (
+	"container/heap"
+	"math"
+	"math/rand"
+	"sort"
+	"sync"
+)
+
+// Config — параметры графа. M — число связей узла на верхних слоях (на
+// нижнем вдвое больше), EfConstruction и EfSearch — ширина поиска при
+// вставке и запросе: больше — точнее и медленнее.
+type Config struct {
+	M              int
+	EfConstruction int
+	EfSearch       int
+}
+
+func DefaultConfig() Config {
+	return Config{M: 16, EfConstruction: 200, EfSearch: 64}
+}
+
+// Result — найденный документ и его косинусная близость к запросу.
+type Result struct {
+	ID    string
+	Score float32
+}
+
+type node struct {
+	id      string
+	vec     []float32
+	links   [][]int32
+	deleted bool
+}
+
+// HNSW — иерархический граф «малого мира». Удаление помечает узел, не
+// перестраивая связи; такие узлы не попадают в выдачу.
+type HNSW struct {
+	mu sync.RWMutex
+
+	cfg      Config
+	dim      int
+	nodes    []*node
+	byID     map[string]int32
+	entry    int32
+	maxLevel int
+	deleted  int
+	rng      *rand.Rand
+}
+
+func New(cfg Config) *HNSW {
+	def := DefaultConfig()
+	if cfg.M <= 1 {
+		cfg.M = def.M
+	}
+	if cfg.EfConstruction <= 0 {
+		cfg.EfConstruction = def.EfConstruction
+	}
+	if cfg.EfSearch <= 0 {
+		cfg.EfSearch = def.EfSearch
+	}
+	return &HNSW{
+		cfg:   cfg,
+		byID:  make(map[string]int32),
+		entry: -1,
+		rng:   rand.New(rand.NewSource(42)),
+	}
+}
+
+// Len — число живых (не удалённых) векторов.
+func (h *HNSW) Len() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.byID)
+}
+
+// Dim — размерность векторов индекса; 0, пока индекс пуст.
+func (h *HNSW) Dim() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.dim
+}
+
+// Garbage — доля удалённых узлов, которые ещё занимают место в графе.
+func (h *HNSW) Garbage() float64 {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	if len(h.nodes) == 0 {
+		return 0
+	}
+	return float64(h.deleted) / float64(len(h.nodes))
+}
+
+// Add вставляет вектор или заменяет вектор с тем же id. Векторы другой
+// размерности и нулевые векторы индекс не принимает.
+func (h *HNSW) Add(id string, vec []float32) bool {
+	v, ok := normalize(vec)
+	if !ok {
+		return false
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.dim == 0 {
+		h.dim = len(v)
+	}
+	if len(v) != h.dim {
+		return false
+	}
+	h.remove(id)
+	h.insert(id, v)
+	return true
+}
+
+func (h *HNSW) Remove(id string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.remove(id)
+}
+
+func (h *HNSW) remove(id string) {
+	idx, ok := h.byID[id]
+	if !ok {
+		return
+	}
+	h.nodes[idx].deleted = true
+	delete(h.byID, id)
+	h.deleted++
+}
+
+// Reset очищает индекс.
+func (h *HNSW) Reset() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.dim = 0
+	h.nodes = nil
+	h.byID = make(map[string]int32)
+	h.entry = -1
+	h.maxLevel = 0
+	h.deleted = 0
+}
+
+func (h *HNSW) randomLevel() int {
+	mult := 1 / math.Log(float64(h.cfg.M))
+	return int(math.Floor(-math.Log(1-h.rng.Float64()) * mult))
+}
+
+func (h *HNSW) maxLinks(level int) int {
+	if level == 0 {
+		return 2 * h.cfg.M
+	}
+	return h.cfg.M
+}
+
+func (h *HNSW) insert(id string, v []float32) {
+	level := h.randomLevel()
+	idx := int32(len(h.nodes))
+	n := &node{id: id, vec: v, links: make([][]int32, level+1)}
+	h.nodes = append(h.nodes, n)
+	h.byID[id] = idx
+
+	if h.entry < 0 {
+		h.entry = idx
+		h.maxLevel = level
+		return
+	}
+
+	ep := h.entry
+	for l := h.maxLevel; l > level; l-- {
+		ep = h.greedy(v, ep, l)
+	}
+	for l := min(level, h.maxLevel); l >= 0; l-- {
+		cands := h.searchLayer(v, []int32{ep}, h.cfg.EfConstruction, l)
+		neighbors := h.selectNeighbors(v, cands, h.cfg.M)
+		n.links[l] = neighbors
+		for _, nb := range neighbors {
+			h.link(nb, idx, l)
+		}
+		ep = cands[0].idx
+	}
+	if level > h.maxLevel {
+		h.maxLevel = level
+		h.entry = idx
+	}
+}
+
+// link добавляет обратную связь и подрезает список соседей до предела слоя.
+func (h *HNSW) link(from, to int32, level int) {
+	n := h.nodes[from]
+	n.links[level] = append(n.links[level], to)
+	limit := h.maxLinks(level)
+	if len(n.links[level]) <= limit {
+		return
+	}
+	cands := make([]scored, 0, len(n.links[level]))
+	for _, nb := range n.links[level] {
+		cands = append(cands, scored{idx: nb, sim: dot(n.vec, h.nodes[nb].vec)})
+	}
+	sort.Slice(cands, func(i, j int) bool { return cands[i].sim > cands[j].sim })
+	n.links[level] = h.selectNeighbors(n.vec, cands, limit)
+}
+
+// selectNeighbors — эвристика HNSW: кандидат берётся, только если он ближе
+// к вставляемой точке, чем к уже выбранным соседям. Так связи расходятся
+// в разные стороны. Свободные места добиваются ближайшими из отброшенных.
+// cands должны быть отсортированы по убыванию близости.
+func (h *HNSW) selectNeighbors(v []float32, cands []scored, m int) []int32 {
+	out := make([]int32, 0, m)
+	var skipped []int32
+	for _, c := range cands {
+		if len(out) >= m {
+			break
+		}
+		good := true
+		for _, s := range out {
+			if dot(h.nodes[c.idx].vec, h.nodes[s].vec) > c.sim {
+				good = false
+				break
+			}
+		}
+		if good {
+			out = append(out, c.idx)
+		} else {
+			skipped = append(skipped, c.idx)
+		}
+	}
+	for _, s := range skipped {
+		if len(out) >= m {
+			break
+		}
+		out = append(out, s)
+	}
+	return out
+}
+
+// greedy спускается по слою к самой близкой точке.
+func (h *HNSW) greedy(q []float32, ep int32, level int) int32 {
+	best := dot(q, h.nodes[ep].vec)
+	for changed := true; changed; {
+		changed = false
+		for _, nb := range h.nodes[ep].links[level] {
+			if s := dot(q, h.nodes[nb].vec); s > best {
+				best, ep, changed = s, nb, true
+			}
+		}
+	}
+	return ep
+}
+
+// searchLayer — поиск ef ближайших на одном слое. Возвращает кандидатов по
+// убыванию близости, удалённые узлы в том числе: они нужны для обхода графа.
+func (h *HNSW) searchLayer(q []float32, eps []int32, ef, level int) []scored {
+	visited := make(map[int32]struct{}, ef*4)
+	cand := &maxHeap{}
+	res := &minHeap{}
+	for _, ep := range eps {
+		s := scored{idx: ep, sim: dot(q, h.nodes[ep].vec)}
+		visited[ep] = struct{}{}
+		heap.Push(cand, s)
+		heap.Push(res, s)
+	}
+	for cand.Len() > 0 {
+		c := heap.Pop(cand).(scored)
+		if res.Len() >= ef && c.sim < (*res)[0].sim {
+			break
+		}
+		for _, nb := range h.nodes[c.idx].links[level] {
+			if _, ok := visited[nb]; ok {
+				continue
+			}
+			visited[nb] = struct{}{}
+			s := scored{idx: nb, sim: dot(q, h.nodes[nb].vec)}
+			if res.Len() < ef || s.sim > (*res)[0].sim {
+				heap.Push(cand, s)
+				heap.Push(res, s)
+				if res.Len() > ef {
+					heap.Pop(res)
+				}
+			}
+		}
+	}
+	out := make([]scored, res.Len())
+	for i := len(out) - 1; i >= 0; i-- {
+		out[i] = heap.Pop(res).(scored)
+	}
+	return out
+}
+
+// Search возвращает до k ближайших к запросу документов. ef — ширина поиска;
+// 0 — из настроек индекса. Если accept задан, в выдачу попадают только
+// подходящие id, а поиск расширяется, пока их не наберётся k или граф не
+// кончится.
+func (h *HNSW) Search(query []float32, k, ef int, accept func(id string) bool) []Result {
+	q, ok := normalize(query)
+	if !ok || k <= 0 {
+		return nil
+	}
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	if h.entry < 0 || len(q) != h.dim {
+		return nil
+	}
+	if ef <= 0 {
+		ef = h.cfg.EfSearch
+	}
+	ef = max(ef, k)
+
+	ep := h.entry
+	for l := h.maxLevel; l > 0; l-- {
+		ep = h.greedy(q, ep, l)
+	}
+	for {
+		cands := h.searchLayer(q, []int32{ep}, ef, 0)
+		out := make([]Result, 0, k)
+		for _, c := range cands {
+			n := h.nodes[c.idx]
+			if n.deleted || (accept != nil && !accept(n.id)) {
+				continue
+			}
+			out = append(out, Result{ID: n.id, Score: c.sim})
+			if len(out) == k {
+				return out
+			}
+		}
+		if len(cands) < ef || ef >= len(h.nodes) {
+			return out
+		}
+		ef *= 2
+	}
+}
+
+// Exact — точный перебор всех векторов индекса. Нужен для оценки полноты
+// приближённого поиска.
+func (h *HNSW) Exact(query []float32, k int) []Result {
+	q, ok := normalize(query)
+	if !ok || k <= 0 {
+		return nil
+	}
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	if len(q) != h.dim {
+		return nil
+	}
+	out := make([]Result, 0, len(h.byID))
+	for _, n := range h.nodes {
+		if !n.deleted {
+			out = append(out, Result{ID: n.id, Score: dot(q, n.vec)})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Score > out[j].Score })
+	if len(out) > k {
+		out = out[:k]
+	}
+	return out
+}
+
+func normalize(vec []float32) ([]float32, bool) {
+	var norm float64
+	for _, x := range vec {
+		norm += float64(x) * float64(x)
+	}
+	if norm == 0 {
+		return nil, false
+	}
+	inv := float32(1 / math.Sqrt(norm))
+	out := make([]float32, len(vec))
+	for i, x := range vec {
+		out[i] = x * inv
+	}
+	return out, true
+}
+
+func dot(a, b []float32) float32 {
+	var s float32
+	for i := range a {
+		s += a[i] * b[i]
+	}
+	return s
+}
+
+type scored struct {
+	idx int32
+	sim float32
+}
+
+type maxHeap []scored
+
+func (h maxHeap) Len() int           { return len(h) }
+func (h maxHeap) Less(i, j int) bool { return h[i].sim > h[j].sim }
+func (h maxHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *maxHeap) Push(x any)        { *h = append(*h, x.(scored)) }
+func (h *maxHeap) Pop() any {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
+
+type minHeap []scored
+
+func (h minHeap) Len() int           { return len(h) }
+func (h minHeap) Less(i, j int) bool { return h[i].sim < h[j].sim }
+func (h minHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *minHeap) Push(x any)        { *h = append(*h, x.(scored)) }
+func (h *minHeap) Pop() any {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
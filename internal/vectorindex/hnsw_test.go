@@ -0,0 +1,80 @@
+package vectorindex
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+// corpus — n векторов вокруг нескольких центров: так устроены эмбеддинги
+// лора, где тексты одной темы лежат рядом.
+func corpus(rng *rand.Rand, n, dim, clusters int) [][]float32 {
+	centers := make([][]float32, clusters)
+	for i := range centers {
+		centers[i] = randVec(rng, dim, 1)
+	}
+	out := make([][]float32, n)
+	for i := range out {
+		c := centers[rng.Intn(clusters)]
+		v := randVec(rng, dim, 0.3)
+		for j := range v {
+			v[j] += c[j]
+		}
+		out[i] = v
+	}
+	return out
+}
+
+func randVec(rng *rand.Rand, dim int, scale float64) []float32 {
+	v := make([]float32, dim)
+	for i := range v {
+		v[i] = float32(rng.NormFloat64() * scale)
+	}
+	return v
+}
+
+// recall — доля точных k ближайших, которые нашёл приближённый поиск.
+func recall(h *HNSW, queries [][]float32, k int) float64 {
+	var hit, total int
+	for _, q := range queries {
+		want := make(map[string]bool, k)
+		for _, r := range h.Exact(q, k) {
+			want[r.ID] = true
+		}
+		for _, r := range h.Search(q, k, 0, nil) {
+			if want[r.ID] {
+				hit++
+			}
+		}
+		total += len(want)
+	}
+	return float64(hit) / float64(total)
+}
+
+// TestSearchRecall сверяет поиск с настройками по умолчанию (ими пользуется
+// бот) с точным перебором. На этом корпусе полнота около 0.93; порог ниже с
+// запасом, падение под него — поломка графа, а не шум.
+func TestSearchRecall(t *testing.T) {
+	const (
+		n, dim, k = 3000, 64, 10
+		minRecall = 0.9
+	)
+	rng := rand.New(rand.NewSource(1))
+	h := New(DefaultConfig())
+	for i, v := range corpus(rng, n, dim, 20) {
+		h.Add(fmt.Sprintf("doc-%d", i), v)
+	}
+	queries := corpus(rng, 200, dim, 20)
+
+	if r := recall(h, queries, k); r < minRecall {
+		t.Fatalf("recall@%d = %.3f, want >= %.2f", k, r, minRecall)
+	}
+
+	// удалённые узлы остаются в графе проходными и не должны ронять полноту
+	for i := 0; i < n; i += 5 {
+		h.Remove(fmt.Sprintf("doc-%d", i))
+	}
+	if r := recall(h, queries, k); r < minRecall {
+		t.Fatalf("recall@%d after removals = %.3f, want >= %.2f", k, r, minRecall)
+	}
+}
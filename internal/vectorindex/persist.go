@@ -0,0 +1,119 @@
+package vectorindex
+
+import (
+	"bufio"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// formatVersion меняется, когда старые файлы индекса больше нельзя читать.
+const formatVersion = 1
+
+var ErrFormat = errors.New("unsupported index file")
+
+type fileNode struct {
+	ID      string
+	Vec     []float32
+	Links   [][]int32
+	Deleted bool
+}
+
+type fileIndex struct {
+	Version  int
+	Meta     string
+	Config   Config
+	Dim      int
+	Entry    int32
+	MaxLevel int
+	Nodes    []fileNode
+}
+
+// Save пишет индекс в w. meta — произвольная отметка владельца, по которой
+// он потом поймёт, не устарел ли файл.
+func (h *HNSW) Save(w io.Writer, meta string) error {
+	h.mu.RLock()
+	f := fileIndex{
+		Version:  formatVersion,
+		Meta:     meta,
+		Config:   h.cfg,
+		Dim:      h.dim,
+		Entry:    h.entry,
+		MaxLevel: h.maxLevel,
+		Nodes:    make([]fileNode, len(h.nodes)),
+	}
+	for i, n := range h.nodes {
+		f.Nodes[i] = fileNode{ID: n.id, Vec: n.vec, Links: n.links, Deleted: n.deleted}
+	}
+	err := gob.NewEncoder(w).Encode(&f)
+	h.mu.RUnlock()
+	return err
+}
+
+// Load читает индекс, записанный Save, и возвращает его отметку meta.
+func Load(r io.Reader) (*HNSW, string, error) {
+	var f fileIndex
+	if err := gob.NewDecoder(r).Decode(&f); err != nil {
+		return nil, "", err
+	}
+	if f.Version != formatVersion {
+		return nil, "", fmt.Errorf("%w: version %d", ErrFormat, f.Version)
+	}
+	h := New(f.Config)
+	h.dim = f.Dim
+	h.entry = f.Entry
+	h.maxLevel = f.MaxLevel
+	h.nodes = make([]*node, len(f.Nodes))
+	for i, fn := range f.Nodes {
+		if fn.Deleted {
+			h.deleted++
+		} else {
+			h.byID[fn.ID] = int32(i)
+		}
+		h.nodes[i] = &node{id: fn.ID, vec: fn.Vec, links: fn.Links, deleted: fn.Deleted}
+	}
+	if h.entry >= int32(len(h.nodes)) {
+		return nil, "", fmt.Errorf("%w: bad entry point", ErrFormat)
+	}
+	return h, f.Meta, nil
+}
+
+// SaveFile атомарно сохраняет индекс: сначала во временный файл рядом,
+// потом переименованием.
+func (h *HNSW) SaveFile(path, meta string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	w := bufio.NewWriter(tmp)
+	if err := h.Save(w, meta); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// LoadFile читает индекс с диска. Отсутствие файла — os.ErrNotExist.
+func LoadFile(path string) (*HNSW, string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, "", err
+	}
+	defer f.Close()
+	return Load(bufio.NewReader(f))
+}
@@ -0,0 +1,30 @@
+-- Колонка characters.inventory больше не используется: инвентарь хранится в character_items.
+-- Старый текст из неё переносит InventoryService.ImportLegacy при запуске бота
+-- (разбор записей и нормализация названий сделаны в Go, SQLite их не умеет).
+
+CREATE TABLE IF NOT EXISTS items (
+                                     id INTEGER PRIMARY KEY AUTOINCREMENT,
+                                     name TEXT NOT NULL,
+                                     name_key TEXT NOT NULL,
+                                     description TEXT,
+                                     rarity TEXT NOT NULL DEFAULT 'common',
+                                     value INTEGER NOT NULL DEFAULT 0,
+                                     equip_slot TEXT NOT NULL DEFAULT '',
+                                     properties TEXT NOT NULL DEFAULT '{}',
+                                     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_items_name_key ON items(name_key);
+
+CREATE TABLE IF NOT EXISTS character_items (
+                                               id INTEGER PRIMARY KEY AUTOINCREMENT,
+                                               character_id INTEGER NOT NULL,
+                                               item_id INTEGER NOT NULL,
+                                               quantity INTEGER NOT NULL DEFAULT 1,
+                                               is_equipped BOOLEAN NOT NULL DEFAULT 0,
+                                               created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+                                               FOREIGN KEY(character_id) REFERENCES characters(id) ON DELETE CASCADE,
+                                               FOREIGN KEY(item_id) REFERENCES items(id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_character_items_owner ON character_items(character_id, item_id);
@@ -0,0 +1,21 @@
+CREATE TABLE IF NOT EXISTS shop_stock (
+                                          shop_id TEXT NOT NULL,
+                                          item_key TEXT NOT NULL,
+                                          quantity INTEGER NOT NULL DEFAULT 0,
+                                          restocked_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+                                          PRIMARY KEY (shop_id, item_key)
+);
+
+CREATE TABLE IF NOT EXISTS gold_ledger (
+                                           id INTEGER PRIMARY KEY AUTOINCREMENT,
+                                           character_id INTEGER NOT NULL,
+                                           amount INTEGER NOT NULL,
+                                           balance_after INTEGER NOT NULL,
+                                           reason TEXT NOT NULL,
+                                           source TEXT NOT NULL,
+                                           ref_id TEXT NOT NULL DEFAULT '',
+                                           created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+                                           FOREIGN KEY(character_id) REFERENCES characters(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_gold_ledger_char ON gold_ledger(character_id, created_at);
@@ -0,0 +1,7 @@
+-- Стартовые записи журнала: баланс персонажей до введения журнала золота.
+INSERT INTO gold_ledger (character_id, amount, balance_after, reason, source)
+SELECT c.id, c.gold - IFNULL(l.total, 0), c.gold, 'Начальный баланс', 'gm'
+FROM characters c
+LEFT JOIN (SELECT character_id, SUM(amount) AS total FROM gold_ledger GROUP BY character_id) l
+       ON l.character_id = c.id
+WHERE c.gold != IFNULL(l.total, 0);
@@ -0,0 +1,12 @@
+ALTER TABLE characters ADD COLUMN xp INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE characters ADD COLUMN level INTEGER NOT NULL DEFAULT 1;
+
+CREATE TABLE IF NOT EXISTS character_abilities (
+    id           INTEGER PRIMARY KEY AUTOINCREMENT,
+    character_id INTEGER NOT NULL REFERENCES characters(id) ON DELETE CASCADE,
+    name         TEXT NOT NULL,
+    description  TEXT NOT NULL DEFAULT '',
+    source       TEXT NOT NULL,
+    unlocked_at  DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (character_id, name)
+);
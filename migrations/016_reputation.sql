@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS factions (
+    id          INTEGER PRIMARY KEY AUTOINCREMENT,
+    key         TEXT NOT NULL UNIQUE,
+    name        TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT ''
+);
+
+CREATE TABLE IF NOT EXISTS character_reputation (
+    character_id INTEGER NOT NULL REFERENCES characters(id) ON DELETE CASCADE,
+    faction_id   INTEGER NOT NULL REFERENCES factions(id) ON DELETE CASCADE,
+    score        INTEGER NOT NULL DEFAULT 0,
+    updated_at   DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (character_id, faction_id)
+);
+
+ALTER TABLE quests ADD COLUMN faction_id INTEGER REFERENCES factions(id);
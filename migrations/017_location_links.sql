@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS location_links (
+    from_id      INTEGER NOT NULL REFERENCES locations(id) ON DELETE CASCADE,
+    to_id        INTEGER NOT NULL REFERENCES locations(id) ON DELETE CASCADE,
+    travel_hours INTEGER NOT NULL DEFAULT 1,
+    danger       INTEGER NOT NULL DEFAULT 0,
+    encounters   TEXT NOT NULL DEFAULT '[]',
+    PRIMARY KEY (from_id, to_id)
+);
@@ -0,0 +1,4 @@
+-- сцены привязаны к персонажу; без этой колонки GetActiveForCharacter не работал
+ALTER TABLE scenes ADD COLUMN character_id INTEGER REFERENCES characters(id) ON DELETE CASCADE;
+
+CREATE INDEX IF NOT EXISTS idx_scenes_character ON scenes(character_id, is_active);
@@ -0,0 +1 @@
+ALTER TABLE locations ADD COLUMN is_active BOOLEAN NOT NULL DEFAULT 1;
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS world_clock (
+    id         INTEGER PRIMARY KEY CHECK (id = 1),
+    minutes    INTEGER NOT NULL,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- сроки квестов и завоз в лавки считаются в игровых минутах
+ALTER TABLE quests ADD COLUMN deadline_at INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE shop_stock ADD COLUMN restocked_min INTEGER NOT NULL DEFAULT 0;
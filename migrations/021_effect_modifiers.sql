@@ -0,0 +1,4 @@
+ALTER TABLE character_effects ADD COLUMN hp_per_turn INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE character_effects ADD COLUMN power_mod INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE character_effects ADD COLUMN blocked_abilities TEXT NOT NULL DEFAULT '';
+ALTER TABLE character_effects ADD COLUMN stacks INTEGER NOT NULL DEFAULT 1;
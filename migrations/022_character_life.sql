@@ -0,0 +1,11 @@
+-- состояние жизни хранится в status: жив / ранен / при смерти / мёртв
+UPDATE characters SET status = 'жив' WHERE status IS NULL OR status = '';
+
+-- игровая минута, когда умирающий персонаж истечёт кровью
+ALTER TABLE characters ADD COLUMN dying_until INTEGER NOT NULL DEFAULT 0;
+-- архивный персонаж не активен, у игрока может быть новый
+ALTER TABLE characters ADD COLUMN archived_at TIMESTAMP;
+CREATE INDEX IF NOT EXISTS idx_characters_vk_active ON characters(vk_user_id, archived_at);
+
+-- kind = 'resurrection' — квест на воскрешение, доступный мёртвому персонажу
+ALTER TABLE quests ADD COLUMN kind TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,9 @@
+-- у аккаунта VK может быть несколько персонажей; играет он за активного
+CREATE TABLE IF NOT EXISTS player_accounts (
+    vk_user_id          INTEGER PRIMARY KEY,
+    active_character_id INTEGER REFERENCES characters(id),
+    updated_at          TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT OR IGNORE INTO player_accounts (vk_user_id, active_character_id)
+SELECT vk_user_id, MAX(id) FROM characters WHERE archived_at IS NULL GROUP BY vk_user_id;
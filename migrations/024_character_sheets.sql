@@ -0,0 +1,15 @@
+-- анкеты ждут проверки ГМа: pending / approved / rejected / changes / withdrawn
+CREATE TABLE IF NOT EXISTS character_sheets (
+    id           INTEGER PRIMARY KEY AUTOINCREMENT,
+    character_id INTEGER NOT NULL REFERENCES characters(id),
+    vk_user_id   INTEGER NOT NULL,
+    peer_id      INTEGER NOT NULL DEFAULT 0,
+    sheet_json   TEXT NOT NULL,
+    status       TEXT NOT NULL DEFAULT 'pending',
+    comment      TEXT NOT NULL DEFAULT '',
+    created_at   TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    reviewed_at  TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_character_sheets_status ON character_sheets(status, id);
+CREATE INDEX IF NOT EXISTS idx_character_sheets_character ON character_sheets(character_id, id);
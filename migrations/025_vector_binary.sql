@@ -0,0 +1,3 @@
+-- норма вектора считается при записи, чтобы поиск не пересчитывал её для каждой строки.
+-- Сами векторы из JSON в двоичный формат перекодирует приложение при старте (UpgradeVectors).
+ALTER TABLE lore_vectors ADD COLUMN norm REAL NOT NULL DEFAULT 0;
@@ -0,0 +1,3 @@
+-- Фрагменты одного документа ищутся по metadata.parent при расширении контекста
+-- (соседние фрагменты, родительский раздел). Запрос в GetByParent использует то же выражение.
+CREATE INDEX IF NOT EXISTS idx_lore_vectors_parent ON lore_vectors(json_extract(metadata, '$.parent'));
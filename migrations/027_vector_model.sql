@@ -0,0 +1,30 @@
+-- Векторы разных моделей эмбеддингов несравнимы, поэтому у каждой строки записаны
+-- модель и размерность. Один документ может лежать сразу в двух моделях (переход
+-- через cmd/reembed), так что ключ — (id, model). SQLite не меняет первичный ключ
+-- на месте: таблица пересоздаётся. rowid сохраняется, чтобы не разъехался lore_fts.
+-- Всё, что лежало до этой миграции, посчитано text-embedding-004 (768).
+CREATE TABLE lore_vectors_new (
+    id TEXT NOT NULL,
+    model TEXT NOT NULL,
+    dims INTEGER NOT NULL,
+    title TEXT NOT NULL,
+    content TEXT NOT NULL,
+    zone TEXT NOT NULL,
+    tags TEXT NOT NULL,
+    vector BLOB NOT NULL,
+    metadata TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    norm REAL NOT NULL DEFAULT 0,
+    PRIMARY KEY (id, model)
+);
+
+INSERT INTO lore_vectors_new (rowid, id, model, dims, title, content, zone, tags, vector, metadata, created_at, norm)
+SELECT rowid, id, 'text-embedding-004', 768, title, content, zone, tags, vector, metadata, created_at, norm
+FROM lore_vectors;
+
+DROP TABLE lore_vectors;
+ALTER TABLE lore_vectors_new RENAME TO lore_vectors;
+
+CREATE INDEX IF NOT EXISTS idx_lore_vectors_model_zone ON lore_vectors(model, zone);
+CREATE INDEX IF NOT EXISTS idx_lore_vectors_created ON lore_vectors(created_at);
+CREATE INDEX IF NOT EXISTS idx_lore_vectors_parent ON lore_vectors(json_extract(metadata, '$.parent'));
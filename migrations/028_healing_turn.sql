@@ -0,0 +1,4 @@
+-- Лимит лечения предметами считается за ход мира, а не за одно использование:
+-- сколько уже вылечено и в какой ход.
+ALTER TABLE characters ADD COLUMN healed_turn INTEGER NOT NULL DEFAULT -1;
+ALTER TABLE characters ADD COLUMN healed_amount INTEGER NOT NULL DEFAULT 0;
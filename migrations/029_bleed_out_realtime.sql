@@ -0,0 +1,7 @@
+-- dying_until теперь unix-время, а не игровая минута: кровотечение идёт по
+-- реальному времени. Тем, кто уже при смерти, отсчёт начинается заново.
+UPDATE characters
+SET dying_until = CAST(strftime('%s', 'now') AS INTEGER) + 2 * 60 * 60
+WHERE status = 'при смерти';
+
+UPDATE characters SET dying_until = 0 WHERE status <> 'при смерти';
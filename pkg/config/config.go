@@ -12,6 +12,17 @@ const (
 	DefaultGeminiModel = "gemini-2.5-flash"
 	DefaultOpenAIModel = "gpt-4.1"
 	DefaultDBPath      = "aurora.db"
+	// DefaultMinutesPerAction — на сколько игровых минут сдвигает часы одно действие игрока.
+	DefaultMinutesPerAction = 10
+	// DefaultMaxCharacters — сколько персонажей в игре может быть у одного аккаунта VK.
+	DefaultMaxCharacters = 3
+	DefaultVectorBackend = "sqlite"
+	// DefaultEmbeddingProvider — чем считаются эмбеддинги лора: gemini, openai или ollama.
+	DefaultEmbeddingProvider = "gemini"
+	// DefaultEmbeddingRPM — запросов к API эмбеддингов в минуту (лимит Gemini).
+	DefaultEmbeddingRPM = 1500
+	// DefaultEmbeddingWorkers — сколько запросов к API эмбеддингов идёт одновременно.
+	DefaultEmbeddingWorkers = 4
 )
 
 type Config struct {
@@ -24,6 +35,28 @@ type Config struct {
 	LLMModel    string
 	DBPath      string
 	GMUserID    int
+	// WorldTimeRatio — игровых минут за минуту реального времени; 0 — часы идут только от действий и ГМа.
+	WorldTimeRatio   int
+	MinutesPerAction int
+	MaxCharacters    int
+	// VectorIndexPath — файл HNSW-индекса лора; по умолчанию рядом с базой.
+	VectorIndexPath string
+	// VectorEncoding — формат векторов лора в базе: f32, f16 или i8.
+	VectorEncoding string
+	// VectorBackend — где хранятся векторы лора: sqlite или pgvector.
+	VectorBackend string
+	PGVectorDSN   string
+	// EmbeddingRPM и EmbeddingWorkers — темп и параллельность индексации.
+	EmbeddingRPM     int
+	EmbeddingWorkers int
+	// EmbeddingProvider, EmbeddingModel и EmbeddingDims — модель эмбеддингов;
+	// пустая модель и нулевая размерность — значения провайдера по умолчанию.
+	EmbeddingProvider string
+	EmbeddingModel    string
+	EmbeddingDims     int
+	// EmbeddingURL — адрес сервера для openai и ollama, EmbeddingKey — ключ API.
+	EmbeddingURL string
+	EmbeddingKey string
 }
 
 func Load() (*Config, error) {
@@ -51,6 +84,15 @@ func Load() (*Config, error) {
 	if dbPath == "" {
 		dbPath = DefaultDBPath
 	}
+	indexPath := get("VECTOR_INDEX_PATH")
+	if indexPath == "" {
+		indexPath = dbPath + ".hnsw"
+	}
+	vectorBackend := strings.ToLower(strings.TrimSpace(get("VECTOR_BACKEND")))
+	if vectorBackend == "" {
+		vectorBackend = DefaultVectorBackend
+	}
+	pgDSN := get("PG_VECTOR_DSN")
 	gmIDStr := get("GM_USER_ID")
 
 	rpPeerIDStr := get("RP_PEER_ID")
@@ -68,6 +110,16 @@ func Load() (*Config, error) {
 		}
 	}
 
+	switch vectorBackend {
+	case "sqlite":
+	case "pgvector":
+		if pgDSN == "" {
+			return nil, fmt.Errorf("PG_VECTOR_DSN is required when VECTOR_BACKEND=pgvector")
+		}
+	default:
+		return nil, fmt.Errorf("invalid VECTOR_BACKEND: %q", vectorBackend)
+	}
+
 	groupID, err := strconv.Atoi(group)
 	if err != nil {
 		return nil, fmt.Errorf("invalid VK_GROUP_ID: %w", err)
@@ -89,6 +141,60 @@ func Load() (*Config, error) {
 		}
 	}
 
+	timeRatio, err := intEnv(get("WORLD_TIME_RATIO"), 0)
+	if err != nil {
+		return nil, fmt.Errorf("invalid WORLD_TIME_RATIO: %w", err)
+	}
+	perAction, err := intEnv(get("WORLD_MINUTES_PER_ACTION"), DefaultMinutesPerAction)
+	if err != nil {
+		return nil, fmt.Errorf("invalid WORLD_MINUTES_PER_ACTION: %w", err)
+	}
+
+	maxChars, err := intEnv(get("MAX_CHARACTERS"), DefaultMaxCharacters)
+	if err != nil {
+		return nil, fmt.Errorf("invalid MAX_CHARACTERS: %w", err)
+	}
+
+	embeddingRPM, err := intEnv(get("EMBEDDING_RPM"), DefaultEmbeddingRPM)
+	if err != nil {
+		return nil, fmt.Errorf("invalid EMBEDDING_RPM: %w", err)
+	}
+	embeddingWorkers, err := intEnv(get("EMBEDDING_WORKERS"), DefaultEmbeddingWorkers)
+	if err != nil {
+		return nil, fmt.Errorf("invalid EMBEDDING_WORKERS: %w", err)
+	}
+	embeddingDims, err := intEnv(get("EMBEDDING_DIMS"), 0)
+	if err != nil {
+		return nil, fmt.Errorf("invalid EMBEDDING_DIMS: %w", err)
+	}
+
+	embeddingProvider := strings.ToLower(strings.TrimSpace(get("EMBEDDING_PROVIDER")))
+	if embeddingProvider == "" {
+		embeddingProvider = DefaultEmbeddingProvider
+	}
+	embeddingURL := get("EMBEDDING_URL")
+	embeddingKey := get("EMBEDDING_API_KEY")
+	switch embeddingProvider {
+	case "gemini":
+		if embeddingKey == "" {
+			embeddingKey = geminiKey
+		}
+		if embeddingKey == "" {
+			return nil, fmt.Errorf("GEMINI_API_KEY is required when EMBEDDING_PROVIDER=gemini")
+		}
+	case "openai":
+		if embeddingKey == "" {
+			embeddingKey = openAIKey
+		}
+		// локальным совместимым серверам ключ обычно не нужен
+		if embeddingKey == "" && embeddingURL == "" {
+			return nil, fmt.Errorf("EMBEDDING_API_KEY or OPENAI_API_KEY is required when EMBEDDING_PROVIDER=openai")
+		}
+	case "ollama":
+	default:
+		return nil, fmt.Errorf("invalid EMBEDDING_PROVIDER: %q", embeddingProvider)
+	}
+
 	return &Config{
 		VKToken:     vkToken,
 		VKGroupID:   groupID,
@@ -99,5 +205,28 @@ func Load() (*Config, error) {
 		DBPath:      dbPath,
 		GMUserID:    gmID,
 		RPPeerID:    rpPeerID,
+
+		WorldTimeRatio:   timeRatio,
+		MinutesPerAction: perAction,
+		MaxCharacters:    maxChars,
+		VectorIndexPath:  indexPath,
+		VectorEncoding:   get("VECTOR_ENCODING"),
+		VectorBackend:    vectorBackend,
+		PGVectorDSN:      pgDSN,
+		EmbeddingRPM:     embeddingRPM,
+		EmbeddingWorkers: embeddingWorkers,
+
+		EmbeddingProvider: embeddingProvider,
+		EmbeddingModel:    get("EMBEDDING_MODEL"),
+		EmbeddingDims:     embeddingDims,
+		EmbeddingURL:      embeddingURL,
+		EmbeddingKey:      embeddingKey,
 	}, nil
 }
+
+func intEnv(v string, def int) (int, error) {
+	if v == "" {
+		return def, nil
+	}
+	return strconv.Atoi(v)
+}